@@ -2,26 +2,97 @@ package main
 
 import (
 	"database/sql"
+	"github.com/absoluteyl/tasks-go/internal/dialect"
 	"github.com/absoluteyl/tasks-go/internal/handler"
 	"github.com/absoluteyl/tasks-go/internal/middleware"
 	"github.com/absoluteyl/tasks-go/internal/repository"
 	"github.com/absoluteyl/tasks-go/internal/service"
+	"github.com/absoluteyl/tasks-go/pkg/auth"
 	"github.com/codegangsta/negroni"
 	"github.com/go-zoo/bone"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"time"
 
+	_ "github.com/lib/pq"
 	_ "github.com/mattn/go-sqlite3"
 )
 
+// memoryDriver keeps tasks and projects in memory. Everything else lives in
+// an in-memory SQLite database, so nothing survives a restart.
+const memoryDriver = "memory"
+
 var dbDriver = os.Getenv("DB_DRIVER")
 var dbPath = os.Getenv("DB_PATH")
 
+var taskTransitions = service.DefaultTaskTransitions
+var autoCompleteParents bool
+var idempotencyKeyTTL = service.DefaultIdempotencyKeyTTL
+
 func main() {
 	db := connectDB(dbDriver, dbPath)
 	defer db.Close()
 
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		err := runMigrate(db, os.Args[2:], os.Stdout)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "set-role" {
+		err := runSetRole(db, os.Args[2:], os.Stdout)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	err := migrateDB(db)
+	if err != nil {
+		log.Fatalf("Error migrating database: %v", err)
+	}
+
+	err = repository.EnsureTaskSearchIndex(db)
+	if err != nil {
+		log.Fatalf("Error setting up task search: %v", err)
+	}
+	if dbDriver == "sqlite3" && !repository.FTSEnabled {
+		log.Print("Task search runs without an FTS5 index; build with -tags sqlite_fts5 to enable it")
+	}
+
+	err = auth.LoadKeySetFromEnv()
+	if err != nil {
+		log.Fatalf("Error loading signing keys: %v", err)
+	}
+
+	if value := os.Getenv("TASK_STATUS_TRANSITIONS"); value != "" {
+		taskTransitions, err = service.ParseTaskTransitions(value)
+		if err != nil {
+			log.Fatalf("Error parsing TASK_STATUS_TRANSITIONS: %v", err)
+		}
+	}
+
+	if value := os.Getenv("AUTO_COMPLETE_PARENT_TASKS"); value != "" {
+		autoCompleteParents, err = strconv.ParseBool(value)
+		if err != nil {
+			log.Fatalf("Error parsing AUTO_COMPLETE_PARENT_TASKS: %q is not a boolean", value)
+		}
+	}
+
+	if value := os.Getenv("IDEMPOTENCY_KEY_TTL"); value != "" {
+		idempotencyKeyTTL, err = time.ParseDuration(value)
+		if err != nil || idempotencyKeyTTL <= 0 {
+			log.Fatalf("Error parsing IDEMPOTENCY_KEY_TTL: %q is not a positive duration", value)
+		}
+	}
+
+	go purgeExpiredTokens(newTokenService(db), time.Hour)
+	go purgeExpiredIdempotencyKeys(newIdempotencyService(db), time.Hour)
+
 	mux := setupRouter(db)
 
 	n := negroni.Classic()
@@ -30,23 +101,149 @@ func main() {
 }
 
 func setupRouter(db *sql.DB) *bone.Mux {
-	taskRepository := repository.NewTaskRepository(db)
-	taskService := service.NewTaskServiceWithRepository(taskRepository)
+	taskStore := newTaskStore(dbDriver, db)
+	projectStore := newProjectStore(taskStore, db)
+	taskService := service.NewTaskServiceWithRepository(taskStore)
+	taskService.SetTransitions(taskTransitions)
+	taskService.SetAutoCompleteParents(autoCompleteParents)
+	taskService.SetProjectStore(projectStore)
 	taskHandler := handler.NewTaskHandler(taskService)
+	tagHandler := handler.NewTagHandler(taskService)
+	dependencyHandler := handler.NewDependencyHandler(taskService)
+	projectService := service.NewProjectServiceWithRepository(projectStore, taskService)
+	projectHandler := handler.NewProjectHandler(projectService)
+
+	userRepository := repository.NewUserRepository(db)
+	userService := service.NewUserServiceWithRepository(userRepository)
+	userHandler := handler.NewUserHandler(userService)
+	tokenService := newTokenService(db)
+	authHandler := handler.NewAuthHandler(userService, tokenService)
+	apiKeyRepository := repository.NewAPIKeyRepository(db)
+	apiKeyService := service.NewAPIKeyServiceWithRepositories(apiKeyRepository, userRepository)
+	apiKeyHandler := handler.NewAPIKeyHandler(apiKeyService)
+	jwtMiddleware := middleware.NewJWTMiddleware(tokenService, apiKeyService)
+	idempotencyMiddleware := middleware.NewIdempotencyMiddleware(newIdempotencyService(db))
+
+	// API key routes are left out of idempotency, since stored responses
+	// would keep the plain key around.
+	authorized := func(scope string, h http.HandlerFunc) http.Handler {
+		return jwtMiddleware.Handler(middleware.RequireScope(scope, idempotencyMiddleware.Handler(h)))
+	}
 
 	mux := bone.New()
 
-	mux.Post("/auth", http.HandlerFunc(handler.CreateAuthHandler))
+	mux.Post("/auth", http.HandlerFunc(authHandler.CreateAuthHandler))
+	mux.Post("/auth/refresh", http.HandlerFunc(authHandler.RefreshAuthHandler))
+	mux.Post("/auth/logout", jwtMiddleware.Handler(http.HandlerFunc(authHandler.LogoutAuthHandler)))
+	mux.Get("/.well-known/jwks.json", http.HandlerFunc(handler.GetJWKSHandler))
+	mux.Post("/users", http.HandlerFunc(userHandler.CreateUserHandler))
+	mux.Put("/users/:id/role", authorized(auth.ScopeUsersAdmin, userHandler.UpdateUserRoleHandler))
 
-	mux.Post("/task", middleware.JWTMiddleware(http.HandlerFunc(taskHandler.CreateTaskHandler)))
-	mux.Get("/tasks", middleware.JWTMiddleware(http.HandlerFunc(taskHandler.GetTasksHandler)))
-	mux.Put("/task/:id", middleware.JWTMiddleware(http.HandlerFunc(taskHandler.UpdateTaskHandler)))
-	mux.Delete("/task/:id", middleware.JWTMiddleware(http.HandlerFunc(taskHandler.DeleteTaskHandler)))
+	mux.Post("/api-keys", jwtMiddleware.Handler(http.HandlerFunc(apiKeyHandler.CreateAPIKeyHandler)))
+	mux.Get("/api-keys", jwtMiddleware.Handler(http.HandlerFunc(apiKeyHandler.GetAPIKeysHandler)))
+	mux.Delete("/api-keys/:id", jwtMiddleware.Handler(http.HandlerFunc(apiKeyHandler.DeleteAPIKeyHandler)))
+
+	mux.Post("/task", authorized(auth.ScopeTasksWrite, taskHandler.CreateTaskHandler))
+	mux.Post("/tasks/bulk", authorized(auth.ScopeTasksWrite, taskHandler.BulkTasksHandler))
+	mux.Get("/tasks", authorized(auth.ScopeTasksRead, taskHandler.GetTasksHandler))
+	mux.Get("/tasks/search", authorized(auth.ScopeTasksRead, taskHandler.SearchTasksHandler))
+	mux.Get("/task/:id", authorized(auth.ScopeTasksRead, taskHandler.GetTaskHandler))
+	mux.Get("/task/:id/children", authorized(auth.ScopeTasksRead, taskHandler.GetChildTasksHandler))
+	mux.Get("/task/:id/subtree", authorized(auth.ScopeTasksRead, taskHandler.GetTaskSubtreeHandler))
+	mux.Put("/task/:id", authorized(auth.ScopeTasksWrite, taskHandler.UpdateTaskHandler))
+	mux.Patch("/task/:id", authorized(auth.ScopeTasksWrite, taskHandler.PatchTaskHandler))
+	mux.Delete("/task/:id", authorized(auth.ScopeTasksWrite, taskHandler.DeleteTaskHandler))
+
+	mux.Post("/task/:id/dependencies", authorized(auth.ScopeTasksWrite, dependencyHandler.AddDependencyHandler))
+	mux.Delete("/task/:id/dependencies/:dependsOnId", authorized(auth.ScopeTasksWrite, dependencyHandler.RemoveDependencyHandler))
+	mux.Get("/tasks/:id/dependency-graph", authorized(auth.ScopeTasksRead, dependencyHandler.GetDependencyGraphHandler))
+
+	mux.Post("/projects", authorized(auth.ScopeTasksWrite, projectHandler.CreateProjectHandler))
+	mux.Get("/projects", authorized(auth.ScopeTasksRead, projectHandler.GetProjectsHandler))
+	mux.Get("/projects/:id", authorized(auth.ScopeTasksRead, projectHandler.GetProjectHandler))
+	mux.Put("/projects/:id", authorized(auth.ScopeTasksWrite, projectHandler.UpdateProjectHandler))
+	mux.Delete("/projects/:id", authorized(auth.ScopeTasksWrite, projectHandler.DeleteProjectHandler))
+	mux.Post("/projects/:id/archive", authorized(auth.ScopeTasksWrite, projectHandler.ArchiveProjectHandler))
+	mux.Post("/projects/:id/unarchive", authorized(auth.ScopeTasksWrite, projectHandler.UnarchiveProjectHandler))
+	mux.Get("/projects/:id/tasks", authorized(auth.ScopeTasksRead, projectHandler.GetProjectTasksHandler))
+	mux.Post("/projects/:id/tasks", authorized(auth.ScopeTasksWrite, projectHandler.MoveTasksHandler))
+
+	mux.Get("/tags", authorized(auth.ScopeTasksRead, tagHandler.GetTagsHandler))
+	mux.Post("/tags", authorized(auth.ScopeTasksWrite, tagHandler.CreateTagHandler))
+	mux.Put("/tags/:id", authorized(auth.ScopeTasksWrite, tagHandler.UpdateTagHandler))
+	mux.Delete("/tags/:id", authorized(auth.ScopeTasksWrite, tagHandler.DeleteTagHandler))
 
 	return mux
 }
 
+func newTaskStore(dbDriver string, db *sql.DB) repository.TaskStore {
+	if dbDriver == memoryDriver {
+		return repository.NewMemoryTaskStore()
+	}
+	return repository.NewTaskRepository(db)
+}
+
+// newProjectStore keeps projects next to the tasks, as deleting a project
+// looks for tasks in it.
+func newProjectStore(taskStore repository.TaskStore, db *sql.DB) repository.ProjectStore {
+	if memoryTaskStore, ok := taskStore.(*repository.MemoryTaskStore); ok {
+		return repository.NewMemoryProjectStore(memoryTaskStore)
+	}
+	return repository.NewProjectRepository(db)
+}
+
+func newTokenService(db *sql.DB) *service.TokenService {
+	return service.NewTokenServiceWithRepositories(
+		repository.NewUserRepository(db),
+		repository.NewRefreshTokenRepository(db),
+		repository.NewRevokedTokenRepository(db),
+	)
+}
+
+func newIdempotencyService(db *sql.DB) *service.IdempotencyService {
+	idempotencyService := service.NewIdempotencyServiceWithRepository(repository.NewIdempotencyKeyRepository(db))
+	idempotencyService.SetTTL(idempotencyKeyTTL)
+	return idempotencyService
+}
+
+func purgeExpiredTokens(tokenService *service.TokenService, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		err := tokenService.PurgeExpiredTokens()
+		if err != nil {
+			log.Printf("Error purging expired tokens: %v", err)
+		}
+	}
+}
+
+func purgeExpiredIdempotencyKeys(idempotencyService *service.IdempotencyService, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		err := idempotencyService.PurgeExpired()
+		if err != nil {
+			log.Printf("Error purging expired idempotency keys: %v", err)
+		}
+	}
+}
+
 func connectDB(dbDriver string, dbPath string) *sql.DB {
+	if dbDriver == memoryDriver {
+		// Every connection to :memory: opens a separate database.
+		db, err := sql.Open("sqlite3", dialect.SQLiteDSN(":memory:"))
+		if err != nil {
+			log.Fatalf("Error opening database: %v", err)
+		}
+		db.SetMaxOpenConns(1)
+		return db
+	}
+
+	if dbDriver == "sqlite3" {
+		dbPath = dialect.SQLiteDSN(dbPath)
+	}
 	db, err := sql.Open(dbDriver, dbPath)
 	if err != nil {
 		log.Fatalf("Error opening database: %v", err)
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"github.com/absoluteyl/tasks-go/internal/repository"
 	"github.com/stretchr/testify/assert"
 	"os"
 	"testing"
@@ -13,6 +14,7 @@ func TestMain(m *testing.M) {
 
 func TestRouter(t *testing.T) {
 	t.Run("ConnectDB", testConnectDB)
+	t.Run("ConnectMemoryDB", testConnectMemoryDB)
 	t.Run("SetupRouter", testSetupRouter)
 }
 
@@ -27,6 +29,20 @@ func testConnectDB(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func testConnectMemoryDB(t *testing.T) {
+	db := connectDB(memoryDriver, "")
+	defer db.Close()
+
+	err := migrateDB(db)
+	assert.NoError(t, err)
+
+	_, ok := newTaskStore(memoryDriver, db).(*repository.MemoryTaskStore)
+	assert.True(t, ok, "Memory driver does not keep tasks in memory")
+
+	_, ok = newTaskStore("sqlite3", db).(*repository.TaskRepository)
+	assert.True(t, ok, "SQLite driver does not keep tasks in the database")
+}
+
 func testSetupRouter(t *testing.T) {
 	var dbDriver = "sqlite3"
 	var dbPath = "../../db/tasks.db"
@@ -37,10 +53,11 @@ func testSetupRouter(t *testing.T) {
 	mux := setupRouter(db)
 
 	expectedRoutes := map[string][]string{
-		"POST":   {"/auth", "/task"},
-		"GET":    {"/tasks"},
-		"PUT":    {"/task/:id"},
-		"DELETE": {"/task/:id"},
+		"POST":   {"/auth", "/auth/refresh", "/auth/logout", "/users", "/api-keys", "/task", "/tasks/bulk", "/task/:id/dependencies", "/projects", "/projects/:id/archive", "/projects/:id/unarchive", "/projects/:id/tasks", "/tags"},
+		"GET":    {"/.well-known/jwks.json", "/api-keys", "/tasks", "/tasks/search", "/task/:id", "/task/:id/children", "/task/:id/subtree", "/tasks/:id/dependency-graph", "/projects", "/projects/:id", "/projects/:id/tasks", "/tags"},
+		"PUT":    {"/users/:id/role", "/task/:id", "/projects/:id", "/tags/:id"},
+		"PATCH":  {"/task/:id"},
+		"DELETE": {"/api-keys/:id", "/task/:id", "/task/:id/dependencies/:dependsOnId", "/projects/:id", "/tags/:id"},
 	}
 
 	for method, routes := range expectedRoutes {
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/absoluteyl/tasks-go/internal/migration"
+	"io"
+	"text/tabwriter"
+)
+
+var errMigrateUsage = errors.New("usage: tasks migrate up|down|status")
+
+func migrateDB(db *sql.DB) error {
+	migrator, err := migration.NewMigrator(db)
+	if err != nil {
+		return err
+	}
+
+	_, err = migrator.Up()
+	return err
+}
+
+func runMigrate(db *sql.DB, args []string, out io.Writer) error {
+	if len(args) != 1 {
+		return errMigrateUsage
+	}
+
+	migrator, err := migration.NewMigrator(db)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up()
+		for _, m := range applied {
+			fmt.Fprintf(out, "applied %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Fprintln(out, "no pending migrations")
+		}
+	case "down":
+		reverted, err := migrator.Down()
+		if err != nil {
+			return err
+		}
+		if reverted == nil {
+			fmt.Fprintln(out, "no applied migrations")
+			return nil
+		}
+		fmt.Fprintf(out, "reverted %04d_%s\n", reverted.Version, reverted.Name)
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, s := range statuses {
+			appliedAt := "pending"
+			if s.Applied {
+				appliedAt = s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", s.Version, s.Name, appliedAt)
+		}
+		return w.Flush()
+	default:
+		return errMigrateUsage
+	}
+
+	return nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMigrate(t *testing.T) {
+	t.Run("InvalidArgs", testMigrateInvalidArgs)
+	t.Run("UpDownStatus", testMigrateUpDownStatus)
+}
+
+func testMigrateInvalidArgs(t *testing.T) {
+	db := connectDB("sqlite3", t.TempDir()+"/migrate.db")
+	defer db.Close()
+
+	var out bytes.Buffer
+	assert.Equal(t, errMigrateUsage, runMigrate(db, []string{}, &out))
+	assert.Equal(t, errMigrateUsage, runMigrate(db, []string{"sideways"}, &out))
+}
+
+func testMigrateUpDownStatus(t *testing.T) {
+	db := connectDB("sqlite3", t.TempDir()+"/migrate.db")
+	defer db.Close()
+
+	var out bytes.Buffer
+	err := runMigrate(db, []string{"status"}, &out)
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), "pending")
+
+	out.Reset()
+	err = runMigrate(db, []string{"up"}, &out)
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), "applied 0001_create_tasks_table")
+
+	out.Reset()
+	err = runMigrate(db, []string{"up"}, &out)
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), "no pending migrations")
+
+	out.Reset()
+	err = runMigrate(db, []string{"status"}, &out)
+	assert.NoError(t, err)
+	assert.NotContains(t, out.String(), "pending")
+
+	out.Reset()
+	err = migrateDB(db)
+	assert.NoError(t, err)
+
+	out.Reset()
+	err = runMigrate(db, []string{"down"}, &out)
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), "reverted")
+}
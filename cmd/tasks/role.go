@@ -0,0 +1,43 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/absoluteyl/tasks-go/internal/repository"
+	"github.com/absoluteyl/tasks-go/internal/service"
+	"io"
+)
+
+var errSetRoleUsage = errors.New("usage: tasks set-role <username> admin|member|read-only")
+
+// runSetRole changes the role of a user from the command line, which is how
+// the first admin gets appointed.
+func runSetRole(db *sql.DB, args []string, out io.Writer) error {
+	if len(args) != 2 {
+		return errSetRoleUsage
+	}
+
+	err := migrateDB(db)
+	if err != nil {
+		return err
+	}
+
+	userService := service.NewUserServiceWithRepository(repository.NewUserRepository(db))
+
+	user, err := userService.GetUserByUsername(args[0])
+	if err != nil {
+		return err
+	}
+
+	err = userService.UpdateUserRole(user.ID, args[1])
+	if errors.Is(err, service.ErrInvalidRole) {
+		return errSetRoleUsage
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "%s is now %s\n", user.Username, args[1])
+	return nil
+}
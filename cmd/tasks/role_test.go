@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"github.com/absoluteyl/tasks-go/internal/repository"
+	"github.com/absoluteyl/tasks-go/internal/service"
+	"github.com/absoluteyl/tasks-go/pkg/auth"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSetRole(t *testing.T) {
+	t.Run("InvalidArgs", testSetRoleInvalidArgs)
+	t.Run("UnknownUser", testSetRoleUnknownUser)
+	t.Run("SetRole", testSetRole)
+}
+
+func testSetRoleInvalidArgs(t *testing.T) {
+	db := connectDB("sqlite3", t.TempDir()+"/role.db")
+	defer db.Close()
+
+	var out bytes.Buffer
+	assert.Equal(t, errSetRoleUsage, runSetRole(db, []string{"elsa"}, &out))
+}
+
+func testSetRoleUnknownUser(t *testing.T) {
+	db := connectDB("sqlite3", t.TempDir()+"/role.db")
+	defer db.Close()
+
+	var out bytes.Buffer
+	assert.Equal(t, service.ErrUserNotFound, runSetRole(db, []string{"elsa", auth.RoleAdmin}, &out))
+}
+
+func testSetRole(t *testing.T) {
+	db := connectDB("sqlite3", t.TempDir()+"/role.db")
+	defer db.Close()
+
+	err := migrateDB(db)
+	assert.NoError(t, err)
+
+	userRepository := repository.NewUserRepository(db)
+	userID, err := userRepository.CreateUser("elsa", "hashed-password")
+	assert.NoError(t, err)
+
+	var out bytes.Buffer
+	assert.Equal(t, errSetRoleUsage, runSetRole(db, []string{"elsa", "owner"}, &out))
+
+	err = runSetRole(db, []string{"elsa", auth.RoleAdmin}, &out)
+	assert.NoError(t, err)
+	assert.Equal(t, "elsa is now admin\n", out.String())
+
+	user, err := userRepository.GetUserByID(userID)
+	assert.NoError(t, err)
+	assert.Equal(t, auth.RoleAdmin, user.Role)
+}
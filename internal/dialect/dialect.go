@@ -0,0 +1,59 @@
+package dialect
+
+import (
+	"database/sql"
+	"github.com/lib/pq"
+	"strconv"
+	"strings"
+)
+
+// Dialect is the SQL flavour of a database. Queries are written for SQLite,
+// with ? placeholders, and rewritten for other dialects by Rebind.
+type Dialect string
+
+const (
+	SQLite   Dialect = "sqlite"
+	Postgres Dialect = "postgres"
+)
+
+// Of returns the dialect spoken by the driver of db.
+func Of(db *sql.DB) Dialect {
+	if _, ok := db.Driver().(*pq.Driver); ok {
+		return Postgres
+	}
+	return SQLite
+}
+
+// SQLiteDSN turns on foreign key checks for the SQLite database at path.
+// SQLite leaves them off unless every connection asks for them.
+func SQLiteDSN(path string) string {
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+	return path + separator + "_foreign_keys=on"
+}
+
+// Rebind numbers the ? placeholders of query as $1, $2, ... for Postgres.
+// Question marks inside string literals are left alone.
+func (d Dialect) Rebind(query string) string {
+	if d != Postgres {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	inString := false
+	for _, r := range query {
+		switch {
+		case r == '\'':
+			inString = !inString
+		case r == '?' && !inString:
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
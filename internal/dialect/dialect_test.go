@@ -0,0 +1,43 @@
+package dialect
+
+import (
+	"database/sql"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestDialect(t *testing.T) {
+	t.Run("Of", testOf)
+	t.Run("SQLiteDSN", testSQLiteDSN)
+	t.Run("RebindSQLite", testRebindSQLite)
+	t.Run("RebindPostgres", testRebindPostgres)
+}
+
+func testOf(t *testing.T) {
+	sqliteDB, err := sql.Open("sqlite3", ":memory:")
+	assert.NoError(t, err)
+	defer sqliteDB.Close()
+	assert.Equal(t, SQLite, Of(sqliteDB))
+
+	postgresDB, err := sql.Open("postgres", "postgres://localhost/tasks")
+	assert.NoError(t, err)
+	defer postgresDB.Close()
+	assert.Equal(t, Postgres, Of(postgresDB))
+}
+
+func testSQLiteDSN(t *testing.T) {
+	assert.Equal(t, "db/tasks.db?_foreign_keys=on", SQLiteDSN("db/tasks.db"))
+	assert.Equal(t, "file:tasks.db?mode=ro&_foreign_keys=on", SQLiteDSN("file:tasks.db?mode=ro"))
+}
+
+func testRebindSQLite(t *testing.T) {
+	query := `SELECT id FROM tasks WHERE user_id = ? AND name LIKE ?`
+	assert.Equal(t, query, SQLite.Rebind(query))
+}
+
+func testRebindPostgres(t *testing.T) {
+	query := `SELECT id FROM tasks WHERE user_id = ? AND name LIKE ? ESCAPE '\' AND description <> '?' LIMIT ?`
+	expected := `SELECT id FROM tasks WHERE user_id = $1 AND name LIKE $2 ESCAPE '\' AND description <> '?' LIMIT $3`
+	assert.Equal(t, expected, Postgres.Rebind(query))
+}
@@ -0,0 +1,173 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/absoluteyl/tasks-go/internal/service"
+	"github.com/absoluteyl/tasks-go/pkg/auth"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type APIKeyHandler struct {
+	apiKeyService *service.APIKeyService
+}
+
+func NewAPIKeyHandler(apiKeyService *service.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{apiKeyService: apiKeyService}
+}
+
+func (h *APIKeyHandler) CreateAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	SetContentType(w)
+
+	principal, ok := sessionPrincipal(w, r)
+	if !ok {
+		return
+	}
+
+	var apiKeyData map[string]interface{}
+	err := json.NewDecoder(r.Body).Decode(&apiKeyData)
+	if err != nil {
+		SetErrResponse(w, http.StatusBadRequest, ErrBadRequest)
+		return
+	}
+
+	name, _ := apiKeyData["name"].(string)
+	name = strings.TrimSpace(name)
+	if name == "" {
+		SetErrResponse(w, http.StatusBadRequest, ErrMissingAPIKeyName)
+		return
+	}
+
+	var expiresAt *time.Time
+	if apiKeyData["expires_at"] != nil {
+		value, _ := apiKeyData["expires_at"].(string)
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil || !parsed.After(time.Now()) {
+			SetErrResponse(w, http.StatusBadRequest, ErrInvalidAPIKeyExpiresAt)
+			return
+		}
+		expiresAt = &parsed
+	}
+
+	scopes, ok := stringList(apiKeyData["scopes"])
+	if !ok {
+		SetErrResponse(w, http.StatusBadRequest, ErrInvalidAPIKeyScopes)
+		return
+	}
+
+	apiKey, key, err := h.apiKeyService.CreateAPIKey(principal.UserID, name, scopes, principal.Scopes, expiresAt)
+	if errors.Is(err, service.ErrScopeNotAllowed) {
+		SetErrResponse(w, http.StatusForbidden, ErrAPIKeyScopeNotAllowed)
+		return
+	}
+	if err != nil {
+		SetErrResponse(w, http.StatusInternalServerError, ErrInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	response := map[string]interface{}{
+		"result": map[string]interface{}{
+			"id":           apiKey.ID,
+			"name":         apiKey.Name,
+			"prefix":       apiKey.Prefix,
+			"scopes":       apiKey.Scopes,
+			"expires_at":   apiKey.ExpiresAt,
+			"last_used_at": apiKey.LastUsedAt,
+			"created_at":   apiKey.CreatedAt,
+			"key":          key,
+		},
+	}
+	jsonEncode(w, response)
+}
+
+func (h *APIKeyHandler) GetAPIKeysHandler(w http.ResponseWriter, r *http.Request) {
+	SetContentType(w)
+
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	apiKeys, err := h.apiKeyService.GetAPIKeys(userID)
+	if err != nil {
+		SetErrResponse(w, http.StatusInternalServerError, ErrInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"result": apiKeys,
+	}
+	jsonEncode(w, response)
+}
+
+func (h *APIKeyHandler) DeleteAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	SetContentType(w)
+
+	principal, ok := sessionPrincipal(w, r)
+	if !ok {
+		return
+	}
+	userID := principal.UserID
+
+	id := strings.TrimPrefix(r.URL.Path, "/api-keys/")
+	if id == "" {
+		SetErrResponse(w, http.StatusBadRequest, ErrMissingAPIKeyID)
+		return
+	}
+
+	apiKeyID, err := strconv.Atoi(id)
+	if err != nil {
+		SetErrResponse(w, http.StatusBadRequest, ErrInvalidAPIKeyID)
+		return
+	}
+
+	err = h.apiKeyService.DeleteAPIKey(userID, apiKeyID)
+	if errors.Is(err, service.ErrAPIKeyNotFound) {
+		SetErrResponse(w, http.StatusNotFound, ErrAPIKeyNotFound)
+		return
+	}
+	if err != nil {
+		SetErrResponse(w, http.StatusInternalServerError, ErrInternalServerError)
+		return
+	}
+}
+
+// sessionPrincipal turns away requests authenticated with an API key, so
+// that a leaked key can neither mint keys that outlive it nor revoke the
+// user's other keys.
+func sessionPrincipal(w http.ResponseWriter, r *http.Request) (auth.Principal, bool) {
+	principal, ok := currentPrincipal(w, r)
+	if !ok {
+		return auth.Principal{}, false
+	}
+	if principal.APIKeyID != 0 {
+		SetErrResponse(w, http.StatusForbidden, ErrAPIKeyNotAllowed)
+		return auth.Principal{}, false
+	}
+	return principal, true
+}
+
+func stringList(value interface{}) ([]string, bool) {
+	if value == nil {
+		return nil, true
+	}
+
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil, false
+	}
+
+	list := make([]string, 0, len(items))
+	for _, item := range items {
+		s, ok := item.(string)
+		if !ok {
+			return nil, false
+		}
+		list = append(list, s)
+	}
+	return list, true
+}
@@ -0,0 +1,237 @@
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/absoluteyl/tasks-go/pkg/auth"
+	. "github.com/absoluteyl/tasks-go/pkg/testutils"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+var createdAPIKeyID int
+
+func TestAPIKeyHandler(t *testing.T) {
+	t.Run("CreateMissingName", testCreateAPIKeyMissingName)
+	t.Run("CreateInvalidExpiresAt", testCreateAPIKeyInvalidExpiresAt)
+	t.Run("CreatePastExpiresAt", testCreateAPIKeyPastExpiresAt)
+	t.Run("CreateInvalidScopes", testCreateAPIKeyInvalidScopes)
+	t.Run("CreateScopeNotAllowed", testCreateAPIKeyScopeNotAllowed)
+	t.Run("CreateWithAPIKey", testCreateAPIKeyWithAPIKey)
+	t.Run("Create", testCreateAPIKey)
+	t.Run("GetList", testGetAPIKeys)
+	t.Run("DeleteInvalidID", testDeleteAPIKeyInvalidID)
+	t.Run("DeleteOtherUser", testDeleteAPIKeyOtherUser)
+	t.Run("DeleteWithAPIKey", testDeleteAPIKeyWithAPIKey)
+	t.Run("Delete", testDeleteAPIKey)
+}
+
+func testCreateAPIKeyMissingName(t *testing.T) {
+	req := prepareCreateAPIKeyRequest(t, PrepareJsonBody(t, map[string]interface{}{}))
+
+	rr := httptest.NewRecorder()
+	apiKeyHandler.CreateAPIKeyHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusBadRequest)
+
+	response := ParseResponse(t, rr)
+	ResultShouldBe(t, ErrMissingAPIKeyName, response["result"])
+}
+
+func testCreateAPIKeyInvalidExpiresAt(t *testing.T) {
+	req := prepareCreateAPIKeyRequest(t, PrepareJsonBody(t, map[string]interface{}{
+		"name":       "CI",
+		"expires_at": "tomorrow",
+	}))
+
+	rr := httptest.NewRecorder()
+	apiKeyHandler.CreateAPIKeyHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusBadRequest)
+
+	response := ParseResponse(t, rr)
+	ResultShouldBe(t, ErrInvalidAPIKeyExpiresAt, response["result"])
+}
+
+func testCreateAPIKeyPastExpiresAt(t *testing.T) {
+	req := prepareCreateAPIKeyRequest(t, PrepareJsonBody(t, map[string]interface{}{
+		"name":       "CI",
+		"expires_at": time.Now().Add(-time.Hour).Format(time.RFC3339),
+	}))
+
+	rr := httptest.NewRecorder()
+	apiKeyHandler.CreateAPIKeyHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusBadRequest)
+
+	response := ParseResponse(t, rr)
+	ResultShouldBe(t, ErrInvalidAPIKeyExpiresAt, response["result"])
+}
+
+func testCreateAPIKeyInvalidScopes(t *testing.T) {
+	req := prepareCreateAPIKeyRequest(t, PrepareJsonBody(t, map[string]interface{}{
+		"name":   "CI",
+		"scopes": "tasks:read",
+	}))
+
+	rr := httptest.NewRecorder()
+	apiKeyHandler.CreateAPIKeyHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusBadRequest)
+
+	response := ParseResponse(t, rr)
+	ResultShouldBe(t, ErrInvalidAPIKeyScopes, response["result"])
+}
+
+func testCreateAPIKeyScopeNotAllowed(t *testing.T) {
+	req := prepareCreateAPIKeyRequest(t, PrepareJsonBody(t, map[string]interface{}{
+		"name":   "CI",
+		"scopes": []string{auth.ScopeUsersAdmin},
+	}))
+
+	rr := httptest.NewRecorder()
+	apiKeyHandler.CreateAPIKeyHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusForbidden)
+
+	response := ParseResponse(t, rr)
+	ResultShouldBe(t, ErrAPIKeyScopeNotAllowed, response["result"])
+}
+
+func testCreateAPIKeyWithAPIKey(t *testing.T) {
+	req := withAPIKeyPrincipal(prepareCreateAPIKeyRequest(t, PrepareJsonBody(t, map[string]interface{}{
+		"name": "Forever",
+	})))
+
+	rr := httptest.NewRecorder()
+	apiKeyHandler.CreateAPIKeyHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusForbidden)
+
+	response := ParseResponse(t, rr)
+	ResultShouldBe(t, ErrAPIKeyNotAllowed, response["result"])
+}
+
+func testCreateAPIKey(t *testing.T) {
+	expiresAt := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+	req := prepareCreateAPIKeyRequest(t, PrepareJsonBody(t, map[string]interface{}{
+		"name":       "CI",
+		"scopes":     []string{auth.ScopeTasksRead},
+		"expires_at": expiresAt.Format(time.RFC3339),
+	}))
+
+	rr := httptest.NewRecorder()
+	apiKeyHandler.CreateAPIKeyHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusCreated)
+
+	response := ParseResponse(t, rr)
+	ResultShouldExist(t, response)
+
+	result := response["result"].(map[string]interface{})
+	assert.Equal(t, "CI", result["name"])
+	assert.Equal(t, []interface{}{auth.ScopeTasksRead}, result["scopes"])
+	assert.Equal(t, expiresAt.Format(time.RFC3339), result["expires_at"])
+	assert.NotEmpty(t, result["key"], "Plain key not returned on creation")
+	assert.Contains(t, result["key"], result["prefix"])
+
+	createdAPIKeyID = int(result["id"].(float64))
+}
+
+func testGetAPIKeys(t *testing.T) {
+	req, err := http.NewRequest("GET", "/api-keys", nil)
+	assert.NoError(t, err, "Error creating request")
+	req = WithPrincipal(req, taskOwnerID)
+
+	rr := httptest.NewRecorder()
+	apiKeyHandler.GetAPIKeysHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusOK)
+
+	response := ParseResponse(t, rr)
+	results, ok := response["result"].([]interface{})
+	assert.True(t, ok, "Unexpected result type")
+	assert.Len(t, results, 1)
+
+	result := results[0].(map[string]interface{})
+	assert.Equal(t, "CI", result["name"])
+	assert.NotContains(t, result, "key", "Plain key returned in listing")
+	assert.NotContains(t, result, "key_hash", "Key hash returned in listing")
+}
+
+func testDeleteAPIKeyInvalidID(t *testing.T) {
+	req, err := http.NewRequest("DELETE", "/api-keys/invalid", nil)
+	assert.NoError(t, err, "Error creating request")
+	req = WithPrincipal(req, taskOwnerID)
+
+	rr := httptest.NewRecorder()
+	apiKeyHandler.DeleteAPIKeyHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusBadRequest)
+
+	response := ParseResponse(t, rr)
+	ResultShouldBe(t, ErrInvalidAPIKeyID, response["result"])
+}
+
+func testDeleteAPIKeyOtherUser(t *testing.T) {
+	req := WithPrincipal(prepareDeleteAPIKeyRequest(t, createdAPIKeyID), otherUserID)
+
+	rr := httptest.NewRecorder()
+	apiKeyHandler.DeleteAPIKeyHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusNotFound)
+
+	response := ParseResponse(t, rr)
+	ResultShouldBe(t, ErrAPIKeyNotFound, response["result"])
+}
+
+func testDeleteAPIKeyWithAPIKey(t *testing.T) {
+	req := withAPIKeyPrincipal(prepareDeleteAPIKeyRequest(t, createdAPIKeyID))
+
+	rr := httptest.NewRecorder()
+	apiKeyHandler.DeleteAPIKeyHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusForbidden)
+
+	response := ParseResponse(t, rr)
+	ResultShouldBe(t, ErrAPIKeyNotAllowed, response["result"])
+}
+
+func testDeleteAPIKey(t *testing.T) {
+	req := prepareDeleteAPIKeyRequest(t, createdAPIKeyID)
+
+	rr := httptest.NewRecorder()
+	apiKeyHandler.DeleteAPIKeyHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusOK)
+}
+
+func prepareCreateAPIKeyRequest(t *testing.T, body []byte) *http.Request {
+	req, err := http.NewRequest("POST", "/api-keys", bytes.NewBuffer(body))
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+	return WithPrincipal(req, taskOwnerID)
+}
+
+func prepareDeleteAPIKeyRequest(t *testing.T, id int) *http.Request {
+	req, err := http.NewRequest("DELETE", fmt.Sprintf("/api-keys/%d", id), nil)
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+	return WithPrincipal(req, taskOwnerID)
+}
+
+// withAPIKeyPrincipal authenticates the request as if it carried an API key
+// of the task owner.
+func withAPIKeyPrincipal(req *http.Request) *http.Request {
+	ctx := auth.NewContext(req.Context(), auth.Principal{
+		UserID:   taskOwnerID,
+		APIKeyID: 1,
+		Scopes:   auth.ScopesForRole(auth.RoleMember),
+	})
+	return req.WithContext(ctx)
+}
@@ -1,36 +1,121 @@
 package handler
 
 import (
+	"encoding/json"
+	"errors"
+	"github.com/absoluteyl/tasks-go/internal/service"
 	"github.com/absoluteyl/tasks-go/pkg/auth"
+	"io"
 	"net/http"
-	"os"
-	"time"
 )
 
-var (
-	// FIXME: Get user credentials somewhere else
-	username = os.Getenv("USERNAME")
-	password = os.Getenv("PASSWORD")
-)
+type AuthHandler struct {
+	userService  *service.UserService
+	tokenService *service.TokenService
+}
+
+func NewAuthHandler(userService *service.UserService, tokenService *service.TokenService) *AuthHandler {
+	return &AuthHandler{userService: userService, tokenService: tokenService}
+}
 
-func CreateAuthHandler(w http.ResponseWriter, r *http.Request) {
+func (h *AuthHandler) CreateAuthHandler(w http.ResponseWriter, r *http.Request) {
 	SetContentType(w)
 
-	user, pass, ok := r.BasicAuth()
-	if !ok || user != username || pass != password {
-		w.Header().Set("WWW-Authenticate", `Basic realm="Please enter your username and password"`)
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		setBasicAuthChallenge(w)
 		SetErrResponse(w, http.StatusUnauthorized, ErrUnauthorized)
 		return
 	}
 
-	token, err := auth.GenerateToken(time.Now().Unix())
+	user, err := h.userService.Authenticate(username, password)
+	if errors.Is(err, service.ErrInvalidCredentials) {
+		setBasicAuthChallenge(w)
+		SetErrResponse(w, http.StatusUnauthorized, ErrUnauthorized)
+		return
+	}
+	if err != nil {
+		SetErrResponse(w, http.StatusInternalServerError, ErrInternalServerError)
+		return
+	}
+
+	tokens, err := h.tokenService.IssueTokens(user)
 	if err != nil {
 		SetErrResponse(w, http.StatusInternalServerError, ErrInternalServerError)
 		return
 	}
 
 	response := map[string]interface{}{
-		"result": token,
+		"result": tokens,
 	}
 	jsonEncode(w, response)
 }
+
+func (h *AuthHandler) RefreshAuthHandler(w http.ResponseWriter, r *http.Request) {
+	SetContentType(w)
+
+	var refreshData map[string]interface{}
+	err := json.NewDecoder(r.Body).Decode(&refreshData)
+	if err != nil {
+		SetErrResponse(w, http.StatusBadRequest, ErrBadRequest)
+		return
+	}
+
+	refreshToken, _ := refreshData["refresh_token"].(string)
+	if refreshToken == "" {
+		SetErrResponse(w, http.StatusBadRequest, ErrMissingRefreshToken)
+		return
+	}
+
+	tokens, err := h.tokenService.RefreshTokens(refreshToken)
+	if errors.Is(err, service.ErrInvalidRefreshToken) {
+		SetErrResponse(w, http.StatusUnauthorized, ErrInvalidRefreshToken)
+		return
+	}
+	if err != nil {
+		SetErrResponse(w, http.StatusInternalServerError, ErrInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"result": tokens,
+	}
+	jsonEncode(w, response)
+}
+
+func (h *AuthHandler) LogoutAuthHandler(w http.ResponseWriter, r *http.Request) {
+	SetContentType(w)
+
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		SetErrResponse(w, http.StatusUnauthorized, ErrUnauthorized)
+		return
+	}
+
+	var logoutData map[string]interface{}
+	err := json.NewDecoder(r.Body).Decode(&logoutData)
+	if err != nil && !errors.Is(err, io.EOF) {
+		SetErrResponse(w, http.StatusBadRequest, ErrBadRequest)
+		return
+	}
+
+	if principal.TokenID != "" {
+		err = h.tokenService.RevokeToken(principal.TokenID, principal.ExpiresAt)
+		if err != nil {
+			SetErrResponse(w, http.StatusInternalServerError, ErrInternalServerError)
+			return
+		}
+	}
+
+	if refreshToken, _ := logoutData["refresh_token"].(string); refreshToken != "" {
+		err = h.tokenService.RevokeRefreshToken(principal.UserID, refreshToken)
+		if err != nil {
+			SetErrResponse(w, http.StatusInternalServerError, ErrInternalServerError)
+			return
+		}
+	}
+}
+
+func setBasicAuthChallenge(w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", `Basic realm="Please enter your username and password"`)
+}
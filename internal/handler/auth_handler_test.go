@@ -1,25 +1,72 @@
 package handler
 
 import (
+	"bytes"
+	"github.com/absoluteyl/tasks-go/pkg/auth"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
 	. "github.com/absoluteyl/tasks-go/pkg/testutils"
 )
 
+const (
+	authUsername = "username"
+	authPassword = "password"
+)
+
 func TestAuthHandler(t *testing.T) {
+	_, err := userService.CreateUser(authUsername, authPassword)
+	assert.NoError(t, err)
+
+	t.Run("Create", testCreateAuthWithoutCredentials)
 	t.Run("Create", testCreateAuthWithInvalidCredentials)
+	t.Run("Create", testCreateAuthWithUnknownUser)
 	t.Run("Create", testCreateAuth)
+
+	t.Run("RefreshMissingToken", testRefreshAuthMissingToken)
+	t.Run("RefreshInvalidToken", testRefreshAuthInvalidToken)
+	t.Run("Refresh", testRefreshAuth)
+	t.Run("RefreshReusedToken", testRefreshAuthReusedToken)
+
+	t.Run("LogoutUnauthenticated", testLogoutAuthUnauthenticated)
+	t.Run("Logout", testLogoutAuth)
+}
+
+var issuedRefreshToken string
+var rotatedRefreshToken string
+
+func testCreateAuthWithoutCredentials(t *testing.T) {
+	req, err := http.NewRequest("POST", "/auth", nil)
+	assert.NoError(t, err, "Error creating request")
+
+	rr := httptest.NewRecorder()
+	authHandler.CreateAuthHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusUnauthorized)
+	assert.NotEmpty(t, rr.Header().Get("WWW-Authenticate"))
 }
 
 func testCreateAuthWithInvalidCredentials(t *testing.T) {
-	req := prepareCreateAuthRequest(t, "invalid", "invalid")
+	req := prepareCreateAuthRequest(t, authUsername, "invalid")
+
+	rr := httptest.NewRecorder()
+	authHandler.CreateAuthHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusUnauthorized)
+
+	response := ParseResponse(t, rr)
+	ResultShouldBe(t, "Unauthorized", response["result"])
+}
+
+func testCreateAuthWithUnknownUser(t *testing.T) {
+	req := prepareCreateAuthRequest(t, "invalid", authPassword)
 
 	rr := httptest.NewRecorder()
-	CreateAuthHandler(rr, req)
+	authHandler.CreateAuthHandler(rr, req)
 
 	HttpStatusShouldBe(t, rr, http.StatusUnauthorized)
 
@@ -28,18 +75,83 @@ func testCreateAuthWithInvalidCredentials(t *testing.T) {
 }
 
 func testCreateAuth(t *testing.T) {
-	req := prepareCreateAuthRequest(t, username, password)
+	req := prepareCreateAuthRequest(t, authUsername, authPassword)
 
 	rr := httptest.NewRecorder()
-	CreateAuthHandler(rr, req)
+	authHandler.CreateAuthHandler(rr, req)
 
 	HttpStatusShouldBe(t, rr, http.StatusOK)
 
 	response := ParseResponse(t, rr)
 	result, ok := ResultShouldExist(t, response)
 
-	_, ok = result.(string)
-	assert.True(t, ok, "Result field is not a string")
+	tokens, ok := result.(map[string]interface{})
+	assert.True(t, ok, "Result field is not an object")
+	assert.NotEmpty(t, tokens["access_token"], "Missing access token")
+	assert.Equal(t, "Bearer", tokens["token_type"])
+	assert.NotZero(t, tokens["expires_in"], "Missing access token lifetime")
+	assert.NotEmpty(t, tokens["refresh_token"], "Missing refresh token")
+
+	issuedRefreshToken = tokens["refresh_token"].(string)
+}
+
+func testRefreshAuthMissingToken(t *testing.T) {
+	req := prepareRefreshAuthRequest(t, PrepareJsonBody(t, map[string]interface{}{}))
+
+	rr := httptest.NewRecorder()
+	authHandler.RefreshAuthHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusBadRequest)
+
+	response := ParseResponse(t, rr)
+	ResultShouldBe(t, ErrMissingRefreshToken, response["result"])
+}
+
+func testRefreshAuthInvalidToken(t *testing.T) {
+	req := prepareRefreshAuthRequest(t, PrepareJsonBody(t, map[string]interface{}{
+		"refresh_token": "invalid",
+	}))
+
+	rr := httptest.NewRecorder()
+	authHandler.RefreshAuthHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusUnauthorized)
+
+	response := ParseResponse(t, rr)
+	ResultShouldBe(t, ErrInvalidRefreshToken, response["result"])
+}
+
+func testRefreshAuth(t *testing.T) {
+	req := prepareRefreshAuthRequest(t, PrepareJsonBody(t, map[string]interface{}{
+		"refresh_token": issuedRefreshToken,
+	}))
+
+	rr := httptest.NewRecorder()
+	authHandler.RefreshAuthHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusOK)
+
+	response := ParseResponse(t, rr)
+	result, _ := ResultShouldExist(t, response)
+
+	tokens, ok := result.(map[string]interface{})
+	assert.True(t, ok, "Result field is not an object")
+	assert.NotEmpty(t, tokens["access_token"], "Missing access token")
+	assert.NotEmpty(t, tokens["refresh_token"], "Missing refresh token")
+	assert.NotEqual(t, issuedRefreshToken, tokens["refresh_token"], "Refresh token was not rotated")
+
+	rotatedRefreshToken = tokens["refresh_token"].(string)
+}
+
+func testRefreshAuthReusedToken(t *testing.T) {
+	req := prepareRefreshAuthRequest(t, PrepareJsonBody(t, map[string]interface{}{
+		"refresh_token": issuedRefreshToken,
+	}))
+
+	rr := httptest.NewRecorder()
+	authHandler.RefreshAuthHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusUnauthorized)
 }
 
 func prepareCreateAuthRequest(t *testing.T, user string, pass string) *http.Request {
@@ -50,3 +162,47 @@ func prepareCreateAuthRequest(t *testing.T, user string, pass string) *http.Requ
 	req.SetBasicAuth(user, pass)
 	return req
 }
+
+func prepareRefreshAuthRequest(t *testing.T, body []byte) *http.Request {
+	req, err := http.NewRequest("POST", "/auth/refresh", bytes.NewBuffer(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return req
+}
+
+func testLogoutAuthUnauthenticated(t *testing.T) {
+	req, err := http.NewRequest("POST", "/auth/logout", nil)
+	assert.NoError(t, err, "Error creating request")
+
+	rr := httptest.NewRecorder()
+	authHandler.LogoutAuthHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusUnauthorized)
+}
+
+func testLogoutAuth(t *testing.T) {
+	principal := auth.Principal{
+		UserID:    1,
+		TokenID:   "logout-jti",
+		ExpiresAt: time.Now().Add(time.Minute),
+	}
+	reqBody := PrepareJsonBody(t, map[string]interface{}{
+		"refresh_token": rotatedRefreshToken,
+	})
+	req, err := http.NewRequest("POST", "/auth/logout", bytes.NewBuffer(reqBody))
+	assert.NoError(t, err, "Error creating request")
+	req = req.WithContext(auth.NewContext(req.Context(), principal))
+
+	rr := httptest.NewRecorder()
+	authHandler.LogoutAuthHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusOK)
+
+	revoked, err := tokenService.IsTokenRevoked(principal.TokenID)
+	assert.NoError(t, err)
+	assert.True(t, revoked, "Access token was not revoked")
+
+	_, err = tokenService.RefreshTokens(rotatedRefreshToken)
+	assert.Error(t, err, "Refresh token was not revoked")
+}
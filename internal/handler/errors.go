@@ -6,7 +6,89 @@ const (
 	ErrInvalidTaskID       = "Invalid attribute: id"
 	ErrNotAllowTaskID      = "Not allowed attribute: id"
 	ErrMissingTaskName     = "Missing attribute: name"
+	ErrInvalidTaskName     = "Invalid attribute: name must be a non-empty string"
+	ErrInvalidTaskDesc     = "Invalid attribute: description must be a string"
+	ErrInvalidTaskStatus   = "Invalid attribute: status must be one of todo, in_progress, blocked, done, cancelled"
+	ErrInvalidTaskPriority = "Invalid attribute: priority must be an integer between 0 and 3"
+	ErrInvalidTaskDueAt    = "Invalid attribute: due_at must be an RFC 3339 timestamp"
+	ErrInvalidTaskTags     = "Invalid attribute: tags must be a list of tag names of at most 50 characters without commas"
+	ErrInvalidTaskParent   = "Invalid attribute: parent_id must be a task id or null"
+	ErrInvalidTaskProject  = "Invalid attribute: project_id must be a project id or null"
+	ErrInvalidRecurrence   = "Invalid attribute: recurrence must be an RRULE with FREQ of DAILY, WEEKLY or MONTHLY and optional INTERVAL, BYDAY, UNTIL or COUNT"
+	ErrNotAllowTaskTimes   = "Not allowed attribute: created_at, updated_at and completed_at are read-only"
+	ErrNotAllowComputed    = "Not allowed attribute: progress and blocked are computed by the server"
+	ErrInvalidTransition   = "Invalid status transition"
+	ErrInvalidLimit        = "Invalid parameter: limit must be a positive integer"
+	ErrInvalidCursor       = "Invalid parameter: cursor"
+	ErrInvalidStatusFilter = "Invalid parameter: status must list statuses among todo, in_progress, blocked, done, cancelled"
+	ErrInvalidDateFilter   = "Invalid parameter: created_after, created_before, due_after and due_before must be RFC 3339 timestamps"
+	ErrInvalidTagFilter    = "Invalid parameter: tag must list tag names separated by commas"
+	ErrInvalidSort         = "Invalid parameter: sort must list fields among id, name, status, priority, due_at, created_at, updated_at, completed_at, prefixed with - for descending order"
+	ErrInvalidSearchQuery  = "Invalid parameter: q must contain at least one word"
 	ErrTaskNotFound        = "Task not found"
+	ErrParentTaskNotFound  = "Parent task not found"
+	ErrTaskCycle           = "Invalid attribute: parent_id must not be the task itself or one of its subtasks"
+	ErrTaskModified        = "Task has been modified since it was fetched"
+	ErrUnsupportedPatch    = "Unsupported patch format: use application/merge-patch+json or application/json-patch+json"
+	ErrInvalidPatch        = "Invalid patch document"
+	ErrPatchTestFailed     = "Patch test operation failed"
+	ErrPatchNotApplicable  = "Patch cannot be applied to the task"
+	ErrPatchReadOnly       = "Not allowed patch: id, created_at, updated_at, completed_at, version, progress and blocked are read-only"
+
 	ErrInternalServerError = "Internal Server Error"
 	ErrUnauthorized        = "Unauthorized"
+	ErrMissingUsername     = "Missing attribute: username"
+	ErrMissingPassword     = "Missing attribute: password"
+	ErrPasswordTooShort    = "Invalid attribute: password must be at least 8 characters"
+	ErrPasswordTooLong     = "Invalid attribute: password must be at most 72 bytes"
+	ErrUserExists          = "User already exists"
+	ErrMissingRefreshToken = "Missing attribute: refresh_token"
+	ErrInvalidRefreshToken = "Invalid or expired refresh token"
+	ErrMissingUserID       = "Missing attribute: id"
+	ErrInvalidUserID       = "Invalid attribute: id"
+	ErrMissingRole         = "Missing attribute: role"
+	ErrInvalidRole         = "Invalid attribute: role must be one of admin, member, read-only"
+	ErrUserNotFound        = "User not found"
+
+	ErrMissingAPIKeyID        = "Missing attribute: id"
+	ErrInvalidAPIKeyID        = "Invalid attribute: id"
+	ErrMissingAPIKeyName      = "Missing attribute: name"
+	ErrInvalidAPIKeyExpiresAt = "Invalid attribute: expires_at must be a future RFC 3339 timestamp"
+	ErrAPIKeyNotFound         = "API key not found"
+	ErrInvalidAPIKeyScopes    = "Invalid attribute: scopes must be a list of scopes"
+	ErrAPIKeyScopeNotAllowed  = "Not allowed attribute: scopes exceed the scopes of the current credentials"
+	ErrAPIKeyNotAllowed       = "API keys can only be managed with a session token"
+
+	ErrMissingTagID   = "Missing attribute: id"
+	ErrInvalidTagID   = "Invalid attribute: id"
+	ErrInvalidTagName = "Invalid attribute: name must be a non-empty string of at most 50 characters without commas"
+	ErrTagNotFound    = "Tag not found"
+	ErrTagExists      = "Tag already exists"
+
+	ErrMissingProjectID      = "Missing attribute: id"
+	ErrInvalidProjectID      = "Invalid attribute: id"
+	ErrMissingProjectName    = "Missing attribute: name"
+	ErrInvalidProjectName    = "Invalid attribute: name must be a non-empty string"
+	ErrInvalidProjectDesc    = "Invalid attribute: description must be a string"
+	ErrInvalidArchivedFilter = "Invalid parameter: include_archived must be a boolean"
+	ErrMissingTaskIDs        = "Missing attribute: task_ids"
+	ErrInvalidTaskIDs        = "Invalid attribute: task_ids must be a non-empty list of task ids"
+	ErrTooManyTaskIDs        = "Invalid attribute: task_ids must not list more than 100 tasks"
+	ErrProjectNotFound       = "Project not found"
+	ErrProjectArchived       = "Project is archived"
+	ErrProjectNotEmpty       = "Project still has tasks: move or delete them first"
+
+	ErrMissingDependencyID    = "Missing attribute: depends_on_id"
+	ErrInvalidDependencyID    = "Invalid attribute: depends_on_id must be a task id"
+	ErrDependencyTaskNotFound = "Dependency task not found"
+	ErrDependencyCycle        = "Invalid attribute: depends_on_id must not be the task itself or a task waiting for it"
+	ErrDependencyExists       = "Dependency already exists"
+	ErrDependencyNotFound     = "Dependency not found"
+	ErrInvalidGraphFormat     = "Invalid parameter: format must be json or dot"
+
+	ErrInvalidBulkMode       = "Invalid attribute: mode must be all_or_nothing or best_effort"
+	ErrMissingBulkOperations = "Missing attribute: operations"
+	ErrInvalidBulkOperation  = "Invalid attribute: operations must be create operations with a task, update operations with an id and a task, or delete operations with an id"
+	ErrTooManyBulkOperations = "Invalid attribute: operations must not list more than 100 operations"
+	ErrBulkRolledBack        = "Rolled back because another operation failed"
 )
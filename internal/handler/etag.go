@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"github.com/absoluteyl/tasks-go/internal/model"
+	"strconv"
+	"strings"
+)
+
+// taskETag derives the entity tag from the task version, which changes with
+// every update.
+func taskETag(task model.Task) string {
+	return `"` + strconv.Itoa(task.Version) + `"`
+}
+
+// ifNoneMatch reports whether an If-None-Match header value lists etag,
+// using the weak comparison RFC 9110 prescribes for that header.
+func ifNoneMatch(header string, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// ifMatch reports whether an If-Match header value lists etag. Weak tags
+// never match, as If-Match requires the strong comparison.
+func ifMatch(header string, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -2,6 +2,7 @@ package handler
 
 import (
 	"encoding/json"
+	"github.com/absoluteyl/tasks-go/pkg/auth"
 	"net/http"
 )
 
@@ -23,3 +24,17 @@ func jsonEncode(w http.ResponseWriter, response map[string]interface{}) {
 		w.WriteHeader(http.StatusInternalServerError)
 	}
 }
+
+func currentPrincipal(w http.ResponseWriter, r *http.Request) (auth.Principal, bool) {
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		SetErrResponse(w, http.StatusUnauthorized, ErrUnauthorized)
+		return auth.Principal{}, false
+	}
+	return principal, true
+}
+
+func currentUserID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	principal, ok := currentPrincipal(w, r)
+	return principal.UserID, ok
+}
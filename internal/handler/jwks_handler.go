@@ -0,0 +1,19 @@
+package handler
+
+import (
+	"encoding/json"
+	"github.com/absoluteyl/tasks-go/pkg/auth"
+	"net/http"
+)
+
+// GetJWKSHandler publishes the token verification keys in the JSON Web Key
+// Set format, so it is not wrapped in the usual result envelope.
+func GetJWKSHandler(w http.ResponseWriter, r *http.Request) {
+	SetContentType(w)
+	w.Header().Set("Cache-Control", "public, max-age=300")
+
+	err := json.NewEncoder(w).Encode(auth.CurrentKeySet().JWKS())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"github.com/absoluteyl/tasks-go/pkg/auth"
+	. "github.com/absoluteyl/tasks-go/pkg/testutils"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestJWKSHandler(t *testing.T) {
+	t.Run("GetWithSharedSecret", testGetJWKSWithSharedSecret)
+	t.Run("Get", testGetJWKS)
+}
+
+func testGetJWKSWithSharedSecret(t *testing.T) {
+	jwks := requestJWKS(t)
+	assert.Empty(t, jwks.Keys, "Shared secret is published")
+}
+
+func testGetJWKS(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	previous := auth.CurrentKeySet()
+	defer auth.UseKeySet(previous)
+	auth.UseKeySet(auth.NewKeySet(auth.SigningKey{
+		ID:     "key-1",
+		Method: jwt.SigningMethodES256,
+		Key:    key,
+	}))
+
+	jwks := requestJWKS(t)
+	assert.Len(t, jwks.Keys, 1)
+	assert.Equal(t, "key-1", jwks.Keys[0].Kid)
+	assert.Equal(t, "EC", jwks.Keys[0].Kty)
+}
+
+func requestJWKS(t *testing.T) auth.JWKS {
+	req, err := http.NewRequest("GET", "/.well-known/jwks.json", nil)
+	assert.NoError(t, err, "Error creating request")
+
+	rr := httptest.NewRecorder()
+	GetJWKSHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusOK)
+
+	var jwks auth.JWKS
+	err = json.Unmarshal(rr.Body.Bytes(), &jwks)
+	assert.NoError(t, err, "Error unmarshaling JWKS response")
+	return jwks
+}
@@ -0,0 +1,345 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/absoluteyl/tasks-go/internal/model"
+	"github.com/absoluteyl/tasks-go/internal/service"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ProjectService is what ProjectHandler needs from the project service,
+// implemented by *service.ProjectService.
+type ProjectService interface {
+	CreateProject(project *model.Project) (int, error)
+	GetProjects(userID int, includeArchived bool) ([]model.Project, error)
+	GetProjectByID(userID int, id int) (model.Project, error)
+	UpdateProject(userID int, id int, name string, description string) (model.Project, error)
+	ArchiveProject(userID int, id int) (model.Project, error)
+	UnarchiveProject(userID int, id int) (model.Project, error)
+	DeleteProject(userID int, id int) error
+	GetProjectTasks(userID int, id int, query model.TaskQuery, cursor string) ([]model.Task, string, error)
+	MoveTasks(userID int, id int, taskIDs []int) ([]model.Task, error)
+}
+
+type ProjectHandler struct {
+	projectService ProjectService
+}
+
+func NewProjectHandler(projectService ProjectService) *ProjectHandler {
+	return &ProjectHandler{projectService: projectService}
+}
+
+func (h *ProjectHandler) CreateProjectHandler(w http.ResponseWriter, r *http.Request) {
+	SetContentType(w)
+
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	project := model.Project{UserID: userID}
+	if !projectFromBody(w, r, &project) {
+		return
+	}
+
+	var err error
+	project.ID, err = h.projectService.CreateProject(&project)
+	if err != nil {
+		SetErrResponse(w, http.StatusInternalServerError, ErrInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	response := map[string]interface{}{
+		"result": project,
+	}
+	jsonEncode(w, response)
+}
+
+// GetProjectsHandler lists the active projects, and the archived ones too
+// with include_archived=true.
+func (h *ProjectHandler) GetProjectsHandler(w http.ResponseWriter, r *http.Request) {
+	SetContentType(w)
+
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	includeArchived := false
+	if value := r.URL.Query().Get("include_archived"); value != "" {
+		var err error
+		includeArchived, err = strconv.ParseBool(value)
+		if err != nil {
+			SetErrResponse(w, http.StatusBadRequest, ErrInvalidArchivedFilter)
+			return
+		}
+	}
+
+	projects, err := h.projectService.GetProjects(userID, includeArchived)
+	if err != nil {
+		SetErrResponse(w, http.StatusInternalServerError, ErrInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"result": projects,
+	}
+	jsonEncode(w, response)
+}
+
+func (h *ProjectHandler) GetProjectHandler(w http.ResponseWriter, r *http.Request) {
+	projectAction(w, r, h.projectService.GetProjectByID)
+}
+
+// UpdateProjectHandler replaces the name and description of a project.
+func (h *ProjectHandler) UpdateProjectHandler(w http.ResponseWriter, r *http.Request) {
+	SetContentType(w)
+
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	projectID, ok := projectIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	var project model.Project
+	if !projectFromBody(w, r, &project) {
+		return
+	}
+
+	updated, err := h.projectService.UpdateProject(userID, projectID, project.Name, project.Description)
+	respondWithProject(w, updated, err)
+}
+
+// ArchiveProjectHandler archives a project, which keeps its tasks but takes
+// no new ones.
+func (h *ProjectHandler) ArchiveProjectHandler(w http.ResponseWriter, r *http.Request) {
+	projectAction(w, r, h.projectService.ArchiveProject)
+}
+
+func (h *ProjectHandler) UnarchiveProjectHandler(w http.ResponseWriter, r *http.Request) {
+	projectAction(w, r, h.projectService.UnarchiveProject)
+}
+
+// DeleteProjectHandler deletes a project once it has no tasks left.
+func (h *ProjectHandler) DeleteProjectHandler(w http.ResponseWriter, r *http.Request) {
+	SetContentType(w)
+
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	projectID, ok := projectIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.projectService.DeleteProject(userID, projectID)
+	if errors.Is(err, service.ErrProjectNotFound) {
+		SetErrResponse(w, http.StatusNotFound, ErrProjectNotFound)
+		return
+	}
+	if errors.Is(err, service.ErrProjectNotEmpty) {
+		SetErrResponse(w, http.StatusConflict, ErrProjectNotEmpty)
+		return
+	}
+	if err != nil {
+		SetErrResponse(w, http.StatusInternalServerError, ErrInternalServerError)
+		return
+	}
+}
+
+// GetProjectTasksHandler lists the tasks of a project, with the paging,
+// filters and sort order of the task list.
+func (h *ProjectHandler) GetProjectTasksHandler(w http.ResponseWriter, r *http.Request) {
+	SetContentType(w)
+
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	projectID, ok := projectIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	listTasks(w, r, func(query model.TaskQuery, cursor string) ([]model.Task, string, error) {
+		return h.projectService.GetProjectTasks(userID, projectID, query, cursor)
+	})
+}
+
+// MoveTasksHandler moves the tasks listed as task_ids into a project, all
+// or none of them.
+func (h *ProjectHandler) MoveTasksHandler(w http.ResponseWriter, r *http.Request) {
+	SetContentType(w)
+
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	projectID, ok := projectIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	var moveData map[string]interface{}
+	err := json.NewDecoder(r.Body).Decode(&moveData)
+	if err != nil {
+		SetErrResponse(w, http.StatusBadRequest, ErrBadRequest)
+		return
+	}
+
+	value, ok := moveData["task_ids"]
+	if !ok {
+		SetErrResponse(w, http.StatusBadRequest, ErrMissingTaskIDs)
+		return
+	}
+	taskIDs, ok := taskIDList(value)
+	if !ok {
+		SetErrResponse(w, http.StatusBadRequest, ErrInvalidTaskIDs)
+		return
+	}
+
+	tasks, err := h.projectService.MoveTasks(userID, projectID, taskIDs)
+	if errors.Is(err, service.ErrProjectNotFound) {
+		SetErrResponse(w, http.StatusNotFound, ErrProjectNotFound)
+		return
+	}
+	if errors.Is(err, service.ErrTaskNotFound) {
+		SetErrResponse(w, http.StatusNotFound, ErrTaskNotFound)
+		return
+	}
+	if errors.Is(err, service.ErrProjectArchived) {
+		SetErrResponse(w, http.StatusUnprocessableEntity, ErrProjectArchived)
+		return
+	}
+	if errors.Is(err, service.ErrTooManyTaskOperations) {
+		SetErrResponse(w, http.StatusBadRequest, ErrTooManyTaskIDs)
+		return
+	}
+	if err != nil {
+		SetErrResponse(w, http.StatusInternalServerError, ErrInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"result": tasks,
+	}
+	jsonEncode(w, response)
+}
+
+// projectAction responds with the project action returns for the project
+// of the path.
+func projectAction(
+	w http.ResponseWriter, r *http.Request,
+	action func(userID int, id int) (model.Project, error),
+) {
+	SetContentType(w)
+
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	projectID, ok := projectIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	project, err := action(userID, projectID)
+	respondWithProject(w, project, err)
+}
+
+func respondWithProject(w http.ResponseWriter, project model.Project, err error) {
+	if errors.Is(err, service.ErrProjectNotFound) {
+		SetErrResponse(w, http.StatusNotFound, ErrProjectNotFound)
+		return
+	}
+	if err != nil {
+		SetErrResponse(w, http.StatusInternalServerError, ErrInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"result": project,
+	}
+	jsonEncode(w, response)
+}
+
+// projectIDFromPath reads the id of /projects/:id and of the paths below it.
+func projectIDFromPath(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, _, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/projects/"), "/")
+	if id == "" {
+		SetErrResponse(w, http.StatusBadRequest, ErrMissingProjectID)
+		return 0, false
+	}
+
+	projectID, err := strconv.Atoi(id)
+	if err != nil {
+		SetErrResponse(w, http.StatusBadRequest, ErrInvalidProjectID)
+		return 0, false
+	}
+
+	return projectID, true
+}
+
+// projectFromBody sets the name and description of project from the
+// request body, leaving the description empty when the body has none.
+func projectFromBody(w http.ResponseWriter, r *http.Request, project *model.Project) bool {
+	var projectData map[string]interface{}
+	err := json.NewDecoder(r.Body).Decode(&projectData)
+	if err != nil {
+		SetErrResponse(w, http.StatusBadRequest, ErrBadRequest)
+		return false
+	}
+
+	value, ok := projectData["name"]
+	if !ok {
+		SetErrResponse(w, http.StatusBadRequest, ErrMissingProjectName)
+		return false
+	}
+	name, _ := value.(string)
+	name = strings.TrimSpace(name)
+	if name == "" {
+		SetErrResponse(w, http.StatusBadRequest, ErrInvalidProjectName)
+		return false
+	}
+
+	value = projectData["description"]
+	description, ok := value.(string)
+	if value != nil && !ok {
+		SetErrResponse(w, http.StatusBadRequest, ErrInvalidProjectDesc)
+		return false
+	}
+
+	project.Name = name
+	project.Description = description
+	return true
+}
+
+func taskIDList(value interface{}) ([]int, bool) {
+	items, ok := value.([]interface{})
+	if !ok || len(items) == 0 {
+		return nil, false
+	}
+
+	ids := make([]int, len(items))
+	for i, item := range items {
+		id, ok := intValue(item)
+		if !ok || id <= 0 {
+			return nil, false
+		}
+		ids[i] = id
+	}
+	return ids, true
+}
@@ -0,0 +1,334 @@
+package handler
+
+import (
+	"fmt"
+	"github.com/absoluteyl/tasks-go/internal/model"
+	"github.com/absoluteyl/tasks-go/internal/repository"
+	"github.com/absoluteyl/tasks-go/internal/service"
+	. "github.com/absoluteyl/tasks-go/pkg/testutils"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// The project tests keep their projects and tasks in memory, so that the
+// ids of the tasks of TestTaskHandler stay predictable.
+const projectOwnerID = 70
+
+var projectTaskService *service.TaskService
+var projectHandler *ProjectHandler
+var projectTaskHandler *TaskHandler
+var projectID int
+
+func TestProjectHandler(t *testing.T) {
+	taskStore := repository.NewMemoryTaskStore()
+	projectStore := repository.NewMemoryProjectStore(taskStore)
+	projectTaskService = service.NewTaskServiceWithRepository(taskStore)
+	projectTaskService.SetProjectStore(projectStore)
+	projectHandler = NewProjectHandler(service.NewProjectServiceWithRepository(projectStore, projectTaskService))
+	projectTaskHandler = NewTaskHandler(projectTaskService)
+
+	t.Run("CreateInvalidAttributes", testCreateProjectInvalidAttributes)
+	t.Run("Create", testCreateProject)
+	t.Run("Get", testGetProject)
+	t.Run("GetNotExist", testGetProjectNotExist)
+	t.Run("Update", testUpdateProject)
+	t.Run("CreateTaskInProject", testCreateTaskInProject)
+	t.Run("CreateTaskInMissingProject", testCreateTaskInMissingProject)
+	t.Run("MoveTasks", testMoveTasks)
+	t.Run("MoveTasksInvalidAttributes", testMoveTasksInvalidAttributes)
+	t.Run("MoveTasksNotExist", testMoveTasksNotExist)
+	t.Run("GetTasks", testGetProjectTasks)
+	t.Run("Archive", testArchiveProject)
+	t.Run("GetListArchived", testGetProjectsArchived)
+	t.Run("MoveTasksArchived", testMoveTasksArchived)
+	t.Run("Unarchive", testUnarchiveProject)
+	t.Run("DeleteNotEmpty", testDeleteProjectNotEmpty)
+	t.Run("Delete", testDeleteProject)
+}
+
+func testCreateProjectInvalidAttributes(t *testing.T) {
+	invalidData := []struct {
+		projectData map[string]interface{}
+		message     string
+	}{
+		{map[string]interface{}{}, ErrMissingProjectName},
+		{map[string]interface{}{"name": " "}, ErrInvalidProjectName},
+		{map[string]interface{}{"name": "Home", "description": 1}, ErrInvalidProjectDesc},
+	}
+
+	for _, data := range invalidData {
+		req := prepareJSONRequest(t, "POST", "/projects", data.projectData, projectOwnerID)
+
+		rr := httptest.NewRecorder()
+		projectHandler.CreateProjectHandler(rr, req)
+
+		HttpStatusShouldBe(t, rr, http.StatusBadRequest)
+		ResultShouldBe(t, data.message, ParseResponse(t, rr)["result"])
+	}
+}
+
+func testCreateProject(t *testing.T) {
+	req := prepareJSONRequest(t, "POST", "/projects", map[string]interface{}{"name": " Household "}, projectOwnerID)
+
+	rr := httptest.NewRecorder()
+	projectHandler.CreateProjectHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusCreated)
+
+	result := ParseResponse(t, rr)["result"].(map[string]interface{})
+	assert.Equal(t, "Household", result["name"])
+	assert.Equal(t, "", result["description"])
+	assert.Nil(t, result["archived_at"])
+	projectID = int(result["id"].(float64))
+}
+
+func testGetProject(t *testing.T) {
+	req := prepareJSONRequest(t, "GET", projectPath(projectID, ""), nil, projectOwnerID)
+
+	rr := httptest.NewRecorder()
+	projectHandler.GetProjectHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusOK)
+
+	result := ParseResponse(t, rr)["result"].(map[string]interface{})
+	assert.Equal(t, float64(projectID), result["id"])
+	assert.Equal(t, "Household", result["name"])
+}
+
+func testGetProjectNotExist(t *testing.T) {
+	req := prepareJSONRequest(t, "GET", projectPath(9999, ""), nil, projectOwnerID)
+
+	rr := httptest.NewRecorder()
+	projectHandler.GetProjectHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusNotFound)
+	ResultShouldBe(t, ErrProjectNotFound, ParseResponse(t, rr)["result"])
+
+	req = prepareJSONRequest(t, "GET", "/projects/home", nil, projectOwnerID)
+
+	rr = httptest.NewRecorder()
+	projectHandler.GetProjectHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusBadRequest)
+	ResultShouldBe(t, ErrInvalidProjectID, ParseResponse(t, rr)["result"])
+}
+
+func testUpdateProject(t *testing.T) {
+	req := prepareJSONRequest(t, "PUT", projectPath(projectID, ""), map[string]interface{}{
+		"name":        "Home",
+		"description": "Chores around the house",
+	}, projectOwnerID)
+
+	rr := httptest.NewRecorder()
+	projectHandler.UpdateProjectHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusOK)
+
+	result := ParseResponse(t, rr)["result"].(map[string]interface{})
+	assert.Equal(t, "Home", result["name"])
+	assert.Equal(t, "Chores around the house", result["description"])
+}
+
+func testCreateTaskInProject(t *testing.T) {
+	req := prepareJSONRequest(t, "POST", "/task", map[string]interface{}{
+		"name":       "Vacuum",
+		"project_id": projectID,
+	}, projectOwnerID)
+
+	rr := httptest.NewRecorder()
+	projectTaskHandler.CreateTaskHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusCreated)
+
+	result := ParseResponse(t, rr)["result"].(map[string]interface{})
+	assert.Equal(t, float64(projectID), result["project_id"])
+}
+
+func testCreateTaskInMissingProject(t *testing.T) {
+	req := prepareJSONRequest(t, "POST", "/task", map[string]interface{}{
+		"name":       "Vacuum",
+		"project_id": 9999,
+	}, projectOwnerID)
+
+	rr := httptest.NewRecorder()
+	projectTaskHandler.CreateTaskHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusUnprocessableEntity)
+	ResultShouldBe(t, ErrProjectNotFound, ParseResponse(t, rr)["result"])
+}
+
+func testMoveTasks(t *testing.T) {
+	var taskIDs []interface{}
+	for _, name := range []string{"Mow lawn", "Water plants"} {
+		id, err := projectTaskService.CreateTask(&model.Task{UserID: projectOwnerID, Name: name})
+		assert.NoError(t, err)
+		taskIDs = append(taskIDs, id)
+	}
+
+	req := prepareJSONRequest(t, "POST", projectPath(projectID, "/tasks"), map[string]interface{}{
+		"task_ids": taskIDs,
+	}, projectOwnerID)
+
+	rr := httptest.NewRecorder()
+	projectHandler.MoveTasksHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusOK)
+
+	tasks := ParseResponse(t, rr)["result"].([]interface{})
+	assert.Len(t, tasks, 2)
+	for _, task := range tasks {
+		assert.Equal(t, float64(projectID), task.(map[string]interface{})["project_id"])
+	}
+}
+
+func testMoveTasksInvalidAttributes(t *testing.T) {
+	invalidData := []struct {
+		moveData map[string]interface{}
+		message  string
+	}{
+		{map[string]interface{}{}, ErrMissingTaskIDs},
+		{map[string]interface{}{"task_ids": []interface{}{}}, ErrInvalidTaskIDs},
+		{map[string]interface{}{"task_ids": []interface{}{"1"}}, ErrInvalidTaskIDs},
+	}
+
+	for _, data := range invalidData {
+		req := prepareJSONRequest(t, "POST", projectPath(projectID, "/tasks"), data.moveData, projectOwnerID)
+
+		rr := httptest.NewRecorder()
+		projectHandler.MoveTasksHandler(rr, req)
+
+		HttpStatusShouldBe(t, rr, http.StatusBadRequest)
+		ResultShouldBe(t, data.message, ParseResponse(t, rr)["result"])
+	}
+}
+
+func testMoveTasksNotExist(t *testing.T) {
+	req := prepareJSONRequest(t, "POST", projectPath(projectID, "/tasks"), map[string]interface{}{
+		"task_ids": []interface{}{9999},
+	}, projectOwnerID)
+
+	rr := httptest.NewRecorder()
+	projectHandler.MoveTasksHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusNotFound)
+	ResultShouldBe(t, ErrTaskNotFound, ParseResponse(t, rr)["result"])
+}
+
+func testGetProjectTasks(t *testing.T) {
+	req := prepareJSONRequest(t, "GET", projectPath(projectID, "/tasks?limit=2"), nil, projectOwnerID)
+
+	rr := httptest.NewRecorder()
+	projectHandler.GetProjectTasksHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusOK)
+
+	response := ParseResponse(t, rr)
+	tasks := response["result"].([]interface{})
+	assert.Len(t, tasks, 2)
+	assert.Equal(t, "Vacuum", tasks[0].(map[string]interface{})["name"])
+	assert.NotNil(t, response["next_cursor"])
+
+	req = prepareJSONRequest(t, "GET", projectPath(9999, "/tasks"), nil, projectOwnerID)
+
+	rr = httptest.NewRecorder()
+	projectHandler.GetProjectTasksHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusNotFound)
+	ResultShouldBe(t, ErrProjectNotFound, ParseResponse(t, rr)["result"])
+}
+
+func testArchiveProject(t *testing.T) {
+	req := prepareJSONRequest(t, "POST", projectPath(projectID, "/archive"), nil, projectOwnerID)
+
+	rr := httptest.NewRecorder()
+	projectHandler.ArchiveProjectHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusOK)
+
+	result := ParseResponse(t, rr)["result"].(map[string]interface{})
+	assert.NotNil(t, result["archived_at"])
+}
+
+func testGetProjectsArchived(t *testing.T) {
+	for query, count := range map[string]int{"": 0, "?include_archived=true": 1} {
+		req := prepareJSONRequest(t, "GET", "/projects"+query, nil, projectOwnerID)
+
+		rr := httptest.NewRecorder()
+		projectHandler.GetProjectsHandler(rr, req)
+
+		HttpStatusShouldBe(t, rr, http.StatusOK)
+		assert.Len(t, ParseResponse(t, rr)["result"], count)
+	}
+
+	req := prepareJSONRequest(t, "GET", "/projects?include_archived=maybe", nil, projectOwnerID)
+
+	rr := httptest.NewRecorder()
+	projectHandler.GetProjectsHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusBadRequest)
+	ResultShouldBe(t, ErrInvalidArchivedFilter, ParseResponse(t, rr)["result"])
+}
+
+func testMoveTasksArchived(t *testing.T) {
+	id, err := projectTaskService.CreateTask(&model.Task{UserID: projectOwnerID, Name: "Dust"})
+	assert.NoError(t, err)
+
+	req := prepareJSONRequest(t, "POST", projectPath(projectID, "/tasks"), map[string]interface{}{
+		"task_ids": []interface{}{id},
+	}, projectOwnerID)
+
+	rr := httptest.NewRecorder()
+	projectHandler.MoveTasksHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusUnprocessableEntity)
+	ResultShouldBe(t, ErrProjectArchived, ParseResponse(t, rr)["result"])
+}
+
+func testUnarchiveProject(t *testing.T) {
+	req := prepareJSONRequest(t, "POST", projectPath(projectID, "/unarchive"), nil, projectOwnerID)
+
+	rr := httptest.NewRecorder()
+	projectHandler.UnarchiveProjectHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusOK)
+
+	result := ParseResponse(t, rr)["result"].(map[string]interface{})
+	assert.Nil(t, result["archived_at"])
+}
+
+func testDeleteProjectNotEmpty(t *testing.T) {
+	req := prepareJSONRequest(t, "DELETE", projectPath(projectID, ""), nil, projectOwnerID)
+
+	rr := httptest.NewRecorder()
+	projectHandler.DeleteProjectHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusConflict)
+	ResultShouldBe(t, ErrProjectNotEmpty, ParseResponse(t, rr)["result"])
+}
+
+func testDeleteProject(t *testing.T) {
+	tasks, _, err := projectTaskService.GetTasks(projectOwnerID, model.TaskQuery{ProjectID: &projectID}, "")
+	assert.NoError(t, err)
+	for _, task := range tasks {
+		err = projectTaskService.DeleteTask(projectOwnerID, task.ID)
+		assert.NoError(t, err)
+	}
+
+	req := prepareJSONRequest(t, "DELETE", projectPath(projectID, ""), nil, projectOwnerID)
+
+	rr := httptest.NewRecorder()
+	projectHandler.DeleteProjectHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusOK)
+
+	rr = httptest.NewRecorder()
+	projectHandler.GetProjectHandler(rr, prepareJSONRequest(t, "GET", projectPath(projectID, ""), nil, projectOwnerID))
+
+	HttpStatusShouldBe(t, rr, http.StatusNotFound)
+}
+
+func projectPath(id int, path string) string {
+	return fmt.Sprintf("/projects/%d%s", id, path)
+}
@@ -0,0 +1,178 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/absoluteyl/tasks-go/internal/model"
+	"github.com/absoluteyl/tasks-go/internal/service"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// TagService is what TagHandler needs from the task service, implemented by
+// *service.TaskService.
+type TagService interface {
+	GetTags(userID int) ([]model.Tag, error)
+	CreateTag(tag *model.Tag) (int, error)
+	RenameTag(userID int, id int, name string) (model.Tag, error)
+	DeleteTag(userID int, id int) error
+}
+
+type TagHandler struct {
+	tagService TagService
+}
+
+func NewTagHandler(tagService TagService) *TagHandler {
+	return &TagHandler{tagService: tagService}
+}
+
+func (h *TagHandler) GetTagsHandler(w http.ResponseWriter, r *http.Request) {
+	SetContentType(w)
+
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	tags, err := h.tagService.GetTags(userID)
+	if err != nil {
+		SetErrResponse(w, http.StatusInternalServerError, ErrInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"result": tags,
+	}
+	jsonEncode(w, response)
+}
+
+func (h *TagHandler) CreateTagHandler(w http.ResponseWriter, r *http.Request) {
+	SetContentType(w)
+
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	name, ok := tagNameFromBody(w, r)
+	if !ok {
+		return
+	}
+
+	tag := model.Tag{UserID: userID, Name: name}
+	var err error
+	tag.ID, err = h.tagService.CreateTag(&tag)
+	if errors.Is(err, service.ErrTagExists) {
+		SetErrResponse(w, http.StatusConflict, ErrTagExists)
+		return
+	}
+	if err != nil {
+		SetErrResponse(w, http.StatusInternalServerError, ErrInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	response := map[string]interface{}{
+		"result": tag,
+	}
+	jsonEncode(w, response)
+}
+
+// UpdateTagHandler renames a tag, which renames it on every task carrying it.
+func (h *TagHandler) UpdateTagHandler(w http.ResponseWriter, r *http.Request) {
+	SetContentType(w)
+
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	tagID, ok := tagIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	name, ok := tagNameFromBody(w, r)
+	if !ok {
+		return
+	}
+
+	tag, err := h.tagService.RenameTag(userID, tagID, name)
+	if errors.Is(err, service.ErrTagNotFound) {
+		SetErrResponse(w, http.StatusNotFound, ErrTagNotFound)
+		return
+	}
+	if errors.Is(err, service.ErrTagExists) {
+		SetErrResponse(w, http.StatusConflict, ErrTagExists)
+		return
+	}
+	if err != nil {
+		SetErrResponse(w, http.StatusInternalServerError, ErrInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"result": tag,
+	}
+	jsonEncode(w, response)
+}
+
+// DeleteTagHandler deletes a tag and takes it off every task.
+func (h *TagHandler) DeleteTagHandler(w http.ResponseWriter, r *http.Request) {
+	SetContentType(w)
+
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	tagID, ok := tagIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.tagService.DeleteTag(userID, tagID)
+	if errors.Is(err, service.ErrTagNotFound) {
+		SetErrResponse(w, http.StatusNotFound, ErrTagNotFound)
+		return
+	}
+	if err != nil {
+		SetErrResponse(w, http.StatusInternalServerError, ErrInternalServerError)
+		return
+	}
+}
+
+func tagIDFromPath(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id := strings.TrimPrefix(r.URL.Path, "/tags/")
+	if id == "" {
+		SetErrResponse(w, http.StatusBadRequest, ErrMissingTagID)
+		return 0, false
+	}
+
+	tagID, err := strconv.Atoi(id)
+	if err != nil {
+		SetErrResponse(w, http.StatusBadRequest, ErrInvalidTagID)
+		return 0, false
+	}
+
+	return tagID, true
+}
+
+func tagNameFromBody(w http.ResponseWriter, r *http.Request) (string, bool) {
+	var tagData map[string]interface{}
+	err := json.NewDecoder(r.Body).Decode(&tagData)
+	if err != nil {
+		SetErrResponse(w, http.StatusBadRequest, ErrBadRequest)
+		return "", false
+	}
+
+	name, _ := tagData["name"].(string)
+	name = strings.TrimSpace(name)
+	if !model.IsValidTagName(name) {
+		SetErrResponse(w, http.StatusBadRequest, ErrInvalidTagName)
+		return "", false
+	}
+
+	return name, true
+}
@@ -0,0 +1,164 @@
+package handler
+
+import (
+	"fmt"
+	"github.com/absoluteyl/tasks-go/internal/model"
+	. "github.com/absoluteyl/tasks-go/pkg/testutils"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// tagOwnerID keeps the tags of these tests apart from the tasks of
+// TestTaskHandler.
+const tagOwnerID = 40
+
+var createdTagID int
+
+func TestTagHandler(t *testing.T) {
+	t.Run("CreateInvalidName", testCreateTagInvalidName)
+	t.Run("Create", testCreateTag)
+	t.Run("CreateExisting", testCreateTagExisting)
+	t.Run("GetList", testGetTags)
+	t.Run("RenameInvalidID", testRenameTagInvalidID)
+	t.Run("RenameOtherUser", testRenameTagOtherUser)
+	t.Run("RenameExisting", testRenameTagExisting)
+	t.Run("Rename", testRenameTag)
+	t.Run("DeleteOtherUser", testDeleteTagOtherUser)
+	t.Run("Delete", testDeleteTag)
+}
+
+func testCreateTagInvalidName(t *testing.T) {
+	for _, name := range []interface{}{nil, 1, " ", "home,garden", strings.Repeat("x", model.MaxTagNameLength+1)} {
+		req := prepareJSONRequest(t, "POST", "/tags", map[string]interface{}{"name": name}, tagOwnerID)
+
+		rr := httptest.NewRecorder()
+		tagHandler.CreateTagHandler(rr, req)
+
+		HttpStatusShouldBe(t, rr, http.StatusBadRequest)
+
+		response := ParseResponse(t, rr)
+		ResultShouldBe(t, ErrInvalidTagName, response["result"])
+	}
+}
+
+func testCreateTag(t *testing.T) {
+	for _, name := range []string{" infra ", "backend"} {
+		req := prepareJSONRequest(t, "POST", "/tags", map[string]interface{}{"name": name}, tagOwnerID)
+
+		rr := httptest.NewRecorder()
+		tagHandler.CreateTagHandler(rr, req)
+
+		HttpStatusShouldBe(t, rr, http.StatusCreated)
+
+		response := ParseResponse(t, rr)
+		result := response["result"].(map[string]interface{})
+		assert.Equal(t, strings.TrimSpace(name), result["name"])
+		assert.NotEmpty(t, result["created_at"])
+		createdTagID = int(result["id"].(float64))
+	}
+}
+
+func testCreateTagExisting(t *testing.T) {
+	req := prepareJSONRequest(t, "POST", "/tags", map[string]interface{}{"name": "infra"}, tagOwnerID)
+
+	rr := httptest.NewRecorder()
+	tagHandler.CreateTagHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusConflict)
+
+	response := ParseResponse(t, rr)
+	ResultShouldBe(t, ErrTagExists, response["result"])
+}
+
+func testGetTags(t *testing.T) {
+	req := prepareJSONRequest(t, "GET", "/tags", nil, tagOwnerID)
+
+	rr := httptest.NewRecorder()
+	tagHandler.GetTagsHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusOK)
+
+	response := ParseResponse(t, rr)
+	results := response["result"].([]interface{})
+	assert.Len(t, results, 2)
+	assert.Equal(t, "backend", results[0].(map[string]interface{})["name"])
+	assert.Equal(t, "infra", results[1].(map[string]interface{})["name"])
+}
+
+func testRenameTagInvalidID(t *testing.T) {
+	req := prepareJSONRequest(t, "PUT", "/tags/backend", map[string]interface{}{"name": "api"}, tagOwnerID)
+
+	rr := httptest.NewRecorder()
+	tagHandler.UpdateTagHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusBadRequest)
+
+	response := ParseResponse(t, rr)
+	ResultShouldBe(t, ErrInvalidTagID, response["result"])
+}
+
+func testRenameTagOtherUser(t *testing.T) {
+	req := prepareJSONRequest(t, "PUT", fmt.Sprintf("/tags/%d", createdTagID), map[string]interface{}{"name": "api"}, otherUserID)
+
+	rr := httptest.NewRecorder()
+	tagHandler.UpdateTagHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusNotFound)
+
+	response := ParseResponse(t, rr)
+	ResultShouldBe(t, ErrTagNotFound, response["result"])
+}
+
+func testRenameTagExisting(t *testing.T) {
+	req := prepareJSONRequest(t, "PUT", fmt.Sprintf("/tags/%d", createdTagID), map[string]interface{}{"name": "infra"}, tagOwnerID)
+
+	rr := httptest.NewRecorder()
+	tagHandler.UpdateTagHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusConflict)
+
+	response := ParseResponse(t, rr)
+	ResultShouldBe(t, ErrTagExists, response["result"])
+}
+
+func testRenameTag(t *testing.T) {
+	req := prepareJSONRequest(t, "PUT", fmt.Sprintf("/tags/%d", createdTagID), map[string]interface{}{"name": "api"}, tagOwnerID)
+
+	rr := httptest.NewRecorder()
+	tagHandler.UpdateTagHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusOK)
+
+	response := ParseResponse(t, rr)
+	result := response["result"].(map[string]interface{})
+	assert.Equal(t, float64(createdTagID), result["id"])
+	assert.Equal(t, "api", result["name"])
+}
+
+func testDeleteTagOtherUser(t *testing.T) {
+	req := prepareJSONRequest(t, "DELETE", fmt.Sprintf("/tags/%d", createdTagID), nil, otherUserID)
+
+	rr := httptest.NewRecorder()
+	tagHandler.DeleteTagHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusNotFound)
+
+	response := ParseResponse(t, rr)
+	ResultShouldBe(t, ErrTagNotFound, response["result"])
+}
+
+func testDeleteTag(t *testing.T) {
+	req := prepareJSONRequest(t, "DELETE", fmt.Sprintf("/tags/%d", createdTagID), nil, tagOwnerID)
+
+	rr := httptest.NewRecorder()
+	tagHandler.DeleteTagHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusOK)
+
+	tags, err := tagHandler.tagService.GetTags(tagOwnerID)
+	assert.NoError(t, err)
+	assert.Len(t, tags, 1)
+}
@@ -0,0 +1,212 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/absoluteyl/tasks-go/internal/model"
+	"github.com/absoluteyl/tasks-go/internal/service"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const dotContentType = "text/vnd.graphviz; charset=utf-8"
+
+// DependencyService is what DependencyHandler needs from the task service,
+// implemented by *service.TaskService.
+type DependencyService interface {
+	AddTaskDependency(dependency model.TaskDependency) error
+	RemoveTaskDependency(dependency model.TaskDependency) error
+	GetDependencyGraph(userID int, id int) (model.DependencyGraph, error)
+}
+
+type DependencyHandler struct {
+	dependencyService DependencyService
+}
+
+func NewDependencyHandler(dependencyService DependencyService) *DependencyHandler {
+	return &DependencyHandler{dependencyService: dependencyService}
+}
+
+// AddDependencyHandler makes the task of the path wait for the task given
+// as depends_on_id.
+func (h *DependencyHandler) AddDependencyHandler(w http.ResponseWriter, r *http.Request) {
+	SetContentType(w)
+
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	taskID, ok := taskIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	var dependencyData map[string]interface{}
+	err := json.NewDecoder(r.Body).Decode(&dependencyData)
+	if err != nil {
+		SetErrResponse(w, http.StatusBadRequest, ErrBadRequest)
+		return
+	}
+
+	value, ok := dependencyData["depends_on_id"]
+	if !ok {
+		SetErrResponse(w, http.StatusBadRequest, ErrMissingDependencyID)
+		return
+	}
+	dependsOnID, ok := intValue(value)
+	if !ok || dependsOnID <= 0 {
+		SetErrResponse(w, http.StatusBadRequest, ErrInvalidDependencyID)
+		return
+	}
+
+	dependency := model.TaskDependency{UserID: userID, TaskID: taskID, DependsOnID: dependsOnID}
+	err = h.dependencyService.AddTaskDependency(dependency)
+	if errors.Is(err, service.ErrTaskNotFound) {
+		SetErrResponse(w, http.StatusNotFound, ErrTaskNotFound)
+		return
+	}
+	if errors.Is(err, service.ErrDependencyTaskNotFound) {
+		SetErrResponse(w, http.StatusUnprocessableEntity, ErrDependencyTaskNotFound)
+		return
+	}
+	if errors.Is(err, service.ErrDependencyCycle) {
+		SetErrResponse(w, http.StatusUnprocessableEntity, ErrDependencyCycle)
+		return
+	}
+	if errors.Is(err, service.ErrDependencyExists) {
+		SetErrResponse(w, http.StatusConflict, ErrDependencyExists)
+		return
+	}
+	if err != nil {
+		SetErrResponse(w, http.StatusInternalServerError, ErrInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	response := map[string]interface{}{
+		"result": dependency,
+	}
+	jsonEncode(w, response)
+}
+
+func (h *DependencyHandler) RemoveDependencyHandler(w http.ResponseWriter, r *http.Request) {
+	SetContentType(w)
+
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	taskID, ok := taskIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	dependsOnID, ok := dependsOnIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.dependencyService.RemoveTaskDependency(model.TaskDependency{
+		UserID: userID, TaskID: taskID, DependsOnID: dependsOnID,
+	})
+	if errors.Is(err, service.ErrDependencyNotFound) {
+		SetErrResponse(w, http.StatusNotFound, ErrDependencyNotFound)
+		return
+	}
+	if err != nil {
+		SetErrResponse(w, http.StatusInternalServerError, ErrInternalServerError)
+		return
+	}
+}
+
+// GetDependencyGraphHandler responds with the dependency graph around a
+// task, as JSON or, with format=dot, in the DOT language of Graphviz.
+func (h *DependencyHandler) GetDependencyGraphHandler(w http.ResponseWriter, r *http.Request) {
+	SetContentType(w)
+
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	taskID, ok := taskIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "dot" {
+		SetErrResponse(w, http.StatusBadRequest, ErrInvalidGraphFormat)
+		return
+	}
+
+	graph, err := h.dependencyService.GetDependencyGraph(userID, taskID)
+	if errors.Is(err, service.ErrTaskNotFound) {
+		SetErrResponse(w, http.StatusNotFound, ErrTaskNotFound)
+		return
+	}
+	if err != nil {
+		SetErrResponse(w, http.StatusInternalServerError, ErrInternalServerError)
+		return
+	}
+
+	if format == "dot" {
+		w.Header().Set("Content-Type", dotContentType)
+		w.Write([]byte(dependencyGraphDOT(graph)))
+		return
+	}
+
+	response := map[string]interface{}{
+		"result": graph,
+	}
+	jsonEncode(w, response)
+}
+
+// dependencyGraphDOT draws each dependency as an edge from the task depended
+// on to the task waiting for it, so that edges follow the order of work.
+// The task the graph was requested for is bold and blocked tasks are red.
+func dependencyGraphDOT(graph model.DependencyGraph) string {
+	var dot strings.Builder
+	dot.WriteString("digraph dependencies {\n")
+	for _, task := range graph.Tasks {
+		attributes := []string{"label=" + dotQuote(task.Name+"\n"+string(task.Status))}
+		if task.ID == graph.TaskID {
+			attributes = append(attributes, "style=bold")
+		}
+		if task.Blocked {
+			attributes = append(attributes, "color=red")
+		}
+		fmt.Fprintf(&dot, "\ttask%d [%s];\n", task.ID, strings.Join(attributes, ", "))
+	}
+	for _, dependency := range graph.Dependencies {
+		fmt.Fprintf(&dot, "\ttask%d -> task%d;\n", dependency.DependsOnID, dependency.TaskID)
+	}
+	dot.WriteString("}\n")
+	return dot.String()
+}
+
+var dotEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func dotQuote(s string) string {
+	return `"` + dotEscaper.Replace(s) + `"`
+}
+
+func dependsOnIDFromPath(w http.ResponseWriter, r *http.Request) (int, bool) {
+	_, id, _ := strings.Cut(r.URL.Path, "/dependencies/")
+	if id == "" {
+		SetErrResponse(w, http.StatusBadRequest, ErrMissingDependencyID)
+		return 0, false
+	}
+
+	dependsOnID, err := strconv.Atoi(id)
+	if err != nil {
+		SetErrResponse(w, http.StatusBadRequest, ErrInvalidDependencyID)
+		return 0, false
+	}
+
+	return dependsOnID, true
+}
@@ -0,0 +1,239 @@
+package handler
+
+import (
+	"fmt"
+	"github.com/absoluteyl/tasks-go/internal/model"
+	"github.com/absoluteyl/tasks-go/internal/repository"
+	"github.com/absoluteyl/tasks-go/internal/service"
+	. "github.com/absoluteyl/tasks-go/pkg/testutils"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// The dependency tests keep their tasks in memory, so that the ids of the
+// tasks of TestTaskHandler stay predictable.
+const dependencyOwnerID = 60
+
+var dependencyService *service.TaskService
+var dependencyHandler *DependencyHandler
+var dependencyTaskIDs []int
+
+func TestDependencyHandler(t *testing.T) {
+	dependencyService = service.NewTaskServiceWithRepository(repository.NewMemoryTaskStore())
+	dependencyHandler = NewDependencyHandler(dependencyService)
+
+	for _, name := range []string{"Pour \"foundation\"", "Build walls", "Paint walls"} {
+		id, err := dependencyService.CreateTask(&model.Task{UserID: dependencyOwnerID, Name: name})
+		assert.NoError(t, err)
+		dependencyTaskIDs = append(dependencyTaskIDs, id)
+	}
+
+	t.Run("AddInvalidAttributes", testAddDependencyInvalidAttributes)
+	t.Run("AddNotExist", testAddDependencyNotExist)
+	t.Run("Add", testAddDependency)
+	t.Run("AddExisting", testAddDependencyExisting)
+	t.Run("AddCycle", testAddDependencyCycle)
+	t.Run("GetGraph", testGetDependencyGraph)
+	t.Run("GetGraphDOT", testGetDependencyGraphDOT)
+	t.Run("GetGraphInvalidFormat", testGetDependencyGraphInvalidFormat)
+	t.Run("Remove", testRemoveDependency)
+	t.Run("RemoveNotExist", testRemoveDependencyNotExist)
+	t.Run("BlockerStatusChange", testBlockerStatusChange)
+}
+
+func testAddDependencyInvalidAttributes(t *testing.T) {
+	invalidData := []struct {
+		dependencyData map[string]interface{}
+		message        string
+	}{
+		{map[string]interface{}{}, ErrMissingDependencyID},
+		{map[string]interface{}{"depends_on_id": "1"}, ErrInvalidDependencyID},
+		{map[string]interface{}{"depends_on_id": 0}, ErrInvalidDependencyID},
+	}
+
+	for _, data := range invalidData {
+		req := prepareJSONRequest(t, "POST", "/task/1/dependencies", data.dependencyData, dependencyOwnerID)
+
+		rr := httptest.NewRecorder()
+		dependencyHandler.AddDependencyHandler(rr, req)
+
+		HttpStatusShouldBe(t, rr, http.StatusBadRequest)
+
+		response := ParseResponse(t, rr)
+		ResultShouldBe(t, data.message, response["result"])
+	}
+}
+
+func testAddDependencyNotExist(t *testing.T) {
+	req := prepareJSONRequest(t, "POST", "/task/9999/dependencies", map[string]interface{}{
+		"depends_on_id": dependencyTaskIDs[0],
+	}, dependencyOwnerID)
+	rr := httptest.NewRecorder()
+	dependencyHandler.AddDependencyHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusNotFound)
+	ResultShouldBe(t, ErrTaskNotFound, ParseResponse(t, rr)["result"])
+
+	req = prepareJSONRequest(t, "POST", dependenciesPath(dependencyTaskIDs[1]), map[string]interface{}{
+		"depends_on_id": 9999,
+	}, dependencyOwnerID)
+	rr = httptest.NewRecorder()
+	dependencyHandler.AddDependencyHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusUnprocessableEntity)
+	ResultShouldBe(t, ErrDependencyTaskNotFound, ParseResponse(t, rr)["result"])
+}
+
+func testAddDependency(t *testing.T) {
+	for i := 1; i < len(dependencyTaskIDs); i++ {
+		req := prepareJSONRequest(t, "POST", dependenciesPath(dependencyTaskIDs[i]), map[string]interface{}{
+			"depends_on_id": dependencyTaskIDs[i-1],
+		}, dependencyOwnerID)
+
+		rr := httptest.NewRecorder()
+		dependencyHandler.AddDependencyHandler(rr, req)
+
+		HttpStatusShouldBe(t, rr, http.StatusCreated)
+
+		result := ParseResponse(t, rr)["result"].(map[string]interface{})
+		assert.Equal(t, float64(dependencyTaskIDs[i]), result["task_id"])
+		assert.Equal(t, float64(dependencyTaskIDs[i-1]), result["depends_on_id"])
+	}
+}
+
+func testAddDependencyExisting(t *testing.T) {
+	req := prepareJSONRequest(t, "POST", dependenciesPath(dependencyTaskIDs[1]), map[string]interface{}{
+		"depends_on_id": dependencyTaskIDs[0],
+	}, dependencyOwnerID)
+
+	rr := httptest.NewRecorder()
+	dependencyHandler.AddDependencyHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusConflict)
+	ResultShouldBe(t, ErrDependencyExists, ParseResponse(t, rr)["result"])
+}
+
+func testAddDependencyCycle(t *testing.T) {
+	req := prepareJSONRequest(t, "POST", dependenciesPath(dependencyTaskIDs[0]), map[string]interface{}{
+		"depends_on_id": dependencyTaskIDs[2],
+	}, dependencyOwnerID)
+
+	rr := httptest.NewRecorder()
+	dependencyHandler.AddDependencyHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusUnprocessableEntity)
+	ResultShouldBe(t, ErrDependencyCycle, ParseResponse(t, rr)["result"])
+}
+
+func testGetDependencyGraph(t *testing.T) {
+	req := prepareJSONRequest(t, "GET", dependencyGraphPath(dependencyTaskIDs[1], ""), nil, dependencyOwnerID)
+
+	rr := httptest.NewRecorder()
+	dependencyHandler.GetDependencyGraphHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusOK)
+
+	result := ParseResponse(t, rr)["result"].(map[string]interface{})
+	assert.Equal(t, float64(dependencyTaskIDs[1]), result["task_id"])
+
+	tasks := result["tasks"].([]interface{})
+	assert.Len(t, tasks, 3)
+	assert.Equal(t, false, tasks[0].(map[string]interface{})["blocked"])
+	assert.Equal(t, true, tasks[1].(map[string]interface{})["blocked"])
+	assert.Len(t, result["dependencies"], 2)
+}
+
+func testGetDependencyGraphDOT(t *testing.T) {
+	req := prepareJSONRequest(t, "GET", dependencyGraphPath(dependencyTaskIDs[1], "?format=dot"), nil, dependencyOwnerID)
+
+	rr := httptest.NewRecorder()
+	dependencyHandler.GetDependencyGraphHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusOK)
+	assert.Equal(t, dotContentType, rr.Header().Get("Content-Type"))
+
+	expected := fmt.Sprintf(`digraph dependencies {
+	task%[1]d [label="Pour \"foundation\"\ntodo"];
+	task%[2]d [label="Build walls\ntodo", style=bold, color=red];
+	task%[3]d [label="Paint walls\ntodo", color=red];
+	task%[1]d -> task%[2]d;
+	task%[2]d -> task%[3]d;
+}
+`, dependencyTaskIDs[0], dependencyTaskIDs[1], dependencyTaskIDs[2])
+	assert.Equal(t, expected, rr.Body.String())
+}
+
+func testGetDependencyGraphInvalidFormat(t *testing.T) {
+	req := prepareJSONRequest(t, "GET", dependencyGraphPath(dependencyTaskIDs[1], "?format=svg"), nil, dependencyOwnerID)
+
+	rr := httptest.NewRecorder()
+	dependencyHandler.GetDependencyGraphHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusBadRequest)
+	ResultShouldBe(t, ErrInvalidGraphFormat, ParseResponse(t, rr)["result"])
+}
+
+func testRemoveDependency(t *testing.T) {
+	etag := getDependencyTask(t, dependencyTaskIDs[1], "").Header().Get("ETag")
+
+	path := fmt.Sprintf("%s/%d", dependenciesPath(dependencyTaskIDs[1]), dependencyTaskIDs[0])
+	req := prepareJSONRequest(t, "DELETE", path, nil, dependencyOwnerID)
+
+	rr := httptest.NewRecorder()
+	dependencyHandler.RemoveDependencyHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusOK)
+
+	rr = getDependencyTask(t, dependencyTaskIDs[1], etag)
+	HttpStatusShouldBe(t, rr, http.StatusOK)
+	assert.Equal(t, false, ParseResponse(t, rr)["result"].(map[string]interface{})["blocked"])
+}
+
+func testBlockerStatusChange(t *testing.T) {
+	rr := getDependencyTask(t, dependencyTaskIDs[2], "")
+	assert.Equal(t, true, ParseResponse(t, rr)["result"].(map[string]interface{})["blocked"])
+	etag := rr.Header().Get("ETag")
+
+	blocker, err := dependencyService.GetTaskByID(dependencyOwnerID, dependencyTaskIDs[1])
+	assert.NoError(t, err)
+	blocker.Status = model.TaskStatusDone
+	assert.NoError(t, dependencyService.UpdateTask(&blocker))
+
+	rr = getDependencyTask(t, dependencyTaskIDs[2], etag)
+	HttpStatusShouldBe(t, rr, http.StatusOK)
+	assert.Equal(t, false, ParseResponse(t, rr)["result"].(map[string]interface{})["blocked"])
+}
+
+func testRemoveDependencyNotExist(t *testing.T) {
+	path := fmt.Sprintf("%s/%d", dependenciesPath(dependencyTaskIDs[1]), dependencyTaskIDs[0])
+	req := prepareJSONRequest(t, "DELETE", path, nil, dependencyOwnerID)
+
+	rr := httptest.NewRecorder()
+	dependencyHandler.RemoveDependencyHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusNotFound)
+	ResultShouldBe(t, ErrDependencyNotFound, ParseResponse(t, rr)["result"])
+}
+
+// getDependencyTask polls the task, sending etag as If-None-Match unless it
+// is empty.
+func getDependencyTask(t *testing.T, taskID int, etag string) *httptest.ResponseRecorder {
+	req := prepareJSONRequest(t, "GET", fmt.Sprintf("/task/%d", taskID), nil, dependencyOwnerID)
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+
+	rr := httptest.NewRecorder()
+	NewTaskHandler(dependencyService).GetTaskHandler(rr, req)
+	return rr
+}
+
+func dependenciesPath(taskID int) string {
+	return fmt.Sprintf("/task/%d/dependencies", taskID)
+}
+
+func dependencyGraphPath(taskID int, query string) string {
+	return fmt.Sprintf("/tasks/%d/dependency-graph%s", taskID, query)
+}
@@ -2,23 +2,82 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"github.com/absoluteyl/tasks-go/internal/model"
 	"github.com/absoluteyl/tasks-go/internal/service"
+	"github.com/absoluteyl/tasks-go/pkg/jsonpatch"
+	"io"
+	"math"
+	"mime"
 	"net/http"
+	"reflect"
 	"strconv"
 	"strings"
+	"time"
 )
 
+const (
+	mergePatchType = "application/merge-patch+json"
+	jsonPatchType  = "application/json-patch+json"
+)
+
+const (
+	bulkModeAllOrNothing = "all_or_nothing"
+	bulkModeBestEffort   = "best_effort"
+)
+
+var readOnlyTaskAttributes = []string{"id", "created_at", "updated_at", "completed_at", "version", "progress", "blocked"}
+
+type bulkTaskRequest struct {
+	Mode       string              `json:"mode"`
+	Operations []bulkTaskOperation `json:"operations"`
+}
+
+type bulkTaskOperation struct {
+	Op      string                 `json:"op"`
+	ID      int                    `json:"id"`
+	Version int                    `json:"version"`
+	Task    map[string]interface{} `json:"task"`
+}
+
+// taskDataError carries a validation message out of a task operation.
+type taskDataError string
+
+func (e taskDataError) Error() string {
+	return string(e)
+}
+
+// TaskService is what TaskHandler needs from the task service, implemented
+// by *service.TaskService.
+type TaskService interface {
+	CreateTask(task *model.Task) (int, error)
+	GetTasks(userID int, query model.TaskQuery, cursor string) ([]model.Task, string, error)
+	GetTaskByID(userID int, id int) (model.Task, error)
+	GetChildTasks(userID int, id int, query model.TaskQuery, cursor string) ([]model.Task, string, error)
+	GetTaskSubtree(userID int, id int) (model.TaskTree, error)
+	UpdateTask(task *model.Task) error
+	DeleteTask(userID int, id int) error
+	DeleteTaskAtVersion(userID int, id int, version int) error
+	SearchTasks(userID int, query string, limit int) ([]model.TaskSearchResult, error)
+	BulkTasks(userID int, operations []service.TaskOperation, allOrNothing bool) ([]service.TaskOperationResult, error)
+}
+
 type TaskHandler struct {
-	taskService *service.TaskService
+	taskService TaskService
 }
 
-func NewTaskHandler(taskService *service.TaskService) *TaskHandler {
+func NewTaskHandler(taskService TaskService) *TaskHandler {
 	return &TaskHandler{taskService: taskService}
 }
 
 func (h *TaskHandler) CreateTaskHandler(w http.ResponseWriter, r *http.Request) {
 	SetContentType(w)
 
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
 	var taskData map[string]interface{}
 	err := json.NewDecoder(r.Body).Decode(&taskData)
 	if err != nil {
@@ -31,25 +90,40 @@ func (h *TaskHandler) CreateTaskHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	createdTaskID, err := h.taskService.CreateTask(taskData["name"].(string))
+	task := model.Task{UserID: userID, Status: model.TaskStatusTodo}
+	if msg := applyTaskData(&task, taskData); msg != "" {
+		SetErrResponse(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	createdTaskID, err := h.taskService.CreateTask(&task)
+	if errors.Is(err, service.ErrParentTaskNotFound) {
+		SetErrResponse(w, http.StatusUnprocessableEntity, ErrParentTaskNotFound)
+		return
+	}
+	if errors.Is(err, service.ErrProjectNotFound) {
+		SetErrResponse(w, http.StatusUnprocessableEntity, ErrProjectNotFound)
+		return
+	}
+	if errors.Is(err, service.ErrProjectArchived) {
+		SetErrResponse(w, http.StatusUnprocessableEntity, ErrProjectArchived)
+		return
+	}
 	if err != nil {
 		SetErrResponse(w, http.StatusInternalServerError, ErrInternalServerError)
 		return
 	}
 
-	newTask, err := h.taskService.GetTaskByID(createdTaskID)
+	newTask, err := h.taskService.GetTaskByID(userID, createdTaskID)
 	if err != nil {
 		SetErrResponse(w, http.StatusInternalServerError, ErrInternalServerError)
 		return
 	}
 
+	w.Header().Set("ETag", taskETag(newTask))
 	w.WriteHeader(http.StatusCreated)
 	response := map[string]interface{}{
-		"result": map[string]interface{}{
-			"id":     newTask.ID,
-			"name":   newTask.Name,
-			"status": newTask.Status,
-		},
+		"result": newTask,
 	}
 	jsonEncode(w, response)
 }
@@ -57,67 +131,348 @@ func (h *TaskHandler) CreateTaskHandler(w http.ResponseWriter, r *http.Request)
 func (h *TaskHandler) GetTasksHandler(w http.ResponseWriter, r *http.Request) {
 	SetContentType(w)
 
-	tasks, err := h.taskService.GetTasks()
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	listTasks(w, r, func(query model.TaskQuery, cursor string) ([]model.Task, string, error) {
+		return h.taskService.GetTasks(userID, query, cursor)
+	})
+}
+
+// GetChildTasksHandler lists the direct subtasks of a task, with the paging,
+// filters and sort order of the task list.
+func (h *TaskHandler) GetChildTasksHandler(w http.ResponseWriter, r *http.Request) {
+	SetContentType(w)
+
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	taskID, ok := taskIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	listTasks(w, r, func(query model.TaskQuery, cursor string) ([]model.Task, string, error) {
+		return h.taskService.GetChildTasks(userID, taskID, query, cursor)
+	})
+}
+
+// GetTaskSubtreeHandler responds with a task and all of its subtasks,
+// nested under children.
+func (h *TaskHandler) GetTaskSubtreeHandler(w http.ResponseWriter, r *http.Request) {
+	SetContentType(w)
+
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	taskID, ok := taskIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	tree, err := h.taskService.GetTaskSubtree(userID, taskID)
+	if errors.Is(err, service.ErrTaskNotFound) {
+		SetErrResponse(w, http.StatusNotFound, ErrTaskNotFound)
+		return
+	}
 	if err != nil {
 		SetErrResponse(w, http.StatusInternalServerError, ErrInternalServerError)
 		return
 	}
 
 	response := map[string]interface{}{
-		"result": tasks,
+		"result": tree,
 	}
 	jsonEncode(w, response)
 }
 
-func (h *TaskHandler) UpdateTaskHandler(w http.ResponseWriter, r *http.Request) {
+// listTasks responds with the page of tasks list returns for the query and
+// cursor parameters of the request.
+func listTasks(
+	w http.ResponseWriter, r *http.Request,
+	list func(query model.TaskQuery, cursor string) ([]model.Task, string, error),
+) {
+	query, msg := parseTaskQuery(r)
+	if msg != "" {
+		SetErrResponse(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	tasks, nextCursor, err := list(query, r.URL.Query().Get("cursor"))
+	if errors.Is(err, service.ErrTaskNotFound) {
+		SetErrResponse(w, http.StatusNotFound, ErrTaskNotFound)
+		return
+	}
+	if errors.Is(err, service.ErrProjectNotFound) {
+		SetErrResponse(w, http.StatusNotFound, ErrProjectNotFound)
+		return
+	}
+	if errors.Is(err, service.ErrInvalidCursor) {
+		SetErrResponse(w, http.StatusBadRequest, ErrInvalidCursor)
+		return
+	}
+	if err != nil {
+		SetErrResponse(w, http.StatusInternalServerError, ErrInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"result":      tasks,
+		"next_cursor": nil,
+	}
+	if nextCursor != "" {
+		response["next_cursor"] = nextCursor
+	}
+	jsonEncode(w, response)
+}
+
+func (h *TaskHandler) SearchTasksHandler(w http.ResponseWriter, r *http.Request) {
 	SetContentType(w)
 
-	id := strings.TrimPrefix(r.URL.Path, "/task/")
-	if id == "" {
-		SetErrResponse(w, http.StatusBadRequest, ErrMissingTaskID)
+	userID, ok := currentUserID(w, r)
+	if !ok {
 		return
 	}
 
-	taskID, err := strconv.Atoi(id)
+	params := r.URL.Query()
+	limit := 0
+	if value := params.Get("limit"); value != "" {
+		var err error
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			SetErrResponse(w, http.StatusBadRequest, ErrInvalidLimit)
+			return
+		}
+	}
+
+	results, err := h.taskService.SearchTasks(userID, params.Get("q"), limit)
+	if errors.Is(err, service.ErrInvalidSearchQuery) {
+		SetErrResponse(w, http.StatusBadRequest, ErrInvalidSearchQuery)
+		return
+	}
 	if err != nil {
-		SetErrResponse(w, http.StatusBadRequest, ErrInvalidTaskID)
+		SetErrResponse(w, http.StatusInternalServerError, ErrInternalServerError)
+		return
+	}
+
+	jsonEncode(w, map[string]interface{}{"result": results})
+}
+
+func (h *TaskHandler) GetTaskHandler(w http.ResponseWriter, r *http.Request) {
+	SetContentType(w)
+
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	taskID, ok := taskIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	task, err := h.taskService.GetTaskByID(userID, taskID)
+	if errors.Is(err, service.ErrTaskNotFound) {
+		SetErrResponse(w, http.StatusNotFound, ErrTaskNotFound)
+		return
+	}
+	if err != nil {
+		SetErrResponse(w, http.StatusInternalServerError, ErrInternalServerError)
+		return
+	}
+
+	etag := taskETag(task)
+	w.Header().Set("ETag", etag)
+	if ifNoneMatch(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	response := map[string]interface{}{
+		"result": task,
+	}
+	jsonEncode(w, response)
+}
+
+// UpdateTaskHandler replaces the writable attributes of a task; the ones
+// missing from the body are reset to their defaults.
+func (h *TaskHandler) UpdateTaskHandler(w http.ResponseWriter, r *http.Request) {
+	SetContentType(w)
+
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	taskID, ok := taskIDFromPath(w, r)
+	if !ok {
 		return
 	}
 
 	var taskData map[string]interface{}
-	err = json.NewDecoder(r.Body).Decode(&taskData)
+	err := json.NewDecoder(r.Body).Decode(&taskData)
 	if err != nil {
 		SetErrResponse(w, http.StatusBadRequest, ErrBadRequest)
 		return
 	}
 
-	if taskData["id"] != nil {
-		SetErrResponse(w, http.StatusBadRequest, ErrNotAllowTaskID)
+	h.saveTask(w, r, userID, taskID, func(task *model.Task) (int, string) {
+		if msg := replaceTaskData(task, taskData); msg != "" {
+			return http.StatusBadRequest, msg
+		}
+		return 0, ""
+	})
+}
+
+// PatchTaskHandler applies a JSON Merge Patch or a JSON Patch, depending on
+// the Content-Type, to the JSON representation of a task.
+func (h *TaskHandler) PatchTaskHandler(w http.ResponseWriter, r *http.Request) {
+	SetContentType(w)
+
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	taskID, ok := taskIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		SetErrResponse(w, http.StatusBadRequest, ErrBadRequest)
 		return
 	}
 
-	existingTask, err := h.taskService.GetTaskByID(taskID)
-	if err != nil || existingTask.ID == 0 {
+	var patch func(doc interface{}) (interface{}, error)
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case mergePatchType:
+		var mergePatch map[string]interface{}
+		err = json.Unmarshal(body, &mergePatch)
+		if err != nil || mergePatch == nil {
+			SetErrResponse(w, http.StatusBadRequest, ErrInvalidPatch)
+			return
+		}
+		patch = func(doc interface{}) (interface{}, error) {
+			return jsonpatch.MergePatch(doc, mergePatch), nil
+		}
+	case jsonPatchType:
+		operations, err := jsonpatch.Decode(body)
+		if err != nil {
+			SetErrResponse(w, http.StatusBadRequest, ErrInvalidPatch)
+			return
+		}
+		patch = func(doc interface{}) (interface{}, error) {
+			return jsonpatch.Apply(doc, operations)
+		}
+	default:
+		w.Header().Set("Accept-Patch", mergePatchType+", "+jsonPatchType)
+		SetErrResponse(w, http.StatusUnsupportedMediaType, ErrUnsupportedPatch)
+		return
+	}
+
+	h.saveTask(w, r, userID, taskID, func(task *model.Task) (int, string) {
+		doc, err := taskDocument(*task)
+		if err != nil {
+			return http.StatusInternalServerError, ErrInternalServerError
+		}
+
+		patched, err := patch(doc)
+		if errors.Is(err, jsonpatch.ErrTestFailed) {
+			return http.StatusConflict, ErrPatchTestFailed
+		}
+		if err != nil {
+			return http.StatusUnprocessableEntity, ErrPatchNotApplicable
+		}
+
+		taskData, ok := patched.(map[string]interface{})
+		if !ok {
+			return http.StatusUnprocessableEntity, ErrPatchNotApplicable
+		}
+		for _, key := range readOnlyTaskAttributes {
+			if !reflect.DeepEqual(doc[key], taskData[key]) {
+				return http.StatusUnprocessableEntity, ErrPatchReadOnly
+			}
+			delete(taskData, key)
+		}
+
+		if msg := replaceTaskData(task, taskData); msg != "" {
+			return http.StatusUnprocessableEntity, msg
+		}
+		return 0, ""
+	})
+}
+
+// saveTask loads the task, lets modify change it and stores the result.
+// modify returns a status code and message to abort with an error response.
+func (h *TaskHandler) saveTask(
+	w http.ResponseWriter, r *http.Request, userID int, taskID int,
+	modify func(task *model.Task) (int, string),
+) {
+	task, err := h.taskService.GetTaskByID(userID, taskID)
+	if errors.Is(err, service.ErrTaskNotFound) {
 		SetErrResponse(w, http.StatusNotFound, ErrTaskNotFound)
 		return
 	}
+	if err != nil {
+		SetErrResponse(w, http.StatusInternalServerError, ErrInternalServerError)
+		return
+	}
 
-	if taskData["name"] != nil {
-		existingTask.Name = taskData["name"].(string)
+	if !preconditionHolds(w, r, task) {
+		return
 	}
 
-	if taskData["status"] != nil {
-		existingTask.Status = int(taskData["status"].(float64))
+	if code, msg := modify(&task); msg != "" {
+		SetErrResponse(w, code, msg)
+		return
 	}
 
-	err = h.taskService.UpdateTask(&existingTask)
+	err = h.taskService.UpdateTask(&task)
+	if errors.Is(err, service.ErrTaskNotFound) {
+		SetErrResponse(w, http.StatusNotFound, ErrTaskNotFound)
+		return
+	}
+	if errors.Is(err, service.ErrTaskVersionConflict) {
+		SetErrResponse(w, http.StatusPreconditionFailed, ErrTaskModified)
+		return
+	}
+	if errors.Is(err, service.ErrInvalidStatusTransition) {
+		SetErrResponse(w, http.StatusUnprocessableEntity, ErrInvalidTransition)
+		return
+	}
+	if errors.Is(err, service.ErrParentTaskNotFound) {
+		SetErrResponse(w, http.StatusUnprocessableEntity, ErrParentTaskNotFound)
+		return
+	}
+	if errors.Is(err, service.ErrTaskCycle) {
+		SetErrResponse(w, http.StatusUnprocessableEntity, ErrTaskCycle)
+		return
+	}
+	if errors.Is(err, service.ErrProjectNotFound) {
+		SetErrResponse(w, http.StatusUnprocessableEntity, ErrProjectNotFound)
+		return
+	}
+	if errors.Is(err, service.ErrProjectArchived) {
+		SetErrResponse(w, http.StatusUnprocessableEntity, ErrProjectArchived)
+		return
+	}
 	if err != nil {
 		SetErrResponse(w, http.StatusInternalServerError, ErrInternalServerError)
 		return
 	}
 
+	w.Header().Set("ETag", taskETag(task))
 	response := map[string]interface{}{
-		"result": existingTask,
+		"result": task,
 	}
 	jsonEncode(w, response)
 }
@@ -125,27 +480,449 @@ func (h *TaskHandler) UpdateTaskHandler(w http.ResponseWriter, r *http.Request)
 func (h *TaskHandler) DeleteTaskHandler(w http.ResponseWriter, r *http.Request) {
 	SetContentType(w)
 
-	id := strings.TrimPrefix(r.URL.Path, "/task/")
-	if id == "" {
-		SetErrResponse(w, http.StatusBadRequest, ErrMissingTaskID)
+	userID, ok := currentUserID(w, r)
+	if !ok {
 		return
 	}
 
-	taskID, err := strconv.Atoi(id)
-	if err != nil {
-		SetErrResponse(w, http.StatusBadRequest, ErrInvalidTaskID)
+	taskID, ok := taskIDFromPath(w, r)
+	if !ok {
 		return
 	}
 
-	existingTask, err := h.taskService.GetTaskByID(taskID)
-	if err != nil || existingTask.ID == 0 {
+	var err error
+	if r.Header.Get("If-Match") == "" {
+		err = h.taskService.DeleteTask(userID, taskID)
+	} else {
+		var task model.Task
+		task, err = h.taskService.GetTaskByID(userID, taskID)
+		if err == nil {
+			if !preconditionHolds(w, r, task) {
+				return
+			}
+			err = h.taskService.DeleteTaskAtVersion(userID, taskID, task.Version)
+		}
+	}
+	if errors.Is(err, service.ErrTaskNotFound) {
 		SetErrResponse(w, http.StatusNotFound, ErrTaskNotFound)
 		return
 	}
+	if errors.Is(err, service.ErrTaskVersionConflict) {
+		SetErrResponse(w, http.StatusPreconditionFailed, ErrTaskModified)
+		return
+	}
+	if err != nil {
+		SetErrResponse(w, http.StatusInternalServerError, ErrInternalServerError)
+		return
+	}
+}
+
+// BulkTasksHandler runs a list of create, update and delete operations in
+// one transaction and responds with the status and result of each.
+func (h *TaskHandler) BulkTasksHandler(w http.ResponseWriter, r *http.Request) {
+	SetContentType(w)
+
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
 
-	err = h.taskService.DeleteTask(taskID)
+	var request bulkTaskRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		SetErrResponse(w, http.StatusBadRequest, ErrBadRequest)
+		return
+	}
+
+	allOrNothing := true
+	switch request.Mode {
+	case "", bulkModeAllOrNothing:
+	case bulkModeBestEffort:
+		allOrNothing = false
+	default:
+		SetErrResponse(w, http.StatusBadRequest, ErrInvalidBulkMode)
+		return
+	}
+
+	if len(request.Operations) == 0 {
+		SetErrResponse(w, http.StatusBadRequest, ErrMissingBulkOperations)
+		return
+	}
+
+	operations := make([]service.TaskOperation, len(request.Operations))
+	for i, operation := range request.Operations {
+		operations[i], ok = taskOperation(operation)
+		if !ok {
+			SetErrResponse(w, http.StatusBadRequest, ErrInvalidBulkOperation)
+			return
+		}
+	}
+
+	results, err := h.taskService.BulkTasks(userID, operations, allOrNothing)
+	if errors.Is(err, service.ErrTooManyTaskOperations) {
+		SetErrResponse(w, http.StatusBadRequest, ErrTooManyBulkOperations)
+		return
+	}
 	if err != nil {
 		SetErrResponse(w, http.StatusInternalServerError, ErrInternalServerError)
 		return
 	}
+
+	status := http.StatusOK
+	items := make([]map[string]interface{}, len(results))
+	for i, result := range results {
+		code, value := bulkTaskResult(request.Operations[i].Op, result)
+		if code >= http.StatusBadRequest {
+			status = http.StatusMultiStatus
+		}
+		items[i] = map[string]interface{}{
+			"status": code,
+			"result": value,
+		}
+	}
+
+	w.WriteHeader(status)
+	response := map[string]interface{}{
+		"result": items,
+	}
+	jsonEncode(w, response)
+}
+
+func taskOperation(operation bulkTaskOperation) (service.TaskOperation, bool) {
+	taskOperation := service.TaskOperation{
+		Op:      operation.Op,
+		TaskID:  operation.ID,
+		Version: operation.Version,
+	}
+
+	switch operation.Op {
+	case service.TaskOperationCreate:
+		if operation.ID != 0 || operation.Version != 0 || operation.Task == nil {
+			return service.TaskOperation{}, false
+		}
+		taskOperation.Modify = func(task *model.Task) error {
+			task.Status = model.TaskStatusTodo
+			if _, ok := operation.Task["name"]; !ok {
+				return taskDataError(ErrMissingTaskName)
+			}
+			if msg := applyTaskData(task, operation.Task); msg != "" {
+				return taskDataError(msg)
+			}
+			return nil
+		}
+	case service.TaskOperationUpdate:
+		if operation.ID <= 0 || operation.Task == nil {
+			return service.TaskOperation{}, false
+		}
+		taskOperation.Modify = func(task *model.Task) error {
+			if msg := applyTaskData(task, operation.Task); msg != "" {
+				return taskDataError(msg)
+			}
+			return nil
+		}
+	case service.TaskOperationDelete:
+		if operation.ID <= 0 || operation.Task != nil {
+			return service.TaskOperation{}, false
+		}
+	default:
+		return service.TaskOperation{}, false
+	}
+
+	return taskOperation, true
+}
+
+// bulkTaskResult maps the outcome of an operation to the status code and
+// result the matching single task endpoint would respond with.
+func bulkTaskResult(op string, result service.TaskOperationResult) (int, interface{}) {
+	var dataErr taskDataError
+	switch {
+	case result.Err == nil && op == service.TaskOperationCreate:
+		return http.StatusCreated, result.Task
+	case result.Err == nil && op == service.TaskOperationDelete:
+		return http.StatusOK, nil
+	case result.Err == nil:
+		return http.StatusOK, result.Task
+	case errors.As(result.Err, &dataErr):
+		return http.StatusBadRequest, string(dataErr)
+	case errors.Is(result.Err, service.ErrTaskNotFound):
+		return http.StatusNotFound, ErrTaskNotFound
+	case errors.Is(result.Err, service.ErrTaskVersionConflict):
+		return http.StatusPreconditionFailed, ErrTaskModified
+	case errors.Is(result.Err, service.ErrInvalidStatusTransition):
+		return http.StatusUnprocessableEntity, ErrInvalidTransition
+	case errors.Is(result.Err, service.ErrParentTaskNotFound):
+		return http.StatusUnprocessableEntity, ErrParentTaskNotFound
+	case errors.Is(result.Err, service.ErrTaskCycle):
+		return http.StatusUnprocessableEntity, ErrTaskCycle
+	case errors.Is(result.Err, service.ErrProjectNotFound):
+		return http.StatusUnprocessableEntity, ErrProjectNotFound
+	case errors.Is(result.Err, service.ErrProjectArchived):
+		return http.StatusUnprocessableEntity, ErrProjectArchived
+	case errors.Is(result.Err, service.ErrBulkRolledBack):
+		return http.StatusFailedDependency, ErrBulkRolledBack
+	}
+	return http.StatusInternalServerError, ErrInternalServerError
+}
+
+// taskIDFromPath reads the id of /task/:id, of the paths below it and of
+// /tasks/:id/dependency-graph.
+func taskIDFromPath(w http.ResponseWriter, r *http.Request) (int, bool) {
+	path := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/tasks/"), "/task/")
+	id, _, _ := strings.Cut(path, "/")
+	if id == "" {
+		SetErrResponse(w, http.StatusBadRequest, ErrMissingTaskID)
+		return 0, false
+	}
+
+	taskID, err := strconv.Atoi(id)
+	if err != nil {
+		SetErrResponse(w, http.StatusBadRequest, ErrInvalidTaskID)
+		return 0, false
+	}
+
+	return taskID, true
+}
+
+// preconditionHolds checks the If-Match header of a write against the
+// current task and responds with 412 Precondition Failed when it is stale.
+func preconditionHolds(w http.ResponseWriter, r *http.Request, task model.Task) bool {
+	header := r.Header.Get("If-Match")
+	if header == "" || ifMatch(header, taskETag(task)) {
+		return true
+	}
+
+	w.Header().Set("ETag", taskETag(task))
+	SetErrResponse(w, http.StatusPreconditionFailed, ErrTaskModified)
+	return false
+}
+
+// parseTaskQuery reads the paging, filter and sort parameters of the task
+// list. It returns the error message for the first invalid parameter.
+func parseTaskQuery(r *http.Request) (model.TaskQuery, string) {
+	params := r.URL.Query()
+	var query model.TaskQuery
+
+	if value := params.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			return model.TaskQuery{}, ErrInvalidLimit
+		}
+		query.Limit = limit
+	}
+
+	if value := params.Get("status"); value != "" {
+		for _, status := range strings.Split(value, ",") {
+			status := model.TaskStatus(strings.TrimSpace(status))
+			if !status.IsValid() {
+				return model.TaskQuery{}, ErrInvalidStatusFilter
+			}
+			query.Statuses = append(query.Statuses, status)
+		}
+	}
+
+	query.Name = strings.TrimSpace(params.Get("name"))
+
+	// Each tag parameter matches any of its comma separated tags, and tasks
+	// have to match every tag parameter.
+	for _, value := range params["tag"] {
+		var tags []string
+		for _, tag := range strings.Split(value, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == "" {
+				return model.TaskQuery{}, ErrInvalidTagFilter
+			}
+			tags = append(tags, tag)
+		}
+		query.Tags = append(query.Tags, tags)
+	}
+
+	ranges := []struct {
+		param  string
+		target **time.Time
+	}{
+		{"created_after", &query.CreatedAfter},
+		{"created_before", &query.CreatedBefore},
+		{"due_after", &query.DueAfter},
+		{"due_before", &query.DueBefore},
+	}
+	for _, rng := range ranges {
+		value := params.Get(rng.param)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return model.TaskQuery{}, ErrInvalidDateFilter
+		}
+		*rng.target = &t
+	}
+
+	if value := params.Get("sort"); value != "" {
+		sort, ok := model.ParseTaskSort(value)
+		if !ok {
+			return model.TaskQuery{}, ErrInvalidSort
+		}
+		query.Sort = sort
+	}
+
+	return query, ""
+}
+
+// taskDocument is the JSON representation of task that patches apply to.
+func taskDocument(task model.Task) (map[string]interface{}, error) {
+	encoded, err := json.Marshal(task)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc map[string]interface{}
+	err = json.Unmarshal(encoded, &doc)
+	return doc, err
+}
+
+// replaceTaskData sets every writable attribute of task from taskData and
+// resets the ones taskData leaves out.
+func replaceTaskData(task *model.Task, taskData map[string]interface{}) string {
+	task.Description = ""
+	task.Status = model.TaskStatusTodo
+	task.Priority = model.TaskPriorityNone
+	task.DueAt = nil
+	task.Tags = nil
+	task.ParentID = nil
+	task.ProjectID = nil
+	task.Recurrence = ""
+
+	if msg := applyTaskData(task, taskData); msg != "" {
+		return msg
+	}
+	if _, ok := taskData["name"]; !ok {
+		return ErrMissingTaskName
+	}
+	return ""
+}
+
+// applyTaskData copies the writable attributes present in taskData onto task.
+// It returns the error message for the first invalid attribute.
+func applyTaskData(task *model.Task, taskData map[string]interface{}) string {
+	if taskData["id"] != nil {
+		return ErrNotAllowTaskID
+	}
+
+	for _, key := range []string{"created_at", "updated_at", "completed_at"} {
+		if _, ok := taskData[key]; ok {
+			return ErrNotAllowTaskTimes
+		}
+	}
+
+	for _, key := range []string{"progress", "blocked"} {
+		if _, ok := taskData[key]; ok {
+			return ErrNotAllowComputed
+		}
+	}
+
+	if value, ok := taskData["name"]; ok {
+		name, _ := value.(string)
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return ErrInvalidTaskName
+		}
+		task.Name = name
+	}
+
+	if value, ok := taskData["description"]; ok {
+		description, ok := value.(string)
+		if value != nil && !ok {
+			return ErrInvalidTaskDesc
+		}
+		task.Description = description
+	}
+
+	if value, ok := taskData["status"]; ok {
+		status, _ := value.(string)
+		if !model.TaskStatus(status).IsValid() {
+			return ErrInvalidTaskStatus
+		}
+		task.Status = model.TaskStatus(status)
+	}
+
+	if value, ok := taskData["priority"]; ok {
+		priority, ok := intValue(value)
+		if !ok || priority < model.TaskPriorityNone || priority > model.TaskPriorityHigh {
+			return ErrInvalidTaskPriority
+		}
+		task.Priority = priority
+	}
+
+	if value, ok := taskData["due_at"]; ok {
+		task.DueAt = nil
+		if value != nil {
+			text, _ := value.(string)
+			dueAt, err := time.Parse(time.RFC3339, text)
+			if err != nil {
+				return ErrInvalidTaskDueAt
+			}
+			task.DueAt = &dueAt
+		}
+	}
+
+	if value, ok := taskData["tags"]; ok {
+		tags, ok := stringList(value)
+		if !ok {
+			return ErrInvalidTaskTags
+		}
+		for i := range tags {
+			tags[i] = strings.TrimSpace(tags[i])
+			if !model.IsValidTagName(tags[i]) {
+				return ErrInvalidTaskTags
+			}
+		}
+		task.Tags = tags
+	}
+
+	if value, ok := taskData["parent_id"]; ok {
+		task.ParentID = nil
+		if value != nil {
+			parentID, ok := intValue(value)
+			if !ok || parentID <= 0 {
+				return ErrInvalidTaskParent
+			}
+			task.ParentID = &parentID
+		}
+	}
+
+	if value, ok := taskData["project_id"]; ok {
+		task.ProjectID = nil
+		if value != nil {
+			projectID, ok := intValue(value)
+			if !ok || projectID <= 0 {
+				return ErrInvalidTaskProject
+			}
+			task.ProjectID = &projectID
+		}
+	}
+
+	if value, ok := taskData["recurrence"]; ok {
+		recurrence, ok := value.(string)
+		if value != nil && !ok {
+			return ErrInvalidRecurrence
+		}
+		task.Recurrence = ""
+		if recurrence != "" {
+			rule, ok := model.ParseRecurrenceRule(recurrence)
+			if !ok {
+				return ErrInvalidRecurrence
+			}
+			task.Recurrence = rule.String()
+		}
+	}
+
+	return ""
+}
+
+func intValue(value interface{}) (int, bool) {
+	number, ok := value.(float64)
+	if !ok || number != math.Trunc(number) {
+		return 0, false
+	}
+	return int(number), true
 }
@@ -3,6 +3,7 @@ package handler
 import (
 	"bytes"
 	"database/sql"
+	"errors"
 	"fmt"
 	"github.com/absoluteyl/tasks-go/internal/model"
 	"github.com/absoluteyl/tasks-go/internal/repository"
@@ -13,13 +14,26 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
+	"time"
 )
 
 var testDB *sql.DB
 var taskRepo *repository.TaskRepository
 var taskService *service.TaskService
 var taskHandler *TaskHandler
+var userService *service.UserService
+var userHandler *UserHandler
+var tokenService *service.TokenService
+var authHandler *AuthHandler
+var apiKeyHandler *APIKeyHandler
+var tagHandler *TagHandler
+
+const (
+	taskOwnerID = 1
+	otherUserID = 2
+)
 
 func TestMain(m *testing.M) {
 	t := &testing.T{}
@@ -36,7 +50,12 @@ func setup(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	err = PrepareTaskTable(testDB)
+	err = MigrateDB(testDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = repository.EnsureTaskSearchIndex(testDB)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -44,6 +63,18 @@ func setup(t *testing.T) {
 	taskRepo = repository.NewTaskRepository(testDB)
 	taskService = service.NewTaskServiceWithRepository(taskRepo)
 	taskHandler = NewTaskHandler(taskService)
+
+	userRepo := repository.NewUserRepository(testDB)
+	userService = service.NewUserServiceWithRepository(userRepo)
+	userHandler = NewUserHandler(userService)
+	tokenService = service.NewTokenServiceWithRepositories(
+		userRepo,
+		repository.NewRefreshTokenRepository(testDB),
+		repository.NewRevokedTokenRepository(testDB),
+	)
+	authHandler = NewAuthHandler(userService, tokenService)
+	tagHandler = NewTagHandler(taskService)
+	apiKeyHandler = NewAPIKeyHandler(service.NewAPIKeyServiceWithRepositories(repository.NewAPIKeyRepository(testDB), userRepo))
 }
 
 func teardown() {
@@ -54,23 +85,103 @@ func teardown() {
 }
 
 func TestTaskHandler(t *testing.T) {
+	t.Run("CreateUnauthenticated", testCreateUnauthenticated)
 	t.Run("CreateMissingName", testCreateMissingName)
+	t.Run("CreateInvalidAttributes", testCreateInvalidAttributes)
 	t.Run("Create", testCreate)
 
 	t.Run("GetList", testGetList)
+	t.Run("GetListOtherUser", testGetListOtherUser)
+	t.Run("GetListInvalidLimit", testGetListInvalidLimit)
+	t.Run("GetListInvalidCursor", testGetListInvalidCursor)
+	t.Run("GetListWithLimit", testGetListWithLimit)
+	t.Run("GetListInvalidFilters", testGetListInvalidFilters)
+	t.Run("GetListFilteredAndSorted", testGetListFilteredAndSorted)
+	t.Run("GetListByTags", testGetListByTags)
+	t.Run("SearchInvalidParameters", testSearchInvalidParameters)
+	t.Run("Search", testSearch)
+
+	t.Run("GetWithInvalidID", testGetWithInvalidID)
+	t.Run("GetNotExist", testGetNotExist)
+	t.Run("GetOtherUser", testGetOtherUser)
+	t.Run("Get", testGet)
+	t.Run("GetNotModified", testGetNotModified)
 
 	t.Run("UpdateWithoutID", testUpdateWithoutID)
 	t.Run("UpdateWithoutID", testUpdateWithInvalidID)
 	t.Run("UpdateWithIDInBody", testUpdateWithIDInBody)
+	t.Run("UpdateWithTimestampsInBody", testUpdateWithTimestampsInBody)
 	t.Run("UpdateNotExist", testUpdateNotExist)
-	t.Run("UpdateOnlyName", testUpdateOnlyName)
-	t.Run("UpdateOnlyStatus", testUpdateOnlyStatus)
+	t.Run("UpdateOtherUser", testUpdateOtherUser)
+	t.Run("PatchUnsupportedType", testPatchUnsupportedType)
+	t.Run("PatchInvalidDocument", testPatchInvalidDocument)
+	t.Run("PatchNotExist", testPatchNotExist)
+	t.Run("MergePatchName", testMergePatchName)
+	t.Run("MergePatchRemove", testMergePatchRemove)
+	t.Run("MergePatchInvalidAttribute", testMergePatchInvalidAttribute)
+	t.Run("JSONPatchStatus", testJSONPatchStatus)
+	t.Run("JSONPatchTestFailed", testJSONPatchTestFailed)
+	t.Run("JSONPatchReadOnly", testJSONPatchReadOnly)
+	t.Run("JSONPatchMissingPath", testJSONPatchMissingPath)
+	t.Run("UpdateMissingName", testUpdateMissingName)
 	t.Run("Update", testUpdate)
+	t.Run("UpdateInvalidTransition", testUpdateInvalidTransition)
+	t.Run("UpdateStaleIfMatch", testUpdateStaleIfMatch)
+	t.Run("UpdateWithIfMatch", testUpdateWithIfMatch)
 
 	t.Run("DeleteNotExist", testDeleteNotExist)
+	t.Run("DeleteOtherUser", testDeleteOtherUser)
+	t.Run("DeleteStaleIfMatch", testDeleteStaleIfMatch)
 	t.Run("Delete", testDelete)
 }
 
+func TestBulkTasksHandler(t *testing.T) {
+	t.Run("InvalidRequests", testBulkInvalidRequests)
+	t.Run("AllOrNothingFailure", testBulkAllOrNothingFailure)
+	t.Run("BestEffort", testBulkBestEffort)
+	t.Run("AllOrNothing", testBulkAllOrNothing)
+}
+
+// hierarchyOwnerID keeps the subtasks of these tests apart from the tasks of
+// TestTaskHandler.
+const hierarchyOwnerID = 50
+
+var parentTaskID, childTaskID int
+
+func TestTaskHierarchyHandler(t *testing.T) {
+	t.Run("CreateMissingParent", testCreateSubtaskMissingParent)
+	t.Run("Create", testCreateSubtasks)
+	t.Run("GetChildrenNotExist", testGetChildTasksNotExist)
+	t.Run("GetChildren", testGetChildTasks)
+	t.Run("GetSubtree", testGetTaskSubtree)
+	t.Run("UpdateCycle", testUpdateSubtaskCycle)
+}
+
+// recurrenceOwnerID keeps the recurring tasks of these tests apart from the
+// tasks of TestTaskHandler.
+const recurrenceOwnerID = 80
+
+var recurringTaskID int
+
+func TestRecurringTaskHandler(t *testing.T) {
+	t.Run("Create", testCreateRecurringTask)
+	t.Run("Complete", testCompleteRecurringTask)
+}
+
+func testCreateUnauthenticated(t *testing.T) {
+	reqBody := PrepareJsonBody(t, map[string]interface{}{"name": "Eat Dinner"})
+	req, err := http.NewRequest("POST", "/task", bytes.NewBuffer(reqBody))
+	assert.NoError(t, err, "Error creating request")
+
+	rr := httptest.NewRecorder()
+	taskHandler.CreateTaskHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusUnauthorized)
+
+	response := ParseResponse(t, rr)
+	ResultShouldBe(t, ErrUnauthorized, response["result"])
+}
+
 func testCreateMissingName(t *testing.T) {
 	taskData := map[string]interface{}{}
 
@@ -87,9 +198,53 @@ func testCreateMissingName(t *testing.T) {
 	ResultShouldBe(t, ErrMissingTaskName, response["result"])
 }
 
+func testCreateInvalidAttributes(t *testing.T) {
+	invalidData := []struct {
+		taskData map[string]interface{}
+		message  string
+	}{
+		{map[string]interface{}{"name": ""}, ErrInvalidTaskName},
+		{map[string]interface{}{"name": 1}, ErrInvalidTaskName},
+		{map[string]interface{}{"name": "Eat Dinner", "description": 1}, ErrInvalidTaskDesc},
+		{map[string]interface{}{"name": "Eat Dinner", "status": "finished"}, ErrInvalidTaskStatus},
+		{map[string]interface{}{"name": "Eat Dinner", "priority": 4}, ErrInvalidTaskPriority},
+		{map[string]interface{}{"name": "Eat Dinner", "priority": 1.5}, ErrInvalidTaskPriority},
+		{map[string]interface{}{"name": "Eat Dinner", "due_at": "tomorrow"}, ErrInvalidTaskDueAt},
+		{map[string]interface{}{"name": "Eat Dinner", "tags": "kitchen"}, ErrInvalidTaskTags},
+		{map[string]interface{}{"name": "Eat Dinner", "tags": []interface{}{"kitchen", 1}}, ErrInvalidTaskTags},
+		{map[string]interface{}{"name": "Eat Dinner", "tags": []interface{}{"home,kitchen"}}, ErrInvalidTaskTags},
+		{map[string]interface{}{"name": "Eat Dinner", "tags": []interface{}{" "}}, ErrInvalidTaskTags},
+		{map[string]interface{}{"name": "Eat Dinner", "parent_id": "1"}, ErrInvalidTaskParent},
+		{map[string]interface{}{"name": "Eat Dinner", "parent_id": 0}, ErrInvalidTaskParent},
+		{map[string]interface{}{"name": "Eat Dinner", "project_id": 1.5}, ErrInvalidTaskProject},
+		{map[string]interface{}{"name": "Eat Dinner", "recurrence": "FREQ=YEARLY"}, ErrInvalidRecurrence},
+		{map[string]interface{}{"name": "Eat Dinner", "recurrence": 7}, ErrInvalidRecurrence},
+		{map[string]interface{}{"name": "Eat Dinner", "created_at": "2024-01-01T00:00:00Z"}, ErrNotAllowTaskTimes},
+		{map[string]interface{}{"name": "Eat Dinner", "progress": 50}, ErrNotAllowComputed},
+		{map[string]interface{}{"name": "Eat Dinner", "blocked": false}, ErrNotAllowComputed},
+	}
+
+	for _, data := range invalidData {
+		req := prepareCreateTaskRequest(t, PrepareJsonBody(t, data.taskData))
+
+		rr := httptest.NewRecorder()
+		taskHandler.CreateTaskHandler(rr, req)
+
+		HttpStatusShouldBe(t, rr, http.StatusBadRequest)
+
+		response := ParseResponse(t, rr)
+		ResultShouldBe(t, data.message, response["result"])
+	}
+}
+
 func testCreate(t *testing.T) {
+	dueAt := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
 	taskData := map[string]interface{}{
-		"name": "Eat Dinner",
+		"name":        "Eat Dinner",
+		"description": "Pasta with tomato sauce",
+		"priority":    model.TaskPriorityHigh,
+		"due_at":      dueAt.Format(time.RFC3339),
+		"tags":        []interface{}{"kitchen", " home ", "kitchen"},
 	}
 
 	reqBody := PrepareJsonBody(t, taskData)
@@ -99,6 +254,7 @@ func testCreate(t *testing.T) {
 	taskHandler.CreateTaskHandler(rr, req)
 
 	HttpStatusShouldBe(t, rr, http.StatusCreated)
+	assert.Equal(t, `"1"`, rr.Header().Get("ETag"))
 
 	response := ParseResponse(t, rr)
 	ResultShouldExist(t, response)
@@ -107,12 +263,19 @@ func testCreate(t *testing.T) {
 	taskShouldBe(t, model.Task{
 		ID:     1,
 		Name:   taskData["name"].(string),
-		Status: 0,
+		Status: model.TaskStatusTodo,
 	}, model.Task{
 		ID:     int(result["id"].(float64)),
 		Name:   result["name"].(string),
-		Status: int(result["status"].(float64)),
+		Status: model.TaskStatus(result["status"].(string)),
 	})
+	assert.Equal(t, taskData["description"], result["description"])
+	assert.Equal(t, float64(model.TaskPriorityHigh), result["priority"])
+	assert.Equal(t, taskData["due_at"], result["due_at"])
+	assert.Equal(t, []interface{}{"home", "kitchen"}, result["tags"])
+	assert.NotEmpty(t, result["created_at"])
+	assert.Equal(t, result["created_at"], result["updated_at"])
+	assert.Nil(t, result["completed_at"])
 }
 
 func testGetList(t *testing.T) {
@@ -120,7 +283,7 @@ func testGetList(t *testing.T) {
 		{
 			ID:     1,
 			Name:   "Eat Dinner",
-			Status: 0,
+			Status: model.TaskStatusTodo,
 		},
 	}
 
@@ -146,20 +309,315 @@ func testGetList(t *testing.T) {
 		taskShouldBe(t, task, model.Task{
 			ID:     int(result["id"].(float64)),
 			Name:   result["name"].(string),
-			Status: int(result["status"].(float64)),
+			Status: model.TaskStatus(result["status"].(string)),
 		})
 	}
 }
 
+func testGetListOtherUser(t *testing.T) {
+	req := WithPrincipal(prepareGetTasksRequest(t), otherUserID)
+
+	rr := httptest.NewRecorder()
+	taskHandler.GetTasksHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusOK)
+
+	response := ParseResponse(t, rr)
+	results, ok := response["result"].([]interface{})
+	assert.True(t, ok, "Unexpected result type")
+	assert.Empty(t, results, "Tasks of other users are visible")
+}
+
+func testGetListInvalidLimit(t *testing.T) {
+	req := prepareGetTasksRequestWithQuery(t, "limit=0")
+
+	rr := httptest.NewRecorder()
+	taskHandler.GetTasksHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusBadRequest)
+
+	response := ParseResponse(t, rr)
+	ResultShouldBe(t, ErrInvalidLimit, response["result"])
+}
+
+func testGetListInvalidCursor(t *testing.T) {
+	req := prepareGetTasksRequestWithQuery(t, "cursor=invalid")
+
+	rr := httptest.NewRecorder()
+	taskHandler.GetTasksHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusBadRequest)
+
+	response := ParseResponse(t, rr)
+	ResultShouldBe(t, ErrInvalidCursor, response["result"])
+}
+
+func testGetListWithLimit(t *testing.T) {
+	taskID, err := taskService.CreateTask(&model.Task{UserID: taskOwnerID, Name: "Wash Dishes"})
+	assert.NoError(t, err)
+	defer taskService.DeleteTask(taskOwnerID, taskID)
+
+	req := prepareGetTasksRequestWithQuery(t, "limit=1")
+
+	rr := httptest.NewRecorder()
+	taskHandler.GetTasksHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusOK)
+
+	response := ParseResponse(t, rr)
+	results := response["result"].([]interface{})
+	assert.Len(t, results, 1)
+	assert.Equal(t, "Eat Dinner", results[0].(map[string]interface{})["name"])
+
+	nextCursor, ok := response["next_cursor"].(string)
+	assert.True(t, ok, "Missing next cursor")
+
+	req = prepareGetTasksRequestWithQuery(t, "limit=1&cursor="+nextCursor)
+
+	rr = httptest.NewRecorder()
+	taskHandler.GetTasksHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusOK)
+
+	response = ParseResponse(t, rr)
+	results = response["result"].([]interface{})
+	assert.Len(t, results, 1)
+	assert.Equal(t, "Wash Dishes", results[0].(map[string]interface{})["name"])
+	assert.Nil(t, response["next_cursor"])
+}
+
+func testGetListInvalidFilters(t *testing.T) {
+	invalidQueries := map[string]string{
+		"status=todo,finished":  ErrInvalidStatusFilter,
+		"due_before=tomorrow":   ErrInvalidDateFilter,
+		"created_after=2024-01": ErrInvalidDateFilter,
+		"sort=-owner":           ErrInvalidSort,
+		"sort=name,,id":         ErrInvalidSort,
+		"tag=home,,kitchen":     ErrInvalidTagFilter,
+	}
+
+	for query, message := range invalidQueries {
+		req := prepareGetTasksRequestWithQuery(t, query)
+
+		rr := httptest.NewRecorder()
+		taskHandler.GetTasksHandler(rr, req)
+
+		HttpStatusShouldBe(t, rr, http.StatusBadRequest)
+
+		response := ParseResponse(t, rr)
+		ResultShouldBe(t, message, response["result"])
+	}
+}
+
+func testGetListFilteredAndSorted(t *testing.T) {
+	for _, name := range []string{"Eat Lunch", "Wash Dishes"} {
+		taskID, err := taskService.CreateTask(&model.Task{UserID: taskOwnerID, Name: name, Priority: model.TaskPriorityLow})
+		assert.NoError(t, err)
+		defer taskService.DeleteTask(taskOwnerID, taskID)
+	}
+
+	req := prepareGetTasksRequestWithQuery(t, "status=todo&name=eat&sort=-priority,-name")
+
+	rr := httptest.NewRecorder()
+	taskHandler.GetTasksHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusOK)
+
+	response := ParseResponse(t, rr)
+	results := response["result"].([]interface{})
+	assert.Len(t, results, 2)
+	assert.Equal(t, "Eat Dinner", results[0].(map[string]interface{})["name"])
+	assert.Equal(t, "Eat Lunch", results[1].(map[string]interface{})["name"])
+}
+
+func testGetListByTags(t *testing.T) {
+	tasksData := []model.Task{
+		{UserID: taskOwnerID, Name: "Fix Sink", Tags: []string{"home", "urgent"}},
+		{UserID: taskOwnerID, Name: "Call Plumber", Tags: []string{"urgent"}},
+	}
+	for i := range tasksData {
+		taskID, err := taskService.CreateTask(&tasksData[i])
+		assert.NoError(t, err)
+		defer taskService.DeleteTask(taskOwnerID, taskID)
+	}
+
+	queries := map[string][]string{
+		"tag=home":              {"Eat Dinner", "Fix Sink"},
+		"tag=home&tag=urgent":   {"Fix Sink"},
+		"tag=kitchen,urgent":    {"Eat Dinner", "Fix Sink", "Call Plumber"},
+		"tag=kitchen&tag=plumb": {},
+	}
+
+	for query, names := range queries {
+		req := prepareGetTasksRequestWithQuery(t, query)
+
+		rr := httptest.NewRecorder()
+		taskHandler.GetTasksHandler(rr, req)
+
+		HttpStatusShouldBe(t, rr, http.StatusOK)
+
+		response := ParseResponse(t, rr)
+		results := response["result"].([]interface{})
+		resultNames := []string{}
+		for _, result := range results {
+			resultNames = append(resultNames, result.(map[string]interface{})["name"].(string))
+		}
+		assert.Equal(t, names, resultNames, query)
+	}
+}
+
+func testSearchInvalidParameters(t *testing.T) {
+	invalidQueries := map[string]string{
+		"":              ErrInvalidSearchQuery,
+		"q=%22%2A%22":   ErrInvalidSearchQuery,
+		"q=eat&limit=0": ErrInvalidLimit,
+	}
+
+	for query, message := range invalidQueries {
+		req := prepareSearchTasksRequest(t, query)
+
+		rr := httptest.NewRecorder()
+		taskHandler.SearchTasksHandler(rr, req)
+
+		HttpStatusShouldBe(t, rr, http.StatusBadRequest)
+
+		response := ParseResponse(t, rr)
+		ResultShouldBe(t, message, response["result"])
+	}
+}
+
+func testSearch(t *testing.T) {
+	taskID, err := taskService.CreateTask(&model.Task{UserID: taskOwnerID, Name: "Wash Dishes", Description: "Right after dinner"})
+	assert.NoError(t, err)
+	defer taskService.DeleteTask(taskOwnerID, taskID)
+
+	req := prepareSearchTasksRequest(t, "q=dinn%2A")
+
+	rr := httptest.NewRecorder()
+	taskHandler.SearchTasksHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusOK)
+
+	response := ParseResponse(t, rr)
+	results := response["result"].([]interface{})
+	assert.Len(t, results, 2)
+
+	first := results[0].(map[string]interface{})
+	assert.Equal(t, "Eat Dinner", first["task"].(map[string]interface{})["name"])
+	assert.Equal(t, "Eat <mark>Dinner</mark>", first["highlight"])
+
+	second := results[1].(map[string]interface{})
+	assert.Equal(t, float64(taskID), second["task"].(map[string]interface{})["id"])
+	assert.Equal(t, "Right after <mark>dinner</mark>", second["snippet"])
+}
+
+func testGetWithInvalidID(t *testing.T) {
+	req, err := http.NewRequest("GET", "/task/invalid", nil)
+	assert.NoError(t, err, "Error creating request")
+	req = WithPrincipal(req, taskOwnerID)
+
+	rr := httptest.NewRecorder()
+	taskHandler.GetTaskHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusBadRequest)
+
+	response := ParseResponse(t, rr)
+	ResultShouldBe(t, ErrInvalidTaskID, response["result"])
+}
+
+func testGetNotExist(t *testing.T) {
+	req := prepareGetTaskRequest(t, 999)
+
+	rr := httptest.NewRecorder()
+	taskHandler.GetTaskHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusNotFound)
+
+	response := ParseResponse(t, rr)
+	ResultShouldBe(t, ErrTaskNotFound, response["result"])
+}
+
+func testGetOtherUser(t *testing.T) {
+	req := WithPrincipal(prepareGetTaskRequest(t, 1), otherUserID)
+
+	rr := httptest.NewRecorder()
+	taskHandler.GetTaskHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusNotFound)
+
+	response := ParseResponse(t, rr)
+	ResultShouldBe(t, ErrTaskNotFound, response["result"])
+}
+
+func testGet(t *testing.T) {
+	req := prepareGetTaskRequest(t, 1)
+
+	rr := httptest.NewRecorder()
+	taskHandler.GetTaskHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusOK)
+	assert.NotEmpty(t, rr.Header().Get("ETag"), "ETag header not set")
+
+	response := ParseResponse(t, rr)
+	ResultShouldExist(t, response)
+
+	result := response["result"].(map[string]interface{})
+	taskShouldBe(t, model.Task{
+		ID:     1,
+		Name:   "Eat Dinner",
+		Status: model.TaskStatusTodo,
+	}, model.Task{
+		ID:     int(result["id"].(float64)),
+		Name:   result["name"].(string),
+		Status: model.TaskStatus(result["status"].(string)),
+	})
+	assert.Equal(t, "Pasta with tomato sauce", result["description"])
+}
+
+func testGetNotModified(t *testing.T) {
+	rr := httptest.NewRecorder()
+	taskHandler.GetTaskHandler(rr, prepareGetTaskRequest(t, 1))
+	etag := rr.Header().Get("ETag")
+
+	req := prepareGetTaskRequest(t, 1)
+	req.Header.Set("If-None-Match", `"stale", `+etag)
+
+	rr = httptest.NewRecorder()
+	taskHandler.GetTaskHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusNotModified)
+	assert.Equal(t, etag, rr.Header().Get("ETag"))
+	assert.Empty(t, rr.Body.String(), "Body sent with 304 response")
+
+	task, err := taskService.GetTaskByID(taskOwnerID, 1)
+	assert.NoError(t, err)
+	task.Priority = model.TaskPriorityLow
+	assert.NoError(t, taskService.UpdateTask(&task))
+	defer func() {
+		task.Priority = model.TaskPriorityHigh
+		assert.NoError(t, taskService.UpdateTask(&task))
+	}()
+
+	req = prepareGetTaskRequest(t, 1)
+	req.Header.Set("If-None-Match", etag)
+
+	rr = httptest.NewRecorder()
+	taskHandler.GetTaskHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusOK)
+	assert.NotEqual(t, etag, rr.Header().Get("ETag"), "ETag unchanged after update")
+}
+
 func testUpdateWithoutID(t *testing.T) {
 	taskData := map[string]interface{}{
 		"name":   "Eat Lunch",
-		"status": 1,
+		"status": model.TaskStatusDone,
 	}
 
 	reqBody := PrepareJsonBody(t, taskData)
 	req, err := http.NewRequest("PUT", "/task/", bytes.NewBuffer(reqBody))
 	assert.NoError(t, err, "Error creating request")
+	req = WithPrincipal(req, taskOwnerID)
 
 	rr := httptest.NewRecorder()
 	taskHandler.UpdateTaskHandler(rr, req)
@@ -174,12 +632,13 @@ func testUpdateWithoutID(t *testing.T) {
 func testUpdateWithInvalidID(t *testing.T) {
 	taskData := map[string]interface{}{
 		"name":   "Eat Lunch",
-		"status": 1,
+		"status": model.TaskStatusDone,
 	}
 
 	reqBody := PrepareJsonBody(t, taskData)
 	req, err := http.NewRequest("PUT", "/task/invalid", bytes.NewBuffer(reqBody))
 	assert.NoError(t, err, "Error creating request")
+	req = WithPrincipal(req, taskOwnerID)
 
 	rr := httptest.NewRecorder()
 	taskHandler.UpdateTaskHandler(rr, req)
@@ -209,10 +668,27 @@ func testUpdateWithIDInBody(t *testing.T) {
 	ResultShouldBe(t, ErrNotAllowTaskID, response["result"])
 }
 
+func testUpdateWithTimestampsInBody(t *testing.T) {
+	taskData := map[string]interface{}{
+		"completed_at": "2024-01-01T00:00:00Z",
+	}
+
+	reqBody := PrepareJsonBody(t, taskData)
+	req := prepareUpdateTaskRequest(t, 1, reqBody)
+
+	rr := httptest.NewRecorder()
+	taskHandler.UpdateTaskHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusBadRequest)
+
+	response := ParseResponse(t, rr)
+	ResultShouldBe(t, ErrNotAllowTaskTimes, response["result"])
+}
+
 func testUpdateNotExist(t *testing.T) {
 	taskData := map[string]interface{}{
 		"name":   "Eat Lunch",
-		"status": 1,
+		"status": model.TaskStatusDone,
 	}
 
 	reqBody := PrepareJsonBody(t, taskData)
@@ -228,44 +704,78 @@ func testUpdateNotExist(t *testing.T) {
 	ResultShouldBe(t, "Task not found", response["result"])
 }
 
-func testUpdateOnlyName(t *testing.T) {
+func testUpdateOtherUser(t *testing.T) {
 	taskData := map[string]interface{}{
-		"name": "Eat Lunch",
+		"name": "Steal Lunch",
 	}
 
 	reqBody := PrepareJsonBody(t, taskData)
-	req := prepareUpdateTaskRequest(t, 1, reqBody)
+	req := WithPrincipal(prepareUpdateTaskRequest(t, 1, reqBody), otherUserID)
 
 	rr := httptest.NewRecorder()
 	taskHandler.UpdateTaskHandler(rr, req)
 
-	HttpStatusShouldBe(t, rr, http.StatusOK)
+	HttpStatusShouldBe(t, rr, http.StatusNotFound)
 
 	response := ParseResponse(t, rr)
-	ResultShouldExist(t, response)
+	ResultShouldBe(t, ErrTaskNotFound, response["result"])
+}
 
-	result := response["result"].(map[string]interface{})
-	taskShouldBe(t, model.Task{
-		ID:     1,
-		Name:   taskData["name"].(string),
-		Status: 0,
-	}, model.Task{
-		ID:     int(result["id"].(float64)),
-		Name:   result["name"].(string),
-		Status: int(result["status"].(float64)),
-	})
+func testPatchUnsupportedType(t *testing.T) {
+	reqBody := PrepareJsonBody(t, map[string]interface{}{"name": "Eat Lunch"})
+	req := preparePatchTaskRequest(t, 1, "application/json", reqBody)
+
+	rr := httptest.NewRecorder()
+	taskHandler.PatchTaskHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusUnsupportedMediaType)
+	assert.Equal(t, mergePatchType+", "+jsonPatchType, rr.Header().Get("Accept-Patch"))
+
+	response := ParseResponse(t, rr)
+	ResultShouldBe(t, ErrUnsupportedPatch, response["result"])
 }
 
-func testUpdateOnlyStatus(t *testing.T) {
-	taskData := map[string]interface{}{
-		"status": 1,
+func testPatchInvalidDocument(t *testing.T) {
+	invalidPatches := []struct {
+		contentType string
+		body        string
+	}{
+		{mergePatchType, `["name"]`},
+		{mergePatchType, `null`},
+		{jsonPatchType, `{"op": "remove", "path": "/due_at"}`},
+		{jsonPatchType, `[{"op": "rename", "path": "/name"}]`},
 	}
 
-	reqBody := PrepareJsonBody(t, taskData)
-	req := prepareUpdateTaskRequest(t, 1, reqBody)
+	for _, patch := range invalidPatches {
+		req := preparePatchTaskRequest(t, 1, patch.contentType, []byte(patch.body))
+
+		rr := httptest.NewRecorder()
+		taskHandler.PatchTaskHandler(rr, req)
+
+		HttpStatusShouldBe(t, rr, http.StatusBadRequest)
+
+		response := ParseResponse(t, rr)
+		ResultShouldBe(t, ErrInvalidPatch, response["result"])
+	}
+}
+
+func testPatchNotExist(t *testing.T) {
+	req := preparePatchTaskRequest(t, 999, mergePatchType, []byte(`{"name": "Eat Lunch"}`))
 
 	rr := httptest.NewRecorder()
-	taskHandler.UpdateTaskHandler(rr, req)
+	taskHandler.PatchTaskHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusNotFound)
+
+	response := ParseResponse(t, rr)
+	ResultShouldBe(t, ErrTaskNotFound, response["result"])
+}
+
+func testMergePatchName(t *testing.T) {
+	req := preparePatchTaskRequest(t, 1, mergePatchType+"; charset=utf-8", []byte(`{"name": "Eat Lunch"}`))
+
+	rr := httptest.NewRecorder()
+	taskHandler.PatchTaskHandler(rr, req)
 
 	HttpStatusShouldBe(t, rr, http.StatusOK)
 
@@ -276,25 +786,64 @@ func testUpdateOnlyStatus(t *testing.T) {
 	taskShouldBe(t, model.Task{
 		ID:     1,
 		Name:   "Eat Lunch",
-		Status: taskData["status"].(int),
+		Status: model.TaskStatusTodo,
 	}, model.Task{
 		ID:     int(result["id"].(float64)),
 		Name:   result["name"].(string),
-		Status: int(result["status"].(float64)),
+		Status: model.TaskStatus(result["status"].(string)),
 	})
+	assert.Equal(t, "Pasta with tomato sauce", result["description"], "Untouched attribute changed")
+	assert.Equal(t, float64(model.TaskPriorityHigh), result["priority"], "Untouched attribute changed")
+	assert.NotNil(t, result["due_at"], "Untouched attribute changed")
 }
 
-func testUpdate(t *testing.T) {
-	taskData := map[string]interface{}{
-		"name":   "Eat Breakfast",
-		"status": 1,
+func testMergePatchRemove(t *testing.T) {
+	req := preparePatchTaskRequest(t, 1, mergePatchType, []byte(`{"due_at": null, "priority": 1}`))
+
+	rr := httptest.NewRecorder()
+	taskHandler.PatchTaskHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusOK)
+
+	response := ParseResponse(t, rr)
+	result := response["result"].(map[string]interface{})
+	assert.Nil(t, result["due_at"], "Due date not removed")
+	assert.Equal(t, float64(model.TaskPriorityLow), result["priority"])
+	assert.Equal(t, "Pasta with tomato sauce", result["description"], "Untouched attribute changed")
+}
+
+func testMergePatchInvalidAttribute(t *testing.T) {
+	invalidPatches := map[string]string{
+		`{"name": null}`:         ErrMissingTaskName,
+		`{"status": "finished"}`: ErrInvalidTaskStatus,
+		`{"priority": 9}`:        ErrInvalidTaskPriority,
+		`{"version": 9}`:         ErrPatchReadOnly,
+		`{"created_at": null}`:   ErrPatchReadOnly,
 	}
 
-	reqBody := PrepareJsonBody(t, taskData)
-	req := prepareUpdateTaskRequest(t, 1, reqBody)
+	for body, message := range invalidPatches {
+		req := preparePatchTaskRequest(t, 1, mergePatchType, []byte(body))
+
+		rr := httptest.NewRecorder()
+		taskHandler.PatchTaskHandler(rr, req)
+
+		HttpStatusShouldBe(t, rr, http.StatusUnprocessableEntity)
+
+		response := ParseResponse(t, rr)
+		ResultShouldBe(t, message, response["result"])
+	}
+}
+
+func testJSONPatchStatus(t *testing.T) {
+	body := `[
+		{"op": "test", "path": "/name", "value": "Eat Lunch"},
+		{"op": "replace", "path": "/status", "value": "done"},
+		{"op": "remove", "path": "/description"}
+	]`
+	req := preparePatchTaskRequest(t, 1, jsonPatchType, []byte(body))
 
 	rr := httptest.NewRecorder()
-	taskHandler.UpdateTaskHandler(rr, req)
+	taskHandler.PatchTaskHandler(rr, req)
 
 	HttpStatusShouldBe(t, rr, http.StatusOK)
 
@@ -304,16 +853,162 @@ func testUpdate(t *testing.T) {
 	result := response["result"].(map[string]interface{})
 	taskShouldBe(t, model.Task{
 		ID:     1,
-		Name:   taskData["name"].(string),
-		Status: taskData["status"].(int),
+		Name:   "Eat Lunch",
+		Status: model.TaskStatusDone,
 	}, model.Task{
 		ID:     int(result["id"].(float64)),
 		Name:   result["name"].(string),
-		Status: int(result["status"].(float64)),
+		Status: model.TaskStatus(result["status"].(string)),
 	})
+	assert.NotNil(t, result["completed_at"], "Completion time not set")
+	assert.Equal(t, "", result["description"], "Description not removed")
+	assert.Equal(t, float64(model.TaskPriorityLow), result["priority"], "Untouched attribute changed")
 }
 
-func testDeleteNotExist(t *testing.T) {
+func testJSONPatchTestFailed(t *testing.T) {
+	body := `[
+		{"op": "test", "path": "/status", "value": "todo"},
+		{"op": "replace", "path": "/status", "value": "in_progress"}
+	]`
+	req := preparePatchTaskRequest(t, 1, jsonPatchType, []byte(body))
+
+	rr := httptest.NewRecorder()
+	taskHandler.PatchTaskHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusConflict)
+
+	response := ParseResponse(t, rr)
+	ResultShouldBe(t, ErrPatchTestFailed, response["result"])
+}
+
+func testJSONPatchReadOnly(t *testing.T) {
+	body := `[{"op": "replace", "path": "/id", "value": 2}]`
+	req := preparePatchTaskRequest(t, 1, jsonPatchType, []byte(body))
+
+	rr := httptest.NewRecorder()
+	taskHandler.PatchTaskHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusUnprocessableEntity)
+
+	response := ParseResponse(t, rr)
+	ResultShouldBe(t, ErrPatchReadOnly, response["result"])
+}
+
+func testJSONPatchMissingPath(t *testing.T) {
+	body := `[{"op": "replace", "path": "/owner", "value": 2}]`
+	req := preparePatchTaskRequest(t, 1, jsonPatchType, []byte(body))
+
+	rr := httptest.NewRecorder()
+	taskHandler.PatchTaskHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusUnprocessableEntity)
+
+	response := ParseResponse(t, rr)
+	ResultShouldBe(t, ErrPatchNotApplicable, response["result"])
+}
+
+func testUpdateMissingName(t *testing.T) {
+	reqBody := PrepareJsonBody(t, map[string]interface{}{"status": model.TaskStatusDone})
+	req := prepareUpdateTaskRequest(t, 1, reqBody)
+
+	rr := httptest.NewRecorder()
+	taskHandler.UpdateTaskHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusBadRequest)
+
+	response := ParseResponse(t, rr)
+	ResultShouldBe(t, ErrMissingTaskName, response["result"])
+}
+
+func testUpdate(t *testing.T) {
+	taskData := map[string]interface{}{
+		"name":   "Eat Breakfast",
+		"status": model.TaskStatusDone,
+	}
+
+	reqBody := PrepareJsonBody(t, taskData)
+	req := prepareUpdateTaskRequest(t, 1, reqBody)
+
+	rr := httptest.NewRecorder()
+	taskHandler.UpdateTaskHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusOK)
+
+	response := ParseResponse(t, rr)
+	ResultShouldExist(t, response)
+
+	result := response["result"].(map[string]interface{})
+	taskShouldBe(t, model.Task{
+		ID:     1,
+		Name:   taskData["name"].(string),
+		Status: taskData["status"].(model.TaskStatus),
+	}, model.Task{
+		ID:     int(result["id"].(float64)),
+		Name:   result["name"].(string),
+		Status: model.TaskStatus(result["status"].(string)),
+	})
+	assert.Equal(t, float64(model.TaskPriorityNone), result["priority"], "Omitted attribute not reset")
+}
+
+func testUpdateInvalidTransition(t *testing.T) {
+	taskData := map[string]interface{}{
+		"name":   "Eat Breakfast",
+		"status": model.TaskStatusBlocked,
+	}
+
+	reqBody := PrepareJsonBody(t, taskData)
+	req := prepareUpdateTaskRequest(t, 1, reqBody)
+
+	rr := httptest.NewRecorder()
+	taskHandler.UpdateTaskHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusUnprocessableEntity)
+
+	response := ParseResponse(t, rr)
+	ResultShouldBe(t, ErrInvalidTransition, response["result"])
+}
+
+func testUpdateStaleIfMatch(t *testing.T) {
+	reqBody := PrepareJsonBody(t, map[string]interface{}{"name": "Eat Brunch"})
+	req := prepareUpdateTaskRequest(t, 1, reqBody)
+	req.Header.Set("If-Match", `"1"`)
+
+	rr := httptest.NewRecorder()
+	taskHandler.UpdateTaskHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusPreconditionFailed)
+	assert.NotEqual(t, `"1"`, rr.Header().Get("ETag"), "Current ETag not returned")
+
+	response := ParseResponse(t, rr)
+	ResultShouldBe(t, ErrTaskModified, response["result"])
+
+	task, err := taskService.GetTaskByID(taskOwnerID, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "Eat Breakfast", task.Name, "Stale update applied")
+}
+
+func testUpdateWithIfMatch(t *testing.T) {
+	task, err := taskService.GetTaskByID(taskOwnerID, 1)
+	assert.NoError(t, err)
+	etag := taskETag(task)
+
+	reqBody := PrepareJsonBody(t, map[string]interface{}{"name": "Eat Brunch"})
+	req := prepareUpdateTaskRequest(t, 1, reqBody)
+	req.Header.Set("If-Match", etag)
+
+	rr := httptest.NewRecorder()
+	taskHandler.UpdateTaskHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusOK)
+	assert.NotEqual(t, etag, rr.Header().Get("ETag"), "ETag unchanged after update")
+
+	response := ParseResponse(t, rr)
+	result := response["result"].(map[string]interface{})
+	assert.Equal(t, "Eat Brunch", result["name"])
+	assert.Equal(t, float64(task.Version+1), result["version"])
+}
+
+func testDeleteNotExist(t *testing.T) {
 	req := prepareDeleteTaskRequest(t, 999)
 
 	rr := httptest.NewRecorder()
@@ -326,8 +1021,40 @@ func testDeleteNotExist(t *testing.T) {
 	ResultShouldBe(t, "Task not found", response["result"])
 }
 
+func testDeleteOtherUser(t *testing.T) {
+	req := WithPrincipal(prepareDeleteTaskRequest(t, 1), otherUserID)
+
+	rr := httptest.NewRecorder()
+	taskHandler.DeleteTaskHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusNotFound)
+
+	response := ParseResponse(t, rr)
+	ResultShouldBe(t, ErrTaskNotFound, response["result"])
+}
+
+func testDeleteStaleIfMatch(t *testing.T) {
+	req := prepareDeleteTaskRequest(t, 1)
+	req.Header.Set("If-Match", `"1"`)
+
+	rr := httptest.NewRecorder()
+	taskHandler.DeleteTaskHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusPreconditionFailed)
+
+	response := ParseResponse(t, rr)
+	ResultShouldBe(t, ErrTaskModified, response["result"])
+
+	_, err := taskService.GetTaskByID(taskOwnerID, 1)
+	assert.NoError(t, err, "Task deleted despite stale If-Match")
+}
+
 func testDelete(t *testing.T) {
+	task, err := taskService.GetTaskByID(taskOwnerID, 1)
+	assert.NoError(t, err)
+
 	req := prepareDeleteTaskRequest(t, 1)
+	req.Header.Set("If-Match", taskETag(task))
 
 	rr := httptest.NewRecorder()
 	taskHandler.DeleteTaskHandler(rr, req)
@@ -335,12 +1062,428 @@ func testDelete(t *testing.T) {
 	HttpStatusShouldBe(t, rr, http.StatusOK)
 }
 
+func testBulkInvalidRequests(t *testing.T) {
+	invalidRequests := []struct {
+		body    string
+		message string
+	}{
+		{`[]`, ErrBadRequest},
+		{`{"operations": []}`, ErrMissingBulkOperations},
+		{`{"mode": "eventually", "operations": [{"op": "delete", "id": 1}]}`, ErrInvalidBulkMode},
+		{`{"operations": [{"op": "archive", "id": 1}]}`, ErrInvalidBulkOperation},
+		{`{"operations": [{"op": "create", "id": 1, "task": {"name": "Eat Dinner"}}]}`, ErrInvalidBulkOperation},
+		{`{"operations": [{"op": "update", "task": {"name": "Eat Dinner"}}]}`, ErrInvalidBulkOperation},
+		{`{"operations": [{"op": "delete"}]}`, ErrInvalidBulkOperation},
+		{`{"operations": [` + strings.Repeat(`{"op": "delete", "id": 1},`, 100) + `{"op": "delete", "id": 1}]}`, ErrTooManyBulkOperations},
+	}
+
+	for _, request := range invalidRequests {
+		req := prepareBulkTasksRequest(t, []byte(request.body))
+
+		rr := httptest.NewRecorder()
+		taskHandler.BulkTasksHandler(rr, req)
+
+		HttpStatusShouldBe(t, rr, http.StatusBadRequest)
+
+		response := ParseResponse(t, rr)
+		ResultShouldBe(t, request.message, response["result"])
+	}
+}
+
+func testBulkAllOrNothingFailure(t *testing.T) {
+	body := `{"operations": [
+		{"op": "create", "task": {"name": "Plan Sprint"}},
+		{"op": "create", "task": {"name": "Review Sprint", "priority": 9}}
+	]}`
+	req := prepareBulkTasksRequest(t, []byte(body))
+
+	rr := httptest.NewRecorder()
+	taskHandler.BulkTasksHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusMultiStatus)
+
+	results := bulkResults(t, rr)
+	assert.Equal(t, float64(http.StatusFailedDependency), results[0]["status"])
+	assert.Equal(t, ErrBulkRolledBack, results[0]["result"])
+	assert.Equal(t, float64(http.StatusBadRequest), results[1]["status"])
+	assert.Equal(t, ErrInvalidTaskPriority, results[1]["result"])
+
+	tasks, _, err := taskService.GetTasks(taskOwnerID, model.TaskQuery{Name: "Sprint"}, "")
+	assert.NoError(t, err)
+	assert.Empty(t, tasks, "Operations not rolled back")
+}
+
+func testBulkBestEffort(t *testing.T) {
+	body := `{"mode": "best_effort", "operations": [
+		{"op": "create", "task": {"name": "Plan Sprint"}},
+		{"op": "create", "task": {"name": "Review Sprint", "priority": 9}},
+		{"op": "delete", "id": 999}
+	]}`
+	req := prepareBulkTasksRequest(t, []byte(body))
+
+	rr := httptest.NewRecorder()
+	taskHandler.BulkTasksHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusMultiStatus)
+
+	results := bulkResults(t, rr)
+	assert.Equal(t, float64(http.StatusCreated), results[0]["status"])
+	assert.Equal(t, "Plan Sprint", results[0]["result"].(map[string]interface{})["name"])
+	assert.Equal(t, float64(http.StatusBadRequest), results[1]["status"])
+	assert.Equal(t, float64(http.StatusNotFound), results[2]["status"])
+	assert.Equal(t, ErrTaskNotFound, results[2]["result"])
+
+	tasks, _, err := taskService.GetTasks(taskOwnerID, model.TaskQuery{Name: "Sprint"}, "")
+	assert.NoError(t, err)
+	assert.Len(t, tasks, 1)
+}
+
+func testBulkAllOrNothing(t *testing.T) {
+	tasks, _, err := taskService.GetTasks(taskOwnerID, model.TaskQuery{Name: "Plan Sprint"}, "")
+	assert.NoError(t, err)
+	taskID := tasks[0].ID
+
+	body := fmt.Sprintf(`{"mode": "all_or_nothing", "operations": [
+		{"op": "update", "id": %d, "version": 1, "task": {"status": "done"}},
+		{"op": "create", "task": {"name": "Retro Sprint"}},
+		{"op": "delete", "id": %d, "version": 2}
+	]}`, taskID, taskID)
+	req := prepareBulkTasksRequest(t, []byte(body))
+
+	rr := httptest.NewRecorder()
+	taskHandler.BulkTasksHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusOK)
+
+	results := bulkResults(t, rr)
+	assert.Equal(t, float64(http.StatusOK), results[0]["status"])
+	updatedTask := results[0]["result"].(map[string]interface{})
+	assert.Equal(t, string(model.TaskStatusDone), updatedTask["status"])
+	assert.Equal(t, "Plan Sprint", updatedTask["name"], "Untouched attribute changed")
+	assert.Equal(t, float64(http.StatusCreated), results[1]["status"])
+	assert.Equal(t, float64(http.StatusOK), results[2]["status"])
+	assert.Nil(t, results[2]["result"])
+
+	tasks, _, err = taskService.GetTasks(taskOwnerID, model.TaskQuery{Name: "Sprint"}, "")
+	assert.NoError(t, err)
+	assert.Len(t, tasks, 1)
+	assert.Equal(t, "Retro Sprint", tasks[0].Name)
+}
+
+func testCreateSubtaskMissingParent(t *testing.T) {
+	body := PrepareJsonBody(t, map[string]interface{}{"name": "Pack Boxes", "parent_id": 9999})
+	req := WithPrincipal(prepareCreateTaskRequest(t, body), hierarchyOwnerID)
+
+	rr := httptest.NewRecorder()
+	taskHandler.CreateTaskHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusUnprocessableEntity)
+
+	response := ParseResponse(t, rr)
+	ResultShouldBe(t, ErrParentTaskNotFound, response["result"])
+}
+
+func testCreateSubtasks(t *testing.T) {
+	tasksData := []map[string]interface{}{
+		{"name": "Move House"},
+		{"name": "Pack Boxes"},
+		{"name": "Book Van", "status": "done"},
+	}
+
+	for i, taskData := range tasksData {
+		if i > 0 {
+			taskData["parent_id"] = parentTaskID
+		}
+		req := WithPrincipal(prepareCreateTaskRequest(t, PrepareJsonBody(t, taskData)), hierarchyOwnerID)
+
+		rr := httptest.NewRecorder()
+		taskHandler.CreateTaskHandler(rr, req)
+
+		HttpStatusShouldBe(t, rr, http.StatusCreated)
+
+		result := ParseResponse(t, rr)["result"].(map[string]interface{})
+		assert.Equal(t, taskData["parent_id"], intOrNil(result["parent_id"]))
+		assert.Nil(t, result["progress"])
+		if i == 0 {
+			parentTaskID = int(result["id"].(float64))
+		} else if i == 1 {
+			childTaskID = int(result["id"].(float64))
+		}
+	}
+
+	req := WithPrincipal(prepareGetTaskRequest(t, parentTaskID), hierarchyOwnerID)
+
+	rr := httptest.NewRecorder()
+	taskHandler.GetTaskHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusOK)
+
+	result := ParseResponse(t, rr)["result"].(map[string]interface{})
+	assert.Equal(t, float64(50), result["progress"])
+}
+
+func testGetChildTasksNotExist(t *testing.T) {
+	req := WithPrincipal(prepareGetTaskPathRequest(t, parentTaskID, "children"), otherUserID)
+
+	rr := httptest.NewRecorder()
+	taskHandler.GetChildTasksHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusNotFound)
+
+	response := ParseResponse(t, rr)
+	ResultShouldBe(t, ErrTaskNotFound, response["result"])
+}
+
+func testGetChildTasks(t *testing.T) {
+	req := WithPrincipal(prepareGetTaskPathRequest(t, parentTaskID, "children?status=todo"), hierarchyOwnerID)
+
+	rr := httptest.NewRecorder()
+	taskHandler.GetChildTasksHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusOK)
+
+	response := ParseResponse(t, rr)
+	results := response["result"].([]interface{})
+	assert.Len(t, results, 1)
+	assert.Equal(t, "Pack Boxes", results[0].(map[string]interface{})["name"])
+	assert.Nil(t, response["next_cursor"])
+}
+
+func testGetTaskSubtree(t *testing.T) {
+	taskData := map[string]interface{}{"name": "Buy Tape", "parent_id": childTaskID}
+	req := WithPrincipal(prepareCreateTaskRequest(t, PrepareJsonBody(t, taskData)), hierarchyOwnerID)
+	rr := httptest.NewRecorder()
+	taskHandler.CreateTaskHandler(rr, req)
+	HttpStatusShouldBe(t, rr, http.StatusCreated)
+
+	req = WithPrincipal(prepareGetTaskPathRequest(t, parentTaskID, "subtree"), hierarchyOwnerID)
+
+	rr = httptest.NewRecorder()
+	taskHandler.GetTaskSubtreeHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusOK)
+
+	root := ParseResponse(t, rr)["result"].(map[string]interface{})
+	assert.Equal(t, "Move House", root["name"])
+	children := root["children"].([]interface{})
+	assert.Len(t, children, 2)
+
+	child := children[0].(map[string]interface{})
+	assert.Equal(t, "Pack Boxes", child["name"])
+	assert.Equal(t, float64(0), child["progress"])
+	grandchildren := child["children"].([]interface{})
+	assert.Len(t, grandchildren, 1)
+	assert.Equal(t, "Buy Tape", grandchildren[0].(map[string]interface{})["name"])
+	assert.Empty(t, grandchildren[0].(map[string]interface{})["children"])
+
+	assert.Equal(t, "Book Van", children[1].(map[string]interface{})["name"])
+}
+
+func testUpdateSubtaskCycle(t *testing.T) {
+	for _, parentID := range []int{parentTaskID, childTaskID} {
+		body := fmt.Sprintf(`{"parent_id": %d}`, childTaskID)
+		req := WithPrincipal(preparePatchTaskRequest(t, parentID, mergePatchType, []byte(body)), hierarchyOwnerID)
+
+		rr := httptest.NewRecorder()
+		taskHandler.PatchTaskHandler(rr, req)
+
+		HttpStatusShouldBe(t, rr, http.StatusUnprocessableEntity)
+
+		response := ParseResponse(t, rr)
+		ResultShouldBe(t, ErrTaskCycle, response["result"])
+	}
+}
+
+func testCreateRecurringTask(t *testing.T) {
+	taskData := map[string]interface{}{
+		"name":       "Take Out Trash",
+		"due_at":     "2024-01-05T07:00:00Z",
+		"recurrence": "rrule:freq=weekly;byday=fr,mo;count=3",
+	}
+	req := WithPrincipal(prepareCreateTaskRequest(t, PrepareJsonBody(t, taskData)), recurrenceOwnerID)
+
+	rr := httptest.NewRecorder()
+	taskHandler.CreateTaskHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusCreated)
+
+	result := ParseResponse(t, rr)["result"].(map[string]interface{})
+	assert.Equal(t, "FREQ=WEEKLY;BYDAY=MO,FR;COUNT=3", result["recurrence"])
+	recurringTaskID = int(result["id"].(float64))
+}
+
+func testCompleteRecurringTask(t *testing.T) {
+	body := PrepareJsonBody(t, map[string]interface{}{
+		"name":       "Take Out Trash",
+		"status":     "done",
+		"due_at":     "2024-01-05T07:00:00Z",
+		"recurrence": "FREQ=WEEKLY;BYDAY=MO,FR;COUNT=3",
+	})
+	req := WithPrincipal(prepareUpdateTaskRequest(t, recurringTaskID, body), recurrenceOwnerID)
+
+	rr := httptest.NewRecorder()
+	taskHandler.UpdateTaskHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusOK)
+
+	result := ParseResponse(t, rr)["result"].(map[string]interface{})
+	assert.Equal(t, "done", result["status"])
+	assert.Equal(t, "", result["recurrence"])
+
+	tasks, _, err := taskService.GetTasks(recurrenceOwnerID, model.TaskQuery{Statuses: []model.TaskStatus{model.TaskStatusTodo}}, "")
+	assert.NoError(t, err)
+	assert.Len(t, tasks, 1)
+	assert.Equal(t, "Take Out Trash", tasks[0].Name)
+	assert.Equal(t, "2024-01-08T07:00:00Z", tasks[0].DueAt.Format(time.RFC3339))
+	assert.Equal(t, "FREQ=WEEKLY;BYDAY=MO,FR;COUNT=2", tasks[0].Recurrence)
+}
+
+func intOrNil(value interface{}) interface{} {
+	if number, ok := value.(float64); ok {
+		return int(number)
+	}
+	return value
+}
+
+// failingTaskService stands in for a service whose storage is unavailable.
+type failingTaskService struct{}
+
+var errStorageUnavailable = errors.New("storage unavailable")
+
+func (failingTaskService) CreateTask(task *model.Task) (int, error) {
+	return 0, errStorageUnavailable
+}
+
+func (failingTaskService) GetTasks(userID int, query model.TaskQuery, cursor string) ([]model.Task, string, error) {
+	return nil, "", errStorageUnavailable
+}
+
+func (failingTaskService) GetTaskByID(userID int, id int) (model.Task, error) {
+	return model.Task{}, errStorageUnavailable
+}
+
+func (failingTaskService) GetChildTasks(userID int, id int, query model.TaskQuery, cursor string) ([]model.Task, string, error) {
+	return nil, "", errStorageUnavailable
+}
+
+func (failingTaskService) GetTaskSubtree(userID int, id int) (model.TaskTree, error) {
+	return model.TaskTree{}, errStorageUnavailable
+}
+
+func (failingTaskService) UpdateTask(task *model.Task) error {
+	return errStorageUnavailable
+}
+
+func (failingTaskService) DeleteTask(userID int, id int) error {
+	return errStorageUnavailable
+}
+
+func (failingTaskService) DeleteTaskAtVersion(userID int, id int, version int) error {
+	return errStorageUnavailable
+}
+
+func (failingTaskService) SearchTasks(userID int, query string, limit int) ([]model.TaskSearchResult, error) {
+	return nil, errStorageUnavailable
+}
+
+func (failingTaskService) BulkTasks(userID int, operations []service.TaskOperation, allOrNothing bool) ([]service.TaskOperationResult, error) {
+	return nil, errStorageUnavailable
+}
+
+func TestTaskHandlerServiceErrors(t *testing.T) {
+	t.Run("Create", testCreateServiceError)
+	t.Run("GetList", testGetListServiceError)
+	t.Run("Search", testSearchServiceError)
+	t.Run("Get", testGetServiceError)
+	t.Run("GetSubtree", testGetTaskSubtreeServiceError)
+	t.Run("Delete", testDeleteServiceError)
+}
+
+func testCreateServiceError(t *testing.T) {
+	body := PrepareJsonBody(t, map[string]interface{}{"name": "Eat Dinner"})
+	req := WithPrincipal(prepareCreateTaskRequest(t, body), taskOwnerID)
+	serveWithFailingService(t, req, NewTaskHandler(failingTaskService{}).CreateTaskHandler)
+}
+
+func testGetListServiceError(t *testing.T) {
+	req := WithPrincipal(prepareGetTasksRequest(t), taskOwnerID)
+	serveWithFailingService(t, req, NewTaskHandler(failingTaskService{}).GetTasksHandler)
+}
+
+func testSearchServiceError(t *testing.T) {
+	req := prepareSearchTasksRequest(t, "q=dinner")
+	serveWithFailingService(t, req, NewTaskHandler(failingTaskService{}).SearchTasksHandler)
+}
+
+func testGetServiceError(t *testing.T) {
+	req := WithPrincipal(prepareGetTaskRequest(t, 1), taskOwnerID)
+	serveWithFailingService(t, req, NewTaskHandler(failingTaskService{}).GetTaskHandler)
+}
+
+func testGetTaskSubtreeServiceError(t *testing.T) {
+	req := WithPrincipal(prepareGetTaskPathRequest(t, 1, "subtree"), taskOwnerID)
+	serveWithFailingService(t, req, NewTaskHandler(failingTaskService{}).GetTaskSubtreeHandler)
+}
+
+func testDeleteServiceError(t *testing.T) {
+	req := WithPrincipal(prepareDeleteTaskRequest(t, 1), taskOwnerID)
+	serveWithFailingService(t, req, NewTaskHandler(failingTaskService{}).DeleteTaskHandler)
+}
+
+func serveWithFailingService(t *testing.T, req *http.Request, handlerFunc http.HandlerFunc) {
+	rr := httptest.NewRecorder()
+	handlerFunc.ServeHTTP(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusInternalServerError)
+
+	response := ParseResponse(t, rr)
+	ResultShouldExist(t, response)
+	ResultShouldBe(t, ErrInternalServerError, response["result"])
+}
+
+func bulkResults(t *testing.T, rr *httptest.ResponseRecorder) []map[string]interface{} {
+	response := ParseResponse(t, rr)
+	items, ok := response["result"].([]interface{})
+	if !ok {
+		t.Fatalf("Unexpected result type: %v", response["result"])
+	}
+
+	results := make([]map[string]interface{}, len(items))
+	for i, item := range items {
+		results[i] = item.(map[string]interface{})
+	}
+	return results
+}
+
+// prepareJSONRequest builds a request for userID with data, if any, as its
+// JSON body.
+func prepareJSONRequest(t *testing.T, method string, path string, data map[string]interface{}, userID int) *http.Request {
+	var body []byte
+	if data != nil {
+		body = PrepareJsonBody(t, data)
+	}
+
+	req, err := http.NewRequest(method, path, bytes.NewBuffer(body))
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+	return WithPrincipal(req, userID)
+}
+
+func prepareBulkTasksRequest(t *testing.T, body []byte) *http.Request {
+	req, err := http.NewRequest("POST", "/tasks/bulk", bytes.NewBuffer(body))
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+	return WithPrincipal(req, taskOwnerID)
+}
+
 func prepareCreateTaskRequest(t *testing.T, body []byte) *http.Request {
 	req, err := http.NewRequest("POST", "/task", bytes.NewBuffer(body))
 	if err != nil {
 		t.Fatalf("Error creating request: %v", err)
 	}
-	return req
+	return WithPrincipal(req, taskOwnerID)
 }
 
 func prepareGetTasksRequest(t *testing.T) *http.Request {
@@ -348,7 +1491,40 @@ func prepareGetTasksRequest(t *testing.T) *http.Request {
 	if err != nil {
 		t.Fatalf("Error creating request: %v", err)
 	}
-	return req
+	return WithPrincipal(req, taskOwnerID)
+}
+
+func prepareGetTasksRequestWithQuery(t *testing.T, query string) *http.Request {
+	req, err := http.NewRequest("GET", "/tasks?"+query, nil)
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+	return WithPrincipal(req, taskOwnerID)
+}
+
+func prepareSearchTasksRequest(t *testing.T, query string) *http.Request {
+	req, err := http.NewRequest("GET", "/tasks/search?"+query, nil)
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+	return WithPrincipal(req, taskOwnerID)
+}
+
+func prepareGetTaskRequest(t *testing.T, id int) *http.Request {
+	taskID := fmt.Sprintf("%d", id)
+	req, err := http.NewRequest("GET", "/task/"+taskID, nil)
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+	return WithPrincipal(req, taskOwnerID)
+}
+
+func prepareGetTaskPathRequest(t *testing.T, id int, path string) *http.Request {
+	req, err := http.NewRequest("GET", fmt.Sprintf("/task/%d/%s", id, path), nil)
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+	return WithPrincipal(req, taskOwnerID)
 }
 
 func prepareUpdateTaskRequest(t *testing.T, id int, body []byte) *http.Request {
@@ -357,7 +1533,17 @@ func prepareUpdateTaskRequest(t *testing.T, id int, body []byte) *http.Request {
 	if err != nil {
 		t.Fatalf("Error creating request: %v", err)
 	}
-	return req
+	return WithPrincipal(req, taskOwnerID)
+}
+
+func preparePatchTaskRequest(t *testing.T, id int, contentType string, body []byte) *http.Request {
+	taskID := fmt.Sprintf("%d", id)
+	req, err := http.NewRequest("PATCH", "/task/"+taskID, bytes.NewBuffer(body))
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	return WithPrincipal(req, taskOwnerID)
 }
 
 func prepareDeleteTaskRequest(t *testing.T, id int) *http.Request {
@@ -366,7 +1552,7 @@ func prepareDeleteTaskRequest(t *testing.T, id int) *http.Request {
 	if err != nil {
 		t.Fatalf("Error creating request: %v", err)
 	}
-	return req
+	return WithPrincipal(req, taskOwnerID)
 }
 
 func taskShouldBe(t *testing.T, expectedTask model.Task, actualTask model.Task) {
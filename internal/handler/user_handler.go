@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/absoluteyl/tasks-go/internal/service"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// bcrypt only takes the first 72 bytes of a password into account.
+const (
+	minPasswordLength = 8
+	maxPasswordLength = 72
+)
+
+type UserHandler struct {
+	userService *service.UserService
+}
+
+func NewUserHandler(userService *service.UserService) *UserHandler {
+	return &UserHandler{userService: userService}
+}
+
+func (h *UserHandler) CreateUserHandler(w http.ResponseWriter, r *http.Request) {
+	SetContentType(w)
+
+	var userData map[string]interface{}
+	err := json.NewDecoder(r.Body).Decode(&userData)
+	if err != nil {
+		SetErrResponse(w, http.StatusBadRequest, ErrBadRequest)
+		return
+	}
+
+	username, _ := userData["username"].(string)
+	username = strings.TrimSpace(username)
+	if username == "" {
+		SetErrResponse(w, http.StatusBadRequest, ErrMissingUsername)
+		return
+	}
+
+	password, _ := userData["password"].(string)
+	if password == "" {
+		SetErrResponse(w, http.StatusBadRequest, ErrMissingPassword)
+		return
+	}
+	if len(password) < minPasswordLength {
+		SetErrResponse(w, http.StatusBadRequest, ErrPasswordTooShort)
+		return
+	}
+	if len(password) > maxPasswordLength {
+		SetErrResponse(w, http.StatusBadRequest, ErrPasswordTooLong)
+		return
+	}
+
+	userID, err := h.userService.CreateUser(username, password)
+	if errors.Is(err, service.ErrUserExists) {
+		SetErrResponse(w, http.StatusConflict, ErrUserExists)
+		return
+	}
+	if err != nil {
+		SetErrResponse(w, http.StatusInternalServerError, ErrInternalServerError)
+		return
+	}
+
+	newUser, err := h.userService.GetUserByID(userID)
+	if err != nil {
+		SetErrResponse(w, http.StatusInternalServerError, ErrInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	response := map[string]interface{}{
+		"result": newUser,
+	}
+	jsonEncode(w, response)
+}
+
+func (h *UserHandler) UpdateUserRoleHandler(w http.ResponseWriter, r *http.Request) {
+	SetContentType(w)
+
+	id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/users/"), "/role")
+	if id == "" {
+		SetErrResponse(w, http.StatusBadRequest, ErrMissingUserID)
+		return
+	}
+
+	userID, err := strconv.Atoi(id)
+	if err != nil {
+		SetErrResponse(w, http.StatusBadRequest, ErrInvalidUserID)
+		return
+	}
+
+	var roleData map[string]interface{}
+	err = json.NewDecoder(r.Body).Decode(&roleData)
+	if err != nil {
+		SetErrResponse(w, http.StatusBadRequest, ErrBadRequest)
+		return
+	}
+
+	role, _ := roleData["role"].(string)
+	if role == "" {
+		SetErrResponse(w, http.StatusBadRequest, ErrMissingRole)
+		return
+	}
+
+	err = h.userService.UpdateUserRole(userID, role)
+	if errors.Is(err, service.ErrInvalidRole) {
+		SetErrResponse(w, http.StatusBadRequest, ErrInvalidRole)
+		return
+	}
+	if errors.Is(err, service.ErrUserNotFound) {
+		SetErrResponse(w, http.StatusNotFound, ErrUserNotFound)
+		return
+	}
+	if err != nil {
+		SetErrResponse(w, http.StatusInternalServerError, ErrInternalServerError)
+		return
+	}
+
+	user, err := h.userService.GetUserByID(userID)
+	if err != nil {
+		SetErrResponse(w, http.StatusInternalServerError, ErrInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"result": user,
+	}
+	jsonEncode(w, response)
+}
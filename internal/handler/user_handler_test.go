@@ -0,0 +1,230 @@
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/absoluteyl/tasks-go/pkg/auth"
+	. "github.com/absoluteyl/tasks-go/pkg/testutils"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var createdUserID int
+
+func TestUserHandler(t *testing.T) {
+	t.Run("CreateInvalidBody", testCreateUserInvalidBody)
+	t.Run("CreateMissingUsername", testCreateUserMissingUsername)
+	t.Run("CreateMissingPassword", testCreateUserMissingPassword)
+	t.Run("CreatePasswordTooShort", testCreateUserPasswordTooShort)
+	t.Run("CreatePasswordTooLong", testCreateUserPasswordTooLong)
+	t.Run("Create", testCreateUser)
+	t.Run("CreateDuplicate", testCreateDuplicateUser)
+	t.Run("UpdateRoleInvalidID", testUpdateUserRoleInvalidID)
+	t.Run("UpdateRoleMissingRole", testUpdateUserRoleMissingRole)
+	t.Run("UpdateRoleInvalidRole", testUpdateUserRoleInvalidRole)
+	t.Run("UpdateRoleNotExist", testUpdateUserRoleNotExist)
+	t.Run("UpdateRole", testUpdateUserRole)
+}
+
+func testCreateUserInvalidBody(t *testing.T) {
+	req := prepareCreateUserRequest(t, []byte("not json"))
+
+	rr := httptest.NewRecorder()
+	userHandler.CreateUserHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusBadRequest)
+
+	response := ParseResponse(t, rr)
+	ResultShouldBe(t, ErrBadRequest, response["result"])
+}
+
+func testCreateUserMissingUsername(t *testing.T) {
+	reqBody := PrepareJsonBody(t, map[string]interface{}{
+		"password": "let-it-go",
+	})
+	req := prepareCreateUserRequest(t, reqBody)
+
+	rr := httptest.NewRecorder()
+	userHandler.CreateUserHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusBadRequest)
+
+	response := ParseResponse(t, rr)
+	ResultShouldBe(t, ErrMissingUsername, response["result"])
+}
+
+func testCreateUserMissingPassword(t *testing.T) {
+	reqBody := PrepareJsonBody(t, map[string]interface{}{
+		"username": "elsa",
+	})
+	req := prepareCreateUserRequest(t, reqBody)
+
+	rr := httptest.NewRecorder()
+	userHandler.CreateUserHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusBadRequest)
+
+	response := ParseResponse(t, rr)
+	ResultShouldBe(t, ErrMissingPassword, response["result"])
+}
+
+func testCreateUserPasswordTooShort(t *testing.T) {
+	reqBody := PrepareJsonBody(t, map[string]interface{}{
+		"username": "elsa",
+		"password": "short",
+	})
+	req := prepareCreateUserRequest(t, reqBody)
+
+	rr := httptest.NewRecorder()
+	userHandler.CreateUserHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusBadRequest)
+
+	response := ParseResponse(t, rr)
+	ResultShouldBe(t, ErrPasswordTooShort, response["result"])
+}
+
+func testCreateUserPasswordTooLong(t *testing.T) {
+	reqBody := PrepareJsonBody(t, map[string]interface{}{
+		"username": "elsa",
+		"password": strings.Repeat("a", 73),
+	})
+	req := prepareCreateUserRequest(t, reqBody)
+
+	rr := httptest.NewRecorder()
+	userHandler.CreateUserHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusBadRequest)
+
+	response := ParseResponse(t, rr)
+	ResultShouldBe(t, ErrPasswordTooLong, response["result"])
+}
+
+func testCreateUser(t *testing.T) {
+	reqBody := PrepareJsonBody(t, map[string]interface{}{
+		"username": "elsa",
+		"password": "let-it-go",
+	})
+	req := prepareCreateUserRequest(t, reqBody)
+
+	rr := httptest.NewRecorder()
+	userHandler.CreateUserHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusCreated)
+
+	response := ParseResponse(t, rr)
+	ResultShouldExist(t, response)
+
+	result := response["result"].(map[string]interface{})
+	assert.NotZero(t, result["id"])
+	assert.Equal(t, "elsa", result["username"])
+	assert.Equal(t, auth.RoleMember, result["role"])
+	assert.NotContains(t, result, "password_hash")
+
+	createdUserID = int(result["id"].(float64))
+}
+
+func testCreateDuplicateUser(t *testing.T) {
+	reqBody := PrepareJsonBody(t, map[string]interface{}{
+		"username": "elsa",
+		"password": "let-it-go",
+	})
+	req := prepareCreateUserRequest(t, reqBody)
+
+	rr := httptest.NewRecorder()
+	userHandler.CreateUserHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusConflict)
+
+	response := ParseResponse(t, rr)
+	ResultShouldBe(t, ErrUserExists, response["result"])
+}
+
+func prepareCreateUserRequest(t *testing.T, body []byte) *http.Request {
+	req, err := http.NewRequest("POST", "/users", bytes.NewBuffer(body))
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+	return req
+}
+
+func testUpdateUserRoleInvalidID(t *testing.T) {
+	req := prepareUpdateUserRoleRequest(t, "invalid", PrepareJsonBody(t, map[string]interface{}{
+		"role": auth.RoleAdmin,
+	}))
+
+	rr := httptest.NewRecorder()
+	userHandler.UpdateUserRoleHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusBadRequest)
+
+	response := ParseResponse(t, rr)
+	ResultShouldBe(t, ErrInvalidUserID, response["result"])
+}
+
+func testUpdateUserRoleMissingRole(t *testing.T) {
+	req := prepareUpdateUserRoleRequest(t, fmt.Sprint(createdUserID), PrepareJsonBody(t, map[string]interface{}{}))
+
+	rr := httptest.NewRecorder()
+	userHandler.UpdateUserRoleHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusBadRequest)
+
+	response := ParseResponse(t, rr)
+	ResultShouldBe(t, ErrMissingRole, response["result"])
+}
+
+func testUpdateUserRoleInvalidRole(t *testing.T) {
+	req := prepareUpdateUserRoleRequest(t, fmt.Sprint(createdUserID), PrepareJsonBody(t, map[string]interface{}{
+		"role": "owner",
+	}))
+
+	rr := httptest.NewRecorder()
+	userHandler.UpdateUserRoleHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusBadRequest)
+
+	response := ParseResponse(t, rr)
+	ResultShouldBe(t, ErrInvalidRole, response["result"])
+}
+
+func testUpdateUserRoleNotExist(t *testing.T) {
+	req := prepareUpdateUserRoleRequest(t, "999", PrepareJsonBody(t, map[string]interface{}{
+		"role": auth.RoleAdmin,
+	}))
+
+	rr := httptest.NewRecorder()
+	userHandler.UpdateUserRoleHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusNotFound)
+
+	response := ParseResponse(t, rr)
+	ResultShouldBe(t, ErrUserNotFound, response["result"])
+}
+
+func testUpdateUserRole(t *testing.T) {
+	req := prepareUpdateUserRoleRequest(t, fmt.Sprint(createdUserID), PrepareJsonBody(t, map[string]interface{}{
+		"role": auth.RoleReadOnly,
+	}))
+
+	rr := httptest.NewRecorder()
+	userHandler.UpdateUserRoleHandler(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusOK)
+
+	response := ParseResponse(t, rr)
+	result := response["result"].(map[string]interface{})
+	assert.Equal(t, "elsa", result["username"])
+	assert.Equal(t, auth.RoleReadOnly, result["role"])
+}
+
+func prepareUpdateUserRoleRequest(t *testing.T, id string, body []byte) *http.Request {
+	req, err := http.NewRequest("PUT", "/users/"+id+"/role", bytes.NewBuffer(body))
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+	return req
+}
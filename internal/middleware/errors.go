@@ -4,6 +4,15 @@ const (
 	ErrInvalidAuthorization = "Authorization header is missing or not in 'Bearer {token}' format"
 	ErrInvalidToken         = "Invalid or expired token"
 	ErrInvalidClaims        = "Invalid token claims"
-	ErrInvalidIssueAt       = "Invalid issue at"
+	ErrInvalidExpiration    = "Invalid expiration"
 	ErrTokenExpired         = "Token is expired"
+	ErrInvalidSubject       = "Invalid token subject"
+	ErrInvalidTokenID       = "Invalid token id"
+	ErrTokenRevoked         = "Token has been revoked"
+	ErrInvalidAPIKey        = "Invalid or expired API key"
+	ErrInsufficientScope    = "Insufficient scope"
+
+	ErrIdempotencyKeyTooLong    = "Idempotency-Key must be at most 255 characters"
+	ErrIdempotencyKeyMismatch   = "Idempotency-Key was already used with a different request"
+	ErrIdempotencyKeyInProgress = "A request with this Idempotency-Key is still being processed"
 )
@@ -0,0 +1,135 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	. "github.com/absoluteyl/tasks-go/internal/handler"
+	"github.com/absoluteyl/tasks-go/internal/model"
+	"github.com/absoluteyl/tasks-go/internal/service"
+	"github.com/absoluteyl/tasks-go/pkg/auth"
+	"io"
+	"net/http"
+)
+
+const maxIdempotencyKeyLength = 255
+
+type IdempotencyMiddleware struct {
+	idempotencyService *service.IdempotencyService
+}
+
+func NewIdempotencyMiddleware(idempotencyService *service.IdempotencyService) *IdempotencyMiddleware {
+	return &IdempotencyMiddleware{idempotencyService: idempotencyService}
+}
+
+// Handler replays the stored response when a mutating request is repeated
+// with the same Idempotency-Key. Keys are scoped to the principal, so it has
+// to be wrapped by JWTMiddleware. Server errors are not stored, which lets
+// clients retry them with the same key.
+func (m *IdempotencyMiddleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Idempotency-Key")
+		if key == "" || r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		SetContentType(w)
+
+		if len(key) > maxIdempotencyKeyLength {
+			SetErrResponse(w, http.StatusBadRequest, ErrIdempotencyKeyTooLong)
+			return
+		}
+
+		principal, ok := auth.FromContext(r.Context())
+		if !ok {
+			SetErrResponse(w, http.StatusUnauthorized, ErrUnauthorized)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			SetErrResponse(w, http.StatusBadRequest, ErrBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		record, replay, err := m.idempotencyService.Begin(principal.UserID, key, requestHash(r, body))
+		if errors.Is(err, service.ErrIdempotencyKeyMismatch) {
+			SetErrResponse(w, http.StatusUnprocessableEntity, ErrIdempotencyKeyMismatch)
+			return
+		}
+		if errors.Is(err, service.ErrIdempotencyKeyInProgress) {
+			SetErrResponse(w, http.StatusConflict, ErrIdempotencyKeyInProgress)
+			return
+		}
+		if err != nil {
+			SetErrResponse(w, http.StatusInternalServerError, ErrInternalServerError)
+			return
+		}
+
+		if replay {
+			writeStoredResponse(w, record)
+			return
+		}
+
+		completed := false
+		defer func() {
+			if !completed {
+				m.idempotencyService.Release(record)
+			}
+		}()
+
+		recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+
+		if recorder.status >= http.StatusInternalServerError {
+			return
+		}
+
+		record.StatusCode = recorder.status
+		record.ResponseHeaders = w.Header().Clone()
+		record.ResponseBody = recorder.body.Bytes()
+		completed = m.idempotencyService.Complete(&record) == nil
+	})
+}
+
+func requestHash(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(r.Method + " " + r.URL.RequestURI() + "\n"))
+	hash.Write([]byte("Content-Type: " + r.Header.Get("Content-Type") + "\n"))
+	hash.Write([]byte("If-Match: " + r.Header.Get("If-Match") + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func writeStoredResponse(w http.ResponseWriter, record model.IdempotencyKey) {
+	for name, values := range record.ResponseHeaders {
+		w.Header()[name] = values
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(record.StatusCode)
+	w.Write(record.ResponseBody)
+}
+
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	body        bytes.Buffer
+	wroteHeader bool
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
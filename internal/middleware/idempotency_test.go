@@ -0,0 +1,168 @@
+package middleware
+
+import (
+	"bytes"
+	"fmt"
+	. "github.com/absoluteyl/tasks-go/pkg/testutils"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var idempotentCalls int
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	t.Run("NoKey", testIdempotencyNoKey)
+	t.Run("KeyTooLong", testIdempotencyKeyTooLong)
+	t.Run("NoPrincipal", testIdempotencyNoPrincipal)
+	t.Run("FirstRequest", testIdempotencyFirstRequest)
+	t.Run("Replay", testIdempotencyReplay)
+	t.Run("Mismatch", testIdempotencyMismatch)
+	t.Run("HeaderMismatch", testIdempotencyHeaderMismatch)
+	t.Run("OtherUser", testIdempotencyOtherUser)
+	t.Run("ServerError", testIdempotencyServerError)
+}
+
+func testIdempotencyNoKey(t *testing.T) {
+	idempotentCalls = 0
+
+	for i := 0; i < 2; i++ {
+		rr, idempotentHandler := prepareHandlerRecorderWithIdempotency(http.StatusCreated)
+		idempotentHandler.ServeHTTP(rr, WithPrincipal(prepareCreateTaskRequest(t, "", `{"name":"Eat Dinner"}`), 1))
+		HttpStatusShouldBe(t, rr, http.StatusCreated)
+	}
+
+	assert.Equal(t, 2, idempotentCalls)
+}
+
+func testIdempotencyKeyTooLong(t *testing.T) {
+	req := WithPrincipal(prepareCreateTaskRequest(t, strings.Repeat("k", 256), `{"name":"Eat Dinner"}`), 1)
+
+	rr, idempotentHandler := prepareHandlerRecorderWithIdempotency(http.StatusCreated)
+	idempotentHandler.ServeHTTP(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusBadRequest)
+
+	response := ParseResponse(t, rr)
+	ResultShouldExist(t, response)
+	ResultShouldBe(t, ErrIdempotencyKeyTooLong, response["result"])
+}
+
+func testIdempotencyNoPrincipal(t *testing.T) {
+	req := prepareCreateTaskRequest(t, "create-task", `{"name":"Eat Dinner"}`)
+
+	rr, idempotentHandler := prepareHandlerRecorderWithIdempotency(http.StatusCreated)
+	idempotentHandler.ServeHTTP(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusUnauthorized)
+}
+
+func testIdempotencyFirstRequest(t *testing.T) {
+	idempotentCalls = 0
+	req := WithPrincipal(prepareCreateTaskRequest(t, "create-task", `{"name":"Eat Dinner"}`), 1)
+
+	rr, idempotentHandler := prepareHandlerRecorderWithIdempotency(http.StatusCreated)
+	idempotentHandler.ServeHTTP(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusCreated)
+	HttpResponseShouldBe(t, rr, `{"result":"call 1: {\"name\":\"Eat Dinner\"}"}`)
+	assert.Empty(t, rr.Header().Get("Idempotent-Replayed"))
+	assert.Equal(t, 1, idempotentCalls)
+}
+
+func testIdempotencyReplay(t *testing.T) {
+	req := WithPrincipal(prepareCreateTaskRequest(t, "create-task", `{"name":"Eat Dinner"}`), 1)
+
+	rr, idempotentHandler := prepareHandlerRecorderWithIdempotency(http.StatusCreated)
+	idempotentHandler.ServeHTTP(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusCreated)
+	HttpResponseShouldBe(t, rr, `{"result":"call 1: {\"name\":\"Eat Dinner\"}"}`)
+	assert.Equal(t, "true", rr.Header().Get("Idempotent-Replayed"))
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+	assert.Equal(t, 1, idempotentCalls, "Handler called for a replayed request")
+}
+
+func testIdempotencyMismatch(t *testing.T) {
+	req := WithPrincipal(prepareCreateTaskRequest(t, "create-task", `{"name":"Eat Lunch"}`), 1)
+
+	rr, idempotentHandler := prepareHandlerRecorderWithIdempotency(http.StatusCreated)
+	idempotentHandler.ServeHTTP(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusUnprocessableEntity)
+	assert.Equal(t, 1, idempotentCalls)
+
+	response := ParseResponse(t, rr)
+	ResultShouldExist(t, response)
+	ResultShouldBe(t, ErrIdempotencyKeyMismatch, response["result"])
+}
+
+func testIdempotencyHeaderMismatch(t *testing.T) {
+	for header, value := range map[string]string{"Content-Type": "text/plain", "If-Match": `"3"`} {
+		req := WithPrincipal(prepareCreateTaskRequest(t, "create-task", `{"name":"Eat Dinner"}`), 1)
+		req.Header.Set(header, value)
+
+		rr, idempotentHandler := prepareHandlerRecorderWithIdempotency(http.StatusCreated)
+		idempotentHandler.ServeHTTP(rr, req)
+
+		HttpStatusShouldBe(t, rr, http.StatusUnprocessableEntity)
+		assert.Equal(t, 1, idempotentCalls, "Handler called with a different %s", header)
+
+		response := ParseResponse(t, rr)
+		ResultShouldExist(t, response)
+		ResultShouldBe(t, ErrIdempotencyKeyMismatch, response["result"])
+	}
+}
+
+func testIdempotencyOtherUser(t *testing.T) {
+	req := WithPrincipal(prepareCreateTaskRequest(t, "create-task", `{"name":"Eat Dinner"}`), 2)
+
+	rr, idempotentHandler := prepareHandlerRecorderWithIdempotency(http.StatusCreated)
+	idempotentHandler.ServeHTTP(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusCreated)
+	assert.Empty(t, rr.Header().Get("Idempotent-Replayed"))
+	assert.Equal(t, 2, idempotentCalls)
+}
+
+func testIdempotencyServerError(t *testing.T) {
+	idempotentCalls = 0
+
+	for i := 0; i < 2; i++ {
+		req := WithPrincipal(prepareCreateTaskRequest(t, "failing-task", `{"name":"Eat Dinner"}`), 1)
+
+		rr, idempotentHandler := prepareHandlerRecorderWithIdempotency(http.StatusInternalServerError)
+		idempotentHandler.ServeHTTP(rr, req)
+
+		HttpStatusShouldBe(t, rr, http.StatusInternalServerError)
+		assert.Empty(t, rr.Header().Get("Idempotent-Replayed"))
+	}
+
+	assert.Equal(t, 2, idempotentCalls, "Server error replayed")
+}
+
+func prepareCreateTaskRequest(t *testing.T, key string, body string) *http.Request {
+	req, err := http.NewRequest("POST", "/task", bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+	if key != "" {
+		req.Header.Set("Idempotency-Key", key)
+	}
+	return req
+}
+
+func prepareHandlerRecorderWithIdempotency(status int) (*httptest.ResponseRecorder, http.Handler) {
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idempotentCalls++
+		body := new(bytes.Buffer)
+		body.ReadFrom(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		fmt.Fprintf(w, `{"result":%q}`, fmt.Sprintf("call %d: %s", idempotentCalls, body.String()))
+	})
+	return rr, idempotencyMiddleware.Handler(handler)
+}
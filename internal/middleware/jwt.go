@@ -1,18 +1,37 @@
 package middleware
 
 import (
+	"errors"
 	. "github.com/absoluteyl/tasks-go/internal/handler"
+	"github.com/absoluteyl/tasks-go/internal/service"
 	"github.com/absoluteyl/tasks-go/pkg/auth"
 	"github.com/dgrijalva/jwt-go"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 )
 
-func JWTMiddleware(next http.Handler) http.Handler {
+type JWTMiddleware struct {
+	tokenService  *service.TokenService
+	apiKeyService *service.APIKeyService
+}
+
+func NewJWTMiddleware(tokenService *service.TokenService, apiKeyService *service.APIKeyService) *JWTMiddleware {
+	return &JWTMiddleware{tokenService: tokenService, apiKeyService: apiKeyService}
+}
+
+// Handler authenticates requests with either a bearer JWT or an API key,
+// passed as bearer token or in the X-API-Key header.
+func (m *JWTMiddleware) Handler(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		SetContentType(w)
 
+		if apiKey := r.Header.Get("X-API-Key"); apiKey != "" {
+			m.serveAPIKey(w, r, next, apiKey)
+			return
+		}
+
 		authHeader := r.Header.Get("Authorization")
 
 		if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
@@ -22,7 +41,16 @@ func JWTMiddleware(next http.Handler) http.Handler {
 
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 
+		if auth.IsAPIKey(tokenString) {
+			m.serveAPIKey(w, r, next, tokenString)
+			return
+		}
+
 		token, err := auth.ParseToken(tokenString)
+		if auth.IsExpired(err) {
+			SetErrResponse(w, http.StatusUnauthorized, ErrTokenExpired)
+			return
+		}
 		if err != nil || !token.Valid {
 			SetErrResponse(w, http.StatusUnauthorized, ErrInvalidToken)
 			return
@@ -34,16 +62,62 @@ func JWTMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
-		if iat, ok := claims["iat"].(float64); ok {
-			if time.Now().After(time.Unix(int64(iat), 0).Add(1 * time.Minute)) {
-				SetErrResponse(w, http.StatusUnauthorized, ErrTokenExpired)
-				return
-			}
-		} else {
-			SetErrResponse(w, http.StatusUnauthorized, ErrInvalidIssueAt)
+		exp, ok := claims["exp"].(float64)
+		if !ok {
+			SetErrResponse(w, http.StatusUnauthorized, ErrInvalidExpiration)
 			return
 		}
 
-		next.ServeHTTP(w, r)
+		sub, _ := claims["sub"].(string)
+		userID, err := strconv.Atoi(sub)
+		if err != nil || userID <= 0 {
+			SetErrResponse(w, http.StatusUnauthorized, ErrInvalidSubject)
+			return
+		}
+
+		jti, _ := claims["jti"].(string)
+		if jti == "" {
+			SetErrResponse(w, http.StatusUnauthorized, ErrInvalidTokenID)
+			return
+		}
+
+		revoked, err := m.tokenService.IsTokenRevoked(jti)
+		if err != nil {
+			SetErrResponse(w, http.StatusInternalServerError, ErrInternalServerError)
+			return
+		}
+		if revoked {
+			SetErrResponse(w, http.StatusUnauthorized, ErrTokenRevoked)
+			return
+		}
+
+		scope, _ := claims["scope"].(string)
+
+		ctx := auth.NewContext(r.Context(), auth.Principal{
+			UserID:    userID,
+			TokenID:   jti,
+			ExpiresAt: time.Unix(int64(exp), 0),
+			Scopes:    auth.ParseScopes(scope),
+		})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (m *JWTMiddleware) serveAPIKey(w http.ResponseWriter, r *http.Request, next http.Handler, key string) {
+	apiKey, err := m.apiKeyService.AuthenticateAPIKey(key)
+	if errors.Is(err, service.ErrInvalidAPIKey) {
+		SetErrResponse(w, http.StatusUnauthorized, ErrInvalidAPIKey)
+		return
+	}
+	if err != nil {
+		SetErrResponse(w, http.StatusInternalServerError, ErrInternalServerError)
+		return
+	}
+
+	ctx := auth.NewContext(r.Context(), auth.Principal{
+		UserID:   apiKey.UserID,
+		APIKeyID: apiKey.ID,
+		Scopes:   apiKey.Scopes,
 	})
+	next.ServeHTTP(w, r.WithContext(ctx))
 }
@@ -1,20 +1,85 @@
 package middleware
 
 import (
+	"database/sql"
+	"fmt"
+	"github.com/absoluteyl/tasks-go/internal/repository"
+	"github.com/absoluteyl/tasks-go/internal/service"
 	"github.com/absoluteyl/tasks-go/pkg/auth"
 	. "github.com/absoluteyl/tasks-go/pkg/testutils"
+	"github.com/dgrijalva/jwt-go"
+	_ "github.com/mattn/go-sqlite3"
 	"github.com/stretchr/testify/assert"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"testing"
 	"time"
 )
 
+var testDB *sql.DB
+var tokenService *service.TokenService
+var apiKeyService *service.APIKeyService
+var jwtMiddleware *JWTMiddleware
+var idempotencyMiddleware *IdempotencyMiddleware
+
+func TestMain(m *testing.M) {
+	t := &testing.T{}
+	setup(t)
+	code := m.Run()
+	teardown()
+	os.Exit(code)
+}
+
+func setup(t *testing.T) {
+	var err error
+	testDB, err = ConnectDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = MigrateDB(testDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	userRepo := repository.NewUserRepository(testDB)
+	_, err = userRepo.CreateUser("owner", "hashed-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tokenService = service.NewTokenServiceWithRepositories(
+		userRepo,
+		repository.NewRefreshTokenRepository(testDB),
+		repository.NewRevokedTokenRepository(testDB),
+	)
+	apiKeyService = service.NewAPIKeyServiceWithRepositories(repository.NewAPIKeyRepository(testDB), userRepo)
+	jwtMiddleware = NewJWTMiddleware(tokenService, apiKeyService)
+	idempotencyMiddleware = NewIdempotencyMiddleware(
+		service.NewIdempotencyServiceWithRepository(repository.NewIdempotencyKeyRepository(testDB)),
+	)
+}
+
+func teardown() {
+	err := RemoveDB()
+	if err != nil {
+		fmt.Print(err)
+	}
+}
+
 func TestJWTMiddleware(t *testing.T) {
 	t.Run("NoHeader", testNoHeader)
 	t.Run("HeaderInvalid", testHeaderInvalid)
 	t.Run("TokenInvalid", testTokenInvalid)
-	t.Run("TokenOlderThan1Minute", testTokenOlderThan1Minute)
+	t.Run("TokenExpired", testTokenExpired)
+	t.Run("TokenWithoutExpiration", testTokenWithoutExpiration)
+	t.Run("TokenWithoutSubject", testTokenWithoutSubject)
+	t.Run("TokenValid", testTokenValid)
+	t.Run("TokenRevoked", testTokenRevoked)
+	t.Run("APIKeyInvalid", testAPIKeyInvalid)
+	t.Run("APIKeyAsBearer", testAPIKeyAsBearer)
+	t.Run("APIKeyHeader", testAPIKeyHeader)
 }
 
 func testNoHeader(t *testing.T) {
@@ -58,9 +123,9 @@ func testTokenInvalid(t *testing.T) {
 	ResultShouldBe(t, ErrInvalidToken, response["result"])
 }
 
-func testTokenOlderThan1Minute(t *testing.T) {
+func testTokenExpired(t *testing.T) {
 	req := prepareGetTasksRequest(t)
-	tokenString, err := auth.GenerateToken(time.Now().Add(-2 * time.Minute).Unix())
+	tokenString, err := auth.GenerateToken(1, auth.ScopesForRole(auth.RoleMember), time.Now().Add(-auth.AccessTokenTTL()-time.Minute).Unix())
 	assert.NoError(t, err)
 
 	SetupAuthorizationHeader(req, "Bearer "+tokenString)
@@ -75,6 +140,151 @@ func testTokenOlderThan1Minute(t *testing.T) {
 	ResultShouldBe(t, ErrTokenExpired, response["result"])
 }
 
+func testTokenWithoutExpiration(t *testing.T) {
+	req := prepareGetTasksRequest(t)
+	token := auth.SetSignMethod()
+	err := auth.PrepareClaims(token, 1, auth.ScopesForRole(auth.RoleMember), time.Now().Unix())
+	assert.NoError(t, err)
+	delete(token.Claims.(jwt.MapClaims), "exp")
+	tokenString, err := auth.Sign(token)
+	assert.NoError(t, err)
+
+	SetupAuthorizationHeader(req, "Bearer "+tokenString)
+
+	rr, jwtHandler := prepareHandlerRecorderWithMiddleware()
+	jwtHandler.ServeHTTP(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusUnauthorized)
+
+	response := ParseResponse(t, rr)
+	ResultShouldExist(t, response)
+	ResultShouldBe(t, ErrInvalidExpiration, response["result"])
+}
+
+func testTokenWithoutSubject(t *testing.T) {
+	req := prepareGetTasksRequest(t)
+	token := auth.SetSignMethod()
+	err := auth.PrepareClaims(token, 1, auth.ScopesForRole(auth.RoleMember), time.Now().Unix())
+	assert.NoError(t, err)
+	delete(token.Claims.(jwt.MapClaims), "sub")
+	tokenString, err := auth.Sign(token)
+	assert.NoError(t, err)
+
+	SetupAuthorizationHeader(req, "Bearer "+tokenString)
+
+	rr, jwtHandler := prepareHandlerRecorderWithMiddleware()
+	jwtHandler.ServeHTTP(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusUnauthorized)
+
+	response := ParseResponse(t, rr)
+	ResultShouldExist(t, response)
+	ResultShouldBe(t, ErrInvalidSubject, response["result"])
+}
+
+func testTokenValid(t *testing.T) {
+	req := prepareGetTasksRequest(t)
+	tokenString, err := auth.GenerateToken(1, auth.ScopesForRole(auth.RoleMember), time.Now().Unix())
+	assert.NoError(t, err)
+
+	SetupAuthorizationHeader(req, "Bearer "+tokenString)
+
+	var principal auth.Principal
+	var ok bool
+	rr := httptest.NewRecorder()
+	jwtHandler := jwtMiddleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, ok = auth.FromContext(r.Context())
+	}))
+	jwtHandler.ServeHTTP(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusOK)
+	assert.True(t, ok, "Principal not found in request context")
+	assert.Equal(t, 1, principal.UserID)
+	assert.NotEmpty(t, principal.TokenID)
+	assert.True(t, principal.ExpiresAt.After(time.Now()))
+	assert.Equal(t, auth.ScopesForRole(auth.RoleMember), principal.Scopes)
+}
+
+func testTokenRevoked(t *testing.T) {
+	req := prepareGetTasksRequest(t)
+	tokenString, err := auth.GenerateToken(1, auth.ScopesForRole(auth.RoleMember), time.Now().Unix())
+	assert.NoError(t, err)
+
+	token, err := auth.ParseToken(tokenString)
+	assert.NoError(t, err)
+	jti := token.Claims.(jwt.MapClaims)["jti"].(string)
+
+	err = tokenService.RevokeToken(jti, time.Now().Add(auth.AccessTokenTTL()))
+	assert.NoError(t, err)
+
+	SetupAuthorizationHeader(req, "Bearer "+tokenString)
+
+	rr, jwtHandler := prepareHandlerRecorderWithMiddleware()
+	jwtHandler.ServeHTTP(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusUnauthorized)
+
+	response := ParseResponse(t, rr)
+	ResultShouldExist(t, response)
+	ResultShouldBe(t, ErrTokenRevoked, response["result"])
+}
+
+func testAPIKeyInvalid(t *testing.T) {
+	req := prepareGetTasksRequest(t)
+	SetupAuthorizationHeader(req, "Bearer "+auth.APIKeyPrefix+"invalid")
+
+	rr, jwtHandler := prepareHandlerRecorderWithMiddleware()
+	jwtHandler.ServeHTTP(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusUnauthorized)
+
+	response := ParseResponse(t, rr)
+	ResultShouldExist(t, response)
+	ResultShouldBe(t, ErrInvalidAPIKey, response["result"])
+}
+
+func testAPIKeyAsBearer(t *testing.T) {
+	apiKey, key, err := apiKeyService.CreateAPIKey(1, "CI", nil, auth.ScopesForRole(auth.RoleMember), nil)
+	assert.NoError(t, err)
+
+	req := prepareGetTasksRequest(t)
+	SetupAuthorizationHeader(req, "Bearer "+key)
+
+	principal := servePrincipal(t, req)
+	assert.Equal(t, 1, principal.UserID)
+	assert.Equal(t, apiKey.ID, principal.APIKeyID)
+	assert.Equal(t, auth.ScopesForRole(auth.RoleMember), principal.Scopes)
+}
+
+func testAPIKeyHeader(t *testing.T) {
+	apiKey, key, err := apiKeyService.CreateAPIKey(
+		1, "Cron", []string{auth.ScopeTasksRead}, auth.ScopesForRole(auth.RoleMember), nil,
+	)
+	assert.NoError(t, err)
+
+	req := prepareGetTasksRequest(t)
+	req.Header.Set("X-API-Key", key)
+
+	principal := servePrincipal(t, req)
+	assert.Equal(t, 1, principal.UserID)
+	assert.Equal(t, apiKey.ID, principal.APIKeyID)
+	assert.Equal(t, []string{auth.ScopeTasksRead}, principal.Scopes)
+}
+
+func servePrincipal(t *testing.T, req *http.Request) auth.Principal {
+	var principal auth.Principal
+	var ok bool
+	rr := httptest.NewRecorder()
+	jwtHandler := jwtMiddleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, ok = auth.FromContext(r.Context())
+	}))
+	jwtHandler.ServeHTTP(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusOK)
+	assert.True(t, ok, "Principal not found in request context")
+	return principal
+}
+
 func prepareGetTasksRequest(t *testing.T) *http.Request {
 	req, err := http.NewRequest("GET", "/tasks", nil)
 	if err != nil {
@@ -86,6 +296,6 @@ func prepareGetTasksRequest(t *testing.T) *http.Request {
 func prepareHandlerRecorderWithMiddleware() (*httptest.ResponseRecorder, http.Handler) {
 	rr := httptest.NewRecorder()
 	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
-	middlewareHandler := JWTMiddleware(handler)
+	middlewareHandler := jwtMiddleware.Handler(handler)
 	return rr, middlewareHandler
 }
@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"fmt"
+	. "github.com/absoluteyl/tasks-go/internal/handler"
+	"github.com/absoluteyl/tasks-go/pkg/auth"
+	"net/http"
+)
+
+// RequireScope only passes requests whose principal was granted scope. It
+// has to be wrapped by JWTMiddleware, which puts the principal in place.
+func RequireScope(scope string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		SetContentType(w)
+
+		principal, ok := auth.FromContext(r.Context())
+		if !ok {
+			SetErrResponse(w, http.StatusUnauthorized, ErrUnauthorized)
+			return
+		}
+
+		if !principal.HasScope(scope) {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer error="insufficient_scope", scope="%s"`, scope))
+			SetErrResponse(w, http.StatusForbidden, ErrInsufficientScope)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
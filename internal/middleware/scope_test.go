@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"github.com/absoluteyl/tasks-go/pkg/auth"
+	. "github.com/absoluteyl/tasks-go/pkg/testutils"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequireScope(t *testing.T) {
+	t.Run("NoPrincipal", testRequireScopeNoPrincipal)
+	t.Run("InsufficientScope", testRequireScopeInsufficient)
+	t.Run("Granted", testRequireScopeGranted)
+}
+
+func testRequireScopeNoPrincipal(t *testing.T) {
+	req := prepareGetTasksRequest(t)
+
+	rr, scopeHandler := prepareHandlerRecorderWithScope(auth.ScopeTasksRead)
+	scopeHandler.ServeHTTP(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusUnauthorized)
+}
+
+func testRequireScopeInsufficient(t *testing.T) {
+	req := prepareGetTasksRequest(t)
+	req = req.WithContext(auth.NewContext(req.Context(), auth.Principal{
+		UserID: 1,
+		Scopes: auth.ScopesForRole(auth.RoleReadOnly),
+	}))
+
+	rr, scopeHandler := prepareHandlerRecorderWithScope(auth.ScopeTasksWrite)
+	scopeHandler.ServeHTTP(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusForbidden)
+	assert.Equal(t, `Bearer error="insufficient_scope", scope="tasks:write"`, rr.Header().Get("WWW-Authenticate"))
+
+	response := ParseResponse(t, rr)
+	ResultShouldExist(t, response)
+	ResultShouldBe(t, ErrInsufficientScope, response["result"])
+}
+
+func testRequireScopeGranted(t *testing.T) {
+	req := WithPrincipal(prepareGetTasksRequest(t), 1)
+
+	rr, scopeHandler := prepareHandlerRecorderWithScope(auth.ScopeTasksWrite)
+	scopeHandler.ServeHTTP(rr, req)
+
+	HttpStatusShouldBe(t, rr, http.StatusOK)
+}
+
+func prepareHandlerRecorderWithScope(scope string) (*httptest.ResponseRecorder, http.Handler) {
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	return rr, RequireScope(scope, handler)
+}
@@ -0,0 +1,263 @@
+package migration
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"github.com/absoluteyl/tasks-go/internal/dialect"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed migrations
+var migrationFiles embed.FS
+
+// migrationsDir holds a directory of migrations per dialect. Every dialect
+// has the same versions.
+const migrationsDir = "migrations"
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+type Status struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+type Migrator struct {
+	db         *sql.DB
+	dialect    dialect.Dialect
+	migrations []Migration
+}
+
+func NewMigrator(db *sql.DB) (*Migrator, error) {
+	d := dialect.Of(db)
+	migrations, err := loadMigrations(migrationFiles, path.Join(migrationsDir, string(d)))
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, dialect: d, migrations: migrations}, nil
+}
+
+func (m *Migrator) Up() ([]Migration, error) {
+	applied, err := m.appliedVersions()
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+
+		err := m.apply(migration)
+		if err != nil {
+			return done, err
+		}
+		done = append(done, migration)
+	}
+
+	return done, nil
+}
+
+func (m *Migrator) Down() (*Migration, error) {
+	applied, err := m.appliedVersions()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		migration := m.migrations[i]
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+
+		err := m.revert(migration)
+		if err != nil {
+			return nil, err
+		}
+		return &migration, nil
+	}
+
+	return nil, nil
+}
+
+func (m *Migrator) Status() ([]Status, error) {
+	applied, err := m.appliedVersions()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		appliedAt, ok := applied[migration.Version]
+		statuses = append(statuses, Status{
+			Version:   migration.Version,
+			Name:      migration.Name,
+			Applied:   ok,
+			AppliedAt: appliedAt,
+		})
+	}
+
+	return statuses, nil
+}
+
+func (m *Migrator) ensureMigrationsTable() error {
+	createTableSQL := `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMP NOT NULL
+	);`
+	_, err := m.db.Exec(createTableSQL)
+	if err != nil {
+		return fmt.Errorf("Error creating schema_migrations table: %v", err)
+	}
+	return nil
+}
+
+func (m *Migrator) appliedVersions() (map[int]time.Time, error) {
+	err := m.ensureMigrationsTable()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := m.db.Query(`SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int]time.Time{}
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		err := rows.Scan(&version, &appliedAt)
+		if err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+
+	return applied, rows.Err()
+}
+
+func (m *Migrator) apply(migration Migration) error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(migration.Up)
+	if err != nil {
+		return fmt.Errorf("Error applying migration %04d_%s: %v", migration.Version, migration.Name, err)
+	}
+
+	_, err = tx.Exec(
+		m.dialect.Rebind(`INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`),
+		migration.Version, migration.Name, time.Now().UTC(),
+	)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (m *Migrator) revert(migration Migration) error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(migration.Down)
+	if err != nil {
+		return fmt.Errorf("Error reverting migration %04d_%s: %v", migration.Version, migration.Name, err)
+	}
+
+	_, err = tx.Exec(m.dialect.Rebind(`DELETE FROM schema_migrations WHERE version = ?`), migration.Version)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// loadMigrations pairs <version>_<name>.up.sql and .down.sql files and
+// returns them ordered by version.
+func loadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		fileName := entry.Name()
+
+		var direction string
+		switch {
+		case strings.HasSuffix(fileName, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(fileName, ".down.sql"):
+			direction = "down"
+		default:
+			continue
+		}
+
+		base := strings.TrimSuffix(fileName, "."+direction+".sql")
+		versionStr, name, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("Invalid migration file name: %s", fileName)
+		}
+
+		version, err := strconv.Atoi(versionStr)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid migration version: %s", fileName)
+		}
+
+		content, err := fs.ReadFile(fsys, path.Join(dir, fileName))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: name}
+			byVersion[version] = migration
+		}
+
+		if direction == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("Migration %04d_%s must have both up and down files", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
@@ -0,0 +1,197 @@
+package migration
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+	"testing/fstest"
+
+	"github.com/absoluteyl/tasks-go/internal/dialect"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+)
+
+var testDB *sql.DB
+var migrator *Migrator
+
+const testDBPath = "test.db"
+
+func TestMain(m *testing.M) {
+	var err error
+	testDB, err = sql.Open("sqlite3", dialect.SQLiteDSN(testDBPath))
+	if err != nil {
+		panic(err)
+	}
+
+	migrator, err = NewMigrator(testDB)
+	if err != nil {
+		panic(err)
+	}
+
+	code := m.Run()
+	testDB.Close()
+	os.Remove(testDBPath)
+	os.Exit(code)
+}
+
+func TestMigrator(t *testing.T) {
+	t.Run("StatusBeforeUp", testStatusBeforeUp)
+	t.Run("Up", testUp)
+	t.Run("UpIsIdempotent", testUpIsIdempotent)
+	t.Run("StatusAfterUp", testStatusAfterUp)
+	t.Run("Down", testDown)
+	t.Run("TaskStatusConversion", testTaskStatusConversion)
+	t.Run("DownAll", testDownAll)
+	t.Run("LoadMigrationsMissingDown", testLoadMigrationsMissingDown)
+	t.Run("LoadMigrationsInvalidName", testLoadMigrationsInvalidName)
+	t.Run("DialectsInSync", testDialectsInSync)
+}
+
+func testStatusBeforeUp(t *testing.T) {
+	statuses, err := migrator.Status()
+	assert.NoError(t, err)
+	assert.Len(t, statuses, len(migrator.migrations))
+	for _, status := range statuses {
+		assert.False(t, status.Applied)
+	}
+}
+
+func testUp(t *testing.T) {
+	applied, err := migrator.Up()
+	assert.NoError(t, err)
+	assert.Len(t, applied, len(migrator.migrations))
+
+	_, err = testDB.Exec(`INSERT INTO tasks (name) VALUES (?)`, "Eat Dinner")
+	assert.NoError(t, err)
+}
+
+func testUpIsIdempotent(t *testing.T) {
+	applied, err := migrator.Up()
+	assert.NoError(t, err)
+	assert.Empty(t, applied)
+}
+
+func testStatusAfterUp(t *testing.T) {
+	statuses, err := migrator.Status()
+	assert.NoError(t, err)
+	for _, status := range statuses {
+		assert.True(t, status.Applied)
+		assert.False(t, status.AppliedAt.IsZero())
+	}
+}
+
+func testDown(t *testing.T) {
+	last := migrator.migrations[len(migrator.migrations)-1]
+
+	reverted, err := migrator.Down()
+	assert.NoError(t, err)
+	assert.NotNil(t, reverted)
+	assert.Equal(t, last.Version, reverted.Version)
+
+	statuses, err := migrator.Status()
+	assert.NoError(t, err)
+	assert.False(t, statuses[len(statuses)-1].Applied)
+
+	applied, err := migrator.Up()
+	assert.NoError(t, err)
+	assert.Len(t, applied, 1)
+}
+
+func testTaskStatusConversion(t *testing.T) {
+	downTo(t, 8)
+
+	_, err := testDB.Exec(`INSERT INTO tasks (name, status) VALUES ('Open', 0), ('Closed', 1), ('Odd', 7)`)
+	assert.NoError(t, err)
+
+	_, err = migrator.Up()
+	assert.NoError(t, err)
+
+	rows, err := testDB.Query(`SELECT name, status FROM tasks WHERE name IN ('Open', 'Closed', 'Odd') ORDER BY id`)
+	assert.NoError(t, err)
+	defer rows.Close()
+
+	statuses := map[string]string{}
+	for rows.Next() {
+		var name, status string
+		assert.NoError(t, rows.Scan(&name, &status))
+		statuses[name] = status
+	}
+	assert.Equal(t, map[string]string{"Open": "todo", "Closed": "done", "Odd": "todo"}, statuses)
+}
+
+func testDownAll(t *testing.T) {
+	for range migrator.migrations {
+		reverted, err := migrator.Down()
+		assert.NoError(t, err)
+		assert.NotNil(t, reverted)
+	}
+
+	reverted, err := migrator.Down()
+	assert.NoError(t, err)
+	assert.Nil(t, reverted)
+
+	var count int
+	err = testDB.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'tasks'`).Scan(&count)
+	assert.NoError(t, err)
+	assert.Zero(t, count)
+}
+
+func testLoadMigrationsMissingDown(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/0001_create_things.up.sql": {Data: []byte("CREATE TABLE things (id INTEGER);")},
+	}
+
+	_, err := loadMigrations(fsys, "migrations")
+	assert.Error(t, err)
+}
+
+func testLoadMigrationsInvalidName(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/first.up.sql":   {Data: []byte("CREATE TABLE things (id INTEGER);")},
+		"migrations/first.down.sql": {Data: []byte("DROP TABLE things;")},
+	}
+
+	_, err := loadMigrations(fsys, "migrations")
+	assert.Error(t, err)
+}
+
+func testDialectsInSync(t *testing.T) {
+	sqliteMigrations, err := loadMigrations(migrationFiles, "migrations/sqlite")
+	assert.NoError(t, err)
+
+	postgresMigrations, err := loadMigrations(migrationFiles, "migrations/postgres")
+	assert.NoError(t, err)
+
+	assert.Equal(t, len(sqliteMigrations), len(postgresMigrations))
+	for i := range sqliteMigrations {
+		if i >= len(postgresMigrations) {
+			break
+		}
+		assert.Equal(t, sqliteMigrations[i].Version, postgresMigrations[i].Version)
+		assert.Equal(t, sqliteMigrations[i].Name, postgresMigrations[i].Name)
+	}
+}
+
+func downTo(t *testing.T, version int) {
+	for {
+		statuses, err := migrator.Status()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		latest := 0
+		for _, status := range statuses {
+			if status.Applied {
+				latest = status.Version
+			}
+		}
+		if latest <= version {
+			return
+		}
+
+		_, err = migrator.Down()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS tasks (
+	id SERIAL PRIMARY KEY,
+	name TEXT,
+	status INTEGER DEFAULT 0
+);
@@ -0,0 +1,8 @@
+CREATE TABLE refresh_tokens (
+	id SERIAL PRIMARY KEY,
+	user_id INTEGER NOT NULL,
+	token_hash TEXT NOT NULL UNIQUE,
+	expires_at TIMESTAMPTZ NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_refresh_tokens_user_id ON refresh_tokens (user_id);
@@ -0,0 +1,6 @@
+ALTER TABLE tasks DROP COLUMN completed_at;
+ALTER TABLE tasks DROP COLUMN updated_at;
+ALTER TABLE tasks DROP COLUMN created_at;
+ALTER TABLE tasks DROP COLUMN due_at;
+ALTER TABLE tasks DROP COLUMN priority;
+ALTER TABLE tasks DROP COLUMN description;
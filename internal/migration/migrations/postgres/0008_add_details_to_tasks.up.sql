@@ -0,0 +1,8 @@
+ALTER TABLE tasks ADD COLUMN description TEXT NOT NULL DEFAULT '';
+ALTER TABLE tasks ADD COLUMN priority INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE tasks ADD COLUMN due_at TIMESTAMPTZ;
+ALTER TABLE tasks ADD COLUMN created_at TIMESTAMPTZ;
+ALTER TABLE tasks ADD COLUMN updated_at TIMESTAMPTZ;
+ALTER TABLE tasks ADD COLUMN completed_at TIMESTAMPTZ;
+UPDATE tasks SET created_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP;
+UPDATE tasks SET completed_at = updated_at WHERE status = 1;
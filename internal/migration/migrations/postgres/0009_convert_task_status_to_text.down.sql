@@ -0,0 +1,4 @@
+ALTER TABLE tasks ALTER COLUMN status DROP NOT NULL;
+ALTER TABLE tasks ALTER COLUMN status DROP DEFAULT;
+ALTER TABLE tasks ALTER COLUMN status TYPE INTEGER USING CASE status WHEN 'done' THEN 1 ELSE 0 END;
+ALTER TABLE tasks ALTER COLUMN status SET DEFAULT 0;
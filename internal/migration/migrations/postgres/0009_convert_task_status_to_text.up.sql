@@ -0,0 +1,4 @@
+ALTER TABLE tasks ALTER COLUMN status DROP DEFAULT;
+ALTER TABLE tasks ALTER COLUMN status TYPE TEXT USING CASE status WHEN 1 THEN 'done' ELSE 'todo' END;
+ALTER TABLE tasks ALTER COLUMN status SET DEFAULT 'todo';
+ALTER TABLE tasks ALTER COLUMN status SET NOT NULL;
@@ -0,0 +1,13 @@
+CREATE TABLE idempotency_keys (
+	id SERIAL PRIMARY KEY,
+	user_id INTEGER NOT NULL,
+	idempotency_key TEXT NOT NULL,
+	request_hash TEXT NOT NULL,
+	status_code INTEGER NOT NULL DEFAULT 0,
+	response_headers TEXT NOT NULL DEFAULT '{}',
+	response_body BYTEA,
+	expires_at TIMESTAMPTZ NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (user_id, idempotency_key)
+);
+CREATE INDEX idx_idempotency_keys_expires_at ON idempotency_keys (expires_at);
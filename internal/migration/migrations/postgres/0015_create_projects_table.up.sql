@@ -0,0 +1,12 @@
+CREATE TABLE projects (
+	id SERIAL PRIMARY KEY,
+	user_id INTEGER NOT NULL,
+	name TEXT NOT NULL,
+	description TEXT NOT NULL DEFAULT '',
+	archived_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ NOT NULL,
+	updated_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX idx_projects_user_id ON projects (user_id);
+ALTER TABLE tasks ADD COLUMN project_id INTEGER;
+CREATE INDEX idx_tasks_project_id ON tasks (project_id);
@@ -0,0 +1 @@
+ALTER TABLE tasks DROP CONSTRAINT fk_tasks_project_id;
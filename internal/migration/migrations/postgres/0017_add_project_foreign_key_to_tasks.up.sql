@@ -0,0 +1,2 @@
+UPDATE tasks SET project_id = NULL WHERE project_id NOT IN (SELECT id FROM projects);
+ALTER TABLE tasks ADD CONSTRAINT fk_tasks_project_id FOREIGN KEY (project_id) REFERENCES projects (id) ON DELETE RESTRICT;
@@ -0,0 +1 @@
+ALTER TABLE tasks ADD COLUMN due_at_offset INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS tasks (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT,
+	status INTEGER DEFAULT 0
+);
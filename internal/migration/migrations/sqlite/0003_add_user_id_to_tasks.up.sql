@@ -0,0 +1,2 @@
+ALTER TABLE tasks ADD COLUMN user_id INTEGER;
+CREATE INDEX idx_tasks_user_id ON tasks (user_id);
@@ -0,0 +1,5 @@
+CREATE TABLE revoked_tokens (
+	jti TEXT PRIMARY KEY,
+	expires_at TIMESTAMP NOT NULL
+);
+CREATE INDEX idx_revoked_tokens_expires_at ON revoked_tokens (expires_at);
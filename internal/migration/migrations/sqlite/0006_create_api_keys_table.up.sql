@@ -0,0 +1,11 @@
+CREATE TABLE api_keys (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL,
+	name TEXT NOT NULL,
+	prefix TEXT NOT NULL,
+	key_hash TEXT NOT NULL UNIQUE,
+	expires_at TIMESTAMP,
+	last_used_at TIMESTAMP,
+	created_at TIMESTAMP NOT NULL
+);
+CREATE INDEX idx_api_keys_user_id ON api_keys (user_id);
@@ -0,0 +1,2 @@
+ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'member';
+ALTER TABLE api_keys ADD COLUMN scopes TEXT NOT NULL DEFAULT 'tasks:read tasks:write';
@@ -0,0 +1,4 @@
+ALTER TABLE tasks ADD COLUMN status_code INTEGER DEFAULT 0;
+UPDATE tasks SET status_code = CASE status WHEN 'done' THEN 1 ELSE 0 END;
+ALTER TABLE tasks DROP COLUMN status;
+ALTER TABLE tasks RENAME COLUMN status_code TO status;
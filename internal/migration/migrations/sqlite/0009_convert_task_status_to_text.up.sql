@@ -0,0 +1,4 @@
+ALTER TABLE tasks ADD COLUMN status_name TEXT NOT NULL DEFAULT 'todo';
+UPDATE tasks SET status_name = CASE status WHEN 1 THEN 'done' ELSE 'todo' END;
+ALTER TABLE tasks DROP COLUMN status;
+ALTER TABLE tasks RENAME COLUMN status_name TO status;
@@ -0,0 +1,13 @@
+CREATE TABLE tags (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL,
+	name TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	UNIQUE (user_id, name)
+);
+CREATE TABLE task_tags (
+	task_id INTEGER NOT NULL,
+	tag_id INTEGER NOT NULL,
+	PRIMARY KEY (task_id, tag_id)
+);
+CREATE INDEX idx_task_tags_tag_id ON task_tags (tag_id);
@@ -0,0 +1,8 @@
+CREATE TABLE task_dependencies (
+	user_id INTEGER NOT NULL,
+	task_id INTEGER NOT NULL,
+	depends_on_id INTEGER NOT NULL,
+	PRIMARY KEY (task_id, depends_on_id)
+);
+CREATE INDEX idx_task_dependencies_user_id ON task_dependencies (user_id);
+CREATE INDEX idx_task_dependencies_depends_on_id ON task_dependencies (depends_on_id);
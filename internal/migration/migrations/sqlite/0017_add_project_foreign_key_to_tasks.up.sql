@@ -0,0 +1,6 @@
+DROP INDEX idx_tasks_project_id;
+ALTER TABLE tasks RENAME COLUMN project_id TO old_project_id;
+ALTER TABLE tasks ADD COLUMN project_id INTEGER REFERENCES projects (id) ON DELETE RESTRICT;
+UPDATE tasks SET project_id = old_project_id WHERE old_project_id IN (SELECT id FROM projects);
+ALTER TABLE tasks DROP COLUMN old_project_id;
+CREATE INDEX idx_tasks_project_id ON tasks (project_id);
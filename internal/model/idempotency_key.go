@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// IdempotencyKey records the response to a request made with an
+// Idempotency-Key header. StatusCode stays zero while the request is still
+// being processed.
+type IdempotencyKey struct {
+	ID              int
+	UserID          int
+	Key             string
+	RequestHash     string
+	StatusCode      int
+	ResponseHeaders map[string][]string
+	ResponseBody    []byte
+	ExpiresAt       time.Time
+}
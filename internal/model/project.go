@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// Project groups tasks of a user. Archived projects keep their tasks but
+// take no new ones.
+type Project struct {
+	ID          int        `json:"id"`
+	UserID      int        `json:"-"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	ArchivedAt  *time.Time `json:"archived_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
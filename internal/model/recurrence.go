@@ -0,0 +1,180 @@
+package model
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type RecurrenceFrequency string
+
+const (
+	RecurrenceDaily   RecurrenceFrequency = "DAILY"
+	RecurrenceWeekly  RecurrenceFrequency = "WEEKLY"
+	RecurrenceMonthly RecurrenceFrequency = "MONTHLY"
+)
+
+const recurrenceUntilLayout = "20060102T150405Z"
+
+// weekdayCodes are the BYDAY values of RFC 5545, in the order of a week
+// starting on Monday.
+var weekdayCodes = []string{"MO", "TU", "WE", "TH", "FR", "SA", "SU"}
+
+// RecurrenceRule is the subset of RFC 5545 RRULEs tasks recur by: a FREQ of
+// DAILY, WEEKLY or MONTHLY with an optional INTERVAL, BYDAY of plain
+// weekdays for DAILY and WEEKLY, and either UNTIL or COUNT. Count is the
+// number of occurrences left, the current one included, and 0 for no limit.
+type RecurrenceRule struct {
+	Freq      RecurrenceFrequency
+	Interval  int
+	ByWeekday []time.Weekday
+	Until     *time.Time
+	Count     int
+}
+
+// ParseRecurrenceRule reads a rule like "FREQ=WEEKLY;BYDAY=MO,TH;COUNT=10",
+// optionally prefixed with "RRULE:". A date-only UNTIL includes that day.
+func ParseRecurrenceRule(value string) (RecurrenceRule, bool) {
+	value = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(value)), "RRULE:")
+	rule := RecurrenceRule{Interval: 1}
+	seen := map[string]bool{}
+	for _, part := range strings.Split(value, ";") {
+		name, v, ok := strings.Cut(part, "=")
+		name = strings.TrimSpace(name)
+		v = strings.TrimSpace(v)
+		if !ok || seen[name] {
+			return RecurrenceRule{}, false
+		}
+		seen[name] = true
+
+		switch name {
+		case "FREQ":
+			rule.Freq = RecurrenceFrequency(v)
+			if rule.Freq != RecurrenceDaily && rule.Freq != RecurrenceWeekly && rule.Freq != RecurrenceMonthly {
+				return RecurrenceRule{}, false
+			}
+		case "INTERVAL":
+			interval, err := strconv.Atoi(v)
+			if err != nil || interval < 1 {
+				return RecurrenceRule{}, false
+			}
+			rule.Interval = interval
+		case "BYDAY":
+			for _, code := range strings.Split(v, ",") {
+				i := slices.Index(weekdayCodes, code)
+				if i < 0 {
+					return RecurrenceRule{}, false
+				}
+				if day := time.Weekday((i + 1) % 7); !slices.Contains(rule.ByWeekday, day) {
+					rule.ByWeekday = append(rule.ByWeekday, day)
+				}
+			}
+			slices.SortFunc(rule.ByWeekday, func(a, b time.Weekday) int {
+				return weekdayOffset(a) - weekdayOffset(b)
+			})
+		case "UNTIL":
+			until, err := time.Parse(recurrenceUntilLayout, v)
+			if err != nil {
+				until, err = time.Parse("20060102", v)
+				until = until.Add(24*time.Hour - time.Second)
+			}
+			if err != nil {
+				return RecurrenceRule{}, false
+			}
+			rule.Until = &until
+		case "COUNT":
+			count, err := strconv.Atoi(v)
+			if err != nil || count < 1 {
+				return RecurrenceRule{}, false
+			}
+			rule.Count = count
+		default:
+			return RecurrenceRule{}, false
+		}
+	}
+
+	if rule.Freq == "" || (rule.Until != nil && rule.Count > 0) {
+		return RecurrenceRule{}, false
+	}
+	if rule.Freq == RecurrenceMonthly && len(rule.ByWeekday) > 0 {
+		return RecurrenceRule{}, false
+	}
+	return rule, true
+}
+
+// String formats the rule the way ParseRecurrenceRule reads it, leaving
+// out the defaults.
+func (r RecurrenceRule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByWeekday) > 0 {
+		codes := make([]string, len(r.ByWeekday))
+		for i, day := range r.ByWeekday {
+			codes[i] = weekdayCodes[weekdayOffset(day)]
+		}
+		parts = append(parts, "BYDAY="+strings.Join(codes, ","))
+	}
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format(recurrenceUntilLayout))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Next returns the occurrence following the one at start, at the same time
+// of day. Weekdays and days of the month are those of start's location. It
+// reports false when there is none before Until; Count is left to the
+// caller.
+func (r RecurrenceRule) Next(start time.Time) (time.Time, bool) {
+	interval := max(r.Interval, 1)
+	var next time.Time
+	found := false
+
+	switch r.Freq {
+	case RecurrenceDaily:
+		// Steps of interval days cycle through the weekdays within a week.
+		for i := 1; i <= 7 && !found; i++ {
+			next = start.AddDate(0, 0, i*interval)
+			found = len(r.ByWeekday) == 0 || slices.Contains(r.ByWeekday, next.Weekday())
+		}
+	case RecurrenceWeekly:
+		if len(r.ByWeekday) == 0 {
+			next, found = start.AddDate(0, 0, 7*interval), true
+			break
+		}
+
+		offset := weekdayOffset(start.Weekday())
+		for _, day := range r.ByWeekday {
+			if weekdayOffset(day) > offset {
+				next, found = start.AddDate(0, 0, weekdayOffset(day)-offset), true
+				break
+			}
+		}
+		if !found {
+			next, found = start.AddDate(0, 0, 7*interval-offset+weekdayOffset(r.ByWeekday[0])), true
+		}
+	case RecurrenceMonthly:
+		// Months too short for the day of start are skipped.
+		year, month, day := start.Date()
+		for i := 1; i <= 48 && !found; i++ {
+			next = time.Date(year, month+time.Month(i*interval), day,
+				start.Hour(), start.Minute(), start.Second(), start.Nanosecond(), start.Location())
+			found = next.Day() == day
+		}
+	}
+
+	if !found || (r.Until != nil && next.After(*r.Until)) {
+		return time.Time{}, false
+	}
+	return next, true
+}
+
+// weekdayOffset counts the days since Monday.
+func weekdayOffset(day time.Weekday) int {
+	return (int(day) + 6) % 7
+}
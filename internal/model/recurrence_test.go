@@ -0,0 +1,85 @@
+package model
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestRecurrenceRule(t *testing.T) {
+	t.Run("Parse", testParseRecurrenceRule)
+	t.Run("ParseInvalid", testParseInvalidRecurrenceRule)
+	t.Run("Next", testNextRecurrence)
+}
+
+func testParseRecurrenceRule(t *testing.T) {
+	rules := map[string]string{
+		"FREQ=DAILY":                               "FREQ=DAILY",
+		"RRULE:FREQ=WEEKLY;INTERVAL=1":             "FREQ=WEEKLY",
+		"freq=weekly;byday=su,mo,mo;interval=2":    "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,SU",
+		"FREQ=MONTHLY;UNTIL=20241231":              "FREQ=MONTHLY;UNTIL=20241231T235959Z",
+		"FREQ=DAILY;BYDAY=MO,TU,WE,TH,FR;COUNT=10": "FREQ=DAILY;BYDAY=MO,TU,WE,TH,FR;COUNT=10",
+		"FREQ=WEEKLY;UNTIL=20240301T120000Z":       "FREQ=WEEKLY;UNTIL=20240301T120000Z",
+	}
+
+	for value, expected := range rules {
+		rule, ok := ParseRecurrenceRule(value)
+		assert.True(t, ok, value)
+		assert.Equal(t, expected, rule.String())
+	}
+}
+
+func testParseInvalidRecurrenceRule(t *testing.T) {
+	rules := []string{
+		"",
+		"INTERVAL=2",
+		"FREQ=YEARLY",
+		"FREQ=DAILY;FREQ=WEEKLY",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=WEEKLY;BYDAY=1MO",
+		"FREQ=MONTHLY;BYDAY=MO",
+		"FREQ=DAILY;COUNT=0",
+		"FREQ=DAILY;UNTIL=tomorrow",
+		"FREQ=DAILY;COUNT=2;UNTIL=20241231",
+		"FREQ=DAILY;BYMONTH=1",
+	}
+
+	for _, value := range rules {
+		_, ok := ParseRecurrenceRule(value)
+		assert.False(t, ok, value)
+	}
+}
+
+func testNextRecurrence(t *testing.T) {
+	// 2024-01-05 is a Friday.
+	start := time.Date(2024, time.January, 5, 7, 30, 0, 0, time.UTC)
+	next := []struct {
+		rule     string
+		start    time.Time
+		expected string
+	}{
+		{"FREQ=DAILY", start, "2024-01-06T07:30:00Z"},
+		{"FREQ=DAILY;INTERVAL=3", start, "2024-01-08T07:30:00Z"},
+		{"FREQ=DAILY;BYDAY=MO,TU,WE,TH,FR", start, "2024-01-08T07:30:00Z"},
+		{"FREQ=WEEKLY", start, "2024-01-12T07:30:00Z"},
+		{"FREQ=WEEKLY;BYDAY=MO,FR,SA", start, "2024-01-06T07:30:00Z"},
+		{"FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE", start, "2024-01-15T07:30:00Z"},
+		{"FREQ=MONTHLY", start, "2024-02-05T07:30:00Z"},
+		{"FREQ=MONTHLY", time.Date(2024, time.January, 31, 7, 30, 0, 0, time.UTC), "2024-03-31T07:30:00Z"},
+		{"FREQ=MONTHLY;INTERVAL=12", time.Date(2024, time.February, 29, 7, 30, 0, 0, time.UTC), "2028-02-29T07:30:00Z"},
+		{"FREQ=WEEKLY;UNTIL=20240112", start, "2024-01-12T07:30:00Z"},
+		{"FREQ=WEEKLY;UNTIL=20240111", start, ""},
+		{"FREQ=DAILY;INTERVAL=7;BYDAY=MO", start, ""},
+	}
+
+	for _, n := range next {
+		rule, ok := ParseRecurrenceRule(n.rule)
+		assert.True(t, ok, n.rule)
+
+		occurrence, ok := rule.Next(n.start)
+		assert.Equal(t, n.expected != "", ok, n.rule)
+		if ok {
+			assert.Equal(t, n.expected, occurrence.Format(time.RFC3339), n.rule)
+		}
+	}
+}
@@ -0,0 +1,10 @@
+package model
+
+import "time"
+
+type RefreshToken struct {
+	ID        int
+	UserID    int
+	TokenHash string
+	ExpiresAt time.Time
+}
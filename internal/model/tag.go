@@ -0,0 +1,21 @@
+package model
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const MaxTagNameLength = 50
+
+type Tag struct {
+	ID        int       `json:"id"`
+	UserID    int       `json:"-"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// IsValidTagName rejects commas, which separate tags in task filters.
+func IsValidTagName(name string) bool {
+	return name != "" && utf8.RuneCountInString(name) <= MaxTagNameLength && !strings.Contains(name, ",")
+}
@@ -1,7 +1,68 @@
 package model
 
+import "time"
+
+type TaskStatus string
+
+const (
+	TaskStatusTodo       TaskStatus = "todo"
+	TaskStatusInProgress TaskStatus = "in_progress"
+	TaskStatusBlocked    TaskStatus = "blocked"
+	TaskStatusDone       TaskStatus = "done"
+	TaskStatusCancelled  TaskStatus = "cancelled"
+)
+
+var TaskStatuses = []TaskStatus{
+	TaskStatusTodo,
+	TaskStatusInProgress,
+	TaskStatusBlocked,
+	TaskStatusDone,
+	TaskStatusCancelled,
+}
+
+func (s TaskStatus) IsValid() bool {
+	for _, status := range TaskStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+const (
+	TaskPriorityNone   = 0
+	TaskPriorityLow    = 1
+	TaskPriorityMedium = 2
+	TaskPriorityHigh   = 3
+)
+
+// Task is a unit of work of a user. Progress and Blocked are computed on
+// read: Progress is the percentage of done subtasks, not counting cancelled
+// ones, and nil for tasks without subtasks; Blocked is set while a task the
+// task depends on is neither done nor cancelled. Recurrence is a
+// RecurrenceRule in RRULE form, empty for tasks that do not recur.
 type Task struct {
-	ID     int    `json:"id"`
-	Name   string `json:"name"`
-	Status int    `json:"status"`
+	ID          int        `json:"id"`
+	UserID      int        `json:"-"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Status      TaskStatus `json:"status"`
+	Priority    int        `json:"priority"`
+	DueAt       *time.Time `json:"due_at"`
+	Tags        []string   `json:"tags"`
+	ParentID    *int       `json:"parent_id"`
+	ProjectID   *int       `json:"project_id"`
+	Recurrence  string     `json:"recurrence"`
+	Progress    *int       `json:"progress"`
+	Blocked     bool       `json:"blocked"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	CompletedAt *time.Time `json:"completed_at"`
+	Version     int        `json:"version"`
+}
+
+// TaskTree is a task with its subtasks, recursively.
+type TaskTree struct {
+	Task
+	Children []TaskTree `json:"children"`
 }
@@ -0,0 +1,17 @@
+package model
+
+// TaskDependency records that a task cannot start until the task it depends
+// on is done.
+type TaskDependency struct {
+	UserID      int `json:"-"`
+	TaskID      int `json:"task_id"`
+	DependsOnID int `json:"depends_on_id"`
+}
+
+// DependencyGraph holds a task along with the tasks it depends on and the
+// tasks depending on it, transitively, and the dependencies between them.
+type DependencyGraph struct {
+	TaskID       int              `json:"task_id"`
+	Tasks        []Task           `json:"tasks"`
+	Dependencies []TaskDependency `json:"dependencies"`
+}
@@ -0,0 +1,74 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+var TaskSortFields = []string{
+	"id", "name", "status", "priority", "due_at", "created_at", "updated_at", "completed_at",
+}
+
+type TaskSort struct {
+	Field string
+	Desc  bool
+}
+
+// TaskQuery selects a page of tasks. AfterID and AfterValues locate the last
+// task of the previous page: its id and its values of the Sort fields. A
+// task matches Tags when it carries at least one tag of every group.
+// ParentID limits the page to the subtasks of that task, ProjectID to the
+// tasks of that project and IDs, when not empty, to the tasks listed.
+type TaskQuery struct {
+	ParentID      *int
+	ProjectID     *int
+	IDs           []int
+	Statuses      []TaskStatus
+	Name          string
+	Tags          [][]string
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	DueAfter      *time.Time
+	DueBefore     *time.Time
+	Sort          []TaskSort
+	AfterID       int
+	AfterValues   []interface{}
+	Limit         int
+}
+
+// ParseTaskSort reads comma separated field names, each prefixed with "-"
+// for descending order, e.g. "-priority,due_at".
+func ParseTaskSort(value string) ([]TaskSort, bool) {
+	var sort []TaskSort
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		desc := strings.HasPrefix(field, "-")
+		field = strings.TrimPrefix(field, "-")
+
+		if !isTaskSortField(field) {
+			return nil, false
+		}
+		sort = append(sort, TaskSort{Field: field, Desc: desc})
+	}
+	return sort, true
+}
+
+func FormatTaskSort(sort []TaskSort) string {
+	fields := make([]string, len(sort))
+	for i, s := range sort {
+		fields[i] = s.Field
+		if s.Desc {
+			fields[i] = "-" + s.Field
+		}
+	}
+	return strings.Join(fields, ",")
+}
+
+func isTaskSortField(field string) bool {
+	for _, f := range TaskSortFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
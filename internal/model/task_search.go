@@ -0,0 +1,11 @@
+package model
+
+// TaskSearchResult is a task matching a search query. Highlight is the task
+// name and Snippet a fragment of its description, both with the matches
+// wrapped in <mark> tags. A higher Score means a more relevant task.
+type TaskSearchResult struct {
+	Task      Task    `json:"task"`
+	Score     float64 `json:"score"`
+	Highlight string  `json:"highlight"`
+	Snippet   string  `json:"snippet"`
+}
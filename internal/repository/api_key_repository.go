@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"database/sql"
+	"github.com/absoluteyl/tasks-go/internal/model"
+	"strings"
+	"time"
+)
+
+type APIKeyRepository struct {
+	db dialectConn
+}
+
+func NewAPIKeyRepository(db *sql.DB) *APIKeyRepository {
+	return &APIKeyRepository{db: newDialectConn(db)}
+}
+
+const apiKeyColumns = `id, user_id, name, prefix, scopes, key_hash, expires_at, last_used_at, created_at`
+
+func (r *APIKeyRepository) CreateAPIKey(apiKey *model.APIKey) (int, error) {
+	createAPIKeySQL := `
+	INSERT INTO api_keys (user_id, name, prefix, scopes, key_hash, expires_at, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)
+	`
+	return r.db.insert(
+		createAPIKeySQL,
+		apiKey.UserID, apiKey.Name, apiKey.Prefix, strings.Join(apiKey.Scopes, " "), apiKey.KeyHash, nullTime(apiKey.ExpiresAt), apiKey.CreatedAt.UTC(),
+	)
+}
+
+func (r *APIKeyRepository) GetAPIKeys(userID int) ([]model.APIKey, error) {
+	getAPIKeysSQL := `
+	SELECT ` + apiKeyColumns + ` FROM api_keys WHERE user_id = ? ORDER BY id
+	`
+	rows, err := r.db.Query(getAPIKeysSQL, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	apiKeys := []model.APIKey{}
+	for rows.Next() {
+		apiKey, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		apiKeys = append(apiKeys, apiKey)
+	}
+
+	return apiKeys, rows.Err()
+}
+
+func (r *APIKeyRepository) GetAPIKeyByID(userID int, id int) (model.APIKey, error) {
+	getAPIKeyByIDSQL := `
+	SELECT ` + apiKeyColumns + ` FROM api_keys WHERE id = ? AND user_id = ?
+	`
+	return scanAPIKey(r.db.QueryRow(getAPIKeyByIDSQL, id, userID))
+}
+
+func (r *APIKeyRepository) GetAPIKeyByHash(keyHash string) (model.APIKey, error) {
+	getAPIKeyByHashSQL := `
+	SELECT ` + apiKeyColumns + ` FROM api_keys WHERE key_hash = ?
+	`
+	return scanAPIKey(r.db.QueryRow(getAPIKeyByHashSQL, keyHash))
+}
+
+func (r *APIKeyRepository) TouchAPIKey(id int, usedAt time.Time) error {
+	touchAPIKeySQL := `
+	UPDATE api_keys SET last_used_at = ? WHERE id = ?
+	`
+	_, err := r.db.Exec(touchAPIKeySQL, usedAt.UTC(), id)
+	return err
+}
+
+func (r *APIKeyRepository) DeleteAPIKey(userID int, id int) error {
+	deleteAPIKeySQL := `
+	DELETE FROM api_keys WHERE id = ? AND user_id = ?
+	`
+	result, err := r.db.Exec(deleteAPIKeySQL, id, userID)
+	if err != nil {
+		return err
+	}
+
+	return expectAffected(result)
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanAPIKey(row scanner) (model.APIKey, error) {
+	var apiKey model.APIKey
+	var scopes string
+	var expiresAt, lastUsedAt sql.NullTime
+	err := row.Scan(
+		&apiKey.ID, &apiKey.UserID, &apiKey.Name, &apiKey.Prefix, &scopes, &apiKey.KeyHash,
+		&expiresAt, &lastUsedAt, &apiKey.CreatedAt,
+	)
+	if err != nil {
+		return model.APIKey{}, err
+	}
+
+	apiKey.Scopes = strings.Fields(scopes)
+	apiKey.ExpiresAt = timePtr(expiresAt)
+	apiKey.LastUsedAt = timePtr(lastUsedAt)
+	apiKey.CreatedAt = apiKey.CreatedAt.UTC()
+	return apiKey, nil
+}
+
+func nullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: t.UTC(), Valid: true}
+}
+
+func timePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	value := t.Time.UTC()
+	return &value
+}
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"database/sql"
+	"github.com/absoluteyl/tasks-go/internal/model"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+var apiKeyExpiresAt = time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+
+var apiKeyData = model.APIKey{
+	UserID:    1,
+	Name:      "CI",
+	Prefix:    "tsk_abcdefgh",
+	Scopes:    []string{"tasks:read"},
+	KeyHash:   "api-key-hash",
+	ExpiresAt: &apiKeyExpiresAt,
+	CreatedAt: time.Now().UTC().Truncate(time.Second),
+}
+
+func TestAPIKeyRepository(t *testing.T) {
+	t.Run("Create", testCreateAPIKey)
+	t.Run("GetList", testGetAPIKeys)
+	t.Run("GetListOtherUser", testGetAPIKeysOtherUser)
+	t.Run("GetByID", testGetAPIKeyByID)
+	t.Run("GetByHash", testGetAPIKeyByHash)
+	t.Run("Touch", testTouchAPIKey)
+	t.Run("DeleteOtherUser", testDeleteAPIKeyOtherUser)
+	t.Run("Delete", testDeleteAPIKey)
+}
+
+func testCreateAPIKey(t *testing.T) {
+	apiKeyID, err := apiKeyRepo.CreateAPIKey(&apiKeyData)
+	assert.NoError(t, err)
+	assert.NotZero(t, apiKeyID)
+	apiKeyData.ID = apiKeyID
+}
+
+func testGetAPIKeys(t *testing.T) {
+	apiKeys, err := apiKeyRepo.GetAPIKeys(apiKeyData.UserID)
+	assert.NoError(t, err)
+	assert.Len(t, apiKeys, 1)
+	assert.Equal(t, apiKeyData, apiKeys[0])
+}
+
+func testGetAPIKeysOtherUser(t *testing.T) {
+	apiKeys, err := apiKeyRepo.GetAPIKeys(otherUserID)
+	assert.NoError(t, err)
+	assert.Empty(t, apiKeys)
+}
+
+func testGetAPIKeyByID(t *testing.T) {
+	apiKey, err := apiKeyRepo.GetAPIKeyByID(apiKeyData.UserID, apiKeyData.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, apiKeyData, apiKey)
+
+	_, err = apiKeyRepo.GetAPIKeyByID(otherUserID, apiKeyData.ID)
+	assert.Equal(t, sql.ErrNoRows, err)
+}
+
+func testGetAPIKeyByHash(t *testing.T) {
+	apiKey, err := apiKeyRepo.GetAPIKeyByHash(apiKeyData.KeyHash)
+	assert.NoError(t, err)
+	assert.Equal(t, apiKeyData, apiKey)
+	assert.Nil(t, apiKey.LastUsedAt)
+}
+
+func testTouchAPIKey(t *testing.T) {
+	usedAt := time.Now().UTC().Truncate(time.Second)
+	err := apiKeyRepo.TouchAPIKey(apiKeyData.ID, usedAt)
+	assert.NoError(t, err)
+
+	apiKey, err := apiKeyRepo.GetAPIKeyByHash(apiKeyData.KeyHash)
+	assert.NoError(t, err)
+	assert.NotNil(t, apiKey.LastUsedAt)
+	assert.True(t, usedAt.Equal(*apiKey.LastUsedAt))
+}
+
+func testDeleteAPIKeyOtherUser(t *testing.T) {
+	err := apiKeyRepo.DeleteAPIKey(otherUserID, apiKeyData.ID)
+	assert.Equal(t, sql.ErrNoRows, err)
+}
+
+func testDeleteAPIKey(t *testing.T) {
+	err := apiKeyRepo.DeleteAPIKey(apiKeyData.UserID, apiKeyData.ID)
+	assert.NoError(t, err)
+
+	_, err = apiKeyRepo.GetAPIKeyByHash(apiKeyData.KeyHash)
+	assert.Equal(t, sql.ErrNoRows, err)
+}
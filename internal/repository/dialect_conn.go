@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"database/sql"
+	"github.com/absoluteyl/tasks-go/internal/dialect"
+)
+
+// dialectConn runs queries written for SQLite against a database or
+// transaction of any dialect.
+type dialectConn struct {
+	querier
+	dialect dialect.Dialect
+}
+
+func newDialectConn(db *sql.DB) dialectConn {
+	return dialectConn{querier: db, dialect: dialect.Of(db)}
+}
+
+func (c dialectConn) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return c.querier.Exec(c.dialect.Rebind(query), args...)
+}
+
+func (c dialectConn) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return c.querier.Query(c.dialect.Rebind(query), args...)
+}
+
+func (c dialectConn) QueryRow(query string, args ...interface{}) *sql.Row {
+	return c.querier.QueryRow(c.dialect.Rebind(query), args...)
+}
+
+// insert runs an INSERT and returns the id of the new row. It returns
+// sql.ErrNoRows when a conflict clause skipped the row.
+func (c dialectConn) insert(query string, args ...interface{}) (int, error) {
+	if c.dialect == dialect.Postgres {
+		var id int
+		err := c.QueryRow(query+" RETURNING id", args...).Scan(&id)
+		return id, err
+	}
+
+	result, err := c.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	err = expectAffected(result)
+	if err != nil {
+		return 0, err
+	}
+
+	lastInsertID, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(lastInsertID), nil
+}
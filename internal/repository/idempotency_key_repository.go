@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"github.com/absoluteyl/tasks-go/internal/model"
+	"time"
+)
+
+var ErrDuplicateIdempotencyKey = errors.New("duplicate idempotency key")
+
+type IdempotencyKeyRepository struct {
+	db dialectConn
+}
+
+func NewIdempotencyKeyRepository(db *sql.DB) *IdempotencyKeyRepository {
+	return &IdempotencyKeyRepository{db: newDialectConn(db)}
+}
+
+// CreateIdempotencyKey returns ErrDuplicateIdempotencyKey when the user
+// already holds the key, so concurrent requests cannot both claim it.
+func (r *IdempotencyKeyRepository) CreateIdempotencyKey(key *model.IdempotencyKey) (int, error) {
+	createIdempotencyKeySQL := `
+	INSERT INTO idempotency_keys (user_id, idempotency_key, request_hash, expires_at) VALUES (?, ?, ?, ?)
+	ON CONFLICT (user_id, idempotency_key) DO NOTHING
+	`
+	id, err := r.db.insert(createIdempotencyKeySQL, key.UserID, key.Key, key.RequestHash, key.ExpiresAt.UTC())
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrDuplicateIdempotencyKey
+	}
+	return id, err
+}
+
+func (r *IdempotencyKeyRepository) GetIdempotencyKey(userID int, key string) (model.IdempotencyKey, error) {
+	getIdempotencyKeySQL := `
+	SELECT id, user_id, idempotency_key, request_hash, status_code, response_headers, response_body, expires_at
+	FROM idempotency_keys WHERE user_id = ? AND idempotency_key = ?
+	`
+	row := r.db.QueryRow(getIdempotencyKeySQL, userID, key)
+
+	var idempotencyKey model.IdempotencyKey
+	var headers string
+	err := row.Scan(
+		&idempotencyKey.ID, &idempotencyKey.UserID, &idempotencyKey.Key, &idempotencyKey.RequestHash,
+		&idempotencyKey.StatusCode, &headers, &idempotencyKey.ResponseBody, &idempotencyKey.ExpiresAt,
+	)
+	if err != nil {
+		return model.IdempotencyKey{}, err
+	}
+
+	err = json.Unmarshal([]byte(headers), &idempotencyKey.ResponseHeaders)
+	if err != nil {
+		return model.IdempotencyKey{}, err
+	}
+
+	return idempotencyKey, nil
+}
+
+func (r *IdempotencyKeyRepository) CompleteIdempotencyKey(key *model.IdempotencyKey) error {
+	headers, err := json.Marshal(key.ResponseHeaders)
+	if err != nil {
+		return err
+	}
+
+	completeIdempotencyKeySQL := `
+	UPDATE idempotency_keys SET status_code = ?, response_headers = ?, response_body = ? WHERE id = ?
+	`
+	result, err := r.db.Exec(completeIdempotencyKeySQL, key.StatusCode, string(headers), key.ResponseBody, key.ID)
+	if err != nil {
+		return err
+	}
+
+	return expectAffected(result)
+}
+
+func (r *IdempotencyKeyRepository) DeleteIdempotencyKey(id int) error {
+	deleteIdempotencyKeySQL := `
+	DELETE FROM idempotency_keys WHERE id = ?
+	`
+	result, err := r.db.Exec(deleteIdempotencyKeySQL, id)
+	if err != nil {
+		return err
+	}
+
+	return expectAffected(result)
+}
+
+func (r *IdempotencyKeyRepository) DeleteExpiredIdempotencyKeys(now time.Time) error {
+	deleteExpiredSQL := `
+	DELETE FROM idempotency_keys WHERE expires_at <= ?
+	`
+	_, err := r.db.Exec(deleteExpiredSQL, now.UTC())
+	return err
+}
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"database/sql"
+	"github.com/absoluteyl/tasks-go/internal/model"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+)
+
+var idempotencyKeyData = model.IdempotencyKey{
+	UserID:      1,
+	Key:         "create-task-1",
+	RequestHash: "request-hash",
+	ExpiresAt:   time.Now().Add(time.Hour).UTC().Truncate(time.Second),
+}
+
+func TestIdempotencyKeyRepository(t *testing.T) {
+	t.Run("Create", testCreateIdempotencyKey)
+	t.Run("CreateDuplicate", testCreateDuplicateIdempotencyKey)
+	t.Run("CreateSameKeyOtherUser", testCreateIdempotencyKeyOtherUser)
+	t.Run("Get", testGetIdempotencyKey)
+	t.Run("Complete", testCompleteIdempotencyKey)
+	t.Run("DeleteExpired", testDeleteExpiredIdempotencyKeys)
+	t.Run("Delete", testDeleteIdempotencyKey)
+	t.Run("DeleteNotExist", testDeleteIdempotencyKeyNotExist)
+}
+
+func testCreateIdempotencyKey(t *testing.T) {
+	keyID, err := idempotencyKeyRepo.CreateIdempotencyKey(&idempotencyKeyData)
+	assert.NoError(t, err)
+	assert.NotZero(t, keyID)
+	idempotencyKeyData.ID = keyID
+}
+
+func testCreateDuplicateIdempotencyKey(t *testing.T) {
+	duplicate := idempotencyKeyData
+	duplicate.RequestHash = "other-hash"
+
+	_, err := idempotencyKeyRepo.CreateIdempotencyKey(&duplicate)
+	assert.Equal(t, ErrDuplicateIdempotencyKey, err)
+}
+
+func testCreateIdempotencyKeyOtherUser(t *testing.T) {
+	otherKey := idempotencyKeyData
+	otherKey.UserID = otherUserID
+
+	keyID, err := idempotencyKeyRepo.CreateIdempotencyKey(&otherKey)
+	assert.NoError(t, err)
+	assert.NotEqual(t, idempotencyKeyData.ID, keyID)
+}
+
+func testGetIdempotencyKey(t *testing.T) {
+	key, err := idempotencyKeyRepo.GetIdempotencyKey(idempotencyKeyData.UserID, idempotencyKeyData.Key)
+	assert.NoError(t, err)
+	assert.Equal(t, idempotencyKeyData.ID, key.ID)
+	assert.Equal(t, idempotencyKeyData.RequestHash, key.RequestHash)
+	assert.Zero(t, key.StatusCode, "Key completed before its response was stored")
+	assert.True(t, idempotencyKeyData.ExpiresAt.Equal(key.ExpiresAt))
+}
+
+func testCompleteIdempotencyKey(t *testing.T) {
+	idempotencyKeyData.StatusCode = http.StatusCreated
+	idempotencyKeyData.ResponseHeaders = map[string][]string{"Content-Type": {"application/json"}}
+	idempotencyKeyData.ResponseBody = []byte(`{"result":"ok"}`)
+
+	err := idempotencyKeyRepo.CompleteIdempotencyKey(&idempotencyKeyData)
+	assert.NoError(t, err)
+
+	key, err := idempotencyKeyRepo.GetIdempotencyKey(idempotencyKeyData.UserID, idempotencyKeyData.Key)
+	assert.NoError(t, err)
+	assert.Equal(t, idempotencyKeyData.StatusCode, key.StatusCode)
+	assert.Equal(t, idempotencyKeyData.ResponseHeaders, key.ResponseHeaders)
+	assert.Equal(t, idempotencyKeyData.ResponseBody, key.ResponseBody)
+}
+
+func testDeleteExpiredIdempotencyKeys(t *testing.T) {
+	expiredKey := model.IdempotencyKey{
+		UserID:      1,
+		Key:         "expired-key",
+		RequestHash: "request-hash",
+		ExpiresAt:   time.Now().Add(-time.Hour),
+	}
+	_, err := idempotencyKeyRepo.CreateIdempotencyKey(&expiredKey)
+	assert.NoError(t, err)
+
+	err = idempotencyKeyRepo.DeleteExpiredIdempotencyKeys(time.Now())
+	assert.NoError(t, err)
+
+	_, err = idempotencyKeyRepo.GetIdempotencyKey(expiredKey.UserID, expiredKey.Key)
+	assert.Equal(t, sql.ErrNoRows, err)
+
+	_, err = idempotencyKeyRepo.GetIdempotencyKey(idempotencyKeyData.UserID, idempotencyKeyData.Key)
+	assert.NoError(t, err)
+}
+
+func testDeleteIdempotencyKey(t *testing.T) {
+	err := idempotencyKeyRepo.DeleteIdempotencyKey(idempotencyKeyData.ID)
+	assert.NoError(t, err)
+
+	_, err = idempotencyKeyRepo.GetIdempotencyKey(idempotencyKeyData.UserID, idempotencyKeyData.Key)
+	assert.Equal(t, sql.ErrNoRows, err)
+}
+
+func testDeleteIdempotencyKeyNotExist(t *testing.T) {
+	err := idempotencyKeyRepo.DeleteIdempotencyKey(idempotencyKeyData.ID)
+	assert.Equal(t, sql.ErrNoRows, err)
+}
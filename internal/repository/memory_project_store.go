@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"database/sql"
+	"github.com/absoluteyl/tasks-go/internal/model"
+	"sort"
+	"sync"
+)
+
+// MemoryProjectStore keeps projects in memory next to the tasks of a
+// MemoryTaskStore, which it looks at before deleting a project.
+type MemoryProjectStore struct {
+	mu        sync.Mutex
+	projects  map[int]model.Project
+	lastID    int
+	taskStore *MemoryTaskStore
+}
+
+func NewMemoryProjectStore(taskStore *MemoryTaskStore) *MemoryProjectStore {
+	return &MemoryProjectStore{projects: map[int]model.Project{}, taskStore: taskStore}
+}
+
+func (s *MemoryProjectStore) CreateProject(project *model.Project) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastID++
+	stored := copyProject(*project)
+	stored.ID = s.lastID
+	s.projects[stored.ID] = stored
+	return stored.ID, nil
+}
+
+func (s *MemoryProjectStore) GetProjects(userID int, includeArchived bool) ([]model.Project, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	projects := []model.Project{}
+	for _, project := range s.projects {
+		if project.UserID == userID && (includeArchived || project.ArchivedAt == nil) {
+			projects = append(projects, copyProject(project))
+		}
+	}
+
+	sort.Slice(projects, func(i, j int) bool {
+		return projects[i].ID < projects[j].ID
+	})
+	return projects, nil
+}
+
+func (s *MemoryProjectStore) GetProjectByID(userID int, id int) (model.Project, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	project, ok := s.projects[id]
+	if !ok || project.UserID != userID {
+		return model.Project{}, sql.ErrNoRows
+	}
+	return copyProject(project), nil
+}
+
+// UpdateProject holds the task store like DeleteProject, so that it waits
+// for the tasks locking the project.
+func (s *MemoryProjectStore) UpdateProject(project *model.Project) error {
+	return s.taskStore.InTransaction(func(txStore TaskStore) error {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		stored, ok := s.projects[project.ID]
+		if !ok || stored.UserID != project.UserID {
+			return sql.ErrNoRows
+		}
+
+		updated := copyProject(*project)
+		updated.CreatedAt = stored.CreatedAt
+		s.projects[project.ID] = updated
+		return nil
+	})
+}
+
+// DeleteProject holds the task store while it looks for tasks in the
+// project, so that none can be moved into it before it is gone.
+func (s *MemoryProjectStore) DeleteProject(userID int, id int) error {
+	return s.taskStore.InTransaction(func(txStore TaskStore) error {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		project, ok := s.projects[id]
+		if !ok || project.UserID != userID {
+			return sql.ErrNoRows
+		}
+		for _, task := range txStore.(*MemoryTaskStore).state.tasks {
+			if task.ProjectID != nil && *task.ProjectID == id {
+				return ErrProjectNotEmpty
+			}
+		}
+
+		delete(s.projects, id)
+		return nil
+	})
+}
+
+// LockProject only reads the project, as txStore already holds the task
+// store that UpdateProject and DeleteProject wait for.
+func (s *MemoryProjectStore) LockProject(txStore TaskStore, userID int, id int) (model.Project, error) {
+	return s.GetProjectByID(userID, id)
+}
+
+// copyProject keeps stored projects from sharing pointers with callers.
+func copyProject(project model.Project) model.Project {
+	project.CreatedAt = project.CreatedAt.UTC()
+	project.UpdatedAt = project.UpdatedAt.UTC()
+	project.ArchivedAt = copyTime(project.ArchivedAt)
+	return project
+}
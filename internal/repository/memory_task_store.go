@@ -0,0 +1,647 @@
+package repository
+
+import (
+	"database/sql"
+	"github.com/absoluteyl/tasks-go/internal/model"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryTaskStore keeps tasks in memory, so they are lost on restart. It
+// behaves like TaskRepository, including filters, sort order and cursors.
+type MemoryTaskStore struct {
+	mu    *sync.Mutex
+	state *memoryTaskState
+	// inTransaction is set on the stores handed to InTransaction callbacks,
+	// which already hold mu.
+	inTransaction bool
+}
+
+type memoryTaskState struct {
+	tasks        map[int]model.Task
+	lastID       int
+	tags         map[int]model.Tag
+	lastTagID    int
+	dependencies map[model.TaskDependency]bool
+}
+
+func NewMemoryTaskStore() *MemoryTaskStore {
+	return &MemoryTaskStore{
+		mu: &sync.Mutex{},
+		state: &memoryTaskState{
+			tasks:        map[int]model.Task{},
+			tags:         map[int]model.Tag{},
+			dependencies: map[model.TaskDependency]bool{},
+		},
+	}
+}
+
+// InTransaction holds the store for the duration of fn and restores the
+// previous tasks when fn fails. Nested calls restore only their own work.
+func (s *MemoryTaskStore) InTransaction(fn func(txStore TaskStore) error) error {
+	if !s.inTransaction {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+	}
+
+	snapshot := s.state.clone()
+	err := fn(&MemoryTaskStore{mu: s.mu, state: s.state, inTransaction: true})
+	if err != nil {
+		*s.state = snapshot
+	}
+	return err
+}
+
+// LockUserTasks has nothing to do, as InTransaction holds the whole store.
+func (s *MemoryTaskStore) LockUserTasks(userID int) error {
+	return nil
+}
+
+func (s *MemoryTaskStore) lock() func() {
+	if s.inTransaction {
+		return func() {}
+	}
+	s.mu.Lock()
+	return s.mu.Unlock
+}
+
+func (s *MemoryTaskStore) CreateTask(task *model.Task) (int, error) {
+	defer s.lock()()
+
+	s.state.lastID++
+	stored := copyTask(*task)
+	stored.ID = s.state.lastID
+	s.state.tasks[stored.ID] = stored
+	s.state.addTags(stored.UserID, stored.Tags, stored.UpdatedAt)
+	s.state.bumpAncestors(stored.ParentID)
+
+	return stored.ID, nil
+}
+
+func (s *MemoryTaskStore) GetTasks(userID int, query model.TaskQuery) ([]model.Task, error) {
+	defer s.lock()()
+
+	var after []interface{}
+	if query.AfterID > 0 {
+		var err error
+		after, err = bindSortValues(query.Sort, query.AfterValues)
+		if err != nil {
+			return nil, err
+		}
+		after = append(after, query.AfterID)
+	}
+
+	tasks := []model.Task{}
+	for _, task := range s.state.tasks {
+		if task.UserID != userID || !matchesTaskFilters(task, query) {
+			continue
+		}
+		if after != nil && compareSortValues(query.Sort, taskSortValues(task, query.Sort), after) <= 0 {
+			continue
+		}
+		tasks = append(tasks, s.state.read(task))
+	}
+
+	sort.Slice(tasks, func(i, j int) bool {
+		return compareSortValues(query.Sort, taskSortValues(tasks[i], query.Sort), taskSortValues(tasks[j], query.Sort)) < 0
+	})
+
+	if len(tasks) > query.Limit {
+		tasks = tasks[:query.Limit]
+	}
+	return tasks, nil
+}
+
+func (s *MemoryTaskStore) GetTaskByID(userID int, id int) (model.Task, error) {
+	defer s.lock()()
+
+	task, ok := s.state.tasks[id]
+	if !ok || task.UserID != userID {
+		return model.Task{}, sql.ErrNoRows
+	}
+	return s.state.read(task), nil
+}
+
+func (s *MemoryTaskStore) UpdateTask(task *model.Task) error {
+	defer s.lock()()
+
+	stored, ok := s.state.tasks[task.ID]
+	if !ok || stored.UserID != task.UserID || stored.Version != task.Version {
+		return sql.ErrNoRows
+	}
+
+	updated := copyTask(*task)
+	updated.CreatedAt = stored.CreatedAt
+	updated.Version++
+	s.state.tasks[task.ID] = updated
+	s.state.addTags(updated.UserID, updated.Tags, updated.UpdatedAt)
+	if !sameInt(stored.ParentID, updated.ParentID) {
+		s.state.bumpAncestors(stored.ParentID)
+		s.state.bumpAncestors(updated.ParentID)
+	} else if stored.Status != updated.Status {
+		s.state.bumpAncestors(updated.ParentID)
+	}
+	if stored.Status != updated.Status {
+		s.state.bumpDependents(task.ID)
+	}
+
+	task.Version++
+	return nil
+}
+
+func (s *MemoryTaskStore) DeleteTask(userID int, id int) error {
+	defer s.lock()()
+
+	task, ok := s.state.tasks[id]
+	if !ok || task.UserID != userID {
+		return sql.ErrNoRows
+	}
+	s.state.delete(task)
+	return nil
+}
+
+func (s *MemoryTaskStore) DeleteTaskAtVersion(userID int, id int, version int) error {
+	defer s.lock()()
+
+	task, ok := s.state.tasks[id]
+	if !ok || task.UserID != userID || task.Version != version {
+		return sql.ErrNoRows
+	}
+	s.state.delete(task)
+	return nil
+}
+
+// GetTaskSubtree returns the task followed by all of its subtasks, ordered
+// by id.
+func (s *MemoryTaskStore) GetTaskSubtree(userID int, id int) ([]model.Task, error) {
+	defer s.lock()()
+
+	root, ok := s.state.tasks[id]
+	if !ok || root.UserID != userID {
+		return nil, sql.ErrNoRows
+	}
+
+	tasks := []model.Task{s.state.read(root)}
+	seen := map[int]bool{root.ID: true}
+	for i := 0; i < len(tasks); i++ {
+		for _, task := range s.state.tasks {
+			if task.ParentID != nil && *task.ParentID == tasks[i].ID && !seen[task.ID] {
+				seen[task.ID] = true
+				tasks = append(tasks, s.state.read(task))
+			}
+		}
+	}
+
+	sort.Slice(tasks, func(i, j int) bool {
+		return tasks[i].ID < tasks[j].ID
+	})
+	return tasks, nil
+}
+
+// SearchTasks matches the tasks in Go, with the same query syntax and
+// ranking as TaskRepository.SearchTasks.
+func (s *MemoryTaskStore) SearchTasks(userID int, query string, limit int) ([]model.TaskSearchResult, error) {
+	terms, err := parseSearchQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	defer s.lock()()
+
+	var tasks []model.Task
+	for _, task := range s.state.tasks {
+		if task.UserID == userID {
+			tasks = append(tasks, s.state.read(task))
+		}
+	}
+	return matchTasks(tasks, terms, limit), nil
+}
+
+func (s *MemoryTaskStore) AddTaskDependency(dependency model.TaskDependency) error {
+	defer s.lock()()
+
+	if s.state.dependencies[dependency] {
+		return ErrDuplicateDependency
+	}
+	s.state.dependencies[dependency] = true
+	s.state.bump(dependency.TaskID)
+	return nil
+}
+
+func (s *MemoryTaskStore) DeleteTaskDependency(dependency model.TaskDependency) error {
+	defer s.lock()()
+
+	if !s.state.dependencies[dependency] {
+		return sql.ErrNoRows
+	}
+	delete(s.state.dependencies, dependency)
+	s.state.bump(dependency.TaskID)
+	return nil
+}
+
+func (s *MemoryTaskStore) GetTaskDependencies(userID int) ([]model.TaskDependency, error) {
+	defer s.lock()()
+
+	dependencies := []model.TaskDependency{}
+	for dependency := range s.state.dependencies {
+		if dependency.UserID == userID {
+			dependencies = append(dependencies, dependency)
+		}
+	}
+
+	sort.Slice(dependencies, func(i, j int) bool {
+		if dependencies[i].TaskID != dependencies[j].TaskID {
+			return dependencies[i].TaskID < dependencies[j].TaskID
+		}
+		return dependencies[i].DependsOnID < dependencies[j].DependsOnID
+	})
+	return dependencies, nil
+}
+
+func (s *MemoryTaskStore) CreateTag(tag *model.Tag) (int, error) {
+	defer s.lock()()
+
+	if _, ok := s.state.findTag(tag.UserID, tag.Name); ok {
+		return 0, ErrDuplicateTag
+	}
+	return s.state.addTag(tag.UserID, tag.Name, tag.CreatedAt), nil
+}
+
+func (s *MemoryTaskStore) GetTags(userID int) ([]model.Tag, error) {
+	defer s.lock()()
+
+	tags := []model.Tag{}
+	for _, tag := range s.state.tags {
+		if tag.UserID == userID {
+			tags = append(tags, tag)
+		}
+	}
+
+	sort.Slice(tags, func(i, j int) bool {
+		return tags[i].Name < tags[j].Name
+	})
+	return tags, nil
+}
+
+func (s *MemoryTaskStore) GetTagByID(userID int, id int) (model.Tag, error) {
+	defer s.lock()()
+
+	tag, ok := s.state.tags[id]
+	if !ok || tag.UserID != userID {
+		return model.Tag{}, sql.ErrNoRows
+	}
+	return tag, nil
+}
+
+func (s *MemoryTaskStore) UpdateTag(tag *model.Tag) error {
+	defer s.lock()()
+
+	stored, ok := s.state.tags[tag.ID]
+	if !ok || stored.UserID != tag.UserID {
+		return sql.ErrNoRows
+	}
+	if other, ok := s.state.findTag(tag.UserID, tag.Name); ok && other.ID != tag.ID {
+		return ErrDuplicateTag
+	}
+
+	s.state.replaceTaskTag(stored, tag.Name)
+	stored.Name = tag.Name
+	s.state.tags[tag.ID] = stored
+	return nil
+}
+
+func (s *MemoryTaskStore) DeleteTag(userID int, id int) error {
+	defer s.lock()()
+
+	tag, ok := s.state.tags[id]
+	if !ok || tag.UserID != userID {
+		return sql.ErrNoRows
+	}
+
+	s.state.replaceTaskTag(tag, "")
+	delete(s.state.tags, id)
+	return nil
+}
+
+func (s *memoryTaskState) clone() memoryTaskState {
+	tasks := make(map[int]model.Task, len(s.tasks))
+	for id, task := range s.tasks {
+		tasks[id] = task
+	}
+	tags := make(map[int]model.Tag, len(s.tags))
+	for id, tag := range s.tags {
+		tags[id] = tag
+	}
+	dependencies := make(map[model.TaskDependency]bool, len(s.dependencies))
+	for dependency := range s.dependencies {
+		dependencies[dependency] = true
+	}
+	return memoryTaskState{
+		tasks: tasks, lastID: s.lastID, tags: tags, lastTagID: s.lastTagID, dependencies: dependencies,
+	}
+}
+
+// read returns a copy of the stored task with its progress and blocked
+// flag filled in.
+func (s *memoryTaskState) read(task model.Task) model.Task {
+	task = copyTask(task)
+
+	total, done := 0, 0
+	for _, subtask := range s.tasks {
+		if subtask.ParentID == nil || *subtask.ParentID != task.ID || subtask.Status == model.TaskStatusCancelled {
+			continue
+		}
+		total++
+		if subtask.Status == model.TaskStatusDone {
+			done++
+		}
+	}
+	task.Progress = taskProgress(total, done)
+
+	for dependency := range s.dependencies {
+		if dependency.TaskID != task.ID {
+			continue
+		}
+		status := s.tasks[dependency.DependsOnID].Status
+		if status != model.TaskStatusDone && status != model.TaskStatusCancelled {
+			task.Blocked = true
+		}
+	}
+	return task
+}
+
+// delete removes the task and its dependencies and hands its subtasks over
+// to its parent, changing the same versions as TaskRepository does.
+func (s *memoryTaskState) delete(task model.Task) {
+	delete(s.tasks, task.ID)
+	s.bumpDependents(task.ID)
+	for dependency := range s.dependencies {
+		if dependency.TaskID == task.ID || dependency.DependsOnID == task.ID {
+			delete(s.dependencies, dependency)
+		}
+	}
+	for id, subtask := range s.tasks {
+		if subtask.ParentID != nil && *subtask.ParentID == task.ID {
+			subtask.ParentID = copyInt(task.ParentID)
+			subtask.Version++
+			s.tasks[id] = subtask
+		}
+	}
+	s.bumpAncestors(task.ParentID)
+}
+
+func (s *memoryTaskState) bump(id int) {
+	if task, ok := s.tasks[id]; ok {
+		task.Version++
+		s.tasks[id] = task
+	}
+}
+
+// bumpAncestors increments the version of the task with id and of every
+// task above it, as a change among their subtasks changes their progress.
+func (s *memoryTaskState) bumpAncestors(id *int) {
+	seen := map[int]bool{}
+	for id != nil && !seen[*id] {
+		seen[*id] = true
+		s.bump(*id)
+		id = s.tasks[*id].ParentID
+	}
+}
+
+// bumpDependents increments the version of the tasks depending on the task
+// with id, whose blocked flag follows its status.
+func (s *memoryTaskState) bumpDependents(id int) {
+	for dependency := range s.dependencies {
+		if dependency.DependsOnID == id {
+			s.bump(dependency.TaskID)
+		}
+	}
+}
+
+func (s *memoryTaskState) findTag(userID int, name string) (model.Tag, bool) {
+	for _, tag := range s.tags {
+		if tag.UserID == userID && tag.Name == name {
+			return tag, true
+		}
+	}
+	return model.Tag{}, false
+}
+
+func (s *memoryTaskState) addTag(userID int, name string, now time.Time) int {
+	s.lastTagID++
+	s.tags[s.lastTagID] = model.Tag{ID: s.lastTagID, UserID: userID, Name: name, CreatedAt: now.UTC()}
+	return s.lastTagID
+}
+
+// addTags creates the tags among names the user does not have yet.
+func (s *memoryTaskState) addTags(userID int, names []string, now time.Time) {
+	for _, name := range names {
+		if _, ok := s.findTag(userID, name); !ok {
+			s.addTag(userID, name, now)
+		}
+	}
+}
+
+// replaceTaskTag renames tag on the tasks carrying it, or takes it off them
+// when name is empty. Their version changes, like in TaskRepository.
+func (s *memoryTaskState) replaceTaskTag(tag model.Tag, name string) {
+	for id, task := range s.tasks {
+		if task.UserID != tag.UserID || !slices.Contains(task.Tags, tag.Name) {
+			continue
+		}
+
+		tags := []string{}
+		for _, t := range task.Tags {
+			if t != tag.Name {
+				tags = append(tags, t)
+			} else if name != "" {
+				tags = append(tags, name)
+			}
+		}
+		sort.Strings(tags)
+		task.Tags = tags
+		task.Version++
+		s.tasks[id] = task
+	}
+}
+
+// copyTask keeps stored tasks from sharing pointers and tag slices with
+// callers and normalizes times and tags the way the database does. The
+// progress and blocked flag are left to read, as they are never stored.
+func copyTask(task model.Task) model.Task {
+	task.CreatedAt = task.CreatedAt.UTC()
+	task.UpdatedAt = task.UpdatedAt.UTC()
+	task.DueAt = copyDueAt(task.DueAt)
+	task.CompletedAt = copyTime(task.CompletedAt)
+	task.ParentID = copyInt(task.ParentID)
+	task.ProjectID = copyInt(task.ProjectID)
+	task.Progress = nil
+	task.Blocked = false
+	task.Tags = append([]string{}, task.Tags...)
+	sort.Strings(task.Tags)
+	return task
+}
+
+// copyDueAt keeps the UTC offset of the due date, like TaskRepository.
+func copyDueAt(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	copied := atOffset(*t, dueAtOffset(t))
+	return &copied
+}
+
+func copyTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	copied := t.UTC()
+	return &copied
+}
+
+func sameInt(a *int, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func copyInt(i *int) *int {
+	if i == nil {
+		return nil
+	}
+	copied := *i
+	return &copied
+}
+
+func matchesTaskFilters(task model.Task, query model.TaskQuery) bool {
+	if query.ParentID != nil && (task.ParentID == nil || *task.ParentID != *query.ParentID) {
+		return false
+	}
+	if query.ProjectID != nil && (task.ProjectID == nil || *task.ProjectID != *query.ProjectID) {
+		return false
+	}
+	if len(query.IDs) > 0 && !slices.Contains(query.IDs, task.ID) {
+		return false
+	}
+
+	if len(query.Statuses) > 0 {
+		found := false
+		for _, status := range query.Statuses {
+			found = found || task.Status == status
+		}
+		if !found {
+			return false
+		}
+	}
+
+	for _, tags := range query.Tags {
+		found := false
+		for _, tag := range tags {
+			for _, t := range task.Tags {
+				found = found || t == tag
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	// Like LIKE in SQLite, name matching ignores ASCII case.
+	if query.Name != "" && !strings.Contains(strings.ToLower(task.Name), strings.ToLower(query.Name)) {
+		return false
+	}
+
+	if query.CreatedAfter != nil && task.CreatedAt.Before(*query.CreatedAfter) {
+		return false
+	}
+	if query.CreatedBefore != nil && !task.CreatedAt.Before(*query.CreatedBefore) {
+		return false
+	}
+	if query.DueAfter != nil && (task.DueAt == nil || task.DueAt.Before(*query.DueAfter)) {
+		return false
+	}
+	if query.DueBefore != nil && (task.DueAt == nil || !task.DueAt.Before(*query.DueBefore)) {
+		return false
+	}
+
+	return true
+}
+
+// taskSortValues returns the values of the sort fields of task followed by
+// its id, in the types bindSortValue produces.
+func taskSortValues(task model.Task, sort []model.TaskSort) []interface{} {
+	values, _ := bindSortValues(sort, TaskSortValues(task, sort))
+	return append(values, task.ID)
+}
+
+func bindSortValues(sort []model.TaskSort, values []interface{}) ([]interface{}, error) {
+	if len(values) != len(sort) {
+		return nil, ErrInvalidSortValues
+	}
+
+	bound := make([]interface{}, len(sort))
+	for i, s := range sort {
+		column, ok := taskSortColumns[s.Field]
+		if !ok {
+			return nil, ErrInvalidSortValues
+		}
+
+		var err error
+		bound[i], err = bindSortValue(column, values[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+	return bound, nil
+}
+
+// compareSortValues orders two lists of sort values like the ORDER BY of
+// TaskRepository.GetTasks: missing values last, ties broken by ascending id.
+func compareSortValues(sort []model.TaskSort, a []interface{}, b []interface{}) int {
+	for i, s := range sort {
+		if c := compareSortValue(a[i], b[i], s.Desc); c != 0 {
+			return c
+		}
+	}
+	return compareSortValue(a[len(sort)], b[len(sort)], false)
+}
+
+func compareSortValue(a interface{}, b interface{}, desc bool) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	}
+
+	var c int
+	switch a := a.(type) {
+	case int:
+		c = compareOrdered(a, b.(int))
+	case string:
+		c = compareOrdered(a, b.(string))
+	case time.Time:
+		c = a.Compare(b.(time.Time))
+	}
+
+	if desc {
+		return -c
+	}
+	return c
+}
+
+func compareOrdered[T int | string](a T, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
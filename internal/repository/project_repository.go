@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/absoluteyl/tasks-go/internal/dialect"
+	"github.com/absoluteyl/tasks-go/internal/model"
+	"github.com/lib/pq"
+)
+
+var ErrProjectNotEmpty = errors.New("project has tasks")
+
+// ProjectStore is implemented by ProjectRepository and MemoryProjectStore.
+// Like TaskStore, it reports missing projects and projects of other users
+// alike with sql.ErrNoRows.
+type ProjectStore interface {
+	CreateProject(project *model.Project) (int, error)
+	GetProjects(userID int, includeArchived bool) ([]model.Project, error)
+	GetProjectByID(userID int, id int) (model.Project, error)
+	UpdateProject(project *model.Project) error
+	// DeleteProject returns ErrProjectNotEmpty, without deleting anything,
+	// while tasks are still in the project.
+	DeleteProject(userID int, id int) error
+	// LockProject reads the project in the transaction of txStore, which
+	// InTransaction of the task store handed over, and keeps it from being
+	// updated or deleted until that transaction ends.
+	LockProject(txStore TaskStore, userID int, id int) (model.Project, error)
+}
+
+type ProjectRepository struct {
+	db dialectConn
+}
+
+func NewProjectRepository(db *sql.DB) *ProjectRepository {
+	return &ProjectRepository{db: newDialectConn(db)}
+}
+
+const projectColumns = `id, user_id, name, description, archived_at, created_at, updated_at`
+
+func (r *ProjectRepository) CreateProject(project *model.Project) (int, error) {
+	createProjectSQL := `
+	INSERT INTO projects (user_id, name, description, archived_at, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)
+	`
+	return r.db.insert(
+		createProjectSQL,
+		project.UserID, project.Name, project.Description, nullTime(project.ArchivedAt),
+		project.CreatedAt.UTC(), project.UpdatedAt.UTC(),
+	)
+}
+
+// GetProjects lists the projects of the user by id, leaving out archived
+// ones unless includeArchived is set.
+func (r *ProjectRepository) GetProjects(userID int, includeArchived bool) ([]model.Project, error) {
+	condition := "user_id = ? AND archived_at IS NULL"
+	if includeArchived {
+		condition = "user_id = ?"
+	}
+
+	getProjectsSQL := `
+	SELECT ` + projectColumns + ` FROM projects WHERE ` + condition + ` ORDER BY id
+	`
+	rows, err := r.db.Query(getProjectsSQL, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	projects := []model.Project{}
+	for rows.Next() {
+		project, err := scanProject(rows)
+		if err != nil {
+			return nil, err
+		}
+		projects = append(projects, project)
+	}
+
+	return projects, rows.Err()
+}
+
+func (r *ProjectRepository) GetProjectByID(userID int, id int) (model.Project, error) {
+	getProjectByIDSQL := `
+	SELECT ` + projectColumns + ` FROM projects WHERE id = ? AND user_id = ?
+	`
+	return scanProject(r.db.QueryRow(getProjectByIDSQL, id, userID))
+}
+
+func (r *ProjectRepository) UpdateProject(project *model.Project) error {
+	updateProjectSQL := `
+	UPDATE projects SET name = ?, description = ?, archived_at = ?, updated_at = ? WHERE id = ? AND user_id = ?
+	`
+	result, err := r.db.Exec(
+		updateProjectSQL,
+		project.Name, project.Description, nullTime(project.ArchivedAt), project.UpdatedAt.UTC(),
+		project.ID, project.UserID,
+	)
+	if err != nil {
+		return err
+	}
+
+	return expectAffected(result)
+}
+
+// DeleteProject checks for tasks in the same statement, so that no task can
+// be moved into the project between the check and the delete.
+func (r *ProjectRepository) DeleteProject(userID int, id int) error {
+	deleteProjectSQL := `
+	DELETE FROM projects WHERE id = ? AND user_id = ?
+		AND NOT EXISTS (SELECT 1 FROM tasks WHERE tasks.project_id = projects.id)
+	`
+	result, err := r.db.Exec(deleteProjectSQL, id, userID)
+	if isForeignKeyViolation(err) {
+		return ErrProjectNotEmpty
+	}
+	if err != nil {
+		return err
+	}
+
+	err = expectAffected(result)
+	if errors.Is(err, sql.ErrNoRows) {
+		if _, getErr := r.GetProjectByID(userID, id); getErr == nil {
+			return ErrProjectNotEmpty
+		}
+	}
+	return err
+}
+
+// LockProject takes a share lock on the project row on Postgres. SQLite
+// allows one writer at a time, so it takes the write lock instead.
+func (r *ProjectRepository) LockProject(txStore TaskStore, userID int, id int) (model.Project, error) {
+	tx := txStore.(*TaskRepository)
+	lock := " FOR SHARE"
+	if tx.dialect == dialect.SQLite {
+		err := tx.lockWrites()
+		if err != nil {
+			return model.Project{}, err
+		}
+		lock = ""
+	}
+
+	lockProjectSQL := `
+	SELECT ` + projectColumns + ` FROM projects WHERE id = ? AND user_id = ?` + lock
+	return scanProject(tx.conn().QueryRow(lockProjectSQL, id, userID))
+}
+
+// isForeignKeyViolation tells whether Postgres refused the statement for
+// tasks still in the project. The NOT EXISTS of DeleteProject reads the
+// tasks as they were when the statement started, so a task committed into
+// the project meanwhile is only caught by the foreign key.
+func isForeignKeyViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23503"
+}
+
+func scanProject(row scanner) (model.Project, error) {
+	var project model.Project
+	var archivedAt sql.NullTime
+	err := row.Scan(
+		&project.ID, &project.UserID, &project.Name, &project.Description,
+		&archivedAt, &project.CreatedAt, &project.UpdatedAt,
+	)
+	if err != nil {
+		return model.Project{}, err
+	}
+
+	project.ArchivedAt = timePtr(archivedAt)
+	project.CreatedAt = project.CreatedAt.UTC()
+	project.UpdatedAt = project.UpdatedAt.UTC()
+	return project, nil
+}
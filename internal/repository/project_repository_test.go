@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"database/sql"
+	"github.com/absoluteyl/tasks-go/internal/model"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+var projectData = model.Project{
+	UserID:      1,
+	Name:        "Household",
+	Description: "Chores around the house",
+	CreatedAt:   time.Now().UTC().Truncate(time.Second),
+	UpdatedAt:   time.Now().UTC().Truncate(time.Second),
+}
+
+func TestProjectRepository(t *testing.T) {
+	t.Run("Create", testCreateProject)
+	t.Run("GetList", testGetProjects)
+	t.Run("GetListOtherUser", testGetProjectsOtherUser)
+	t.Run("GetByID", testGetProjectByID)
+	t.Run("Update", testUpdateProject)
+	t.Run("Archive", testArchiveProject)
+	t.Run("UpdateOtherUser", testUpdateProjectOtherUser)
+	t.Run("DeleteOtherUser", testDeleteProjectOtherUser)
+	t.Run("Delete", testDeleteProject)
+}
+
+func testCreateProject(t *testing.T) {
+	projectID, err := projectRepo.CreateProject(&projectData)
+	assert.NoError(t, err)
+	assert.NotZero(t, projectID)
+	projectData.ID = projectID
+}
+
+func testGetProjects(t *testing.T) {
+	projects, err := projectRepo.GetProjects(projectData.UserID, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []model.Project{projectData}, projects)
+}
+
+func testGetProjectsOtherUser(t *testing.T) {
+	projects, err := projectRepo.GetProjects(otherUserID, true)
+	assert.NoError(t, err)
+	assert.Empty(t, projects)
+}
+
+func testGetProjectByID(t *testing.T) {
+	project, err := projectRepo.GetProjectByID(projectData.UserID, projectData.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, projectData, project)
+
+	_, err = projectRepo.GetProjectByID(otherUserID, projectData.ID)
+	assert.Equal(t, sql.ErrNoRows, err)
+}
+
+func testUpdateProject(t *testing.T) {
+	projectData.Name = "Home"
+	projectData.Description = ""
+	err := projectRepo.UpdateProject(&projectData)
+	assert.NoError(t, err)
+
+	project, err := projectRepo.GetProjectByID(projectData.UserID, projectData.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, projectData, project)
+}
+
+func testArchiveProject(t *testing.T) {
+	archivedAt := time.Now().UTC().Truncate(time.Second)
+	projectData.ArchivedAt = &archivedAt
+	err := projectRepo.UpdateProject(&projectData)
+	assert.NoError(t, err)
+
+	projects, err := projectRepo.GetProjects(projectData.UserID, false)
+	assert.NoError(t, err)
+	assert.Empty(t, projects)
+
+	projects, err = projectRepo.GetProjects(projectData.UserID, true)
+	assert.NoError(t, err)
+	assert.Equal(t, []model.Project{projectData}, projects)
+}
+
+func testUpdateProjectOtherUser(t *testing.T) {
+	project := projectData
+	project.UserID = otherUserID
+	err := projectRepo.UpdateProject(&project)
+	assert.Equal(t, sql.ErrNoRows, err)
+}
+
+func testDeleteProjectOtherUser(t *testing.T) {
+	err := projectRepo.DeleteProject(otherUserID, projectData.ID)
+	assert.Equal(t, sql.ErrNoRows, err)
+}
+
+func testDeleteProject(t *testing.T) {
+	err := projectRepo.DeleteProject(projectData.UserID, projectData.ID)
+	assert.NoError(t, err)
+
+	_, err = projectRepo.GetProjectByID(projectData.UserID, projectData.ID)
+	assert.Equal(t, sql.ErrNoRows, err)
+}
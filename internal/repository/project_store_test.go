@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"database/sql"
+	"github.com/absoluteyl/tasks-go/internal/model"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// TestProjectStores runs the same checks against every ProjectStore, each
+// next to the TaskStore it is used with.
+func TestProjectStores(t *testing.T) {
+	t.Run("SQLite", func(t *testing.T) {
+		testProjectStore(t, projectRepo, taskRepo, 31)
+	})
+	t.Run("Memory", func(t *testing.T) {
+		taskStore := NewMemoryTaskStore()
+		testProjectStore(t, NewMemoryProjectStore(taskStore), taskStore, 31)
+	})
+}
+
+// testProjectStore archives a project and deletes it once its only task is
+// gone.
+func testProjectStore(t *testing.T, store ProjectStore, taskStore TaskStore, userID int) {
+	now := time.Now().UTC().Truncate(time.Second)
+	project := model.Project{UserID: userID, Name: "Garden", CreatedAt: now, UpdatedAt: now}
+
+	var err error
+	project.ID, err = store.CreateProject(&project)
+	assert.NoError(t, err)
+
+	stored, err := store.GetProjectByID(userID, project.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, project, stored)
+
+	_, err = store.GetProjectByID(userID+1, project.ID)
+	assert.Equal(t, sql.ErrNoRows, err)
+
+	project.ArchivedAt = &now
+	err = store.UpdateProject(&project)
+	assert.NoError(t, err)
+
+	other := project
+	other.UserID = userID + 1
+	err = store.UpdateProject(&other)
+	assert.Equal(t, sql.ErrNoRows, err)
+
+	projects, err := store.GetProjects(userID, false)
+	assert.NoError(t, err)
+	assert.Empty(t, projects)
+
+	projects, err = store.GetProjects(userID, true)
+	assert.NoError(t, err)
+	assert.Equal(t, []model.Project{project}, projects)
+
+	err = taskStore.InTransaction(func(txStore TaskStore) error {
+		locked, err := store.LockProject(txStore, userID, project.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, project, locked)
+
+		_, err = store.LockProject(txStore, userID+1, project.ID)
+		assert.Equal(t, sql.ErrNoRows, err)
+		return nil
+	})
+	assert.NoError(t, err)
+
+	task := model.Task{
+		UserID: userID, Name: "Mow lawn", Status: model.TaskStatusTodo, ProjectID: &project.ID,
+		Tags: []string{}, CreatedAt: now, UpdatedAt: now, Version: 1,
+	}
+	task.ID, err = taskStore.CreateTask(&task)
+	assert.NoError(t, err)
+
+	err = store.DeleteProject(userID, project.ID)
+	assert.Equal(t, ErrProjectNotEmpty, err)
+
+	err = store.DeleteProject(userID+1, project.ID)
+	assert.Equal(t, sql.ErrNoRows, err)
+
+	err = taskStore.DeleteTask(userID, task.ID)
+	assert.NoError(t, err)
+
+	err = store.DeleteProject(userID, project.ID)
+	assert.NoError(t, err)
+
+	_, err = store.GetProjectByID(userID, project.ID)
+	assert.Equal(t, sql.ErrNoRows, err)
+}
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"database/sql"
+	"github.com/absoluteyl/tasks-go/internal/model"
+	"time"
+)
+
+type RefreshTokenRepository struct {
+	db dialectConn
+}
+
+func NewRefreshTokenRepository(db *sql.DB) *RefreshTokenRepository {
+	return &RefreshTokenRepository{db: newDialectConn(db)}
+}
+
+func (r *RefreshTokenRepository) CreateRefreshToken(token *model.RefreshToken) (int, error) {
+	createRefreshTokenSQL := `
+	INSERT INTO refresh_tokens (user_id, token_hash, expires_at) VALUES (?, ?, ?)
+	`
+	return r.db.insert(createRefreshTokenSQL, token.UserID, token.TokenHash, token.ExpiresAt.UTC())
+}
+
+func (r *RefreshTokenRepository) GetRefreshTokenByHash(tokenHash string) (model.RefreshToken, error) {
+	getRefreshTokenSQL := `
+	SELECT id, user_id, token_hash, expires_at FROM refresh_tokens WHERE token_hash = ?
+	`
+	row := r.db.QueryRow(getRefreshTokenSQL, tokenHash)
+
+	var token model.RefreshToken
+	err := row.Scan(&token.ID, &token.UserID, &token.TokenHash, &token.ExpiresAt)
+	if err != nil {
+		return model.RefreshToken{}, err
+	}
+
+	return token, nil
+}
+
+func (r *RefreshTokenRepository) DeleteRefreshToken(tokenHash string) error {
+	deleteRefreshTokenSQL := `
+	DELETE FROM refresh_tokens WHERE token_hash = ?
+	`
+	result, err := r.db.Exec(deleteRefreshTokenSQL, tokenHash)
+	if err != nil {
+		return err
+	}
+
+	return expectAffected(result)
+}
+
+func (r *RefreshTokenRepository) DeleteExpiredRefreshTokens(now time.Time) error {
+	deleteExpiredSQL := `
+	DELETE FROM refresh_tokens WHERE expires_at <= ?
+	`
+	_, err := r.db.Exec(deleteExpiredSQL, now.UTC())
+	return err
+}
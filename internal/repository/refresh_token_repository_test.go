@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"database/sql"
+	"github.com/absoluteyl/tasks-go/internal/model"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+var refreshTokenData = model.RefreshToken{
+	UserID:    1,
+	TokenHash: "token-hash",
+	ExpiresAt: time.Now().Add(time.Hour).UTC().Truncate(time.Second),
+}
+
+func TestRefreshTokenRepository(t *testing.T) {
+	t.Run("Create", testCreateRefreshToken)
+	t.Run("GetByHash", testGetRefreshTokenByHash)
+	t.Run("DeleteExpired", testDeleteExpiredRefreshTokens)
+	t.Run("Delete", testDeleteRefreshToken)
+	t.Run("DeleteNotExist", testDeleteRefreshTokenNotExist)
+}
+
+func testCreateRefreshToken(t *testing.T) {
+	tokenID, err := refreshTokenRepo.CreateRefreshToken(&refreshTokenData)
+	assert.NoError(t, err)
+	assert.NotZero(t, tokenID)
+	refreshTokenData.ID = tokenID
+}
+
+func testGetRefreshTokenByHash(t *testing.T) {
+	token, err := refreshTokenRepo.GetRefreshTokenByHash(refreshTokenData.TokenHash)
+	assert.NoError(t, err)
+	assert.Equal(t, refreshTokenData.ID, token.ID)
+	assert.Equal(t, refreshTokenData.UserID, token.UserID)
+	assert.True(t, refreshTokenData.ExpiresAt.Equal(token.ExpiresAt))
+}
+
+func testDeleteExpiredRefreshTokens(t *testing.T) {
+	expiredToken := model.RefreshToken{
+		UserID:    1,
+		TokenHash: "expired-token-hash",
+		ExpiresAt: time.Now().Add(-time.Hour),
+	}
+	_, err := refreshTokenRepo.CreateRefreshToken(&expiredToken)
+	assert.NoError(t, err)
+
+	err = refreshTokenRepo.DeleteExpiredRefreshTokens(time.Now())
+	assert.NoError(t, err)
+
+	_, err = refreshTokenRepo.GetRefreshTokenByHash(expiredToken.TokenHash)
+	assert.Equal(t, sql.ErrNoRows, err)
+
+	_, err = refreshTokenRepo.GetRefreshTokenByHash(refreshTokenData.TokenHash)
+	assert.NoError(t, err)
+}
+
+func testDeleteRefreshToken(t *testing.T) {
+	err := refreshTokenRepo.DeleteRefreshToken(refreshTokenData.TokenHash)
+	assert.NoError(t, err)
+
+	_, err = refreshTokenRepo.GetRefreshTokenByHash(refreshTokenData.TokenHash)
+	assert.Equal(t, sql.ErrNoRows, err)
+}
+
+func testDeleteRefreshTokenNotExist(t *testing.T) {
+	err := refreshTokenRepo.DeleteRefreshToken(refreshTokenData.TokenHash)
+	assert.Equal(t, sql.ErrNoRows, err)
+}
@@ -0,0 +1,231 @@
+package repository
+
+import (
+	"database/sql"
+	"github.com/absoluteyl/tasks-go/internal/dialect"
+	"github.com/absoluteyl/tasks-go/internal/model"
+	. "github.com/absoluteyl/tasks-go/pkg/testutils"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// TestRepositoryContract runs the same checks against a fresh database of
+// every dialect. Postgres is skipped unless TEST_POSTGRES_DSN is set.
+func TestRepositoryContract(t *testing.T) {
+	t.Run("SQLite", func(t *testing.T) {
+		testRepositoryContract(t, connectContractSQLite(t))
+	})
+	t.Run("Postgres", func(t *testing.T) {
+		testRepositoryContract(t, connectContractPostgres(t))
+	})
+}
+
+func testRepositoryContract(t *testing.T, db *sql.DB) {
+	t.Run("Tasks", func(t *testing.T) {
+		testTaskStore(t, NewTaskRepository(db), NewProjectRepository(db), 1)
+	})
+	t.Run("Users", func(t *testing.T) {
+		testUserContract(t, NewUserRepository(db))
+	})
+	t.Run("Tokens", func(t *testing.T) {
+		testTokenContract(t, NewRefreshTokenRepository(db), NewRevokedTokenRepository(db))
+	})
+	t.Run("APIKeys", func(t *testing.T) {
+		testAPIKeyContract(t, NewAPIKeyRepository(db))
+	})
+	t.Run("IdempotencyKeys", func(t *testing.T) {
+		testIdempotencyKeyContract(t, NewIdempotencyKeyRepository(db))
+	})
+	t.Run("Projects", func(t *testing.T) {
+		testProjectContract(t, NewProjectRepository(db), NewTaskRepository(db))
+	})
+}
+
+func testUserContract(t *testing.T, repo *UserRepository) {
+	userID, err := repo.CreateUser("contract", "hashed-password")
+	assert.NoError(t, err)
+	assert.NotZero(t, userID)
+
+	_, err = repo.CreateUser("contract", "hashed-password")
+	assert.Equal(t, ErrDuplicateUser, err)
+
+	err = repo.UpdateUserRole(userID, "admin")
+	assert.NoError(t, err)
+
+	user, err := repo.GetUserByUsername("contract")
+	assert.NoError(t, err)
+	assert.Equal(t, model.User{ID: userID, Username: "contract", PasswordHash: "hashed-password", Role: "admin"}, user)
+
+	err = repo.UpdateUserRole(userID+1, "admin")
+	assert.Equal(t, sql.ErrNoRows, err)
+}
+
+func testTokenContract(t *testing.T, refreshTokens *RefreshTokenRepository, revokedTokens *RevokedTokenRepository) {
+	expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+
+	tokenID, err := refreshTokens.CreateRefreshToken(&model.RefreshToken{UserID: 1, TokenHash: "contract-hash", ExpiresAt: expiresAt})
+	assert.NoError(t, err)
+	assert.NotZero(t, tokenID)
+
+	token, err := refreshTokens.GetRefreshTokenByHash("contract-hash")
+	assert.NoError(t, err)
+	assert.Equal(t, tokenID, token.ID)
+	assert.True(t, expiresAt.Equal(token.ExpiresAt))
+
+	err = refreshTokens.DeleteExpiredRefreshTokens(expiresAt)
+	assert.NoError(t, err)
+
+	_, err = refreshTokens.GetRefreshTokenByHash("contract-hash")
+	assert.Equal(t, sql.ErrNoRows, err)
+
+	for i := 0; i < 2; i++ {
+		err = revokedTokens.RevokeToken("contract-jti", expiresAt)
+		assert.NoError(t, err)
+	}
+
+	revoked, err := revokedTokens.IsTokenRevoked("contract-jti")
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+}
+
+func testAPIKeyContract(t *testing.T, repo *APIKeyRepository) {
+	apiKey := model.APIKey{
+		UserID:    1,
+		Name:      "contract",
+		Prefix:    "tsk_contract",
+		Scopes:    []string{"tasks:read"},
+		KeyHash:   "contract-key-hash",
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+	}
+
+	var err error
+	apiKey.ID, err = repo.CreateAPIKey(&apiKey)
+	assert.NoError(t, err)
+	assert.NotZero(t, apiKey.ID)
+
+	usedAt := time.Now().UTC().Truncate(time.Second)
+	err = repo.TouchAPIKey(apiKey.ID, usedAt)
+	assert.NoError(t, err)
+	apiKey.LastUsedAt = &usedAt
+
+	apiKeys, err := repo.GetAPIKeys(apiKey.UserID)
+	assert.NoError(t, err)
+	assert.Equal(t, []model.APIKey{apiKey}, apiKeys)
+
+	err = repo.DeleteAPIKey(apiKey.UserID, apiKey.ID)
+	assert.NoError(t, err)
+
+	_, err = repo.GetAPIKeyByHash(apiKey.KeyHash)
+	assert.Equal(t, sql.ErrNoRows, err)
+}
+
+func testProjectContract(t *testing.T, repo *ProjectRepository, taskStore *TaskRepository) {
+	now := time.Now().UTC().Truncate(time.Second)
+	project := model.Project{UserID: 3, Name: "contract", CreatedAt: now, UpdatedAt: now}
+
+	var err error
+	project.ID, err = repo.CreateProject(&project)
+	assert.NoError(t, err)
+	assert.NotZero(t, project.ID)
+
+	project.ArchivedAt = &now
+	err = repo.UpdateProject(&project)
+	assert.NoError(t, err)
+
+	projects, err := repo.GetProjects(project.UserID, false)
+	assert.NoError(t, err)
+	assert.Empty(t, projects)
+
+	projects, err = repo.GetProjects(project.UserID, true)
+	assert.NoError(t, err)
+	assert.Equal(t, []model.Project{project}, projects)
+
+	missingProjectID := project.ID + 1
+	_, err = taskStore.CreateTask(&model.Task{
+		UserID: project.UserID, Name: "contract", Status: model.TaskStatusTodo, ProjectID: &missingProjectID,
+		CreatedAt: now, UpdatedAt: now, Version: 1,
+	})
+	assert.Error(t, err, "Task created in a missing project")
+
+	err = repo.DeleteProject(project.UserID, project.ID)
+	assert.NoError(t, err)
+
+	_, err = repo.GetProjectByID(project.UserID, project.ID)
+	assert.Equal(t, sql.ErrNoRows, err)
+}
+
+func testIdempotencyKeyContract(t *testing.T, repo *IdempotencyKeyRepository) {
+	key := model.IdempotencyKey{
+		UserID:      1,
+		Key:         "contract",
+		RequestHash: "request-hash",
+		ExpiresAt:   time.Now().Add(time.Hour),
+	}
+
+	var err error
+	key.ID, err = repo.CreateIdempotencyKey(&key)
+	assert.NoError(t, err)
+	assert.NotZero(t, key.ID)
+
+	_, err = repo.CreateIdempotencyKey(&key)
+	assert.Equal(t, ErrDuplicateIdempotencyKey, err)
+
+	key.StatusCode = http.StatusCreated
+	key.ResponseHeaders = map[string][]string{"Etag": {`"1"`}}
+	key.ResponseBody = []byte{0, 1, 2}
+	err = repo.CompleteIdempotencyKey(&key)
+	assert.NoError(t, err)
+
+	stored, err := repo.GetIdempotencyKey(key.UserID, key.Key)
+	assert.NoError(t, err)
+	assert.Equal(t, key.StatusCode, stored.StatusCode)
+	assert.Equal(t, key.ResponseHeaders, stored.ResponseHeaders)
+	assert.Equal(t, key.ResponseBody, stored.ResponseBody)
+}
+
+func connectContractSQLite(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite3", dialect.SQLiteDSN(":memory:"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every connection to :memory: opens a separate database.
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	err = MigrateDB(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = EnsureTaskSearchIndex(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func connectContractPostgres(t *testing.T) *sql.DB {
+	db, err := ConnectPostgresDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if db == nil {
+		t.Skip("TEST_POSTGRES_DSN is not set")
+	}
+	t.Cleanup(func() { db.Close() })
+
+	// Start from an empty database, even after an aborted run.
+	err = ResetDB(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ResetDB(db) })
+
+	err = MigrateDB(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
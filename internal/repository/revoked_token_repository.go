@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+)
+
+type RevokedTokenRepository struct {
+	db dialectConn
+}
+
+func NewRevokedTokenRepository(db *sql.DB) *RevokedTokenRepository {
+	return &RevokedTokenRepository{db: newDialectConn(db)}
+}
+
+func (r *RevokedTokenRepository) RevokeToken(jti string, expiresAt time.Time) error {
+	revokeTokenSQL := `
+	INSERT INTO revoked_tokens (jti, expires_at) VALUES (?, ?)
+	ON CONFLICT (jti) DO NOTHING
+	`
+	_, err := r.db.Exec(revokeTokenSQL, jti, expiresAt.UTC())
+	return err
+}
+
+func (r *RevokedTokenRepository) IsTokenRevoked(jti string) (bool, error) {
+	isTokenRevokedSQL := `
+	SELECT COUNT(*) FROM revoked_tokens WHERE jti = ?
+	`
+	var count int
+	err := r.db.QueryRow(isTokenRevokedSQL, jti).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+func (r *RevokedTokenRepository) DeleteExpiredRevokedTokens(now time.Time) error {
+	deleteExpiredSQL := `
+	DELETE FROM revoked_tokens WHERE expires_at <= ?
+	`
+	_, err := r.db.Exec(deleteExpiredSQL, now.UTC())
+	return err
+}
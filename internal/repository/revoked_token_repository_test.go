@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestRevokedTokenRepository(t *testing.T) {
+	t.Run("NotRevoked", testTokenNotRevoked)
+	t.Run("Revoke", testRevokeToken)
+	t.Run("RevokeTwice", testRevokeTokenTwice)
+	t.Run("DeleteExpired", testDeleteExpiredRevokedTokens)
+}
+
+func testTokenNotRevoked(t *testing.T) {
+	revoked, err := revokedTokenRepo.IsTokenRevoked("active-jti")
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+}
+
+func testRevokeToken(t *testing.T) {
+	err := revokedTokenRepo.RevokeToken("revoked-jti", time.Now().Add(time.Hour))
+	assert.NoError(t, err)
+
+	revoked, err := revokedTokenRepo.IsTokenRevoked("revoked-jti")
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+}
+
+func testRevokeTokenTwice(t *testing.T) {
+	err := revokedTokenRepo.RevokeToken("revoked-jti", time.Now().Add(time.Hour))
+	assert.NoError(t, err)
+}
+
+func testDeleteExpiredRevokedTokens(t *testing.T) {
+	err := revokedTokenRepo.RevokeToken("expired-jti", time.Now().Add(-time.Hour))
+	assert.NoError(t, err)
+
+	err = revokedTokenRepo.DeleteExpiredRevokedTokens(time.Now())
+	assert.NoError(t, err)
+
+	revoked, err := revokedTokenRepo.IsTokenRevoked("expired-jti")
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+
+	revoked, err = revokedTokenRepo.IsTokenRevoked("revoked-jti")
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+}
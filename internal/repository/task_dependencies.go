@@ -0,0 +1,170 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/absoluteyl/tasks-go/internal/dialect"
+	"github.com/absoluteyl/tasks-go/internal/model"
+)
+
+var ErrDuplicateDependency = errors.New("duplicate task dependency")
+
+// userTasksLock is the first key of the Postgres advisory locks taken by
+// LockUserTasks, the second one being the user id.
+const userTasksLock = 1
+
+// AddTaskDependency returns ErrDuplicateDependency when the task already
+// depends on the other one. It does not look for cycles. The version of the
+// task changes, as it may now be blocked.
+func (r *TaskRepository) AddTaskDependency(dependency model.TaskDependency) error {
+	return r.transaction(func(tx *TaskRepository) error {
+		addTaskDependencySQL := `
+		INSERT INTO task_dependencies (user_id, task_id, depends_on_id) VALUES (?, ?, ?)
+		ON CONFLICT (task_id, depends_on_id) DO NOTHING
+		`
+		result, err := tx.conn().Exec(addTaskDependencySQL, dependency.UserID, dependency.TaskID, dependency.DependsOnID)
+		if err != nil {
+			return err
+		}
+
+		err = expectAffected(result)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrDuplicateDependency
+		}
+		if err != nil {
+			return err
+		}
+
+		return tx.bumpTask(dependency.UserID, dependency.TaskID)
+	})
+}
+
+// DeleteTaskDependency changes the version of the task, as it may no longer
+// be blocked.
+func (r *TaskRepository) DeleteTaskDependency(dependency model.TaskDependency) error {
+	return r.transaction(func(tx *TaskRepository) error {
+		deleteTaskDependencySQL := `
+		DELETE FROM task_dependencies WHERE user_id = ? AND task_id = ? AND depends_on_id = ?
+		`
+		result, err := tx.conn().Exec(deleteTaskDependencySQL, dependency.UserID, dependency.TaskID, dependency.DependsOnID)
+		if err != nil {
+			return err
+		}
+
+		err = expectAffected(result)
+		if err != nil {
+			return err
+		}
+
+		return tx.bumpTask(dependency.UserID, dependency.TaskID)
+	})
+}
+
+// LockUserTasks takes a transaction-level advisory lock on Postgres. SQLite
+// allows one writer at a time, so taking the write lock ahead of the
+// reads is enough there.
+func (r *TaskRepository) LockUserTasks(userID int) error {
+	if r.dialect == dialect.Postgres {
+		_, err := r.conn().Exec(`SELECT pg_advisory_xact_lock(?, ?)`, userTasksLock, userID)
+		return err
+	}
+	return r.lockWrites()
+}
+
+// lockWrites makes the SQLite transaction a writer right away with an update
+// that changes nothing.
+func (r *TaskRepository) lockWrites() error {
+	_, err := r.conn().Exec(`UPDATE tasks SET version = version WHERE 1 = 0`)
+	return err
+}
+
+// GetTaskDependencies returns all dependencies between the user's tasks,
+// ordered by task and then by the task depended on.
+func (r *TaskRepository) GetTaskDependencies(userID int) ([]model.TaskDependency, error) {
+	getTaskDependenciesSQL := `
+	SELECT user_id, task_id, depends_on_id FROM task_dependencies WHERE user_id = ?
+	ORDER BY task_id, depends_on_id
+	`
+	rows, err := r.conn().Query(getTaskDependenciesSQL, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	dependencies := []model.TaskDependency{}
+	for rows.Next() {
+		var dependency model.TaskDependency
+		err := rows.Scan(&dependency.UserID, &dependency.TaskID, &dependency.DependsOnID)
+		if err != nil {
+			return nil, err
+		}
+		dependencies = append(dependencies, dependency)
+	}
+	return dependencies, rows.Err()
+}
+
+func (r *TaskRepository) bumpTask(userID int, id int) error {
+	bumpTaskSQL := `
+	UPDATE tasks SET version = version + 1 WHERE id = ? AND user_id = ?
+	`
+	_, err := r.conn().Exec(bumpTaskSQL, id, userID)
+	return err
+}
+
+// bumpDependents increments the version of the tasks depending on the task,
+// whose blocked flag follows its status.
+func (r *TaskRepository) bumpDependents(userID int, id int) error {
+	bumpDependentsSQL := `
+	UPDATE tasks SET version = version + 1
+	WHERE id IN (SELECT task_id FROM task_dependencies WHERE user_id = ? AND depends_on_id = ?)
+	`
+	_, err := r.conn().Exec(bumpDependentsSQL, userID, id)
+	return err
+}
+
+// clearTaskDependencies removes the dependencies of the task and those on it.
+func (r *TaskRepository) clearTaskDependencies(userID int, taskID int) error {
+	clearTaskDependenciesSQL := `
+	DELETE FROM task_dependencies WHERE user_id = ? AND (task_id = ? OR depends_on_id = ?)
+	`
+	_, err := r.conn().Exec(clearTaskDependenciesSQL, userID, taskID, taskID)
+	return err
+}
+
+// loadTaskBlocked sets Blocked on the tasks that depend on a task which is
+// neither done nor cancelled.
+func (r *TaskRepository) loadTaskBlocked(tasks []model.Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	index := make(map[int]int, len(tasks))
+	args := make([]interface{}, 0, len(tasks)+2)
+	for i := range tasks {
+		tasks[i].Blocked = false
+		index[tasks[i].ID] = i
+		args = append(args, tasks[i].ID)
+	}
+	args = append(args, model.TaskStatusDone, model.TaskStatusCancelled)
+
+	getBlockedTasksSQL := `
+	SELECT DISTINCT task_dependencies.task_id FROM task_dependencies
+	JOIN tasks ON tasks.id = task_dependencies.depends_on_id
+	WHERE task_dependencies.task_id IN (` + placeholders(len(tasks)) + `) AND tasks.status NOT IN (?, ?)
+	`
+	rows, err := r.conn().Query(getBlockedTasksSQL, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var taskID int
+		err := rows.Scan(&taskID)
+		if err != nil {
+			return err
+		}
+		tasks[index[taskID]].Blocked = true
+	}
+	return rows.Err()
+}
@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"database/sql"
+	"github.com/absoluteyl/tasks-go/internal/model"
+)
+
+// GetTaskSubtree returns the task followed by all of its subtasks, down to
+// the leaves, ordered by id.
+func (r *TaskRepository) GetTaskSubtree(userID int, id int) ([]model.Task, error) {
+	// UNION rather than UNION ALL stops the recursion should the hierarchy
+	// ever contain a cycle.
+	getTaskSubtreeSQL := `
+	WITH RECURSIVE subtree (id) AS (
+		SELECT id FROM tasks WHERE id = ? AND user_id = ?
+		UNION
+		SELECT tasks.id FROM tasks JOIN subtree ON tasks.parent_id = subtree.id
+	)
+	SELECT ` + taskColumns + ` FROM tasks WHERE id IN (SELECT id FROM subtree) ORDER BY id
+	`
+	rows, err := r.conn().Query(getTaskSubtreeSQL, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tasks := []model.Task{}
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(tasks) == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	return tasks, r.loadTaskDetails(tasks)
+}
+
+// reparentSubtasks moves the subtasks of the task up to parentID. Their
+// version changes along with their parent_id.
+func (r *TaskRepository) reparentSubtasks(userID int, id int, parentID sql.NullInt64) error {
+	reparentSubtasksSQL := `
+	UPDATE tasks SET parent_id = ?, version = version + 1 WHERE parent_id = ? AND user_id = ?
+	`
+	_, err := r.conn().Exec(reparentSubtasksSQL, parentID, id, userID)
+	return err
+}
+
+// bumpAncestors increments the version of the task with id and of every
+// task above it, as a change among their subtasks changes their progress.
+func (r *TaskRepository) bumpAncestors(userID int, id sql.NullInt64) error {
+	if !id.Valid {
+		return nil
+	}
+
+	bumpAncestorsSQL := `
+	WITH RECURSIVE ancestors (id) AS (
+		SELECT id FROM tasks WHERE id = ? AND user_id = ?
+		UNION
+		SELECT tasks.parent_id FROM tasks JOIN ancestors ON tasks.id = ancestors.id WHERE tasks.parent_id IS NOT NULL
+	)
+	UPDATE tasks SET version = version + 1 WHERE id IN (SELECT id FROM ancestors)
+	`
+	_, err := r.conn().Exec(bumpAncestorsSQL, id, userID)
+	return err
+}
+
+// loadTaskProgress fills in the progress of the tasks that have subtasks
+// other than cancelled ones.
+func (r *TaskRepository) loadTaskProgress(tasks []model.Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	index := make(map[int]int, len(tasks))
+	args := []interface{}{model.TaskStatusDone}
+	for i := range tasks {
+		tasks[i].Progress = nil
+		index[tasks[i].ID] = i
+		args = append(args, tasks[i].ID)
+	}
+	args = append(args, model.TaskStatusCancelled)
+
+	getTaskProgressSQL := `
+	SELECT parent_id, COUNT(*), COUNT(CASE WHEN status = ? THEN 1 END) FROM tasks
+	WHERE parent_id IN (` + placeholders(len(tasks)) + `) AND status <> ?
+	GROUP BY parent_id
+	`
+	rows, err := r.conn().Query(getTaskProgressSQL, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var parentID, total, done int
+		err := rows.Scan(&parentID, &total, &done)
+		if err != nil {
+			return err
+		}
+		tasks[index[parentID]].Progress = taskProgress(total, done)
+	}
+	return rows.Err()
+}
+
+// taskProgress rounds down, so that only tasks whose subtasks are all done
+// reach 100.
+func taskProgress(total int, done int) *int {
+	if total == 0 {
+		return nil
+	}
+	progress := done * 100 / total
+	return &progress
+}
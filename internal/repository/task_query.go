@@ -0,0 +1,248 @@
+package repository
+
+import (
+	"errors"
+	"github.com/absoluteyl/tasks-go/internal/model"
+	"strings"
+	"time"
+)
+
+var ErrInvalidSortValues = errors.New("sort values do not match sort fields")
+
+type taskSortColumn struct {
+	nullable bool
+	isTime   bool
+	isInt    bool
+}
+
+var taskSortColumns = map[string]taskSortColumn{
+	"id":           {isInt: true},
+	"name":         {},
+	"status":       {},
+	"priority":     {isInt: true},
+	"due_at":       {nullable: true, isTime: true},
+	"created_at":   {isTime: true},
+	"updated_at":   {isTime: true},
+	"completed_at": {nullable: true, isTime: true},
+}
+
+// sortKey is one ORDER BY term. Nullable fields are split in two keys so
+// that tasks without a value sort last in either direction.
+type sortKey struct {
+	expr  string
+	desc  bool
+	value interface{}
+}
+
+func (k sortKey) order() string {
+	if k.desc {
+		return k.expr + " DESC"
+	}
+	return k.expr + " ASC"
+}
+
+// taskFilters translates the filters of query into WHERE conditions and
+// their arguments.
+func taskFilters(query model.TaskQuery) ([]string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+
+	if query.ParentID != nil {
+		conditions = append(conditions, "parent_id = ?")
+		args = append(args, *query.ParentID)
+	}
+
+	if query.ProjectID != nil {
+		conditions = append(conditions, "project_id = ?")
+		args = append(args, *query.ProjectID)
+	}
+
+	if len(query.IDs) > 0 {
+		conditions = append(conditions, "id IN ("+placeholders(len(query.IDs))+")")
+		for _, id := range query.IDs {
+			args = append(args, id)
+		}
+	}
+
+	if len(query.Statuses) > 0 {
+		conditions = append(conditions, "status IN ("+placeholders(len(query.Statuses))+")")
+		for _, status := range query.Statuses {
+			args = append(args, status)
+		}
+	}
+
+	// LIKE ignores case in SQLite but not in Postgres, so both sides are
+	// lowered.
+	if query.Name != "" {
+		conditions = append(conditions, `LOWER(name) LIKE LOWER(?) ESCAPE '\'`)
+		args = append(args, "%"+escapeLike(query.Name)+"%")
+	}
+
+	for _, tags := range query.Tags {
+		conditions = append(conditions, `id IN (
+			SELECT task_tags.task_id FROM task_tags JOIN tags ON tags.id = task_tags.tag_id
+			WHERE tags.name IN (`+placeholders(len(tags))+`)
+		)`)
+		for _, tag := range tags {
+			args = append(args, tag)
+		}
+	}
+
+	ranges := []struct {
+		condition string
+		value     *time.Time
+	}{
+		{"created_at >= ?", query.CreatedAfter},
+		{"created_at < ?", query.CreatedBefore},
+		{"due_at >= ?", query.DueAfter},
+		{"due_at < ?", query.DueBefore},
+	}
+	for _, r := range ranges {
+		if r.value != nil {
+			conditions = append(conditions, r.condition)
+			args = append(args, r.value.UTC())
+		}
+	}
+
+	return conditions, args
+}
+
+// taskSortKeys expands sort into ORDER BY keys, ending with id so that the
+// order is total. values are the sort values of the cursor task, if any.
+func taskSortKeys(sort []model.TaskSort, afterID int, values []interface{}) ([]sortKey, error) {
+	if values != nil && len(values) != len(sort) {
+		return nil, ErrInvalidSortValues
+	}
+
+	var keys []sortKey
+	hasID := false
+	for i, s := range sort {
+		column, ok := taskSortColumns[s.Field]
+		if !ok {
+			return nil, ErrInvalidSortValues
+		}
+
+		var value interface{}
+		if values != nil {
+			var err error
+			value, err = bindSortValue(column, values[i])
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if column.nullable {
+			keys = append(keys, sortKey{expr: "(" + s.Field + " IS NULL)", value: value == nil})
+		}
+		keys = append(keys, sortKey{expr: s.Field, desc: s.Desc, value: value})
+		hasID = hasID || s.Field == "id"
+	}
+
+	if !hasID {
+		keys = append(keys, sortKey{expr: "id", value: afterID})
+	}
+	return keys, nil
+}
+
+// keysetCondition matches the rows ordered after the cursor values of keys.
+func keysetCondition(keys []sortKey) (string, []interface{}) {
+	var alternatives []string
+	var args []interface{}
+
+	for i, key := range keys {
+		var terms []string
+		for _, previous := range keys[:i] {
+			terms = append(terms, previous.expr+" IS NOT DISTINCT FROM ?")
+			args = append(args, previous.value)
+		}
+
+		operator := " > ?"
+		if key.desc {
+			operator = " < ?"
+		}
+		terms = append(terms, key.expr+operator)
+		args = append(args, key.value)
+
+		alternatives = append(alternatives, "("+strings.Join(terms, " AND ")+")")
+	}
+
+	return "(" + strings.Join(alternatives, " OR ") + ")", args
+}
+
+// TaskSortValues returns the values of the sort fields of task in a form
+// that survives a JSON round trip.
+func TaskSortValues(task model.Task, sort []model.TaskSort) []interface{} {
+	values := make([]interface{}, len(sort))
+	for i, s := range sort {
+		switch s.Field {
+		case "id":
+			values[i] = task.ID
+		case "name":
+			values[i] = task.Name
+		case "status":
+			values[i] = string(task.Status)
+		case "priority":
+			values[i] = task.Priority
+		case "due_at":
+			values[i] = formatSortTime(task.DueAt)
+		case "created_at":
+			values[i] = formatSortTime(&task.CreatedAt)
+		case "updated_at":
+			values[i] = formatSortTime(&task.UpdatedAt)
+		case "completed_at":
+			values[i] = formatSortTime(task.CompletedAt)
+		}
+	}
+	return values
+}
+
+func formatSortTime(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+func bindSortValue(column taskSortColumn, value interface{}) (interface{}, error) {
+	if value == nil {
+		if !column.nullable {
+			return nil, ErrInvalidSortValues
+		}
+		return nil, nil
+	}
+
+	switch {
+	case column.isTime:
+		text, ok := value.(string)
+		if !ok {
+			return nil, ErrInvalidSortValues
+		}
+		t, err := time.Parse(time.RFC3339Nano, text)
+		if err != nil {
+			return nil, ErrInvalidSortValues
+		}
+		return t.UTC(), nil
+	case column.isInt:
+		switch number := value.(type) {
+		case int:
+			return number, nil
+		case float64:
+			return int(number), nil
+		}
+		return nil, ErrInvalidSortValues
+	default:
+		text, ok := value.(string)
+		if !ok {
+			return nil, ErrInvalidSortValues
+		}
+		return text, nil
+	}
+}
+
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"github.com/absoluteyl/tasks-go/internal/model"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+const queryUserID = 10
+
+var queryBaseTime = time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+
+func TestTaskQuery(t *testing.T) {
+	t.Run("Setup", testSetupQueryTasks)
+	t.Run("FilterStatus", testFilterTasksByStatus)
+	t.Run("FilterName", testFilterTasksByName)
+	t.Run("FilterCreatedRange", testFilterTasksByCreatedRange)
+	t.Run("FilterDueRange", testFilterTasksByDueRange)
+	t.Run("Sort", testSortTasks)
+	t.Run("KeysetPagination", testKeysetPagination)
+	t.Run("InvalidSortValues", testInvalidSortValues)
+}
+
+func testSetupQueryTasks(t *testing.T) {
+	tasks := []struct {
+		name     string
+		status   model.TaskStatus
+		priority int
+		dueDays  int
+	}{
+		{"Buy milk", model.TaskStatusTodo, 2, 2},
+		{"Buy bread", model.TaskStatusDone, 3, 0},
+		{"Walk 100% of the dog", model.TaskStatusInProgress, 2, 1},
+		{"Call mum_x", model.TaskStatusTodo, 0, 0},
+		{"Write report", model.TaskStatusBlocked, 3, 3},
+	}
+
+	for i, data := range tasks {
+		createdAt := queryBaseTime.Add(time.Duration(i) * time.Hour)
+		task := model.Task{
+			UserID:    queryUserID,
+			Name:      data.name,
+			Status:    data.status,
+			Priority:  data.priority,
+			CreatedAt: createdAt,
+			UpdatedAt: createdAt,
+		}
+		if data.dueDays > 0 {
+			dueAt := queryBaseTime.AddDate(0, 0, data.dueDays)
+			task.DueAt = &dueAt
+		}
+
+		_, err := taskRepo.CreateTask(&task)
+		assert.NoError(t, err)
+	}
+}
+
+func testFilterTasksByStatus(t *testing.T) {
+	names := queryTaskNames(t, model.TaskQuery{Statuses: []model.TaskStatus{model.TaskStatusTodo}})
+	assert.Equal(t, []string{"Buy milk", "Call mum_x"}, names)
+
+	names = queryTaskNames(t, model.TaskQuery{
+		Statuses: []model.TaskStatus{model.TaskStatusDone, model.TaskStatusBlocked},
+	})
+	assert.Equal(t, []string{"Buy bread", "Write report"}, names)
+}
+
+func testFilterTasksByName(t *testing.T) {
+	assert.Equal(t, []string{"Buy milk", "Buy bread"}, queryTaskNames(t, model.TaskQuery{Name: "buy"}))
+	assert.Equal(t, []string{"Walk 100% of the dog"}, queryTaskNames(t, model.TaskQuery{Name: "100%"}))
+	assert.Equal(t, []string{"Call mum_x"}, queryTaskNames(t, model.TaskQuery{Name: "_"}))
+}
+
+func testFilterTasksByCreatedRange(t *testing.T) {
+	after := queryBaseTime.Add(time.Hour)
+	before := queryBaseTime.Add(3 * time.Hour)
+
+	names := queryTaskNames(t, model.TaskQuery{CreatedAfter: &after, CreatedBefore: &before})
+	assert.Equal(t, []string{"Buy bread", "Walk 100% of the dog"}, names)
+}
+
+func testFilterTasksByDueRange(t *testing.T) {
+	after := queryBaseTime.AddDate(0, 0, 1)
+	before := queryBaseTime.AddDate(0, 0, 3)
+
+	names := queryTaskNames(t, model.TaskQuery{DueAfter: &after, DueBefore: &before})
+	assert.Equal(t, []string{"Buy milk", "Walk 100% of the dog"}, names)
+}
+
+func testSortTasks(t *testing.T) {
+	sort, ok := model.ParseTaskSort("-priority,due_at")
+	assert.True(t, ok)
+
+	names := queryTaskNames(t, model.TaskQuery{Sort: sort})
+	assert.Equal(t, []string{"Write report", "Buy bread", "Walk 100% of the dog", "Buy milk", "Call mum_x"}, names)
+
+	sort, ok = model.ParseTaskSort("-due_at")
+	assert.True(t, ok)
+
+	names = queryTaskNames(t, model.TaskQuery{Sort: sort})
+	assert.Equal(t, []string{"Write report", "Buy milk", "Walk 100% of the dog", "Buy bread", "Call mum_x"}, names)
+}
+
+func testKeysetPagination(t *testing.T) {
+	for _, value := range []string{"id", "-id", "name", "-priority,due_at", "due_at,-name", "status,-created_at"} {
+		sort, ok := model.ParseTaskSort(value)
+		assert.True(t, ok)
+
+		expected := queryTaskNames(t, model.TaskQuery{Sort: sort})
+
+		var names []string
+		query := model.TaskQuery{Sort: sort, Limit: 2}
+		for {
+			tasks, err := taskRepo.GetTasks(queryUserID, query)
+			assert.NoError(t, err)
+			for _, task := range tasks {
+				names = append(names, task.Name)
+			}
+			if len(tasks) < query.Limit {
+				break
+			}
+
+			last := tasks[len(tasks)-1]
+			query.AfterID = last.ID
+			query.AfterValues = TaskSortValues(last, sort)
+		}
+
+		assert.Equal(t, expected, names, "Pages differ from full list sorted by %s", value)
+	}
+}
+
+func testInvalidSortValues(t *testing.T) {
+	sort, _ := model.ParseTaskSort("priority")
+
+	_, err := taskRepo.GetTasks(queryUserID, model.TaskQuery{Sort: sort, AfterID: 1, AfterValues: []interface{}{}, Limit: 10})
+	assert.Equal(t, ErrInvalidSortValues, err)
+
+	_, err = taskRepo.GetTasks(queryUserID, model.TaskQuery{Sort: sort, AfterID: 1, AfterValues: []interface{}{"high"}, Limit: 10})
+	assert.Equal(t, ErrInvalidSortValues, err)
+}
+
+func queryTaskNames(t *testing.T, query model.TaskQuery) []string {
+	query.Limit = 100
+	tasks, err := taskRepo.GetTasks(queryUserID, query)
+	assert.NoError(t, err)
+
+	names := []string{}
+	for _, task := range tasks {
+		names = append(names, task.Name)
+	}
+	return names
+}
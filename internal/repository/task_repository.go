@@ -2,39 +2,191 @@ package repository
 
 import (
 	"database/sql"
+	"fmt"
+	"github.com/absoluteyl/tasks-go/internal/dialect"
 	"github.com/absoluteyl/tasks-go/internal/model"
+	"strings"
+	"time"
 )
 
+type querier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// TaskStore persists tasks. Implementations report missing tasks, tasks of
+// other users and stale versions alike with sql.ErrNoRows.
+type TaskStore interface {
+	CreateTask(task *model.Task) (int, error)
+	GetTasks(userID int, query model.TaskQuery) ([]model.Task, error)
+	GetTaskByID(userID int, id int) (model.Task, error)
+	UpdateTask(task *model.Task) error
+	DeleteTask(userID int, id int) error
+	DeleteTaskAtVersion(userID int, id int, version int) error
+	GetTaskSubtree(userID int, id int) ([]model.Task, error)
+	AddTaskDependency(dependency model.TaskDependency) error
+	DeleteTaskDependency(dependency model.TaskDependency) error
+	GetTaskDependencies(userID int) ([]model.TaskDependency, error)
+	SearchTasks(userID int, query string, limit int) ([]model.TaskSearchResult, error)
+	CreateTag(tag *model.Tag) (int, error)
+	GetTags(userID int) ([]model.Tag, error)
+	GetTagByID(userID int, id int) (model.Tag, error)
+	UpdateTag(tag *model.Tag) error
+	DeleteTag(userID int, id int) error
+	// LockUserTasks holds back other transactions changing the user's task
+	// hierarchy or dependencies until the current one ends, so that cycle
+	// checks stay true until the change is written. It has no effect
+	// outside InTransaction.
+	LockUserTasks(userID int) error
+	InTransaction(fn func(txStore TaskStore) error) error
+}
+
 type TaskRepository struct {
-	db *sql.DB
+	db      *sql.DB
+	dialect dialect.Dialect
+	tx      *sql.Tx
+	// depth counts the savepoints nested inside tx.
+	depth int
 }
 
 func NewTaskRepository(db *sql.DB) *TaskRepository {
-	return &TaskRepository{db: db}
+	return &TaskRepository{db: db, dialect: dialect.Of(db)}
 }
 
-func (r *TaskRepository) CreateTask(taskName string) (int, error) {
+// InTransaction runs fn with a store whose queries share one
+// transaction, committed when fn returns nil and rolled back otherwise.
+// Calls on a store that is already inside a transaction nest through
+// savepoints, so an inner failure only undoes the inner work.
+func (r *TaskRepository) InTransaction(fn func(txStore TaskStore) error) error {
+	if r.tx != nil {
+		return r.inSavepoint(fn)
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	err = fn(&TaskRepository{db: r.db, dialect: r.dialect, tx: tx})
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *TaskRepository) inSavepoint(fn func(txStore TaskStore) error) error {
+	savepoint := fmt.Sprintf("task_savepoint_%d", r.depth+1)
+	_, err := r.tx.Exec(`SAVEPOINT ` + savepoint)
+	if err != nil {
+		return err
+	}
+
+	err = fn(&TaskRepository{db: r.db, dialect: r.dialect, tx: r.tx, depth: r.depth + 1})
+	if err != nil {
+		_, rollbackErr := r.tx.Exec(`ROLLBACK TO ` + savepoint)
+		if rollbackErr != nil {
+			return rollbackErr
+		}
+	}
+
+	_, releaseErr := r.tx.Exec(`RELEASE ` + savepoint)
+	if releaseErr != nil {
+		return releaseErr
+	}
+	return err
+}
+
+// transaction runs fn inside InTransaction for the repository methods that
+// take more than one statement.
+func (r *TaskRepository) transaction(fn func(tx *TaskRepository) error) error {
+	return r.InTransaction(func(txStore TaskStore) error {
+		return fn(txStore.(*TaskRepository))
+	})
+}
+
+func (r *TaskRepository) conn() dialectConn {
+	if r.tx != nil {
+		return dialectConn{querier: r.tx, dialect: r.dialect}
+	}
+	return dialectConn{querier: r.db, dialect: r.dialect}
+}
+
+const taskColumns = `id, user_id, name, description, status, priority, due_at, due_at_offset, created_at, updated_at, completed_at, version, parent_id, project_id, recurrence`
+
+func (r *TaskRepository) CreateTask(task *model.Task) (int, error) {
 	createTaskSQL := `
-	INSERT INTO tasks (name) VALUES (?)
+	INSERT INTO tasks (user_id, name, description, status, priority, due_at, due_at_offset, created_at, updated_at,
+		completed_at, version, parent_id, project_id, recurrence)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
-	result, err := r.db.Exec(createTaskSQL, taskName)
+	var id int
+	err := r.transaction(func(tx *TaskRepository) error {
+		var err error
+		id, err = tx.conn().insert(
+			createTaskSQL,
+			task.UserID, task.Name, task.Description, task.Status, task.Priority, nullTime(task.DueAt), dueAtOffset(task.DueAt),
+			task.CreatedAt.UTC(), task.UpdatedAt.UTC(), nullTime(task.CompletedAt), task.Version,
+			nullInt(task.ParentID), nullInt(task.ProjectID), task.Recurrence,
+		)
+		if err != nil {
+			return err
+		}
+
+		err = tx.setTaskTags(task.UserID, id, task.Tags, task.UpdatedAt)
+		if err != nil {
+			return err
+		}
+
+		return tx.bumpAncestors(task.UserID, nullInt(task.ParentID))
+	})
 	if err != nil {
 		return 0, err
 	}
+	return id, nil
+}
 
-	lastInsertID, err := result.LastInsertId()
+// GetTasks returns up to query.Limit tasks of the user that match the
+// filters of query, in query.Sort order and following the cursor task
+// identified by query.AfterID.
+func (r *TaskRepository) GetTasks(userID int, query model.TaskQuery) ([]model.Task, error) {
+	conditions, args := taskFilters(query)
+	conditions = append([]string{"user_id = ?"}, conditions...)
+	args = append([]interface{}{userID}, args...)
+
+	var values []interface{}
+	if query.AfterID > 0 {
+		values = query.AfterValues
+		if values == nil {
+			values = []interface{}{}
+		}
+	}
+
+	keys, err := taskSortKeys(query.Sort, query.AfterID, values)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	return int(lastInsertID), nil
-}
+	if query.AfterID > 0 {
+		condition, keysetArgs := keysetCondition(keys)
+		conditions = append(conditions, condition)
+		args = append(args, keysetArgs...)
+	}
+
+	orderBy := make([]string, len(keys))
+	for i, key := range keys {
+		orderBy[i] = key.order()
+	}
 
-func (r *TaskRepository) GetTasks() ([]model.Task, error) {
 	getTasksSQL := `
-	SELECT id, name, status FROM tasks
+	SELECT ` + taskColumns + ` FROM tasks
+	WHERE ` + strings.Join(conditions, " AND ") + `
+	ORDER BY ` + strings.Join(orderBy, ", ") + `
+	LIMIT ?
 	`
-	rows, err := r.db.Query(getTasksSQL)
+	rows, err := r.conn().Query(getTasksSQL, append(args, query.Limit)...)
 	if err != nil {
 		return nil, err
 	}
@@ -42,52 +194,247 @@ func (r *TaskRepository) GetTasks() ([]model.Task, error) {
 
 	tasks := []model.Task{}
 	for rows.Next() {
-		var task model.Task
-		err := rows.Scan(&task.ID, &task.Name, &task.Status)
+		task, err := scanTask(rows)
 		if err != nil {
 			return nil, err
 		}
 		tasks = append(tasks, task)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
-	return tasks, nil
+	return tasks, r.loadTaskDetails(tasks)
 }
 
+// UpdateTask writes the task only while the stored version still equals
+// task.Version and increments the version on success. A stale version
+// affects no rows, just like a missing task. Moving the task or changing
+// its status also increments the version of the tasks above it, and a new
+// status that of the tasks depending on it.
 func (r *TaskRepository) UpdateTask(task *model.Task) error {
 	updateTaskSQL := `
-	UPDATE tasks SET name = ?, status = ? WHERE id = ?
+	UPDATE tasks
+	SET name = ?, description = ?, status = ?, priority = ?, due_at = ?, due_at_offset = ?, updated_at = ?, completed_at = ?,
+		parent_id = ?, project_id = ?, recurrence = ?, version = version + 1
+	WHERE id = ? AND user_id = ? AND version = ?
 	`
-	_, err := r.db.Exec(updateTaskSQL, task.Name, task.Status, task.ID)
+	err := r.transaction(func(tx *TaskRepository) error {
+		var status model.TaskStatus
+		var parentID sql.NullInt64
+		getStatusSQL := `
+		SELECT status, parent_id FROM tasks WHERE id = ? AND user_id = ?
+		`
+		err := tx.conn().QueryRow(getStatusSQL, task.ID, task.UserID).Scan(&status, &parentID)
+		if err != nil {
+			return err
+		}
+
+		result, err := tx.conn().Exec(
+			updateTaskSQL,
+			task.Name, task.Description, task.Status, task.Priority, nullTime(task.DueAt), dueAtOffset(task.DueAt),
+			task.UpdatedAt.UTC(), nullTime(task.CompletedAt), nullInt(task.ParentID), nullInt(task.ProjectID),
+			task.Recurrence, task.ID, task.UserID, task.Version,
+		)
+		if err != nil {
+			return err
+		}
+
+		err = expectAffected(result)
+		if err != nil {
+			return err
+		}
+
+		err = tx.setTaskTags(task.UserID, task.ID, task.Tags, task.UpdatedAt)
+		if err != nil {
+			return err
+		}
+
+		if status != task.Status {
+			err = tx.bumpDependents(task.UserID, task.ID)
+			if err != nil {
+				return err
+			}
+		}
+
+		newParentID := nullInt(task.ParentID)
+		if newParentID != parentID {
+			err = tx.bumpAncestors(task.UserID, parentID)
+			if err != nil {
+				return err
+			}
+		} else if status == task.Status {
+			return nil
+		}
+		return tx.bumpAncestors(task.UserID, newParentID)
+	})
 	if err != nil {
 		return err
 	}
 
+	task.Version++
 	return nil
 }
 
-func (r *TaskRepository) DeleteTask(id int) error {
+func (r *TaskRepository) DeleteTask(userID int, id int) error {
 	deleteTaskSQL := `
-	DELETE FROM tasks WHERE id = ?
+	DELETE FROM tasks WHERE id = ? AND user_id = ?
 	`
-	_, err := r.db.Exec(deleteTaskSQL, id)
-	if err != nil {
-		return err
-	}
+	return r.deleteTask(userID, id, deleteTaskSQL, id, userID)
+}
 
-	return nil
+func (r *TaskRepository) DeleteTaskAtVersion(userID int, id int, version int) error {
+	deleteTaskSQL := `
+	DELETE FROM tasks WHERE id = ? AND user_id = ? AND version = ?
+	`
+	return r.deleteTask(userID, id, deleteTaskSQL, id, userID, version)
 }
 
-func (r *TaskRepository) GetTaskByID(id int) (model.Task, error) {
+// deleteTask runs deleteTaskSQL, takes the tags and dependencies off the
+// deleted task and hands its subtasks over to its parent. The version of
+// the tasks that depended on it and of the tasks above it changes.
+func (r *TaskRepository) deleteTask(userID int, id int, deleteTaskSQL string, args ...interface{}) error {
+	return r.transaction(func(tx *TaskRepository) error {
+		var parentID sql.NullInt64
+		getParentIDSQL := `
+		SELECT parent_id FROM tasks WHERE id = ? AND user_id = ?
+		`
+		err := tx.conn().QueryRow(getParentIDSQL, id, userID).Scan(&parentID)
+		if err != nil {
+			return err
+		}
+
+		result, err := tx.conn().Exec(deleteTaskSQL, args...)
+		if err != nil {
+			return err
+		}
+
+		err = expectAffected(result)
+		if err != nil {
+			return err
+		}
+
+		err = tx.clearTaskTags(id)
+		if err != nil {
+			return err
+		}
+
+		err = tx.bumpDependents(userID, id)
+		if err != nil {
+			return err
+		}
+
+		err = tx.clearTaskDependencies(userID, id)
+		if err != nil {
+			return err
+		}
+
+		err = tx.reparentSubtasks(userID, id, parentID)
+		if err != nil {
+			return err
+		}
+
+		return tx.bumpAncestors(userID, parentID)
+	})
+}
+
+func (r *TaskRepository) GetTaskByID(userID int, id int) (model.Task, error) {
 	getTaskByIDSQL := `
-	SELECT id, name, status FROM tasks WHERE id = ?
+	SELECT ` + taskColumns + ` FROM tasks WHERE id = ? AND user_id = ?
 	`
-	row := r.db.QueryRow(getTaskByIDSQL, id)
+	task, err := scanTask(r.conn().QueryRow(getTaskByIDSQL, id, userID))
+	if err != nil {
+		return model.Task{}, err
+	}
+
+	tasks := []model.Task{task}
+	err = r.loadTaskDetails(tasks)
+	return tasks[0], err
+}
 
+// scanTask scans the taskColumns of row, followed by any extra columns.
+func scanTask(row scanner, extra ...interface{}) (model.Task, error) {
 	var task model.Task
-	err := row.Scan(&task.ID, &task.Name, &task.Status)
+	var dueAt, completedAt sql.NullTime
+	var dueAtOffset int
+	var parentID, projectID sql.NullInt64
+	dest := []interface{}{
+		&task.ID, &task.UserID, &task.Name, &task.Description, &task.Status, &task.Priority,
+		&dueAt, &dueAtOffset, &task.CreatedAt, &task.UpdatedAt, &completedAt, &task.Version, &parentID, &projectID,
+		&task.Recurrence,
+	}
+	err := row.Scan(append(dest, extra...)...)
 	if err != nil {
 		return model.Task{}, err
 	}
 
+	task.DueAt = timePtr(dueAt)
+	if task.DueAt != nil {
+		*task.DueAt = atOffset(*task.DueAt, dueAtOffset)
+	}
+	task.CompletedAt = timePtr(completedAt)
+	task.ParentID = intPtr(parentID)
+	task.ProjectID = intPtr(projectID)
+	task.CreatedAt = task.CreatedAt.UTC()
+	task.UpdatedAt = task.UpdatedAt.UTC()
 	return task, nil
 }
+
+// dueAtOffset returns the UTC offset in seconds that the due date was given
+// at. It is stored next to the due date, which is stored in UTC, as
+// recurrence rules count days at that offset.
+func dueAtOffset(dueAt *time.Time) int {
+	if dueAt == nil {
+		return 0
+	}
+	_, offset := dueAt.Zone()
+	return offset
+}
+
+// atOffset returns t at the UTC offset in seconds, in UTC for none.
+func atOffset(t time.Time, offset int) time.Time {
+	if offset == 0 {
+		return t.UTC()
+	}
+	return t.In(time.FixedZone("", offset))
+}
+
+// loadTaskDetails fills in what tasks carry besides their columns.
+func (r *TaskRepository) loadTaskDetails(tasks []model.Task) error {
+	err := r.loadTaskTags(tasks)
+	if err != nil {
+		return err
+	}
+
+	err = r.loadTaskProgress(tasks)
+	if err != nil {
+		return err
+	}
+	return r.loadTaskBlocked(tasks)
+}
+
+func nullInt(i *int) sql.NullInt64 {
+	if i == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: int64(*i), Valid: true}
+}
+
+func intPtr(i sql.NullInt64) *int {
+	if !i.Valid {
+		return nil
+	}
+	value := int(i.Int64)
+	return &value
+}
+
+func expectAffected(result sql.Result) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -9,17 +9,35 @@ import (
 	"github.com/stretchr/testify/assert"
 	"os"
 	"testing"
+	"time"
 )
 
 var testDB *sql.DB
 var taskRepo *TaskRepository
+var userRepo *UserRepository
+var refreshTokenRepo *RefreshTokenRepository
+var revokedTokenRepo *RevokedTokenRepository
+var apiKeyRepo *APIKeyRepository
+var idempotencyKeyRepo *IdempotencyKeyRepository
+var projectRepo *ProjectRepository
+
+var taskDueAt = time.Now().Add(48 * time.Hour).UTC().Truncate(time.Second)
 
 var taskData = model.Task{
-	ID:     1,
-	Name:   "Eat Dinner",
-	Status: 0,
+	UserID:      1,
+	Name:        "Eat Dinner",
+	Description: "Pasta with tomato sauce",
+	Status:      model.TaskStatusTodo,
+	Priority:    model.TaskPriorityMedium,
+	DueAt:       &taskDueAt,
+	Tags:        []string{"kitchen"},
+	CreatedAt:   time.Now().UTC().Truncate(time.Second),
+	UpdatedAt:   time.Now().UTC().Truncate(time.Second),
+	Version:     1,
 }
 
+const otherUserID = 2
+
 func TestMain(m *testing.M) {
 	t := &testing.T{}
 	setup(t)
@@ -35,12 +53,23 @@ func setup(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	err = PrepareTaskTable(testDB)
+	err = MigrateDB(testDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = EnsureTaskSearchIndex(testDB)
 	if err != nil {
 		t.Fatal(err)
 	}
 
 	taskRepo = NewTaskRepository(testDB)
+	userRepo = NewUserRepository(testDB)
+	refreshTokenRepo = NewRefreshTokenRepository(testDB)
+	revokedTokenRepo = NewRevokedTokenRepository(testDB)
+	apiKeyRepo = NewAPIKeyRepository(testDB)
+	idempotencyKeyRepo = NewIdempotencyKeyRepository(testDB)
+	projectRepo = NewProjectRepository(testDB)
 }
 
 func teardown() {
@@ -53,50 +82,167 @@ func teardown() {
 func TestTaskRepository(t *testing.T) {
 	t.Run("Create", testCreate)
 	t.Run("GetList", testGetList)
+	t.Run("GetListOtherUser", testGetListOtherUser)
+	t.Run("GetByIDOtherUser", testGetByIDOtherUser)
 	t.Run("Update", testUpdate)
+	t.Run("UpdateOtherUser", testUpdateOtherUser)
+	t.Run("UpdateStaleVersion", testUpdateStaleVersion)
+	t.Run("DeleteOtherUser", testDeleteOtherUser)
+	t.Run("DeleteStaleVersion", testDeleteStaleVersion)
 	t.Run("Delete", testDelete)
+	t.Run("TransactionCommit", testTransactionCommit)
+	t.Run("TransactionRollback", testTransactionRollback)
+	t.Run("TransactionSavepoint", testTransactionSavepoint)
 }
 
 func testCreate(t *testing.T) {
-	taskName := taskData.Name
-
-	taskID, err := taskRepo.CreateTask(taskName)
+	taskID, err := taskRepo.CreateTask(&taskData)
 	assert.NoError(t, err)
 	assert.NotZero(t, taskID)
+	taskData.ID = taskID
 }
 
 func testGetList(t *testing.T) {
-	tasks, err := taskRepo.GetTasks()
+	tasks, err := taskRepo.GetTasks(taskData.UserID, model.TaskQuery{Limit: 10})
 	assert.NoError(t, err)
 	assert.NotEmpty(t, tasks)
 	assert.Len(t, tasks, 1)
+	assert.Equal(t, taskData, tasks[0])
 
-	assert.Equal(t, taskData.ID, tasks[0].ID)
-	assert.Equal(t, taskData.Name, tasks[0].Name)
-	assert.Equal(t, taskData.Status, tasks[0].Status)
+	tasks, err = taskRepo.GetTasks(taskData.UserID, model.TaskQuery{AfterID: taskData.ID, Limit: 10})
+	assert.NoError(t, err)
+	assert.Empty(t, tasks, "Tasks before the given id returned")
 }
 
 func testUpdate(t *testing.T) {
+	completedAt := time.Now().UTC().Truncate(time.Second)
 	taskData.Name = "Eat Lunch"
-	taskData.Status = 1
+	taskData.Description = ""
+	taskData.Status = model.TaskStatusDone
+	taskData.Priority = model.TaskPriorityHigh
+	taskData.DueAt = nil
+	taskData.Tags = []string{}
+	taskData.UpdatedAt = completedAt
+	taskData.CompletedAt = &completedAt
 
 	err := taskRepo.UpdateTask(&taskData)
 	assert.NoError(t, err)
+	assert.Equal(t, 2, taskData.Version, "Version not incremented")
 
-	updatedTask, err := taskRepo.GetTaskByID(taskData.ID)
+	updatedTask, err := taskRepo.GetTaskByID(taskData.UserID, taskData.ID)
 	assert.NoError(t, err)
-	assert.NotEmpty(t, updatedTask)
-
-	assert.Equal(t, taskData.ID, updatedTask.ID)
-	assert.Equal(t, taskData.Name, updatedTask.Name)
-	assert.Equal(t, taskData.Status, updatedTask.Status)
+	assert.Equal(t, taskData, updatedTask)
 }
 
 func testDelete(t *testing.T) {
-	err := taskRepo.DeleteTask(taskData.ID)
+	err := taskRepo.DeleteTask(taskData.UserID, taskData.ID)
 	assert.NoError(t, err)
 
-	_, err = taskRepo.GetTaskByID(taskData.ID)
+	_, err = taskRepo.GetTaskByID(taskData.UserID, taskData.ID)
 	assert.Error(t, err)
 	assert.Equal(t, sql.ErrNoRows, err)
 }
+
+func testGetListOtherUser(t *testing.T) {
+	tasks, err := taskRepo.GetTasks(otherUserID, model.TaskQuery{Limit: 10})
+	assert.NoError(t, err)
+	assert.Empty(t, tasks)
+}
+
+func testGetByIDOtherUser(t *testing.T) {
+	_, err := taskRepo.GetTaskByID(otherUserID, taskData.ID)
+	assert.Equal(t, sql.ErrNoRows, err)
+}
+
+func testUpdateOtherUser(t *testing.T) {
+	task := taskData
+	task.UserID = otherUserID
+	task.Name = "Steal Lunch"
+
+	err := taskRepo.UpdateTask(&task)
+	assert.Equal(t, sql.ErrNoRows, err)
+
+	unchangedTask, err := taskRepo.GetTaskByID(taskData.UserID, taskData.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, taskData.Name, unchangedTask.Name)
+}
+
+func testDeleteOtherUser(t *testing.T) {
+	err := taskRepo.DeleteTask(otherUserID, taskData.ID)
+	assert.Equal(t, sql.ErrNoRows, err)
+
+	_, err = taskRepo.GetTaskByID(taskData.UserID, taskData.ID)
+	assert.NoError(t, err)
+}
+
+func testUpdateStaleVersion(t *testing.T) {
+	task := taskData
+	task.Version--
+	task.Name = "Eat Brunch"
+
+	err := taskRepo.UpdateTask(&task)
+	assert.Equal(t, sql.ErrNoRows, err)
+
+	unchangedTask, err := taskRepo.GetTaskByID(taskData.UserID, taskData.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, taskData, unchangedTask)
+}
+
+func testDeleteStaleVersion(t *testing.T) {
+	err := taskRepo.DeleteTaskAtVersion(taskData.UserID, taskData.ID, taskData.Version-1)
+	assert.Equal(t, sql.ErrNoRows, err)
+
+	_, err = taskRepo.GetTaskByID(taskData.UserID, taskData.ID)
+	assert.NoError(t, err)
+}
+
+const transactionUserID = 20
+
+func testTransactionCommit(t *testing.T) {
+	err := taskRepo.InTransaction(func(txRepo TaskStore) error {
+		_, err := txRepo.CreateTask(&model.Task{UserID: transactionUserID, Name: "Committed", Status: model.TaskStatusTodo})
+		return err
+	})
+	assert.NoError(t, err)
+
+	tasks, err := taskRepo.GetTasks(transactionUserID, model.TaskQuery{Limit: 10})
+	assert.NoError(t, err)
+	assert.Len(t, tasks, 1)
+}
+
+func testTransactionRollback(t *testing.T) {
+	err := taskRepo.InTransaction(func(txRepo TaskStore) error {
+		taskID, err := txRepo.CreateTask(&model.Task{UserID: transactionUserID, Name: "Rolled Back", Status: model.TaskStatusTodo})
+		assert.NoError(t, err)
+
+		_, err = txRepo.GetTaskByID(transactionUserID, taskID)
+		assert.NoError(t, err, "Task not visible inside its transaction")
+
+		return txRepo.DeleteTask(transactionUserID, 999)
+	})
+	assert.Equal(t, sql.ErrNoRows, err)
+
+	tasks, err := taskRepo.GetTasks(transactionUserID, model.TaskQuery{Limit: 10})
+	assert.NoError(t, err)
+	assert.Len(t, tasks, 1, "Rolled back task persisted")
+}
+
+func testTransactionSavepoint(t *testing.T) {
+	err := taskRepo.InTransaction(func(txRepo TaskStore) error {
+		err := txRepo.InTransaction(func(innerRepo TaskStore) error {
+			_, err := innerRepo.CreateTask(&model.Task{UserID: transactionUserID, Name: "Inner", Status: model.TaskStatusTodo})
+			assert.NoError(t, err)
+			return sql.ErrNoRows
+		})
+		assert.Equal(t, sql.ErrNoRows, err)
+
+		_, err = txRepo.CreateTask(&model.Task{UserID: transactionUserID, Name: "Outer", Status: model.TaskStatusTodo})
+		return err
+	})
+	assert.NoError(t, err)
+
+	tasks, err := taskRepo.GetTasks(transactionUserID, model.TaskQuery{Limit: 10})
+	assert.NoError(t, err)
+	assert.Len(t, tasks, 2)
+	assert.Equal(t, "Outer", tasks[1].Name)
+}
@@ -0,0 +1,411 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/absoluteyl/tasks-go/internal/dialect"
+	"github.com/absoluteyl/tasks-go/internal/model"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var ErrInvalidSearchQuery = errors.New("search query has no words")
+
+const (
+	searchMarkStart = "<mark>"
+	searchMarkEnd   = "</mark>"
+	searchEllipsis  = "…"
+	// searchNameWeight makes a match in the name count as much as this many
+	// matches in the description.
+	searchNameWeight = 10
+	snippetTokens    = 12
+)
+
+// EnsureTaskSearchIndex sets up the FTS5 index behind SearchTasks. FTS5 is
+// only compiled into SQLite with the sqlite_fts5 build tag, so the index is
+// kept out of the migrations and rebuilt from the tasks table on every
+// start. Builds without FTS5 drop the triggers an earlier build left behind,
+// as they would make every task write fail.
+func EnsureTaskSearchIndex(db *sql.DB) error {
+	if dialect.Of(db) != dialect.SQLite {
+		return nil
+	}
+
+	if !FTSEnabled {
+		_, err := db.Exec(`
+		DROP TRIGGER IF EXISTS tasks_fts_insert;
+		DROP TRIGGER IF EXISTS tasks_fts_delete;
+		DROP TRIGGER IF EXISTS tasks_fts_update;
+		`)
+		return err
+	}
+
+	_, err := db.Exec(`
+	CREATE VIRTUAL TABLE IF NOT EXISTS tasks_fts USING fts5(name, description, content='tasks', content_rowid='id');
+	CREATE TRIGGER IF NOT EXISTS tasks_fts_insert AFTER INSERT ON tasks BEGIN
+		INSERT INTO tasks_fts (rowid, name, description) VALUES (new.id, new.name, new.description);
+	END;
+	CREATE TRIGGER IF NOT EXISTS tasks_fts_delete AFTER DELETE ON tasks BEGIN
+		INSERT INTO tasks_fts (tasks_fts, rowid, name, description) VALUES ('delete', old.id, old.name, old.description);
+	END;
+	CREATE TRIGGER IF NOT EXISTS tasks_fts_update AFTER UPDATE OF name, description ON tasks BEGIN
+		INSERT INTO tasks_fts (tasks_fts, rowid, name, description) VALUES ('delete', old.id, old.name, old.description);
+		INSERT INTO tasks_fts (rowid, name, description) VALUES (new.id, new.name, new.description);
+	END;
+	INSERT INTO tasks_fts (tasks_fts) VALUES ('rebuild');
+	`)
+	return err
+}
+
+// SearchTasks returns up to limit tasks of the user matching query, most
+// relevant first. See parseSearchQuery for the query syntax. Without FTS5
+// the tasks are matched in Go instead, with the same syntax and ordering.
+func (r *TaskRepository) SearchTasks(userID int, query string, limit int) ([]model.TaskSearchResult, error) {
+	terms, err := parseSearchQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.dialect == dialect.SQLite && FTSEnabled {
+		return r.searchIndex(userID, terms, limit)
+	}
+	return r.searchTable(userID, terms, limit)
+}
+
+func (r *TaskRepository) searchIndex(userID int, terms []searchTerm, limit int) ([]model.TaskSearchResult, error) {
+	searchTasksSQL := `
+	SELECT t.` + strings.ReplaceAll(taskColumns, ", ", ", t.") + `,
+		highlight(tasks_fts, 0, '` + searchMarkStart + `', '` + searchMarkEnd + `'),
+		snippet(tasks_fts, 1, '` + searchMarkStart + `', '` + searchMarkEnd + `', '` + searchEllipsis + `', ` + strconv.Itoa(snippetTokens) + `),
+		-bm25(tasks_fts, ` + strconv.Itoa(searchNameWeight) + `, 1)
+	FROM tasks_fts JOIN tasks t ON t.id = tasks_fts.rowid
+	WHERE tasks_fts MATCH ? AND t.user_id = ?
+	ORDER BY bm25(tasks_fts, ` + strconv.Itoa(searchNameWeight) + `, 1), t.id
+	LIMIT ?
+	`
+	rows, err := r.conn().Query(searchTasksSQL, matchExpression(terms), userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []model.TaskSearchResult{}
+	for rows.Next() {
+		var result model.TaskSearchResult
+		result.Task, err = scanTask(rows, &result.Highlight, &result.Snippet, &result.Score)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	tasks := make([]model.Task, len(results))
+	for i := range results {
+		tasks[i] = results[i].Task
+	}
+	err = r.loadTaskDetails(tasks)
+	for i := range results {
+		results[i].Task = tasks[i]
+	}
+	return results, err
+}
+
+// searchTable narrows the tasks down with LIKE and leaves the matching and
+// ranking to matchTask. Words with non-ASCII letters are left to matchTask
+// alone, as LOWER and LIKE fold only ASCII letters in SQLite.
+func (r *TaskRepository) searchTable(userID int, terms []searchTerm, limit int) ([]model.TaskSearchResult, error) {
+	conditions := []string{"user_id = ?"}
+	args := []interface{}{userID}
+	for _, term := range terms {
+		for _, word := range term.words {
+			if !isASCII(word) {
+				continue
+			}
+			conditions = append(conditions, `(LOWER(name) LIKE ? ESCAPE '\' OR LOWER(description) LIKE ? ESCAPE '\')`)
+			pattern := "%" + escapeLike(word) + "%"
+			args = append(args, pattern, pattern)
+		}
+	}
+
+	searchTasksSQL := `
+	SELECT ` + taskColumns + ` FROM tasks WHERE ` + strings.Join(conditions, " AND ") + `
+	`
+	rows, err := r.conn().Query(searchTasksSQL, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tasks []model.Task
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	err = r.loadTaskDetails(tasks)
+	if err != nil {
+		return nil, err
+	}
+	return matchTasks(tasks, terms, limit), nil
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// searchTerm is a word or a quoted phrase of a search query, split into
+// words. With prefix set, the last word also matches longer words.
+type searchTerm struct {
+	words  []string
+	prefix bool
+}
+
+// parseSearchQuery reads words and "quoted phrases", all of which have to
+// match. A trailing * turns a word or phrase into a prefix, as in rep* or
+// "quarterly rep"*. Punctuation separates words, like in the FTS5 index.
+func parseSearchQuery(query string) ([]searchTerm, error) {
+	var terms []searchTerm
+	rest := strings.TrimSpace(query)
+	for rest != "" {
+		var text string
+		prefix := false
+		if rest[0] == '"' {
+			end := strings.IndexByte(rest[1:], '"')
+			if end < 0 {
+				text, rest = rest[1:], ""
+			} else {
+				text, rest = rest[1:end+1], rest[end+2:]
+			}
+			if strings.HasPrefix(rest, "*") {
+				prefix = true
+				rest = rest[1:]
+			}
+		} else {
+			end := strings.IndexFunc(rest, unicode.IsSpace)
+			if end < 0 {
+				end = len(rest)
+			}
+			text, rest = rest[:end], rest[end:]
+			prefix = strings.HasSuffix(text, "*")
+		}
+
+		var words []string
+		for _, token := range tokenize(text) {
+			words = append(words, token.word)
+		}
+		if len(words) > 0 {
+			terms = append(terms, searchTerm{words: words, prefix: prefix})
+		}
+
+		rest = strings.TrimLeftFunc(rest, unicode.IsSpace)
+	}
+
+	if len(terms) == 0 {
+		return nil, ErrInvalidSearchQuery
+	}
+	return terms, nil
+}
+
+// matchExpression writes terms as an FTS5 query. Words only consist of
+// letters and digits, so quoting them is enough to avoid FTS5 syntax.
+func matchExpression(terms []searchTerm) string {
+	phrases := make([]string, len(terms))
+	for i, term := range terms {
+		phrases[i] = `"` + strings.Join(term.words, " ") + `"`
+		if term.prefix {
+			phrases[i] += "*"
+		}
+	}
+	return strings.Join(phrases, " ")
+}
+
+type searchToken struct {
+	word  string
+	start int
+	end   int
+}
+
+// tokenize splits text into lower case runs of letters and digits, keeping
+// their byte offsets for highlighting.
+func tokenize(text string) []searchToken {
+	var tokens []searchToken
+	start := -1
+	for i, r := range text {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			tokens = append(tokens, searchToken{word: strings.ToLower(text[start:i]), start: start, end: i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, searchToken{word: strings.ToLower(text[start:]), start: start, end: len(text)})
+	}
+	return tokens
+}
+
+// matchTasks ranks tasks like the FTS5 index does: name matches weigh more
+// than description matches and ties are broken by id.
+func matchTasks(tasks []model.Task, terms []searchTerm, limit int) []model.TaskSearchResult {
+	results := []model.TaskSearchResult{}
+	for _, task := range tasks {
+		if result, ok := matchTask(task, terms); ok {
+			results = append(results, result)
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Task.ID < results[j].Task.ID
+	})
+
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+func matchTask(task model.Task, terms []searchTerm) (model.TaskSearchResult, bool) {
+	nameTokens := tokenize(task.Name)
+	descriptionTokens := tokenize(task.Description)
+
+	var nameSpans, descriptionSpans []searchSpan
+	score := 0.0
+	for _, term := range terms {
+		inName := term.spans(nameTokens)
+		inDescription := term.spans(descriptionTokens)
+		if len(inName)+len(inDescription) == 0 {
+			return model.TaskSearchResult{}, false
+		}
+
+		nameSpans = append(nameSpans, inName...)
+		descriptionSpans = append(descriptionSpans, inDescription...)
+		score += float64(searchNameWeight*len(inName) + len(inDescription))
+	}
+
+	return model.TaskSearchResult{
+		Task:      task,
+		Score:     score,
+		Highlight: highlight(task.Name, nameSpans, 0, len(task.Name)),
+		Snippet:   snippet(task.Description, descriptionTokens, descriptionSpans),
+	}, true
+}
+
+// searchSpan is the byte range of a match within a text.
+type searchSpan struct {
+	start int
+	end   int
+}
+
+func (t searchTerm) spans(tokens []searchToken) []searchSpan {
+	var spans []searchSpan
+	for i := 0; i+len(t.words) <= len(tokens); i++ {
+		if t.matchesAt(tokens[i:]) {
+			spans = append(spans, searchSpan{start: tokens[i].start, end: tokens[i+len(t.words)-1].end})
+		}
+	}
+	return spans
+}
+
+func (t searchTerm) matchesAt(tokens []searchToken) bool {
+	for i, word := range t.words {
+		last := i == len(t.words)-1
+		if tokens[i].word != word && !(last && t.prefix && strings.HasPrefix(tokens[i].word, word)) {
+			return false
+		}
+	}
+	return true
+}
+
+// highlight marks the spans within text[from:to].
+func highlight(text string, spans []searchSpan, from int, to int) string {
+	sort.Slice(spans, func(i, j int) bool {
+		return spans[i].start < spans[j].start
+	})
+
+	var b strings.Builder
+	position := from
+	for _, span := range spans {
+		if span.start < position || span.end > to {
+			continue
+		}
+		b.WriteString(text[position:span.start])
+		b.WriteString(searchMarkStart + text[span.start:span.end] + searchMarkEnd)
+		position = span.end
+	}
+	b.WriteString(text[position:to])
+	return b.String()
+}
+
+// snippet returns up to snippetTokens words of text around its first match,
+// from the start of the text when the match fits in there, as FTS5 does.
+func snippet(text string, tokens []searchToken, spans []searchSpan) string {
+	if len(tokens) == 0 {
+		return ""
+	}
+
+	first := 0
+	if len(spans) > 0 {
+		sort.Slice(spans, func(i, j int) bool {
+			return spans[i].start < spans[j].start
+		})
+		// matchEnd is the index of the last word of the first match.
+		matchEnd := 0
+		for matchEnd < len(tokens)-1 && tokens[matchEnd].end < spans[0].end {
+			matchEnd++
+		}
+		if matchEnd >= snippetTokens {
+			first = matchEnd - snippetTokens/2
+		}
+	}
+	if first > len(tokens)-snippetTokens {
+		first = len(tokens) - snippetTokens
+	}
+	if first < 0 {
+		first = 0
+	}
+	last := first + snippetTokens
+	if last > len(tokens) {
+		last = len(tokens)
+	}
+
+	from, to := tokens[first].start, tokens[last-1].end
+	if first == 0 {
+		from = 0
+	}
+	if last == len(tokens) {
+		to = len(text)
+	}
+
+	result := highlight(text, spans, from, to)
+	if first > 0 {
+		result = searchEllipsis + result
+	}
+	if last < len(tokens) {
+		result += searchEllipsis
+	}
+	return result
+}
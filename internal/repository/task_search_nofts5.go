@@ -0,0 +1,6 @@
+//go:build !sqlite_fts5
+
+package repository
+
+// FTSEnabled tells whether SQLite was built with FTS5.
+const FTSEnabled = false
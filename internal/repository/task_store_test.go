@@ -0,0 +1,420 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/absoluteyl/tasks-go/internal/model"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// TestTaskStores runs the same checks against every TaskStore, so that the
+// backends stay interchangeable.
+func TestTaskStores(t *testing.T) {
+	t.Run("SQLite", func(t *testing.T) {
+		testTaskStore(t, taskRepo, projectRepo, 30)
+		testSearchNonASCII(t, taskRepo, 32)
+	})
+	t.Run("Memory", func(t *testing.T) {
+		taskStore := NewMemoryTaskStore()
+		testTaskStore(t, taskStore, NewMemoryProjectStore(taskStore), 30)
+		testSearchNonASCII(t, NewMemoryTaskStore(), 32)
+	})
+}
+
+func testTaskStore(t *testing.T, store TaskStore, projectStore ProjectStore, userID int) {
+	now := time.Now().UTC().Truncate(time.Second)
+	later := now.Add(24 * time.Hour)
+	newTask := func(name string, status model.TaskStatus, priority int, dueAt *time.Time) model.Task {
+		return model.Task{
+			UserID: userID, Name: name, Status: status, Priority: priority, DueAt: dueAt,
+			Tags: []string{}, CreatedAt: now, UpdatedAt: now, Version: 1,
+		}
+	}
+
+	tasks := []model.Task{
+		newTask("Write report", model.TaskStatusTodo, model.TaskPriorityHigh, nil),
+		newTask("Review report", model.TaskStatusInProgress, model.TaskPriorityHigh, &later),
+		newTask("Book flights", model.TaskStatusTodo, model.TaskPriorityLow, &now),
+		newTask("Water plants", model.TaskStatusDone, model.TaskPriorityNone, nil),
+	}
+	tasks[3].Description = "Check the sprinkler and report any leaks to the landlord before the long weekend trip"
+	tasks[0].Tags = []string{"backend", "urgent"}
+	tasks[1].Tags = []string{"backend"}
+	tasks[1].Recurrence = "FREQ=WEEKLY;BYDAY=MO"
+	tasks[2].Tags = []string{"travel"}
+	projectID, err := projectStore.CreateProject(&model.Project{UserID: userID, Name: "Travel", CreatedAt: now, UpdatedAt: now})
+	assert.NoError(t, err)
+	tasks[2].ProjectID = &projectID
+	for i := range tasks {
+		taskID, err := store.CreateTask(&tasks[i])
+		assert.NoError(t, err)
+		tasks[i].ID = taskID
+	}
+
+	task, err := store.GetTaskByID(userID, tasks[1].ID)
+	assert.NoError(t, err)
+	assert.Equal(t, tasks[1], task)
+
+	_, err = store.GetTaskByID(userID+1, tasks[1].ID)
+	assert.Equal(t, sql.ErrNoRows, err)
+
+	projectTasks, err := store.GetTasks(userID, model.TaskQuery{ProjectID: &projectID, Limit: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{tasks[2].ID}, taskIDs(projectTasks))
+	assert.Equal(t, &projectID, projectTasks[0].ProjectID)
+
+	results, err := store.SearchTasks(userID, "report", 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{tasks[0].ID, tasks[1].ID, tasks[3].ID}, searchResultIDs(results))
+	assert.Equal(t, "Write <mark>report</mark>", results[0].Highlight)
+	assert.Equal(t, "Water plants", results[2].Highlight)
+	assert.Contains(t, results[2].Snippet, "<mark>report</mark>")
+	assert.True(t, results[1].Score > results[2].Score)
+
+	results, err = store.SearchTasks(userID, "rev*", 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{tasks[1].ID}, searchResultIDs(results))
+	assert.Equal(t, "<mark>Review</mark> report", results[0].Highlight)
+
+	results, err = store.SearchTasks(userID, `"Book flights"`, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{tasks[2].ID}, searchResultIDs(results))
+
+	results, err = store.SearchTasks(userID, `"flights book"`, 10)
+	assert.NoError(t, err)
+	assert.Empty(t, results)
+
+	results, err = store.SearchTasks(userID, "report", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{tasks[0].ID}, searchResultIDs(results))
+
+	results, err = store.SearchTasks(userID+1, "report", 10)
+	assert.NoError(t, err)
+	assert.Empty(t, results)
+
+	_, err = store.SearchTasks(userID, `"*"`, 10)
+	assert.Equal(t, ErrInvalidSearchQuery, err)
+
+	sort := []model.TaskSort{{Field: "priority", Desc: true}, {Field: "due_at"}}
+	page, err := store.GetTasks(userID, model.TaskQuery{Sort: sort, Limit: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, []model.Task{tasks[1], tasks[0]}, page)
+
+	page, err = store.GetTasks(userID, model.TaskQuery{
+		Sort: sort, AfterID: page[1].ID, AfterValues: TaskSortValues(page[1], sort), Limit: 2,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []model.Task{tasks[2], tasks[3]}, page)
+
+	page, err = store.GetTasks(userID, model.TaskQuery{
+		Statuses: []model.TaskStatus{model.TaskStatusTodo, model.TaskStatusInProgress}, Name: "REPORT", Limit: 10,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []model.Task{tasks[0], tasks[1]}, page)
+
+	page, err = store.GetTasks(userID, model.TaskQuery{DueBefore: &later, Limit: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, []model.Task{tasks[2]}, page)
+
+	_, err = store.GetTasks(userID, model.TaskQuery{Sort: sort, AfterID: tasks[0].ID, AfterValues: []interface{}{"high"}, Limit: 10})
+	assert.Equal(t, ErrInvalidSortValues, err)
+
+	stale := tasks[0]
+	stale.Version = 0
+	err = store.UpdateTask(&stale)
+	assert.Equal(t, sql.ErrNoRows, err)
+
+	tasks[0].Name = "Write final report"
+	err = store.UpdateTask(&tasks[0])
+	assert.NoError(t, err)
+	assert.Equal(t, 2, tasks[0].Version)
+
+	task, err = store.GetTaskByID(userID, tasks[0].ID)
+	assert.NoError(t, err)
+	assert.Equal(t, tasks[0], task)
+
+	page, err = store.GetTasks(userID, model.TaskQuery{Tags: [][]string{{"backend"}, {"urgent"}}, Limit: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, []model.Task{tasks[0]}, page)
+
+	page, err = store.GetTasks(userID, model.TaskQuery{Tags: [][]string{{"urgent", "travel"}}, Limit: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, []model.Task{tasks[0], tasks[2]}, page)
+
+	testTags(t, store, userID, now)
+
+	// Renaming backend and deleting urgent changed the tasks carrying them.
+	tasks[0].Tags = []string{"api"}
+	tasks[0].Version = 4
+	tasks[1].Tags = []string{"api"}
+	tasks[1].Version = 2
+	task, err = store.GetTaskByID(userID, tasks[0].ID)
+	assert.NoError(t, err)
+	assert.Equal(t, tasks[0], task)
+
+	failure := errors.New("failure")
+	err = store.InTransaction(func(txStore TaskStore) error {
+		inner := newTask("Inner", model.TaskStatusTodo, model.TaskPriorityNone, nil)
+		err := txStore.InTransaction(func(innerStore TaskStore) error {
+			_, err := innerStore.CreateTask(&inner)
+			assert.NoError(t, err)
+			return failure
+		})
+		assert.Equal(t, failure, err)
+
+		return txStore.DeleteTask(userID, tasks[3].ID)
+	})
+	assert.NoError(t, err)
+
+	err = store.InTransaction(func(txStore TaskStore) error {
+		err := txStore.DeleteTask(userID, tasks[2].ID)
+		assert.NoError(t, err)
+		return failure
+	})
+	assert.Equal(t, failure, err)
+
+	page, err = store.GetTasks(userID, model.TaskQuery{Limit: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, []model.Task{tasks[0], tasks[1], tasks[2]}, page)
+
+	results, err = store.SearchTasks(userID, "final report", 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{tasks[0].ID}, searchResultIDs(results))
+
+	results, err = store.SearchTasks(userID, "sprinkler", 10)
+	assert.NoError(t, err)
+	assert.Empty(t, results)
+
+	err = store.DeleteTaskAtVersion(userID, tasks[0].ID, 1)
+	assert.Equal(t, sql.ErrNoRows, err)
+
+	err = store.DeleteTaskAtVersion(userID, tasks[0].ID, 4)
+	assert.NoError(t, err)
+
+	err = store.DeleteTask(userID+1, tasks[1].ID)
+	assert.Equal(t, sql.ErrNoRows, err)
+
+	_, err = store.GetTaskByID(userID, tasks[0].ID)
+	assert.Equal(t, sql.ErrNoRows, err)
+
+	testSubtasks(t, store, userID, now)
+	testDependencies(t, store, userID, now)
+}
+
+// testSearchNonASCII looks for words whose case differs outside ASCII.
+func testSearchNonASCII(t *testing.T, store TaskStore, userID int) {
+	now := time.Now().UTC().Truncate(time.Second)
+	task := model.Task{
+		UserID: userID, Name: "Über die Brücke", Description: "ÉCOLE", Status: model.TaskStatusTodo,
+		Tags: []string{}, CreatedAt: now, UpdatedAt: now, Version: 1,
+	}
+	taskID, err := store.CreateTask(&task)
+	assert.NoError(t, err)
+
+	for _, query := range []string{"über", "BRÜCKE", "école", "brü*"} {
+		results, err := store.SearchTasks(userID, query, 10)
+		assert.NoError(t, err)
+		assert.Equal(t, []int{taskID}, searchResultIDs(results), "Query %q", query)
+	}
+
+	results, err := store.SearchTasks(userID, "über brot", 10)
+	assert.NoError(t, err)
+	assert.Empty(t, results)
+}
+
+// testSubtasks builds a small hierarchy and deletes the task in its middle,
+// whose subtask moves up to the root.
+func testSubtasks(t *testing.T, store TaskStore, userID int, now time.Time) {
+	createTask := func(name string, status model.TaskStatus, parentID *int) model.Task {
+		task := model.Task{
+			UserID: userID, Name: name, Status: status, ParentID: parentID,
+			Tags: []string{}, CreatedAt: now, UpdatedAt: now, Version: 1,
+		}
+		var err error
+		task.ID, err = store.CreateTask(&task)
+		assert.NoError(t, err)
+		return task
+	}
+
+	root := createTask("Plan trip", model.TaskStatusTodo, nil)
+	hotel := createTask("Book hotel", model.TaskStatusInProgress, &root.ID)
+	passport := createTask("Renew passport", model.TaskStatusDone, &root.ID)
+	car := createTask("Rent car", model.TaskStatusCancelled, &root.ID)
+	prices := createTask("Compare prices", model.TaskStatusDone, &hotel.ID)
+
+	task, err := store.GetTaskByID(userID, root.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 50, *task.Progress)
+	assert.Equal(t, 5, task.Version)
+
+	// Only a new status changes the progress, and so the version, of the
+	// tasks above.
+	car.Name = "Rent a car"
+	err = store.UpdateTask(&car)
+	assert.NoError(t, err)
+
+	car.Status = model.TaskStatusDone
+	err = store.UpdateTask(&car)
+	assert.NoError(t, err)
+
+	task, err = store.GetTaskByID(userID, root.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 66, *task.Progress)
+	assert.Equal(t, 6, task.Version)
+
+	children, err := store.GetTasks(userID, model.TaskQuery{ParentID: &root.ID, Limit: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{hotel.ID, passport.ID, car.ID}, taskIDs(children))
+	assert.Equal(t, 100, *children[0].Progress)
+	assert.Nil(t, children[1].Progress)
+	assert.Equal(t, &root.ID, children[1].ParentID)
+
+	subtree, err := store.GetTaskSubtree(userID, root.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{root.ID, hotel.ID, passport.ID, car.ID, prices.ID}, taskIDs(subtree))
+
+	_, err = store.GetTaskSubtree(userID+1, root.ID)
+	assert.Equal(t, sql.ErrNoRows, err)
+
+	err = store.DeleteTask(userID, hotel.ID)
+	assert.NoError(t, err)
+
+	task, err = store.GetTaskByID(userID, prices.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, &root.ID, task.ParentID)
+	assert.Equal(t, 2, task.Version)
+
+	task, err = store.GetTaskByID(userID, root.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 100, *task.Progress)
+	assert.Equal(t, 7, task.Version)
+}
+
+// testTags renames backend to api and deletes urgent.
+func testTags(t *testing.T, store TaskStore, userID int, now time.Time) {
+	tags, err := store.GetTags(userID)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"backend", "travel", "urgent"}, tagNames(tags))
+	assert.Equal(t, now, tags[0].CreatedAt)
+
+	_, err = store.CreateTag(&model.Tag{UserID: userID, Name: "backend", CreatedAt: now})
+	assert.Equal(t, ErrDuplicateTag, err)
+
+	infra := model.Tag{UserID: userID, Name: "infra", CreatedAt: now}
+	infra.ID, err = store.CreateTag(&infra)
+	assert.NoError(t, err)
+
+	tag, err := store.GetTagByID(userID, infra.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, infra, tag)
+
+	_, err = store.GetTagByID(userID+1, infra.ID)
+	assert.Equal(t, sql.ErrNoRows, err)
+
+	backend := tags[0]
+	backend.Name = "urgent"
+	err = store.UpdateTag(&backend)
+	assert.Equal(t, ErrDuplicateTag, err)
+
+	backend.Name = "api"
+	err = store.UpdateTag(&backend)
+	assert.NoError(t, err)
+
+	backend.UserID = userID + 1
+	err = store.UpdateTag(&backend)
+	assert.Equal(t, sql.ErrNoRows, err)
+
+	err = store.DeleteTag(userID, tags[2].ID)
+	assert.NoError(t, err)
+
+	err = store.DeleteTag(userID, tags[2].ID)
+	assert.Equal(t, sql.ErrNoRows, err)
+
+	tags, err = store.GetTags(userID)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"api", "infra", "travel"}, tagNames(tags))
+}
+
+// testDependencies makes a task wait for another one and deletes the
+// latter, which takes the dependency along.
+func testDependencies(t *testing.T, store TaskStore, userID int, now time.Time) {
+	var ids []int
+	for _, name := range []string{"Pour foundation", "Build walls", "Paint walls"} {
+		task := model.Task{UserID: userID, Name: name, Status: model.TaskStatusTodo, CreatedAt: now, UpdatedAt: now, Version: 1}
+		id, err := store.CreateTask(&task)
+		assert.NoError(t, err)
+		ids = append(ids, id)
+	}
+
+	dependencies := []model.TaskDependency{
+		{UserID: userID, TaskID: ids[1], DependsOnID: ids[0]},
+		{UserID: userID, TaskID: ids[2], DependsOnID: ids[1]},
+	}
+	for _, dependency := range dependencies {
+		err := store.AddTaskDependency(dependency)
+		assert.NoError(t, err)
+	}
+
+	err := store.AddTaskDependency(dependencies[0])
+	assert.Equal(t, ErrDuplicateDependency, err)
+
+	stored, err := store.GetTaskDependencies(userID)
+	assert.NoError(t, err)
+	assert.Equal(t, dependencies, stored)
+
+	tasks, err := store.GetTasks(userID, model.TaskQuery{IDs: ids, Limit: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, ids, taskIDs(tasks))
+	assert.Equal(t, []bool{false, true, true}, []bool{tasks[0].Blocked, tasks[1].Blocked, tasks[2].Blocked})
+
+	tasks[0].Status = model.TaskStatusDone
+	err = store.UpdateTask(&tasks[0])
+	assert.NoError(t, err)
+
+	// Both the new dependency and the blocker's status changed the task.
+	task, err := store.GetTaskByID(userID, ids[1])
+	assert.NoError(t, err)
+	assert.False(t, task.Blocked)
+	assert.Equal(t, 3, task.Version)
+
+	err = store.DeleteTaskDependency(model.TaskDependency{UserID: userID + 1, TaskID: ids[2], DependsOnID: ids[1]})
+	assert.Equal(t, sql.ErrNoRows, err)
+
+	err = store.DeleteTask(userID, ids[1])
+	assert.NoError(t, err)
+
+	stored, err = store.GetTaskDependencies(userID)
+	assert.NoError(t, err)
+	assert.Empty(t, stored)
+
+	task, err = store.GetTaskByID(userID, ids[2])
+	assert.NoError(t, err)
+	assert.False(t, task.Blocked)
+	assert.Equal(t, 3, task.Version)
+}
+
+func taskIDs(tasks []model.Task) []int {
+	ids := []int{}
+	for _, task := range tasks {
+		ids = append(ids, task.ID)
+	}
+	return ids
+}
+
+func tagNames(tags []model.Tag) []string {
+	names := []string{}
+	for _, tag := range tags {
+		names = append(names, tag.Name)
+	}
+	return names
+}
+
+func searchResultIDs(results []model.TaskSearchResult) []int {
+	ids := []int{}
+	for _, result := range results {
+		ids = append(ids, result.Task.ID)
+	}
+	return ids
+}
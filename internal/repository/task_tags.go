@@ -0,0 +1,225 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/absoluteyl/tasks-go/internal/model"
+	"sort"
+	"time"
+)
+
+var ErrDuplicateTag = errors.New("duplicate tag")
+
+const tagColumns = `id, user_id, name, created_at`
+
+// CreateTag returns ErrDuplicateTag when the user already has a tag of that
+// name.
+func (r *TaskRepository) CreateTag(tag *model.Tag) (int, error) {
+	createTagSQL := `
+	INSERT INTO tags (user_id, name, created_at) VALUES (?, ?, ?)
+	ON CONFLICT (user_id, name) DO NOTHING
+	`
+	id, err := r.conn().insert(createTagSQL, tag.UserID, tag.Name, tag.CreatedAt.UTC())
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrDuplicateTag
+	}
+	return id, err
+}
+
+func (r *TaskRepository) GetTags(userID int) ([]model.Tag, error) {
+	getTagsSQL := `
+	SELECT ` + tagColumns + ` FROM tags WHERE user_id = ?
+	`
+	rows, err := r.conn().Query(getTagsSQL, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []model.Tag{}
+	for rows.Next() {
+		tag, err := scanTag(rows)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Sorted here, as collations differ between dialects.
+	sort.Slice(tags, func(i, j int) bool {
+		return tags[i].Name < tags[j].Name
+	})
+	return tags, nil
+}
+
+func (r *TaskRepository) GetTagByID(userID int, id int) (model.Tag, error) {
+	getTagByIDSQL := `
+	SELECT ` + tagColumns + ` FROM tags WHERE id = ? AND user_id = ?
+	`
+	return scanTag(r.conn().QueryRow(getTagByIDSQL, id, userID))
+}
+
+// UpdateTag renames the tag on every task carrying it, which changes their
+// version. It returns ErrDuplicateTag when another tag of the user already
+// has the name.
+func (r *TaskRepository) UpdateTag(tag *model.Tag) error {
+	return r.transaction(func(tx *TaskRepository) error {
+		updateTagSQL := `
+		UPDATE tags SET name = ?
+		WHERE id = ? AND user_id = ?
+			AND NOT EXISTS (SELECT 1 FROM tags other WHERE other.user_id = ? AND other.name = ? AND other.id <> ?)
+		`
+		result, err := tx.conn().Exec(updateTagSQL, tag.Name, tag.ID, tag.UserID, tag.UserID, tag.Name, tag.ID)
+		if err != nil {
+			return err
+		}
+
+		err = expectAffected(result)
+		if errors.Is(err, sql.ErrNoRows) {
+			if _, getErr := tx.GetTagByID(tag.UserID, tag.ID); getErr == nil {
+				return ErrDuplicateTag
+			}
+		}
+		if err != nil {
+			return err
+		}
+
+		return tx.bumpTaggedTasks(tag.ID)
+	})
+}
+
+// DeleteTag deletes the tag and takes it off every task, which changes
+// their version.
+func (r *TaskRepository) DeleteTag(userID int, id int) error {
+	return r.transaction(func(tx *TaskRepository) error {
+		deleteTagSQL := `
+		DELETE FROM tags WHERE id = ? AND user_id = ?
+		`
+		result, err := tx.conn().Exec(deleteTagSQL, id, userID)
+		if err != nil {
+			return err
+		}
+
+		err = expectAffected(result)
+		if err != nil {
+			return err
+		}
+
+		err = tx.bumpTaggedTasks(id)
+		if err != nil {
+			return err
+		}
+
+		deleteTaskTagsSQL := `
+		DELETE FROM task_tags WHERE tag_id = ?
+		`
+		_, err = tx.conn().Exec(deleteTaskTagsSQL, id)
+		return err
+	})
+}
+
+// bumpTaggedTasks increments the version of the tasks carrying the tag.
+func (r *TaskRepository) bumpTaggedTasks(tagID int) error {
+	bumpTaggedTasksSQL := `
+	UPDATE tasks SET version = version + 1 WHERE id IN (SELECT task_id FROM task_tags WHERE tag_id = ?)
+	`
+	_, err := r.conn().Exec(bumpTaggedTasksSQL, tagID)
+	return err
+}
+
+// setTaskTags replaces the tags of the task, creating the tags the user
+// does not have yet.
+func (r *TaskRepository) setTaskTags(userID int, taskID int, names []string, now time.Time) error {
+	err := r.clearTaskTags(taskID)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		createTagSQL := `
+		INSERT INTO tags (user_id, name, created_at) VALUES (?, ?, ?)
+		ON CONFLICT (user_id, name) DO NOTHING
+		`
+		_, err = r.conn().Exec(createTagSQL, userID, name, now.UTC())
+		if err != nil {
+			return err
+		}
+
+		addTaskTagSQL := `
+		INSERT INTO task_tags (task_id, tag_id) SELECT ?, id FROM tags WHERE user_id = ? AND name = ?
+		`
+		_, err = r.conn().Exec(addTaskTagSQL, taskID, userID, name)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *TaskRepository) clearTaskTags(taskID int) error {
+	clearTaskTagsSQL := `
+	DELETE FROM task_tags WHERE task_id = ?
+	`
+	_, err := r.conn().Exec(clearTaskTagsSQL, taskID)
+	return err
+}
+
+// loadTaskTags fills in the tags of tasks, sorted by name.
+func (r *TaskRepository) loadTaskTags(tasks []model.Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	index := make(map[int]int, len(tasks))
+	ids := make([]interface{}, len(tasks))
+	for i := range tasks {
+		tasks[i].Tags = []string{}
+		index[tasks[i].ID] = i
+		ids[i] = tasks[i].ID
+	}
+
+	getTaskTagsSQL := `
+	SELECT task_tags.task_id, tags.name FROM task_tags JOIN tags ON tags.id = task_tags.tag_id
+	WHERE task_tags.task_id IN (` + placeholders(len(ids)) + `)
+	`
+	rows, err := r.conn().Query(getTaskTagsSQL, ids...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var taskID int
+		var name string
+		err := rows.Scan(&taskID, &name)
+		if err != nil {
+			return err
+		}
+		i := index[taskID]
+		tasks[i].Tags = append(tasks[i].Tags, name)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for i := range tasks {
+		sort.Strings(tasks[i].Tags)
+	}
+	return nil
+}
+
+func scanTag(row scanner) (model.Tag, error) {
+	var tag model.Tag
+	var createdAt time.Time
+	err := row.Scan(&tag.ID, &tag.UserID, &tag.Name, &createdAt)
+	if err != nil {
+		return model.Tag{}, err
+	}
+
+	tag.CreatedAt = createdAt.UTC()
+	return tag, nil
+}
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/absoluteyl/tasks-go/internal/model"
+)
+
+var ErrDuplicateUser = errors.New("duplicate user")
+
+type UserRepository struct {
+	db dialectConn
+}
+
+func NewUserRepository(db *sql.DB) *UserRepository {
+	return &UserRepository{db: newDialectConn(db)}
+}
+
+// CreateUser returns ErrDuplicateUser when the username is taken.
+func (r *UserRepository) CreateUser(username string, passwordHash string) (int, error) {
+	createUserSQL := `
+	INSERT INTO users (username, password_hash) VALUES (?, ?)
+	ON CONFLICT (username) DO NOTHING
+	`
+	id, err := r.db.insert(createUserSQL, username, passwordHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrDuplicateUser
+	}
+	return id, err
+}
+
+func (r *UserRepository) GetUserByID(id int) (model.User, error) {
+	getUserByIDSQL := `
+	SELECT id, username, password_hash, role FROM users WHERE id = ?
+	`
+	row := r.db.QueryRow(getUserByIDSQL, id)
+
+	var user model.User
+	err := row.Scan(&user.ID, &user.Username, &user.PasswordHash, &user.Role)
+	if err != nil {
+		return model.User{}, err
+	}
+
+	return user, nil
+}
+
+func (r *UserRepository) GetUserByUsername(username string) (model.User, error) {
+	getUserByUsernameSQL := `
+	SELECT id, username, password_hash, role FROM users WHERE username = ?
+	`
+	row := r.db.QueryRow(getUserByUsernameSQL, username)
+
+	var user model.User
+	err := row.Scan(&user.ID, &user.Username, &user.PasswordHash, &user.Role)
+	if err != nil {
+		return model.User{}, err
+	}
+
+	return user, nil
+}
+
+func (r *UserRepository) UpdateUserRole(id int, role string) error {
+	updateUserRoleSQL := `
+	UPDATE users SET role = ? WHERE id = ?
+	`
+	result, err := r.db.Exec(updateUserRoleSQL, role, id)
+	if err != nil {
+		return err
+	}
+
+	return expectAffected(result)
+}
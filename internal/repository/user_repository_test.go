@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"database/sql"
+	"github.com/absoluteyl/tasks-go/internal/model"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+var userData = model.User{
+	ID:           1,
+	Username:     "elsa",
+	PasswordHash: "hashed-password",
+	Role:         "member",
+}
+
+func TestUserRepository(t *testing.T) {
+	t.Run("Create", testCreateUser)
+	t.Run("CreateDuplicate", testCreateDuplicateUser)
+	t.Run("GetByID", testGetUserByID)
+	t.Run("GetByUsername", testGetUserByUsername)
+	t.Run("GetByUsernameNotExist", testGetUserByUsernameNotExist)
+	t.Run("UpdateRole", testUpdateUserRole)
+	t.Run("UpdateRoleNotExist", testUpdateUserRoleNotExist)
+}
+
+func testCreateUser(t *testing.T) {
+	userID, err := userRepo.CreateUser(userData.Username, userData.PasswordHash)
+	assert.NoError(t, err)
+	assert.Equal(t, userData.ID, userID)
+}
+
+func testCreateDuplicateUser(t *testing.T) {
+	_, err := userRepo.CreateUser(userData.Username, userData.PasswordHash)
+	assert.Equal(t, ErrDuplicateUser, err)
+}
+
+func testGetUserByID(t *testing.T) {
+	user, err := userRepo.GetUserByID(userData.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, userData, user)
+}
+
+func testGetUserByUsername(t *testing.T) {
+	user, err := userRepo.GetUserByUsername(userData.Username)
+	assert.NoError(t, err)
+	assert.Equal(t, userData, user)
+}
+
+func testGetUserByUsernameNotExist(t *testing.T) {
+	_, err := userRepo.GetUserByUsername("anna")
+	assert.Error(t, err)
+	assert.Equal(t, sql.ErrNoRows, err)
+}
+
+func testUpdateUserRole(t *testing.T) {
+	err := userRepo.UpdateUserRole(userData.ID, "read-only")
+	assert.NoError(t, err)
+
+	user, err := userRepo.GetUserByID(userData.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "read-only", user.Role)
+
+	err = userRepo.UpdateUserRole(userData.ID, userData.Role)
+	assert.NoError(t, err)
+}
+
+func testUpdateUserRoleNotExist(t *testing.T) {
+	err := userRepo.UpdateUserRole(999, "admin")
+	assert.Equal(t, sql.ErrNoRows, err)
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/absoluteyl/tasks-go/internal/model"
+	"github.com/absoluteyl/tasks-go/internal/repository"
+	"github.com/absoluteyl/tasks-go/pkg/auth"
+	"time"
+)
+
+type APIKeyService struct {
+	apiKeyRepository *repository.APIKeyRepository
+	userRepository   *repository.UserRepository
+}
+
+func NewAPIKeyServiceWithRepositories(
+	apiKeyRepository *repository.APIKeyRepository,
+	userRepository *repository.UserRepository,
+) *APIKeyService {
+	return &APIKeyService{
+		apiKeyRepository: apiKeyRepository,
+		userRepository:   userRepository,
+	}
+}
+
+// CreateAPIKey stores a new key for the user and returns it together with
+// the plain key, which cannot be recovered afterwards. The key is limited to
+// the requested scopes, which must be a subset of grantedScopes, or gets all
+// of grantedScopes when none are requested.
+func (s *APIKeyService) CreateAPIKey(
+	userID int, name string, scopes []string, grantedScopes []string, expiresAt *time.Time,
+) (model.APIKey, string, error) {
+	if len(scopes) == 0 {
+		scopes = grantedScopes
+	}
+	for _, scope := range scopes {
+		if !auth.HasScope(grantedScopes, scope) {
+			return model.APIKey{}, "", ErrScopeNotAllowed
+		}
+	}
+
+	key, prefix, err := auth.GenerateAPIKey()
+	if err != nil {
+		return model.APIKey{}, "", err
+	}
+
+	apiKey := model.APIKey{
+		UserID:    userID,
+		Name:      name,
+		Prefix:    prefix,
+		Scopes:    auth.IntersectScopes(scopes, grantedScopes),
+		KeyHash:   auth.HashToken(key),
+		ExpiresAt: expiresAt,
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+	}
+
+	apiKey.ID, err = s.apiKeyRepository.CreateAPIKey(&apiKey)
+	if err != nil {
+		return model.APIKey{}, "", err
+	}
+
+	return apiKey, key, nil
+}
+
+func (s *APIKeyService) GetAPIKeys(userID int) ([]model.APIKey, error) {
+	return s.apiKeyRepository.GetAPIKeys(userID)
+}
+
+func (s *APIKeyService) DeleteAPIKey(userID int, id int) error {
+	err := s.apiKeyRepository.DeleteAPIKey(userID, id)
+	return notFoundAs(err, ErrAPIKeyNotFound)
+}
+
+// AuthenticateAPIKey resolves a plain key to its stored record and records
+// the usage. The returned scopes are narrowed to what the owner's current
+// role still allows.
+func (s *APIKeyService) AuthenticateAPIKey(key string) (model.APIKey, error) {
+	apiKey, err := s.apiKeyRepository.GetAPIKeyByHash(auth.HashToken(key))
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.APIKey{}, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return model.APIKey{}, err
+	}
+
+	now := time.Now()
+	if apiKey.ExpiresAt != nil && !now.Before(*apiKey.ExpiresAt) {
+		return model.APIKey{}, ErrInvalidAPIKey
+	}
+
+	user, err := s.userRepository.GetUserByID(apiKey.UserID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.APIKey{}, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return model.APIKey{}, err
+	}
+	apiKey.Scopes = auth.IntersectScopes(apiKey.Scopes, auth.ScopesForRole(user.Role))
+
+	err = s.apiKeyRepository.TouchAPIKey(apiKey.ID, now)
+	if err != nil {
+		return model.APIKey{}, err
+	}
+
+	return apiKey, nil
+}
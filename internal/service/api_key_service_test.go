@@ -0,0 +1,112 @@
+package service
+
+import (
+	"github.com/absoluteyl/tasks-go/internal/model"
+	"github.com/absoluteyl/tasks-go/pkg/auth"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+var createdAPIKey model.APIKey
+var plainAPIKey string
+
+var memberScopes = auth.ScopesForRole(auth.RoleMember)
+
+func TestAPIKeyService(t *testing.T) {
+	t.Run("Create", testCreateAPIKey)
+	t.Run("CreateWithScopes", testCreateAPIKeyWithScopes)
+	t.Run("CreateWithScopeNotGranted", testCreateAPIKeyWithScopeNotGranted)
+	t.Run("GetList", testGetAPIKeys)
+	t.Run("Authenticate", testAuthenticateAPIKey)
+	t.Run("AuthenticateUnknown", testAuthenticateUnknownAPIKey)
+	t.Run("AuthenticateExpired", testAuthenticateExpiredAPIKey)
+	t.Run("AuthenticateAfterRoleChange", testAuthenticateAPIKeyAfterRoleChange)
+	t.Run("DeleteOtherUser", testDeleteAPIKeyOtherUser)
+	t.Run("Delete", testDeleteAPIKey)
+}
+
+func testCreateAPIKey(t *testing.T) {
+	var err error
+	createdAPIKey, plainAPIKey, err = apiKeyService.CreateAPIKey(1, "CI", nil, memberScopes, nil)
+	assert.NoError(t, err)
+	assert.NotZero(t, createdAPIKey.ID)
+	assert.NotEmpty(t, plainAPIKey)
+	assert.NotEqual(t, plainAPIKey, createdAPIKey.KeyHash)
+	assert.Contains(t, plainAPIKey, createdAPIKey.Prefix)
+	assert.Equal(t, memberScopes, createdAPIKey.Scopes)
+}
+
+func testCreateAPIKeyWithScopes(t *testing.T) {
+	apiKey, key, err := apiKeyService.CreateAPIKey(1, "Dashboard", []string{auth.ScopeTasksRead}, memberScopes, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{auth.ScopeTasksRead}, apiKey.Scopes)
+
+	authenticatedKey, err := apiKeyService.AuthenticateAPIKey(key)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{auth.ScopeTasksRead}, authenticatedKey.Scopes)
+
+	err = apiKeyService.DeleteAPIKey(1, apiKey.ID)
+	assert.NoError(t, err)
+}
+
+func testCreateAPIKeyWithScopeNotGranted(t *testing.T) {
+	_, _, err := apiKeyService.CreateAPIKey(1, "Admin", []string{auth.ScopeUsersAdmin}, memberScopes, nil)
+	assert.Equal(t, ErrScopeNotAllowed, err)
+}
+
+func testGetAPIKeys(t *testing.T) {
+	apiKeys, err := apiKeyService.GetAPIKeys(1)
+	assert.NoError(t, err)
+	assert.Len(t, apiKeys, 1)
+	assert.Equal(t, "CI", apiKeys[0].Name)
+}
+
+func testAuthenticateAPIKey(t *testing.T) {
+	apiKey, err := apiKeyService.AuthenticateAPIKey(plainAPIKey)
+	assert.NoError(t, err)
+	assert.Equal(t, createdAPIKey.ID, apiKey.ID)
+	assert.Equal(t, 1, apiKey.UserID)
+	assert.Equal(t, memberScopes, apiKey.Scopes)
+
+	apiKeys, err := apiKeyService.GetAPIKeys(1)
+	assert.NoError(t, err)
+	assert.NotNil(t, apiKeys[0].LastUsedAt, "Last used timestamp not recorded")
+}
+
+func testAuthenticateUnknownAPIKey(t *testing.T) {
+	_, err := apiKeyService.AuthenticateAPIKey("tsk_unknown")
+	assert.Equal(t, ErrInvalidAPIKey, err)
+}
+
+func testAuthenticateExpiredAPIKey(t *testing.T) {
+	expiresAt := time.Now().Add(-time.Minute)
+	_, expiredKey, err := apiKeyService.CreateAPIKey(1, "Expired", nil, memberScopes, &expiresAt)
+	assert.NoError(t, err)
+
+	_, err = apiKeyService.AuthenticateAPIKey(expiredKey)
+	assert.Equal(t, ErrInvalidAPIKey, err)
+}
+
+func testAuthenticateAPIKeyAfterRoleChange(t *testing.T) {
+	err := userRepo.UpdateUserRole(1, auth.RoleReadOnly)
+	assert.NoError(t, err)
+	defer userRepo.UpdateUserRole(1, auth.RoleMember)
+
+	apiKey, err := apiKeyService.AuthenticateAPIKey(plainAPIKey)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{auth.ScopeTasksRead}, apiKey.Scopes)
+}
+
+func testDeleteAPIKeyOtherUser(t *testing.T) {
+	err := apiKeyService.DeleteAPIKey(2, createdAPIKey.ID)
+	assert.Equal(t, ErrAPIKeyNotFound, err)
+}
+
+func testDeleteAPIKey(t *testing.T) {
+	err := apiKeyService.DeleteAPIKey(1, createdAPIKey.ID)
+	assert.NoError(t, err)
+
+	_, err = apiKeyService.AuthenticateAPIKey(plainAPIKey)
+	assert.Equal(t, ErrInvalidAPIKey, err)
+}
@@ -0,0 +1,44 @@
+package service
+
+import "errors"
+
+var (
+	ErrUserExists         = errors.New("user already exists")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrTaskNotFound       = errors.New("task not found")
+	ErrUserNotFound       = errors.New("user not found")
+	ErrInvalidRole        = errors.New("invalid role")
+
+	ErrInvalidStatusTransition = errors.New("invalid status transition")
+	ErrInvalidCursor           = errors.New("invalid cursor")
+	ErrTaskVersionConflict     = errors.New("task version conflict")
+	ErrInvalidSearchQuery      = errors.New("invalid search query")
+	ErrParentTaskNotFound      = errors.New("parent task not found")
+	ErrTaskCycle               = errors.New("task cannot be its own ancestor")
+
+	ErrTagNotFound = errors.New("tag not found")
+	ErrTagExists   = errors.New("tag already exists")
+
+	ErrDependencyTaskNotFound = errors.New("dependency task not found")
+	ErrDependencyCycle        = errors.New("dependency would create a cycle")
+	ErrDependencyExists       = errors.New("dependency already exists")
+	ErrDependencyNotFound     = errors.New("dependency not found")
+
+	ErrProjectNotFound = errors.New("project not found")
+	ErrProjectArchived = errors.New("project is archived")
+	ErrProjectNotEmpty = errors.New("project still has tasks")
+
+	ErrTooManyTaskOperations = errors.New("too many task operations")
+	ErrInvalidTaskOperation  = errors.New("invalid task operation")
+	ErrBulkRolledBack        = errors.New("rolled back after another operation failed")
+
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+
+	ErrAPIKeyNotFound = errors.New("api key not found")
+	ErrInvalidAPIKey  = errors.New("invalid api key")
+
+	ErrScopeNotAllowed = errors.New("scope not allowed")
+
+	ErrIdempotencyKeyMismatch   = errors.New("idempotency key reused with a different request")
+	ErrIdempotencyKeyInProgress = errors.New("idempotency key is still being processed")
+)
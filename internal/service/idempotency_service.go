@@ -0,0 +1,96 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/absoluteyl/tasks-go/internal/model"
+	"github.com/absoluteyl/tasks-go/internal/repository"
+	"time"
+)
+
+const DefaultIdempotencyKeyTTL = 24 * time.Hour
+
+type IdempotencyService struct {
+	idempotencyKeyRepository *repository.IdempotencyKeyRepository
+	ttl                      time.Duration
+}
+
+func NewIdempotencyServiceWithRepository(idempotencyKeyRepository *repository.IdempotencyKeyRepository) *IdempotencyService {
+	return &IdempotencyService{
+		idempotencyKeyRepository: idempotencyKeyRepository,
+		ttl:                      DefaultIdempotencyKeyTTL,
+	}
+}
+
+// SetTTL sets how long responses are kept for replay.
+func (s *IdempotencyService) SetTTL(ttl time.Duration) {
+	s.ttl = ttl
+}
+
+// Begin claims key for a request with the given hash. When the key was
+// already used for the same request and its response is stored, that record
+// is returned with replay set, and the request must not be processed again.
+func (s *IdempotencyService) Begin(userID int, key string, requestHash string) (model.IdempotencyKey, bool, error) {
+	for {
+		record := model.IdempotencyKey{
+			UserID:      userID,
+			Key:         key,
+			RequestHash: requestHash,
+			ExpiresAt:   time.Now().Add(s.ttl),
+		}
+
+		id, err := s.idempotencyKeyRepository.CreateIdempotencyKey(&record)
+		if err == nil {
+			record.ID = id
+			return record, false, nil
+		}
+		if !errors.Is(err, repository.ErrDuplicateIdempotencyKey) {
+			return model.IdempotencyKey{}, false, err
+		}
+
+		existing, err := s.idempotencyKeyRepository.GetIdempotencyKey(userID, key)
+		if errors.Is(err, sql.ErrNoRows) {
+			// Released or purged since the insert, so try to claim it again.
+			continue
+		}
+		if err != nil {
+			return model.IdempotencyKey{}, false, err
+		}
+
+		if !time.Now().Before(existing.ExpiresAt) {
+			err = s.idempotencyKeyRepository.DeleteIdempotencyKey(existing.ID)
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				return model.IdempotencyKey{}, false, err
+			}
+			continue
+		}
+
+		if existing.RequestHash != requestHash {
+			return model.IdempotencyKey{}, false, ErrIdempotencyKeyMismatch
+		}
+		if existing.StatusCode == 0 {
+			return model.IdempotencyKey{}, false, ErrIdempotencyKeyInProgress
+		}
+
+		return existing, true, nil
+	}
+}
+
+// Complete stores the response of the request that claimed record.
+func (s *IdempotencyService) Complete(record *model.IdempotencyKey) error {
+	return s.idempotencyKeyRepository.CompleteIdempotencyKey(record)
+}
+
+// Release gives up a claimed key without storing a response, so the request
+// can be retried with the same key.
+func (s *IdempotencyService) Release(record model.IdempotencyKey) error {
+	err := s.idempotencyKeyRepository.DeleteIdempotencyKey(record.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	return err
+}
+
+func (s *IdempotencyService) PurgeExpired() error {
+	return s.idempotencyKeyRepository.DeleteExpiredIdempotencyKeys(time.Now())
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"github.com/absoluteyl/tasks-go/internal/model"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+)
+
+var idempotencyRecord model.IdempotencyKey
+
+func TestIdempotencyService(t *testing.T) {
+	t.Run("Begin", testBeginIdempotencyKey)
+	t.Run("BeginInProgress", testBeginIdempotencyKeyInProgress)
+	t.Run("BeginMismatch", testBeginIdempotencyKeyMismatch)
+	t.Run("BeginReplay", testBeginIdempotencyKeyReplay)
+	t.Run("BeginAfterRelease", testBeginIdempotencyKeyAfterRelease)
+	t.Run("BeginExpired", testBeginIdempotencyKeyExpired)
+}
+
+func testBeginIdempotencyKey(t *testing.T) {
+	var replay bool
+	var err error
+	idempotencyRecord, replay, err = idempotencyService.Begin(1, "create-task", "hash")
+	assert.NoError(t, err)
+	assert.False(t, replay)
+	assert.NotZero(t, idempotencyRecord.ID)
+}
+
+func testBeginIdempotencyKeyInProgress(t *testing.T) {
+	_, _, err := idempotencyService.Begin(1, "create-task", "hash")
+	assert.Equal(t, ErrIdempotencyKeyInProgress, err)
+}
+
+func testBeginIdempotencyKeyMismatch(t *testing.T) {
+	_, _, err := idempotencyService.Begin(1, "create-task", "other-hash")
+	assert.Equal(t, ErrIdempotencyKeyMismatch, err)
+}
+
+func testBeginIdempotencyKeyReplay(t *testing.T) {
+	idempotencyRecord.StatusCode = http.StatusCreated
+	idempotencyRecord.ResponseHeaders = map[string][]string{"Content-Type": {"application/json"}}
+	idempotencyRecord.ResponseBody = []byte(`{"result":"ok"}`)
+	err := idempotencyService.Complete(&idempotencyRecord)
+	assert.NoError(t, err)
+
+	record, replay, err := idempotencyService.Begin(1, "create-task", "hash")
+	assert.NoError(t, err)
+	assert.True(t, replay)
+	assert.Equal(t, http.StatusCreated, record.StatusCode)
+	assert.Equal(t, idempotencyRecord.ResponseBody, record.ResponseBody)
+
+	_, replay, err = idempotencyService.Begin(otherUserID, "create-task", "hash")
+	assert.NoError(t, err)
+	assert.False(t, replay, "Key shared between users")
+}
+
+func testBeginIdempotencyKeyAfterRelease(t *testing.T) {
+	record, _, err := idempotencyService.Begin(1, "failed-request", "hash")
+	assert.NoError(t, err)
+
+	err = idempotencyService.Release(record)
+	assert.NoError(t, err)
+
+	_, replay, err := idempotencyService.Begin(1, "failed-request", "other-hash")
+	assert.NoError(t, err)
+	assert.False(t, replay)
+}
+
+func testBeginIdempotencyKeyExpired(t *testing.T) {
+	idempotencyService.SetTTL(-time.Minute)
+	_, _, err := idempotencyService.Begin(1, "expired", "hash")
+	idempotencyService.SetTTL(DefaultIdempotencyKeyTTL)
+	assert.NoError(t, err)
+
+	_, replay, err := idempotencyService.Begin(1, "expired", "other-hash")
+	assert.NoError(t, err)
+	assert.False(t, replay, "Expired key replayed")
+
+	err = idempotencyService.PurgeExpired()
+	assert.NoError(t, err)
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"errors"
+	"github.com/absoluteyl/tasks-go/internal/model"
+	"github.com/absoluteyl/tasks-go/internal/repository"
+	"time"
+)
+
+// ProjectTaskService is the part of TaskService that ProjectService
+// relies on.
+type ProjectTaskService interface {
+	GetTasks(userID int, query model.TaskQuery, cursor string) ([]model.Task, string, error)
+	BulkTasks(userID int, operations []TaskOperation, allOrNothing bool) ([]TaskOperationResult, error)
+}
+
+// ProjectService manages projects. Their tasks go through the task
+// service, which also checks the projects tasks are put into.
+type ProjectService struct {
+	projectStore repository.ProjectStore
+	taskService  ProjectTaskService
+}
+
+func NewProjectServiceWithRepository(
+	projectStore repository.ProjectStore,
+	taskService ProjectTaskService,
+) *ProjectService {
+	return &ProjectService{
+		projectStore: projectStore,
+		taskService:  taskService,
+	}
+}
+
+func (s *ProjectService) CreateProject(project *model.Project) (int, error) {
+	now := time.Now().UTC().Truncate(time.Second)
+	project.CreatedAt = now
+	project.UpdatedAt = now
+	project.ArchivedAt = nil
+
+	return s.projectStore.CreateProject(project)
+}
+
+// GetProjects lists the user's projects by id, archived ones only with
+// includeArchived set.
+func (s *ProjectService) GetProjects(userID int, includeArchived bool) ([]model.Project, error) {
+	return s.projectStore.GetProjects(userID, includeArchived)
+}
+
+func (s *ProjectService) GetProjectByID(userID int, id int) (model.Project, error) {
+	project, err := s.projectStore.GetProjectByID(userID, id)
+	return project, notFoundAs(err, ErrProjectNotFound)
+}
+
+func (s *ProjectService) UpdateProject(userID int, id int, name string, description string) (model.Project, error) {
+	return s.saveProject(userID, id, func(project *model.Project, now time.Time) {
+		project.Name = name
+		project.Description = description
+	})
+}
+
+// ArchiveProject keeps the tasks of the project, but no tasks can be added
+// to it until it is unarchived.
+func (s *ProjectService) ArchiveProject(userID int, id int) (model.Project, error) {
+	return s.saveProject(userID, id, func(project *model.Project, now time.Time) {
+		if project.ArchivedAt == nil {
+			project.ArchivedAt = &now
+		}
+	})
+}
+
+func (s *ProjectService) UnarchiveProject(userID int, id int) (model.Project, error) {
+	return s.saveProject(userID, id, func(project *model.Project, now time.Time) {
+		project.ArchivedAt = nil
+	})
+}
+
+// DeleteProject only deletes projects without tasks and reports the others
+// with ErrProjectNotEmpty, so that tasks are moved out or deleted first.
+func (s *ProjectService) DeleteProject(userID int, id int) error {
+	err := s.projectStore.DeleteProject(userID, id)
+	if errors.Is(err, repository.ErrProjectNotEmpty) {
+		return ErrProjectNotEmpty
+	}
+	return notFoundAs(err, ErrProjectNotFound)
+}
+
+// GetProjectTasks returns a page of the tasks of the project, like
+// GetTasks does for all tasks.
+func (s *ProjectService) GetProjectTasks(userID int, id int, query model.TaskQuery, cursor string) ([]model.Task, string, error) {
+	_, err := s.GetProjectByID(userID, id)
+	if err != nil {
+		return nil, "", err
+	}
+
+	query.ProjectID = &id
+	return s.taskService.GetTasks(userID, query, cursor)
+}
+
+// MoveTasks moves the tasks into the project, all or none of them. It
+// reports the first task that cannot be moved with its error.
+func (s *ProjectService) MoveTasks(userID int, id int, taskIDs []int) ([]model.Task, error) {
+	_, err := s.GetProjectByID(userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	operations := make([]TaskOperation, len(taskIDs))
+	for i, taskID := range taskIDs {
+		operations[i] = TaskOperation{
+			Op:     TaskOperationUpdate,
+			TaskID: taskID,
+			Modify: func(task *model.Task) error {
+				task.ProjectID = &id
+				return nil
+			},
+		}
+	}
+
+	results, err := s.taskService.BulkTasks(userID, operations, true)
+	if err != nil {
+		return nil, err
+	}
+
+	tasks := make([]model.Task, len(results))
+	for i, result := range results {
+		if result.Err != nil && result.Err != ErrBulkRolledBack {
+			return nil, result.Err
+		}
+		tasks[i] = result.Task
+	}
+	return tasks, nil
+}
+
+// saveProject loads the project, lets modify change it and stores the
+// result.
+func (s *ProjectService) saveProject(
+	userID int, id int, modify func(project *model.Project, now time.Time),
+) (model.Project, error) {
+	project, err := s.GetProjectByID(userID, id)
+	if err != nil {
+		return model.Project{}, err
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	modify(&project, now)
+	project.UpdatedAt = now
+
+	err = s.projectStore.UpdateProject(&project)
+	if err != nil {
+		return model.Project{}, notFoundAs(err, ErrProjectNotFound)
+	}
+	return project, nil
+}
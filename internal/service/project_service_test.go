@@ -0,0 +1,196 @@
+package service
+
+import (
+	"github.com/absoluteyl/tasks-go/internal/model"
+	"github.com/absoluteyl/tasks-go/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+const projectUserID = 7
+
+var projectData = model.Project{UserID: projectUserID, Name: "Household"}
+
+// The project tests keep their projects and tasks in memory, as the task
+// tests count on the ids of the tasks they create.
+var projectTaskService *TaskService
+var projectService *ProjectService
+
+func TestProjectService(t *testing.T) {
+	taskStore := repository.NewMemoryTaskStore()
+	projectStore := repository.NewMemoryProjectStore(taskStore)
+	projectTaskService = NewTaskServiceWithRepository(taskStore)
+	projectTaskService.SetProjectStore(projectStore)
+	projectService = NewProjectServiceWithRepository(projectStore, projectTaskService)
+
+	t.Run("Create", testCreateProject)
+	t.Run("Update", testUpdateProject)
+	t.Run("GetByIDOtherUser", testGetProjectByIDOtherUser)
+	t.Run("TaskProject", testTaskProject)
+	t.Run("MoveTasks", testMoveTasks)
+	t.Run("Archive", testArchiveProject)
+	t.Run("CompleteRecurringArchived", testCompleteRecurringTaskArchivedProject)
+	t.Run("Delete", testDeleteProject)
+}
+
+func testCreateProject(t *testing.T) {
+	projectID, err := projectService.CreateProject(&projectData)
+	assert.NoError(t, err)
+	assert.NotZero(t, projectID)
+	projectData.ID = projectID
+
+	projects, err := projectService.GetProjects(projectUserID, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []model.Project{projectData}, projects)
+}
+
+func testUpdateProject(t *testing.T) {
+	project, err := projectService.UpdateProject(projectUserID, projectData.ID, "Home", "Chores")
+	assert.NoError(t, err)
+	assert.Equal(t, "Home", project.Name)
+	assert.Equal(t, "Chores", project.Description)
+	projectData = project
+
+	_, err = projectService.UpdateProject(otherUserID, projectData.ID, "Home", "")
+	assert.Equal(t, ErrProjectNotFound, err)
+}
+
+func testGetProjectByIDOtherUser(t *testing.T) {
+	_, err := projectService.GetProjectByID(otherUserID, projectData.ID)
+	assert.Equal(t, ErrProjectNotFound, err)
+}
+
+func testTaskProject(t *testing.T) {
+	task := model.Task{UserID: projectUserID, Name: "Vacuum", ProjectID: &projectData.ID}
+	taskID, err := projectTaskService.CreateTask(&task)
+	assert.NoError(t, err)
+
+	tasks, nextCursor, err := projectService.GetProjectTasks(projectUserID, projectData.ID, model.TaskQuery{}, "")
+	assert.NoError(t, err)
+	assert.Empty(t, nextCursor)
+	assert.Equal(t, []int{taskID}, taskIDs(tasks))
+
+	missingProjectID := 9999
+	_, err = projectTaskService.CreateTask(&model.Task{UserID: projectUserID, Name: "Dust", ProjectID: &missingProjectID})
+	assert.Equal(t, ErrProjectNotFound, err)
+
+	_, err = projectTaskService.CreateTask(&model.Task{UserID: otherUserID, Name: "Dust", ProjectID: &projectData.ID})
+	assert.Equal(t, ErrProjectNotFound, err)
+
+	_, _, err = projectService.GetProjectTasks(otherUserID, projectData.ID, model.TaskQuery{}, "")
+	assert.Equal(t, ErrProjectNotFound, err)
+}
+
+func testMoveTasks(t *testing.T) {
+	var ids []int
+	for _, name := range []string{"Mow lawn", "Water plants"} {
+		id, err := projectTaskService.CreateTask(&model.Task{UserID: projectUserID, Name: name})
+		assert.NoError(t, err)
+		ids = append(ids, id)
+	}
+
+	_, err := projectService.MoveTasks(projectUserID, projectData.ID, []int{ids[0], 9999})
+	assert.Equal(t, ErrTaskNotFound, err)
+
+	task, err := projectTaskService.GetTaskByID(projectUserID, ids[0])
+	assert.NoError(t, err)
+	assert.Nil(t, task.ProjectID)
+
+	tasks, err := projectService.MoveTasks(projectUserID, projectData.ID, ids)
+	assert.NoError(t, err)
+	assert.Equal(t, ids, taskIDs(tasks))
+	assert.Equal(t, &projectData.ID, tasks[1].ProjectID)
+
+	_, err = projectService.MoveTasks(projectUserID, 9999, ids)
+	assert.Equal(t, ErrProjectNotFound, err)
+}
+
+func testArchiveProject(t *testing.T) {
+	project, err := projectService.ArchiveProject(projectUserID, projectData.ID)
+	assert.NoError(t, err)
+	assert.NotNil(t, project.ArchivedAt)
+
+	projects, err := projectService.GetProjects(projectUserID, false)
+	assert.NoError(t, err)
+	assert.Empty(t, projects)
+
+	projects, err = projectService.GetProjects(projectUserID, true)
+	assert.NoError(t, err)
+	assert.Equal(t, []model.Project{project}, projects)
+
+	_, err = projectTaskService.CreateTask(&model.Task{UserID: projectUserID, Name: "Dust", ProjectID: &projectData.ID})
+	assert.Equal(t, ErrProjectArchived, err)
+
+	tasks, _, err := projectService.GetProjectTasks(projectUserID, projectData.ID, model.TaskQuery{}, "")
+	assert.NoError(t, err)
+	tasks[0].Name = "Vacuum upstairs"
+	err = projectTaskService.UpdateTask(&tasks[0])
+	assert.NoError(t, err)
+
+	project, err = projectService.UnarchiveProject(projectUserID, projectData.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, project.ArchivedAt)
+}
+
+func testCompleteRecurringTaskArchivedProject(t *testing.T) {
+	task := model.Task{UserID: projectUserID, Name: "Water plants", ProjectID: &projectData.ID, Recurrence: "FREQ=WEEKLY"}
+	taskID, err := projectTaskService.CreateTask(&task)
+	assert.NoError(t, err)
+
+	_, err = projectService.ArchiveProject(projectUserID, projectData.ID)
+	assert.NoError(t, err)
+	defer func() {
+		_, err := projectService.UnarchiveProject(projectUserID, projectData.ID)
+		assert.NoError(t, err)
+	}()
+
+	projectTasks, _, err := projectService.GetProjectTasks(projectUserID, projectData.ID, model.TaskQuery{}, "")
+	assert.NoError(t, err)
+
+	task, err = projectTaskService.GetTaskByID(projectUserID, taskID)
+	assert.NoError(t, err)
+	task.Status = model.TaskStatusDone
+	err = projectTaskService.UpdateTask(&task)
+	assert.NoError(t, err)
+
+	tasks, _, err := projectService.GetProjectTasks(projectUserID, projectData.ID, model.TaskQuery{}, "")
+	assert.NoError(t, err)
+	assert.Equal(t, taskIDs(projectTasks), taskIDs(tasks), "Task added to an archived project")
+
+	tasks, _, err = projectTaskService.GetTasks(projectUserID, model.TaskQuery{Sort: []model.TaskSort{{Field: "id", Desc: true}}}, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "Water plants", tasks[0].Name)
+	assert.Equal(t, model.TaskStatusTodo, tasks[0].Status)
+	assert.Equal(t, "FREQ=WEEKLY", tasks[0].Recurrence)
+	assert.Nil(t, tasks[0].ProjectID)
+}
+
+func testDeleteProject(t *testing.T) {
+	err := projectService.DeleteProject(projectUserID, projectData.ID)
+	assert.Equal(t, ErrProjectNotEmpty, err)
+
+	tasks, _, err := projectService.GetProjectTasks(projectUserID, projectData.ID, model.TaskQuery{}, "")
+	assert.NoError(t, err)
+	for _, task := range tasks {
+		task.ProjectID = nil
+		err = projectTaskService.UpdateTask(&task)
+		assert.NoError(t, err)
+	}
+
+	err = projectService.DeleteProject(otherUserID, projectData.ID)
+	assert.Equal(t, ErrProjectNotFound, err)
+
+	err = projectService.DeleteProject(projectUserID, projectData.ID)
+	assert.NoError(t, err)
+
+	_, err = projectService.GetProjectByID(projectUserID, projectData.ID)
+	assert.Equal(t, ErrProjectNotFound, err)
+}
+
+func taskIDs(tasks []model.Task) []int {
+	ids := make([]int, len(tasks))
+	for i, task := range tasks {
+		ids[i] = task.ID
+	}
+	return ids
+}
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"errors"
+	"github.com/absoluteyl/tasks-go/internal/model"
+	"github.com/absoluteyl/tasks-go/internal/repository"
+	"time"
+)
+
+// GetTags lists the user's tags by name. Tags live in the task store along
+// with the tasks carrying them, which is why TaskService manages them.
+// Setting tags on a task creates the missing ones.
+func (s *TaskService) GetTags(userID int) ([]model.Tag, error) {
+	return s.taskStore.GetTags(userID)
+}
+
+func (s *TaskService) CreateTag(tag *model.Tag) (int, error) {
+	tag.CreatedAt = time.Now().UTC().Truncate(time.Second)
+
+	id, err := s.taskStore.CreateTag(tag)
+	if errors.Is(err, repository.ErrDuplicateTag) {
+		return 0, ErrTagExists
+	}
+	return id, err
+}
+
+// RenameTag renames the tag on every task carrying it.
+func (s *TaskService) RenameTag(userID int, id int, name string) (model.Tag, error) {
+	tag, err := s.taskStore.GetTagByID(userID, id)
+	if err != nil {
+		return model.Tag{}, notFoundAs(err, ErrTagNotFound)
+	}
+
+	tag.Name = name
+	err = s.taskStore.UpdateTag(&tag)
+	if errors.Is(err, repository.ErrDuplicateTag) {
+		return model.Tag{}, ErrTagExists
+	}
+	if err != nil {
+		return model.Tag{}, notFoundAs(err, ErrTagNotFound)
+	}
+	return tag, nil
+}
+
+// DeleteTag deletes the tag and takes it off every task.
+func (s *TaskService) DeleteTag(userID int, id int) error {
+	err := s.taskStore.DeleteTag(userID, id)
+	return notFoundAs(err, ErrTagNotFound)
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"github.com/absoluteyl/tasks-go/internal/model"
+	"github.com/absoluteyl/tasks-go/internal/repository"
+)
+
+// taskCursor is the position after the last task of a page: its id and its
+// values of the sort fields. It is handed to clients as opaque base64
+// encoded JSON and only valid with the sort order it was created for.
+type taskCursor struct {
+	ID     int           `json:"id"`
+	Sort   string        `json:"sort,omitempty"`
+	Values []interface{} `json:"values,omitempty"`
+}
+
+func encodeTaskCursor(task model.Task, sort []model.TaskSort) string {
+	encoded, _ := json.Marshal(taskCursor{
+		ID:     task.ID,
+		Sort:   model.FormatTaskSort(sort),
+		Values: repository.TaskSortValues(task, sort),
+	})
+	return base64.RawURLEncoding.EncodeToString(encoded)
+}
+
+// applyTaskCursor positions query after the task encoded in cursor.
+func applyTaskCursor(query *model.TaskQuery, cursor string) error {
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return ErrInvalidCursor
+	}
+
+	var c taskCursor
+	err = json.Unmarshal(decoded, &c)
+	if err != nil || c.ID <= 0 {
+		return ErrInvalidCursor
+	}
+	if c.Sort != model.FormatTaskSort(query.Sort) || len(c.Values) != len(query.Sort) {
+		return ErrInvalidCursor
+	}
+
+	query.AfterID = c.ID
+	query.AfterValues = c.Values
+	return nil
+}
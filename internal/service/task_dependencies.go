@@ -0,0 +1,111 @@
+package service
+
+import (
+	"errors"
+	"github.com/absoluteyl/tasks-go/internal/model"
+	"github.com/absoluteyl/tasks-go/internal/repository"
+	"sort"
+)
+
+// AddTaskDependency makes dependency.TaskID wait for dependency.DependsOnID.
+// It fails with ErrDependencyCycle when the other task already waits for
+// the task, directly or not, or when both are the same task.
+func (s *TaskService) AddTaskDependency(dependency model.TaskDependency) error {
+	return s.taskStore.InTransaction(func(txStore repository.TaskStore) error {
+		err := txStore.LockUserTasks(dependency.UserID)
+		if err != nil {
+			return err
+		}
+
+		_, err = txStore.GetTaskByID(dependency.UserID, dependency.TaskID)
+		if err != nil {
+			return notFoundAs(err, ErrTaskNotFound)
+		}
+
+		_, err = txStore.GetTaskByID(dependency.UserID, dependency.DependsOnID)
+		if err != nil {
+			return notFoundAs(err, ErrDependencyTaskNotFound)
+		}
+
+		dependencies, err := txStore.GetTaskDependencies(dependency.UserID)
+		if err != nil {
+			return err
+		}
+		if reachableTasks(dependencies, dependency.DependsOnID, true)[dependency.TaskID] {
+			return ErrDependencyCycle
+		}
+
+		err = txStore.AddTaskDependency(dependency)
+		if errors.Is(err, repository.ErrDuplicateDependency) {
+			return ErrDependencyExists
+		}
+		return err
+	})
+}
+
+func (s *TaskService) RemoveTaskDependency(dependency model.TaskDependency) error {
+	err := s.taskStore.DeleteTaskDependency(dependency)
+	return notFoundAs(err, ErrDependencyNotFound)
+}
+
+// GetDependencyGraph returns the task with the tasks it depends on and the
+// tasks depending on it, transitively, ordered by id.
+func (s *TaskService) GetDependencyGraph(userID int, id int) (model.DependencyGraph, error) {
+	_, err := s.GetTaskByID(userID, id)
+	if err != nil {
+		return model.DependencyGraph{}, err
+	}
+
+	dependencies, err := s.taskStore.GetTaskDependencies(userID)
+	if err != nil {
+		return model.DependencyGraph{}, err
+	}
+
+	included := reachableTasks(dependencies, id, true)
+	for taskID := range reachableTasks(dependencies, id, false) {
+		included[taskID] = true
+	}
+
+	ids := make([]int, 0, len(included))
+	for taskID := range included {
+		ids = append(ids, taskID)
+	}
+	sort.Ints(ids)
+
+	tasks, err := s.taskStore.GetTasks(userID, model.TaskQuery{IDs: ids, Limit: len(ids)})
+	if err != nil {
+		return model.DependencyGraph{}, err
+	}
+
+	graph := model.DependencyGraph{TaskID: id, Tasks: tasks, Dependencies: []model.TaskDependency{}}
+	for _, dependency := range dependencies {
+		if included[dependency.TaskID] && included[dependency.DependsOnID] {
+			graph.Dependencies = append(graph.Dependencies, dependency)
+		}
+	}
+	return graph, nil
+}
+
+// reachableTasks returns the tasks reachable from id, id included, by
+// following dependencies to the tasks depended on, or with forward unset,
+// back to the tasks depending on them.
+func reachableTasks(dependencies []model.TaskDependency, id int, forward bool) map[int]bool {
+	reached := map[int]bool{id: true}
+	queue := []int{id}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		for _, dependency := range dependencies {
+			from, to := dependency.TaskID, dependency.DependsOnID
+			if !forward {
+				from, to = to, from
+			}
+			if from == current && !reached[to] {
+				reached[to] = true
+				queue = append(queue, to)
+			}
+		}
+	}
+	return reached
+}
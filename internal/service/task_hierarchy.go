@@ -0,0 +1,116 @@
+package service
+
+import (
+	"github.com/absoluteyl/tasks-go/internal/model"
+	"github.com/absoluteyl/tasks-go/internal/repository"
+	"time"
+)
+
+// SetAutoCompleteParents makes tasks move to done by themselves once all
+// their subtasks, not counting cancelled ones, are done.
+func (s *TaskService) SetAutoCompleteParents(autoCompleteParents bool) {
+	s.autoCompleteParents = autoCompleteParents
+}
+
+// GetChildTasks returns a page of the direct subtasks of the task, like
+// GetTasks does for all tasks.
+func (s *TaskService) GetChildTasks(userID int, id int, query model.TaskQuery, cursor string) ([]model.Task, string, error) {
+	_, err := s.GetTaskByID(userID, id)
+	if err != nil {
+		return nil, "", err
+	}
+
+	query.ParentID = &id
+	return s.GetTasks(userID, query, cursor)
+}
+
+// GetTaskSubtree returns the task with its subtasks, recursively, each
+// level ordered by id.
+func (s *TaskService) GetTaskSubtree(userID int, id int) (model.TaskTree, error) {
+	tasks, err := s.taskStore.GetTaskSubtree(userID, id)
+	if err != nil {
+		return model.TaskTree{}, notFoundAs(err, ErrTaskNotFound)
+	}
+
+	var root model.Task
+	children := map[int][]model.Task{}
+	for _, task := range tasks {
+		if task.ID == id {
+			root = task
+			continue
+		}
+		children[*task.ParentID] = append(children[*task.ParentID], task)
+	}
+	return taskTree(root, children), nil
+}
+
+func taskTree(task model.Task, children map[int][]model.Task) model.TaskTree {
+	tree := model.TaskTree{Task: task, Children: []model.TaskTree{}}
+	for _, child := range children[task.ID] {
+		tree.Children = append(tree.Children, taskTree(child, children))
+	}
+	return tree
+}
+
+// checkParent rejects parents the user does not have with
+// ErrParentTaskNotFound, and the task itself or any of its subtasks as
+// parent with ErrTaskCycle.
+func (s *TaskService) checkParent(task *model.Task) error {
+	seen := map[int]bool{}
+	for parentID := task.ParentID; parentID != nil; {
+		if *parentID == task.ID || seen[*parentID] {
+			return ErrTaskCycle
+		}
+		seen[*parentID] = true
+
+		parent, err := s.taskStore.GetTaskByID(task.UserID, *parentID)
+		if err != nil {
+			return notFoundAs(err, ErrParentTaskNotFound)
+		}
+		parentID = parent.ParentID
+	}
+	return nil
+}
+
+// saveTask runs save, and with complete set also completes the ancestors of
+// task that are left with only done subtasks, all in one transaction.
+func (s *TaskService) saveTask(task *model.Task, complete bool, save func(taskStore repository.TaskStore) error) error {
+	completeParents := s.autoCompleteParents && complete && task.ParentID != nil
+
+	return s.taskStore.InTransaction(func(txStore repository.TaskStore) error {
+		err := save(txStore)
+		if err != nil || !completeParents {
+			return err
+		}
+		return s.withStore(txStore).completeParents(task.UserID, *task.ParentID, task.UpdatedAt)
+	})
+}
+
+// completeParents moves the task to done when all its subtasks are done and
+// the transition graph allows it, then goes on with its own parent.
+func (s *TaskService) completeParents(userID int, id int, now time.Time) error {
+	for {
+		parent, err := s.taskStore.GetTaskByID(userID, id)
+		if err != nil {
+			return err
+		}
+
+		done := parent.Progress != nil && *parent.Progress == 100
+		if !done || parent.Status == model.TaskStatusDone || !s.transitions.Allows(parent.Status, model.TaskStatusDone) {
+			return nil
+		}
+
+		parent.Status = model.TaskStatusDone
+		parent.UpdatedAt = now
+		setCompletedAt(&parent, now)
+		err = s.taskStore.UpdateTask(&parent)
+		if err != nil {
+			return err
+		}
+
+		if parent.ParentID == nil {
+			return nil
+		}
+		id = *parent.ParentID
+	}
+}
@@ -0,0 +1,35 @@
+package service
+
+import (
+	"github.com/absoluteyl/tasks-go/internal/model"
+	"github.com/absoluteyl/tasks-go/internal/repository"
+)
+
+// SetProjectStore lets tasks be put into the user's projects. Without it,
+// every project a task names is reported with ErrProjectNotFound.
+func (s *TaskService) SetProjectStore(projectStore repository.ProjectStore) {
+	s.projectStore = projectStore
+}
+
+// checkProject rejects moving the task into a project the user does not
+// have with ErrProjectNotFound, or into an archived one with
+// ErrProjectArchived. Tasks staying in their project pass, archived or not.
+// It must run on the store of the transaction saving the task, which then
+// keeps the project from being archived or deleted before the task is in it.
+func (s *TaskService) checkProject(task *model.Task, currentProjectID *int) error {
+	if task.ProjectID == nil || sameID(task.ProjectID, currentProjectID) {
+		return nil
+	}
+	if s.projectStore == nil {
+		return ErrProjectNotFound
+	}
+
+	project, err := s.projectStore.LockProject(s.taskStore, task.UserID, *task.ProjectID)
+	if err != nil {
+		return notFoundAs(err, ErrProjectNotFound)
+	}
+	if project.ArchivedAt != nil {
+		return ErrProjectArchived
+	}
+	return nil
+}
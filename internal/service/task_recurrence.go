@@ -0,0 +1,66 @@
+package service
+
+import (
+	"errors"
+	"github.com/absoluteyl/tasks-go/internal/model"
+	"time"
+)
+
+// nextOccurrence returns the task to create once the recurring task is
+// done, due one step of its rule after its own due date, or after now when
+// it has none. It returns nil when the rule has no occurrences left. The
+// rule moves on to the new task, with COUNT lowered by one.
+func nextOccurrence(task *model.Task, now time.Time) *model.Task {
+	rule, ok := model.ParseRecurrenceRule(task.Recurrence)
+	if !ok || rule.Count == 1 {
+		return nil
+	}
+
+	start := now
+	if task.DueAt != nil {
+		start = *task.DueAt
+	}
+	dueAt, ok := rule.Next(start)
+	if !ok {
+		return nil
+	}
+	if rule.Count > 1 {
+		rule.Count--
+	}
+
+	return &model.Task{
+		UserID:      task.UserID,
+		Name:        task.Name,
+		Description: task.Description,
+		Status:      model.TaskStatusTodo,
+		Priority:    task.Priority,
+		DueAt:       &dueAt,
+		Tags:        append([]string{}, task.Tags...),
+		ParentID:    task.ParentID,
+		ProjectID:   task.ProjectID,
+		Recurrence:  rule.String(),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		Version:     1,
+	}
+}
+
+// checkOccurrence runs the checks of CreateTask on the next occurrence and
+// leaves out the parent or project it would be rejected for, so that
+// completing a task in an archived project does not add a task to it.
+func (s *TaskService) checkOccurrence(next *model.Task) error {
+	err := s.checkParent(next)
+	if errors.Is(err, ErrParentTaskNotFound) {
+		next.ParentID = nil
+	} else if err != nil {
+		return err
+	}
+
+	err = s.checkProject(next, nil)
+	if errors.Is(err, ErrProjectNotFound) || errors.Is(err, ErrProjectArchived) {
+		next.ProjectID = nil
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
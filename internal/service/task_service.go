@@ -1,34 +1,374 @@
 package service
 
 import (
+	"database/sql"
+	"errors"
 	"github.com/absoluteyl/tasks-go/internal/model"
 	"github.com/absoluteyl/tasks-go/internal/repository"
+	"slices"
+	"sort"
+	"time"
 )
 
+const (
+	DefaultTaskPageSize   = 50
+	MaxTaskPageSize       = 100
+	MaxBulkTaskOperations = 100
+)
+
+const (
+	TaskOperationCreate = "create"
+	TaskOperationUpdate = "update"
+	TaskOperationDelete = "delete"
+)
+
+// TaskOperation is one step of a bulk change. Modify fills in the new task
+// on create and changes the current task on update. A non-zero Version
+// makes updates and deletes fail with ErrTaskVersionConflict unless the
+// task is still at that version.
+type TaskOperation struct {
+	Op      string
+	TaskID  int
+	Version int
+	Modify  func(task *model.Task) error
+}
+
+type TaskOperationResult struct {
+	Task model.Task
+	Err  error
+}
+
 type TaskService struct {
-	taskRepository *repository.TaskRepository
+	taskStore           repository.TaskStore
+	transitions         TaskTransitions
+	autoCompleteParents bool
+	projectStore        repository.ProjectStore
 }
 
-func NewTaskServiceWithRepository(taskRepository *repository.TaskRepository) *TaskService {
-	return &TaskService{taskRepository: taskRepository}
+func NewTaskServiceWithRepository(taskStore repository.TaskStore) *TaskService {
+	return &TaskService{taskStore: taskStore, transitions: DefaultTaskTransitions}
 }
 
-func (s *TaskService) CreateTask(taskName string) (int, error) {
-	return s.taskRepository.CreateTask(taskName)
+func (s *TaskService) SetTransitions(transitions TaskTransitions) {
+	s.transitions = transitions
 }
 
-func (s *TaskService) GetTasks() ([]model.Task, error) {
-	return s.taskRepository.GetTasks()
+func (s *TaskService) CreateTask(task *model.Task) (int, error) {
+	if task.Status == "" {
+		task.Status = model.TaskStatusTodo
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	task.CreatedAt = now
+	task.UpdatedAt = now
+	task.CompletedAt = nil
+	task.Version = 1
+	task.Tags = normalizeTags(task.Tags)
+	setCompletedAt(task, now)
+
+	err := s.checkParent(task)
+	if err != nil {
+		return 0, err
+	}
+
+	var id int
+	err = s.saveTask(task, task.Status == model.TaskStatusDone, func(taskStore repository.TaskStore) error {
+		err := s.withStore(taskStore).checkProject(task, nil)
+		if err != nil {
+			return err
+		}
+
+		id, err = taskStore.CreateTask(task)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
 }
 
+// GetTasks returns a page of the user's tasks matching query and following
+// cursor, which is empty for the first page. The returned cursor points to
+// the next page and is empty on the last one.
+func (s *TaskService) GetTasks(userID int, query model.TaskQuery, cursor string) ([]model.Task, string, error) {
+	if query.Limit <= 0 {
+		query.Limit = DefaultTaskPageSize
+	}
+	if query.Limit > MaxTaskPageSize {
+		query.Limit = MaxTaskPageSize
+	}
+	limit := query.Limit
+
+	if cursor != "" {
+		err := applyTaskCursor(&query, cursor)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	query.Limit = limit + 1
+	tasks, err := s.taskStore.GetTasks(userID, query)
+	if errors.Is(err, repository.ErrInvalidSortValues) {
+		return nil, "", ErrInvalidCursor
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	if len(tasks) <= limit {
+		return tasks, "", nil
+	}
+
+	tasks = tasks[:limit]
+	return tasks, encodeTaskCursor(tasks[limit-1], query.Sort), nil
+}
+
+// SearchTasks returns up to limit of the user's tasks matching query, most
+// relevant first. The limit is capped like the page size of GetTasks.
+func (s *TaskService) SearchTasks(userID int, query string, limit int) ([]model.TaskSearchResult, error) {
+	if limit <= 0 {
+		limit = DefaultTaskPageSize
+	}
+	if limit > MaxTaskPageSize {
+		limit = MaxTaskPageSize
+	}
+
+	results, err := s.taskStore.SearchTasks(userID, query, limit)
+	if errors.Is(err, repository.ErrInvalidSearchQuery) {
+		return nil, ErrInvalidSearchQuery
+	}
+	return results, err
+}
+
+// UpdateTask rejects status changes that are not part of the transition
+// graph with ErrInvalidStatusTransition. The task must carry the version it
+// was read at; ErrTaskVersionConflict reports that it changed since. A new
+// parent must not make the hierarchy a cycle, and a new project must not be
+// archived. Completing a recurring task creates its next occurrence.
 func (s *TaskService) UpdateTask(task *model.Task) error {
-	return s.taskRepository.UpdateTask(task)
+	existingTask, err := s.taskStore.GetTaskByID(task.UserID, task.ID)
+	if err != nil {
+		return notFoundAs(err, ErrTaskNotFound)
+	}
+	if existingTask.Version != task.Version {
+		return ErrTaskVersionConflict
+	}
+	if !s.transitions.Allows(existingTask.Status, task.Status) {
+		return ErrInvalidStatusTransition
+	}
+
+	moved := !sameID(existingTask.ParentID, task.ParentID)
+	now := time.Now().UTC().Truncate(time.Second)
+	task.UpdatedAt = now
+	task.Tags = normalizeTags(task.Tags)
+	setCompletedAt(task, now)
+
+	// A recurring task hands its rule over to its next occurrence, so that
+	// reopening and completing it again does not recur twice.
+	var next *model.Task
+	if task.Status == model.TaskStatusDone && existingTask.Status != model.TaskStatusDone && task.Recurrence != "" {
+		next = nextOccurrence(task, now)
+		task.Recurrence = ""
+	}
+
+	completed := task.Status == model.TaskStatusDone && (existingTask.Status != model.TaskStatusDone || moved)
+	err = s.saveTask(task, completed, func(taskStore repository.TaskStore) error {
+		txService := s.withStore(taskStore)
+		if moved {
+			err := taskStore.LockUserTasks(task.UserID)
+			if err != nil {
+				return err
+			}
+			err = txService.checkParent(task)
+			if err != nil {
+				return err
+			}
+		}
+
+		err := txService.checkProject(task, existingTask.ProjectID)
+		if err != nil {
+			return err
+		}
+
+		err = taskStore.UpdateTask(task)
+		if err != nil || next == nil {
+			return err
+		}
+
+		err = txService.checkOccurrence(next)
+		if err != nil {
+			return err
+		}
+		_, err = taskStore.CreateTask(next)
+		return err
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return s.conflictOrNotFound(task.UserID, task.ID)
+	}
+	return err
+}
+
+func (s *TaskService) DeleteTask(userID int, id int) error {
+	err := s.taskStore.DeleteTask(userID, id)
+	return notFoundAs(err, ErrTaskNotFound)
+}
+
+// DeleteTaskAtVersion deletes the task only if it is still at version.
+func (s *TaskService) DeleteTaskAtVersion(userID int, id int, version int) error {
+	err := s.taskStore.DeleteTaskAtVersion(userID, id, version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return s.conflictOrNotFound(userID, id)
+	}
+	return err
+}
+
+func (s *TaskService) GetTaskByID(userID int, id int) (model.Task, error) {
+	task, err := s.taskStore.GetTaskByID(userID, id)
+	return task, notFoundAs(err, ErrTaskNotFound)
+}
+
+// BulkTasks runs the operations on the user's tasks in one transaction and
+// reports the outcome of each. In all-or-nothing mode the first failure
+// rolls back every operation and the others report ErrBulkRolledBack;
+// otherwise only the failed operations are rolled back. The returned error
+// is reserved for failures of the bulk request as a whole.
+func (s *TaskService) BulkTasks(userID int, operations []TaskOperation, allOrNothing bool) ([]TaskOperationResult, error) {
+	if len(operations) > MaxBulkTaskOperations {
+		return nil, ErrTooManyTaskOperations
+	}
+
+	results := make([]TaskOperationResult, len(operations))
+	failed := false
+	err := s.taskStore.InTransaction(func(txStore repository.TaskStore) error {
+		for i, operation := range operations {
+			if allOrNothing {
+				results[i] = s.withStore(txStore).runOperation(userID, operation)
+				if results[i].Err != nil {
+					failed = true
+					return results[i].Err
+				}
+				continue
+			}
+
+			err := txStore.InTransaction(func(operationStore repository.TaskStore) error {
+				results[i] = s.withStore(operationStore).runOperation(userID, operation)
+				return results[i].Err
+			})
+			if err != nil && err != results[i].Err {
+				return err
+			}
+		}
+		return nil
+	})
+
+	if failed {
+		for i := range results {
+			if results[i].Err == nil {
+				results[i] = TaskOperationResult{Err: ErrBulkRolledBack}
+			}
+		}
+		return results, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func (s *TaskService) runOperation(userID int, operation TaskOperation) TaskOperationResult {
+	switch operation.Op {
+	case TaskOperationCreate:
+		task := model.Task{UserID: userID}
+		err := operation.Modify(&task)
+		if err != nil {
+			return TaskOperationResult{Err: err}
+		}
+
+		task.ID, err = s.CreateTask(&task)
+		return TaskOperationResult{Task: task, Err: err}
+	case TaskOperationUpdate:
+		task, err := s.GetTaskByID(userID, operation.TaskID)
+		if err != nil {
+			return TaskOperationResult{Err: err}
+		}
+		if operation.Version != 0 && operation.Version != task.Version {
+			return TaskOperationResult{Err: ErrTaskVersionConflict}
+		}
+
+		err = operation.Modify(&task)
+		if err != nil {
+			return TaskOperationResult{Err: err}
+		}
+
+		err = s.UpdateTask(&task)
+		return TaskOperationResult{Task: task, Err: err}
+	case TaskOperationDelete:
+		var err error
+		if operation.Version != 0 {
+			err = s.DeleteTaskAtVersion(userID, operation.TaskID, operation.Version)
+		} else {
+			err = s.DeleteTask(userID, operation.TaskID)
+		}
+		return TaskOperationResult{Task: model.Task{ID: operation.TaskID, UserID: userID}, Err: err}
+	}
+
+	return TaskOperationResult{Err: ErrInvalidTaskOperation}
+}
+
+func (s *TaskService) withStore(taskStore repository.TaskStore) *TaskService {
+	return &TaskService{
+		taskStore:           taskStore,
+		transitions:         s.transitions,
+		autoCompleteParents: s.autoCompleteParents,
+		projectStore:        s.projectStore,
+	}
+}
+
+// normalizeTags sorts tags and drops duplicates, the way the stores return
+// them.
+func normalizeTags(tags []string) []string {
+	normalized := []string{}
+	for _, tag := range tags {
+		if !slices.Contains(normalized, tag) {
+			normalized = append(normalized, tag)
+		}
+	}
+	sort.Strings(normalized)
+	return normalized
+}
+
+func sameID(a *int, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// setCompletedAt stamps tasks that just became done and clears the stamp of
+// tasks that were reopened.
+func setCompletedAt(task *model.Task, now time.Time) {
+	if task.Status != model.TaskStatusDone {
+		task.CompletedAt = nil
+		return
+	}
+	if task.CompletedAt == nil {
+		task.CompletedAt = &now
+	}
 }
 
-func (s *TaskService) DeleteTask(id int) error {
-	return s.taskRepository.DeleteTask(id)
+// conflictOrNotFound tells apart why a versioned write affected no rows.
+func (s *TaskService) conflictOrNotFound(userID int, id int) error {
+	_, err := s.taskStore.GetTaskByID(userID, id)
+	if err != nil {
+		return notFoundAs(err, ErrTaskNotFound)
+	}
+	return ErrTaskVersionConflict
 }
 
-func (s *TaskService) GetTaskByID(id int) (model.Task, error) {
-	return s.taskRepository.GetTaskByID(id)
+func notFoundAs(err error, target error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return target
+	}
+	return err
 }
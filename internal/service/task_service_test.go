@@ -5,7 +5,9 @@ import (
 	"fmt"
 	. "github.com/absoluteyl/tasks-go/pkg/testutils"
 	"os"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/absoluteyl/tasks-go/internal/model"
 	"github.com/absoluteyl/tasks-go/internal/repository"
@@ -16,13 +18,24 @@ import (
 var testDB *sql.DB
 var taskRepo *repository.TaskRepository
 var taskService *TaskService
+var userRepo *repository.UserRepository
+var userService *UserService
+var refreshTokenRepo *repository.RefreshTokenRepository
+var revokedTokenRepo *repository.RevokedTokenRepository
+var tokenService *TokenService
+var apiKeyService *APIKeyService
+var idempotencyService *IdempotencyService
 
 var taskData = model.Task{
-	ID:     1,
-	Name:   "Eat Dinner",
-	Status: 0,
+	ID:      1,
+	UserID:  1,
+	Name:    "Eat Dinner",
+	Status:  model.TaskStatusTodo,
+	Version: 1,
 }
 
+const otherUserID = 2
+
 func TestMain(m *testing.M) {
 	t := &testing.T{}
 	setup(t)
@@ -38,13 +51,32 @@ func setup(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	err = PrepareTaskTable(testDB)
+	err = MigrateDB(testDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = repository.EnsureTaskSearchIndex(testDB)
 	if err != nil {
 		t.Fatal(err)
 	}
 
 	taskRepo = repository.NewTaskRepository(testDB)
 	taskService = NewTaskServiceWithRepository(taskRepo)
+	userRepo = repository.NewUserRepository(testDB)
+	userService = NewUserServiceWithRepository(userRepo)
+	refreshTokenRepo = repository.NewRefreshTokenRepository(testDB)
+	revokedTokenRepo = repository.NewRevokedTokenRepository(testDB)
+	tokenService = NewTokenServiceWithRepositories(userRepo, refreshTokenRepo, revokedTokenRepo)
+	apiKeyService = NewAPIKeyServiceWithRepositories(repository.NewAPIKeyRepository(testDB), userRepo)
+	idempotencyService = NewIdempotencyServiceWithRepository(repository.NewIdempotencyKeyRepository(testDB))
+
+	for _, username := range []string{"owner", "other"} {
+		_, err = userRepo.CreateUser(username, "hashed-password")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
 }
 
 func teardown() {
@@ -56,21 +88,59 @@ func teardown() {
 
 func TestTaskService(t *testing.T) {
 	t.Run("Create", testCreate)
+	t.Run("CreateDone", testCreateDone)
 	t.Run("GetList", testGetList)
+	t.Run("GetListPaginated", testGetListPaginated)
+	t.Run("GetListInvalidCursor", testGetListInvalidCursor)
+	t.Run("Search", testSearch)
+	t.Run("SearchInvalidQuery", testSearchInvalidQuery)
+	t.Run("GetListOtherUser", testGetListOtherUser)
+	t.Run("GetByIDOtherUser", testGetByIDOtherUser)
 	t.Run("Update", testUpdate)
+	t.Run("InvalidTransition", testInvalidTransition)
+	t.Run("Reopen", testReopen)
+	t.Run("CustomTransitions", testCustomTransitions)
+	t.Run("UpdateOtherUser", testUpdateOtherUser)
+	t.Run("UpdateStaleVersion", testUpdateStaleVersion)
+	t.Run("DeleteOtherUser", testDeleteOtherUser)
+	t.Run("DeleteStaleVersion", testDeleteStaleVersion)
 	t.Run("Delete", testDelete)
+	t.Run("BulkAllOrNothing", testBulkAllOrNothing)
+	t.Run("BulkBestEffort", testBulkBestEffort)
+	t.Run("BulkTooManyOperations", testBulkTooManyOperations)
+	t.Run("BulkMemoryStore", testBulkMemoryStore)
+	t.Run("Tags", testTags)
+	t.Run("Subtasks", testSubtasks)
+	t.Run("AutoCompleteParents", testAutoCompleteParents)
+	t.Run("Dependencies", testDependencies)
+	t.Run("RecurringTasks", testRecurringTasks)
+	t.Run("RecurringTasksTimeZone", testRecurringTasksTimeZone)
 }
 
 func testCreate(t *testing.T) {
-	taskName := taskData.Name
+	task := model.Task{UserID: taskData.UserID, Name: taskData.Name}
 
-	taskID, err := taskService.CreateTask(taskName)
+	taskID, err := taskService.CreateTask(&task)
 	assert.NoError(t, err)
 	assert.NotZero(t, taskID)
+	assert.False(t, task.CreatedAt.IsZero())
+	assert.Equal(t, task.CreatedAt, task.UpdatedAt)
+	assert.Nil(t, task.CompletedAt)
+}
+
+func testCreateDone(t *testing.T) {
+	task := model.Task{UserID: otherUserID, Name: "Already Done", Status: model.TaskStatusDone}
+
+	taskID, err := taskService.CreateTask(&task)
+	assert.NoError(t, err)
+	assert.NotNil(t, task.CompletedAt)
+
+	err = taskService.DeleteTask(otherUserID, taskID)
+	assert.NoError(t, err)
 }
 
 func testGetList(t *testing.T) {
-	tasks, err := taskService.GetTasks()
+	tasks, nextCursor, err := taskService.GetTasks(taskData.UserID, model.TaskQuery{}, "")
 	assert.NoError(t, err)
 	assert.NotZero(t, len(tasks))
 	assert.Len(t, tasks, 1)
@@ -78,29 +148,573 @@ func testGetList(t *testing.T) {
 	assert.Equal(t, taskData.ID, tasks[0].ID)
 	assert.Equal(t, taskData.Name, tasks[0].Name)
 	assert.Equal(t, taskData.Status, tasks[0].Status)
+	assert.Empty(t, nextCursor)
+}
+
+func testGetListPaginated(t *testing.T) {
+	const pagedUserID = 3
+	for i := 0; i < 5; i++ {
+		_, err := taskService.CreateTask(&model.Task{UserID: pagedUserID, Name: fmt.Sprintf("Task %d", i)})
+		assert.NoError(t, err)
+	}
+
+	var names []string
+	cursor := ""
+	for page := 0; page < 3; page++ {
+		tasks, nextCursor, err := taskService.GetTasks(pagedUserID, model.TaskQuery{Limit: 2}, cursor)
+		assert.NoError(t, err)
+		for _, task := range tasks {
+			names = append(names, task.Name)
+		}
+		if page < 2 {
+			assert.Len(t, tasks, 2)
+			assert.NotEmpty(t, nextCursor)
+		} else {
+			assert.Len(t, tasks, 1)
+			assert.Empty(t, nextCursor)
+		}
+		cursor = nextCursor
+	}
+	assert.Equal(t, []string{"Task 0", "Task 1", "Task 2", "Task 3", "Task 4"}, names)
+
+	tasks, _, err := taskService.GetTasks(pagedUserID, model.TaskQuery{Limit: MaxTaskPageSize + 1}, "")
+	assert.NoError(t, err)
+	assert.Len(t, tasks, 5)
+
+	sort, _ := model.ParseTaskSort("-name")
+	tasks, cursor, err = taskService.GetTasks(pagedUserID, model.TaskQuery{Sort: sort, Limit: 3}, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "Task 4", tasks[0].Name)
+
+	tasks, _, err = taskService.GetTasks(pagedUserID, model.TaskQuery{Sort: sort, Limit: 3}, cursor)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Task 1", "Task 0"}, []string{tasks[0].Name, tasks[1].Name})
+
+	_, _, err = taskService.GetTasks(pagedUserID, model.TaskQuery{Limit: 3}, cursor)
+	assert.Equal(t, ErrInvalidCursor, err, "Cursor accepted for a different sort order")
+}
+
+func testGetListInvalidCursor(t *testing.T) {
+	_, _, err := taskService.GetTasks(taskData.UserID, model.TaskQuery{}, "not-a-cursor")
+	assert.Equal(t, ErrInvalidCursor, err)
+}
+
+func testSearch(t *testing.T) {
+	results, err := taskService.SearchTasks(taskData.UserID, "din*", 0)
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+	assert.Equal(t, taskData.ID, results[0].Task.ID)
+	assert.Equal(t, "Eat <mark>Dinner</mark>", results[0].Highlight)
+
+	results, err = taskService.SearchTasks(otherUserID, "dinner", 0)
+	assert.NoError(t, err)
+	assert.Empty(t, results)
+}
+
+func testSearchInvalidQuery(t *testing.T) {
+	_, err := taskService.SearchTasks(taskData.UserID, `" "`, 0)
+	assert.Equal(t, ErrInvalidSearchQuery, err)
 }
 
 func testUpdate(t *testing.T) {
 	taskData.Name = "Eat Lunch"
-	taskData.Status = 1
+	taskData.Status = model.TaskStatusDone
 
 	err := taskService.UpdateTask(&taskData)
 	assert.NoError(t, err)
 
-	updatedTask, err := taskService.GetTaskByID(taskData.ID)
+	updatedTask, err := taskService.GetTaskByID(taskData.UserID, taskData.ID)
 	assert.NoError(t, err)
 	assert.NotEmpty(t, updatedTask)
 
 	assert.Equal(t, taskData.ID, updatedTask.ID)
 	assert.Equal(t, taskData.Name, updatedTask.Name)
 	assert.Equal(t, taskData.Status, updatedTask.Status)
+	assert.NotNil(t, updatedTask.CompletedAt, "Completion time not set")
+	assert.Equal(t, taskData.CompletedAt, updatedTask.CompletedAt)
+}
+
+func testInvalidTransition(t *testing.T) {
+	task := taskData
+	task.Status = model.TaskStatusInProgress
+
+	err := taskService.UpdateTask(&task)
+	assert.Equal(t, ErrInvalidStatusTransition, err)
+
+	unchangedTask, err := taskService.GetTaskByID(taskData.UserID, taskData.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, model.TaskStatusDone, unchangedTask.Status)
+}
+
+func testCustomTransitions(t *testing.T) {
+	taskService.SetTransitions(TaskTransitions{
+		model.TaskStatusTodo: {model.TaskStatusDone},
+	})
+	defer taskService.SetTransitions(DefaultTaskTransitions)
+
+	task := taskData
+	task.Status = model.TaskStatusInProgress
+	err := taskService.UpdateTask(&task)
+	assert.Equal(t, ErrInvalidStatusTransition, err)
+
+	task.Status = model.TaskStatusDone
+	err = taskService.UpdateTask(&task)
+	assert.NoError(t, err)
+	taskData = task
+}
+
+func testReopen(t *testing.T) {
+	taskData.Status = model.TaskStatusTodo
+
+	err := taskService.UpdateTask(&taskData)
+	assert.NoError(t, err)
+
+	reopenedTask, err := taskService.GetTaskByID(taskData.UserID, taskData.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, model.TaskStatusTodo, reopenedTask.Status)
+	assert.Nil(t, reopenedTask.CompletedAt, "Completion time not cleared")
+}
+
+func testUpdateStaleVersion(t *testing.T) {
+	task := taskData
+	task.Version--
+	task.Name = "Eat Brunch"
+
+	err := taskService.UpdateTask(&task)
+	assert.Equal(t, ErrTaskVersionConflict, err)
+
+	unchangedTask, err := taskService.GetTaskByID(taskData.UserID, taskData.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, taskData.Name, unchangedTask.Name)
+}
+
+func testDeleteStaleVersion(t *testing.T) {
+	err := taskService.DeleteTaskAtVersion(taskData.UserID, taskData.ID, taskData.Version-1)
+	assert.Equal(t, ErrTaskVersionConflict, err)
+
+	err = taskService.DeleteTaskAtVersion(otherUserID, taskData.ID, taskData.Version)
+	assert.Equal(t, ErrTaskNotFound, err)
 }
 
 func testDelete(t *testing.T) {
-	err := taskService.DeleteTask(taskData.ID)
+	err := taskService.DeleteTask(taskData.UserID, taskData.ID)
 	assert.NoError(t, err)
 
-	_, err = taskService.GetTaskByID(taskData.ID)
+	_, err = taskService.GetTaskByID(taskData.UserID, taskData.ID)
 	assert.Error(t, err)
-	assert.Equal(t, sql.ErrNoRows, err)
+	assert.Equal(t, ErrTaskNotFound, err)
+}
+
+func testGetListOtherUser(t *testing.T) {
+	tasks, _, err := taskService.GetTasks(otherUserID, model.TaskQuery{}, "")
+	assert.NoError(t, err)
+	assert.Empty(t, tasks)
+}
+
+func testGetByIDOtherUser(t *testing.T) {
+	_, err := taskService.GetTaskByID(otherUserID, taskData.ID)
+	assert.Equal(t, ErrTaskNotFound, err)
+}
+
+func testUpdateOtherUser(t *testing.T) {
+	task := taskData
+	task.UserID = otherUserID
+	task.Name = "Steal Lunch"
+
+	err := taskService.UpdateTask(&task)
+	assert.Equal(t, ErrTaskNotFound, err)
+
+	unchangedTask, err := taskService.GetTaskByID(taskData.UserID, taskData.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, taskData.Name, unchangedTask.Name)
+}
+
+func testDeleteOtherUser(t *testing.T) {
+	err := taskService.DeleteTask(otherUserID, taskData.ID)
+	assert.Equal(t, ErrTaskNotFound, err)
+
+	_, err = taskService.GetTaskByID(taskData.UserID, taskData.ID)
+	assert.NoError(t, err)
+}
+
+const bulkUserID = 4
+
+func renameTo(name string) func(task *model.Task) error {
+	return func(task *model.Task) error {
+		task.Name = name
+		return nil
+	}
+}
+
+func testBulkAllOrNothing(t *testing.T) {
+	taskID, err := taskService.CreateTask(&model.Task{UserID: bulkUserID, Name: "Existing"})
+	assert.NoError(t, err)
+
+	results, err := taskService.BulkTasks(bulkUserID, []TaskOperation{
+		{Op: TaskOperationCreate, Modify: renameTo("Created")},
+		{Op: TaskOperationUpdate, TaskID: taskID, Modify: renameTo("Renamed")},
+		{Op: TaskOperationDelete, TaskID: 999},
+		{Op: TaskOperationDelete, TaskID: taskID},
+	}, true)
+	assert.NoError(t, err)
+	assert.Len(t, results, 4)
+	assert.Equal(t, ErrBulkRolledBack, results[0].Err)
+	assert.Equal(t, ErrBulkRolledBack, results[1].Err)
+	assert.Equal(t, ErrTaskNotFound, results[2].Err)
+	assert.Equal(t, ErrBulkRolledBack, results[3].Err)
+
+	tasks, _, err := taskService.GetTasks(bulkUserID, model.TaskQuery{}, "")
+	assert.NoError(t, err)
+	assert.Len(t, tasks, 1, "Operations not rolled back")
+	assert.Equal(t, "Existing", tasks[0].Name)
+
+	results, err = taskService.BulkTasks(bulkUserID, []TaskOperation{
+		{Op: TaskOperationCreate, Modify: renameTo("Created")},
+		{Op: TaskOperationUpdate, TaskID: taskID, Version: 1, Modify: renameTo("Renamed")},
+	}, true)
+	assert.NoError(t, err)
+	assert.NoError(t, results[0].Err)
+	assert.NotZero(t, results[0].Task.ID)
+	assert.Equal(t, "Created", results[0].Task.Name)
+	assert.NoError(t, results[1].Err)
+	assert.Equal(t, 2, results[1].Task.Version)
+
+	tasks, _, err = taskService.GetTasks(bulkUserID, model.TaskQuery{}, "")
+	assert.NoError(t, err)
+	assert.Len(t, tasks, 2)
+	assert.Equal(t, "Renamed", tasks[0].Name)
+}
+
+func testBulkBestEffort(t *testing.T) {
+	tasks, _, err := taskService.GetTasks(bulkUserID, model.TaskQuery{}, "")
+	assert.NoError(t, err)
+
+	results, err := taskService.BulkTasks(bulkUserID, []TaskOperation{
+		{Op: TaskOperationUpdate, TaskID: tasks[0].ID, Version: 1, Modify: renameTo("Stale")},
+		{Op: TaskOperationUpdate, TaskID: tasks[1].ID, Modify: func(task *model.Task) error {
+			task.Name = "Half Done"
+			task.Status = model.TaskStatusBlocked
+			return nil
+		}},
+		{Op: TaskOperationDelete, TaskID: tasks[0].ID},
+	}, false)
+	assert.NoError(t, err)
+	assert.Equal(t, ErrTaskVersionConflict, results[0].Err)
+	assert.NoError(t, results[1].Err)
+	assert.NoError(t, results[2].Err)
+
+	remaining, _, err := taskService.GetTasks(bulkUserID, model.TaskQuery{}, "")
+	assert.NoError(t, err)
+	assert.Len(t, remaining, 1)
+	assert.Equal(t, "Half Done", remaining[0].Name)
+}
+
+func testBulkMemoryStore(t *testing.T) {
+	memoryService := NewTaskServiceWithRepository(repository.NewMemoryTaskStore())
+	taskID, err := memoryService.CreateTask(&model.Task{UserID: bulkUserID, Name: "Existing"})
+	assert.NoError(t, err)
+
+	results, err := memoryService.BulkTasks(bulkUserID, []TaskOperation{
+		{Op: TaskOperationCreate, Modify: renameTo("Created")},
+		{Op: TaskOperationUpdate, TaskID: taskID, Version: 2, Modify: renameTo("Renamed")},
+	}, true)
+	assert.NoError(t, err)
+	assert.Equal(t, ErrBulkRolledBack, results[0].Err)
+	assert.Equal(t, ErrTaskVersionConflict, results[1].Err)
+
+	tasks, _, err := memoryService.GetTasks(bulkUserID, model.TaskQuery{}, "")
+	assert.NoError(t, err)
+	assert.Len(t, tasks, 1, "Operations not rolled back")
+	assert.Equal(t, "Existing", tasks[0].Name)
+}
+
+func testBulkTooManyOperations(t *testing.T) {
+	operations := make([]TaskOperation, MaxBulkTaskOperations+1)
+	_, err := taskService.BulkTasks(bulkUserID, operations, true)
+	assert.Equal(t, ErrTooManyTaskOperations, err)
+}
+
+func testTags(t *testing.T) {
+	const taggedUserID = 4
+
+	task := model.Task{UserID: taggedUserID, Name: "Deploy", Tags: []string{"urgent", "backend", "urgent"}}
+	taskID, err := taskService.CreateTask(&task)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"backend", "urgent"}, task.Tags)
+
+	tag := model.Tag{UserID: taggedUserID, Name: "backend"}
+	_, err = taskService.CreateTag(&tag)
+	assert.Equal(t, ErrTagExists, err)
+
+	tags, err := taskService.GetTags(taggedUserID)
+	assert.NoError(t, err)
+	assert.Len(t, tags, 2)
+
+	_, err = taskService.RenameTag(taggedUserID, tags[0].ID, "urgent")
+	assert.Equal(t, ErrTagExists, err)
+
+	_, err = taskService.RenameTag(otherUserID, tags[0].ID, "api")
+	assert.Equal(t, ErrTagNotFound, err)
+
+	renamed, err := taskService.RenameTag(taggedUserID, tags[0].ID, "api")
+	assert.NoError(t, err)
+	assert.Equal(t, "api", renamed.Name)
+
+	err = taskService.DeleteTag(taggedUserID, tags[1].ID)
+	assert.NoError(t, err)
+
+	err = taskService.DeleteTag(taggedUserID, tags[1].ID)
+	assert.Equal(t, ErrTagNotFound, err)
+
+	task, err = taskService.GetTaskByID(taggedUserID, taskID)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"api"}, task.Tags)
+}
+
+const hierarchyUserID = 5
+
+func testSubtasks(t *testing.T) {
+	missingID := 9999
+	_, err := taskService.CreateTask(&model.Task{UserID: hierarchyUserID, Name: "Orphan", ParentID: &missingID})
+	assert.Equal(t, ErrParentTaskNotFound, err)
+
+	root := model.Task{UserID: hierarchyUserID, Name: "Release"}
+	root.ID, err = taskService.CreateTask(&root)
+	assert.NoError(t, err)
+
+	_, err = taskService.CreateTask(&model.Task{UserID: otherUserID, Name: "Intruder", ParentID: &root.ID})
+	assert.Equal(t, ErrParentTaskNotFound, err)
+
+	child := model.Task{UserID: hierarchyUserID, Name: "Write changelog", ParentID: &root.ID}
+	child.ID, err = taskService.CreateTask(&child)
+	assert.NoError(t, err)
+
+	grandchild := model.Task{UserID: hierarchyUserID, Name: "Collect PRs", ParentID: &child.ID}
+	grandchild.ID, err = taskService.CreateTask(&grandchild)
+	assert.NoError(t, err)
+
+	// New subtasks change the progress of every task above them.
+	root, err = taskService.GetTaskByID(hierarchyUserID, root.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, root.Version)
+
+	child, err = taskService.GetTaskByID(hierarchyUserID, child.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, child.Version)
+
+	root.ParentID = &grandchild.ID
+	err = taskService.UpdateTask(&root)
+	assert.Equal(t, ErrTaskCycle, err)
+
+	child.ParentID = &child.ID
+	err = taskService.UpdateTask(&child)
+	assert.Equal(t, ErrTaskCycle, err)
+
+	children, _, err := taskService.GetChildTasks(hierarchyUserID, root.ID, model.TaskQuery{}, "")
+	assert.NoError(t, err)
+	assert.Len(t, children, 1)
+	assert.Equal(t, "Write changelog", children[0].Name)
+
+	_, _, err = taskService.GetChildTasks(otherUserID, root.ID, model.TaskQuery{}, "")
+	assert.Equal(t, ErrTaskNotFound, err)
+
+	tree, err := taskService.GetTaskSubtree(hierarchyUserID, root.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Release", tree.Name)
+	assert.Equal(t, "Write changelog", tree.Children[0].Name)
+	assert.Equal(t, "Collect PRs", tree.Children[0].Children[0].Name)
+	assert.Empty(t, tree.Children[0].Children[0].Children)
+
+	_, err = taskService.GetTaskSubtree(otherUserID, root.ID)
+	assert.Equal(t, ErrTaskNotFound, err)
+}
+
+func testAutoCompleteParents(t *testing.T) {
+	taskService.SetAutoCompleteParents(true)
+	defer taskService.SetAutoCompleteParents(false)
+
+	root := model.Task{UserID: hierarchyUserID, Name: "Launch"}
+	rootID, err := taskService.CreateTask(&root)
+	assert.NoError(t, err)
+
+	var children []model.Task
+	for _, name := range []string{"Build", "Test", "Translate"} {
+		child := model.Task{UserID: hierarchyUserID, Name: name, ParentID: &rootID}
+		child.ID, err = taskService.CreateTask(&child)
+		assert.NoError(t, err)
+		children = append(children, child)
+	}
+
+	children[0].Status = model.TaskStatusDone
+	err = taskService.UpdateTask(&children[0])
+	assert.NoError(t, err)
+
+	children[2].Status = model.TaskStatusCancelled
+	err = taskService.UpdateTask(&children[2])
+	assert.NoError(t, err)
+
+	root, err = taskService.GetTaskByID(hierarchyUserID, rootID)
+	assert.NoError(t, err)
+	assert.Equal(t, model.TaskStatusTodo, root.Status)
+	assert.Equal(t, 50, *root.Progress)
+
+	children[1].Status = model.TaskStatusDone
+	err = taskService.UpdateTask(&children[1])
+	assert.NoError(t, err)
+
+	root, err = taskService.GetTaskByID(hierarchyUserID, rootID)
+	assert.NoError(t, err)
+	assert.Equal(t, model.TaskStatusDone, root.Status, "Parent not completed")
+	assert.NotNil(t, root.CompletedAt)
+	assert.Equal(t, 100, *root.Progress)
+}
+
+func testDependencies(t *testing.T) {
+	const dependencyUserID = 6
+
+	var ids []int
+	for _, name := range []string{"Design", "Implement", "Release", "Announce"} {
+		id, err := taskService.CreateTask(&model.Task{UserID: dependencyUserID, Name: name})
+		assert.NoError(t, err)
+		ids = append(ids, id)
+	}
+	dependency := func(taskID int, dependsOnID int) model.TaskDependency {
+		return model.TaskDependency{UserID: dependencyUserID, TaskID: taskID, DependsOnID: dependsOnID}
+	}
+
+	for _, d := range []model.TaskDependency{dependency(ids[1], ids[0]), dependency(ids[2], ids[1])} {
+		err := taskService.AddTaskDependency(d)
+		assert.NoError(t, err)
+	}
+
+	err := taskService.AddTaskDependency(dependency(ids[1], ids[0]))
+	assert.Equal(t, ErrDependencyExists, err)
+
+	err = taskService.AddTaskDependency(dependency(ids[0], ids[2]))
+	assert.Equal(t, ErrDependencyCycle, err)
+
+	err = taskService.AddTaskDependency(dependency(ids[3], ids[3]))
+	assert.Equal(t, ErrDependencyCycle, err)
+
+	err = taskService.AddTaskDependency(dependency(ids[3], 9999))
+	assert.Equal(t, ErrDependencyTaskNotFound, err)
+
+	err = taskService.AddTaskDependency(model.TaskDependency{UserID: otherUserID, TaskID: ids[3], DependsOnID: ids[2]})
+	assert.Equal(t, ErrTaskNotFound, err)
+
+	graph, err := taskService.GetDependencyGraph(dependencyUserID, ids[1])
+	assert.NoError(t, err)
+	assert.Equal(t, ids[1], graph.TaskID)
+	assert.Len(t, graph.Tasks, 3)
+	assert.Equal(t, []model.TaskDependency{dependency(ids[1], ids[0]), dependency(ids[2], ids[1])}, graph.Dependencies)
+	assert.True(t, graph.Tasks[1].Blocked)
+
+	_, err = taskService.GetDependencyGraph(otherUserID, ids[1])
+	assert.Equal(t, ErrTaskNotFound, err)
+
+	err = taskService.RemoveTaskDependency(dependency(ids[1], ids[0]))
+	assert.NoError(t, err)
+
+	err = taskService.RemoveTaskDependency(dependency(ids[1], ids[0]))
+	assert.Equal(t, ErrDependencyNotFound, err)
+
+	graph, err = taskService.GetDependencyGraph(dependencyUserID, ids[0])
+	assert.NoError(t, err)
+	assert.Len(t, graph.Tasks, 1)
+	assert.Empty(t, graph.Dependencies)
+
+	errs := make(chan error, 2)
+	var wg sync.WaitGroup
+	for _, d := range []model.TaskDependency{dependency(ids[0], ids[3]), dependency(ids[3], ids[0])} {
+		wg.Add(1)
+		go func(d model.TaskDependency) {
+			defer wg.Done()
+			errs <- taskService.AddTaskDependency(d)
+		}(d)
+	}
+	wg.Wait()
+	close(errs)
+	assert.ElementsMatch(t, []error{nil, ErrDependencyCycle}, []error{<-errs, <-errs})
+}
+
+// testRecurringTasks completes a monthly task with two occurrences left
+// twice, reopening it once in between.
+func testRecurringTasks(t *testing.T) {
+	const recurrenceUserID = 8
+
+	dueAt := time.Date(2024, time.January, 31, 9, 0, 0, 0, time.UTC)
+	task := model.Task{
+		UserID: recurrenceUserID, Name: "Pay rent", DueAt: &dueAt, Tags: []string{"home"},
+		Recurrence: "FREQ=MONTHLY;COUNT=2",
+	}
+	taskID, err := taskService.CreateTask(&task)
+	assert.NoError(t, err)
+
+	complete := func(id int, status model.TaskStatus) model.Task {
+		task, err := taskService.GetTaskByID(recurrenceUserID, id)
+		assert.NoError(t, err)
+		task.Status = status
+		err = taskService.UpdateTask(&task)
+		assert.NoError(t, err)
+		return task
+	}
+	todo := func() []model.Task {
+		query := model.TaskQuery{Statuses: []model.TaskStatus{model.TaskStatusTodo}}
+		tasks, _, err := taskService.GetTasks(recurrenceUserID, query, "")
+		assert.NoError(t, err)
+		return tasks
+	}
+
+	completed := complete(taskID, model.TaskStatusDone)
+	assert.Empty(t, completed.Recurrence)
+
+	next := todo()
+	assert.Len(t, next, 1)
+	assert.Equal(t, time.Date(2024, time.March, 31, 9, 0, 0, 0, time.UTC), *next[0].DueAt)
+	assert.Equal(t, "FREQ=MONTHLY;COUNT=1", next[0].Recurrence)
+	assert.Equal(t, []string{"home"}, next[0].Tags)
+
+	complete(taskID, model.TaskStatusTodo)
+	complete(taskID, model.TaskStatusDone)
+	assert.Len(t, todo(), 1)
+
+	complete(next[0].ID, model.TaskStatusDone)
+	assert.Empty(t, todo())
+}
+
+// testRecurringTasksTimeZone completes tasks due early on a Friday and on
+// the last of January in Tokyo, which is still Thursday and the 30th in UTC.
+func testRecurringTasksTimeZone(t *testing.T) {
+	const timeZoneUserID = 9
+	tokyo := time.FixedZone("", 9*60*60)
+
+	for _, test := range []struct {
+		dueAt      time.Time
+		recurrence string
+		next       time.Time
+	}{
+		{time.Date(2024, time.January, 5, 7, 0, 0, 0, tokyo), "FREQ=WEEKLY;BYDAY=MO,FR", time.Date(2024, time.January, 8, 7, 0, 0, 0, tokyo)},
+		{time.Date(2024, time.January, 31, 7, 0, 0, 0, tokyo), "FREQ=MONTHLY", time.Date(2024, time.March, 31, 7, 0, 0, 0, tokyo)},
+	} {
+		task := model.Task{UserID: timeZoneUserID, Name: "Water plants", DueAt: &test.dueAt, Recurrence: test.recurrence}
+		taskID, err := taskService.CreateTask(&task)
+		assert.NoError(t, err)
+
+		task, err = taskService.GetTaskByID(timeZoneUserID, taskID)
+		assert.NoError(t, err)
+		assert.Equal(t, test.dueAt.Format(time.RFC3339), task.DueAt.Format(time.RFC3339))
+
+		task.Status = model.TaskStatusDone
+		err = taskService.UpdateTask(&task)
+		assert.NoError(t, err)
+
+		query := model.TaskQuery{Statuses: []model.TaskStatus{model.TaskStatusTodo}}
+		next, _, err := taskService.GetTasks(timeZoneUserID, query, "")
+		assert.NoError(t, err)
+		assert.Len(t, next, 1)
+		assert.Equal(t, test.next.Format(time.RFC3339), next[0].DueAt.Format(time.RFC3339))
+
+		err = taskService.DeleteTask(timeZoneUserID, next[0].ID)
+		assert.NoError(t, err)
+	}
 }
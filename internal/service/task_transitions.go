@@ -0,0 +1,68 @@
+package service
+
+import (
+	"fmt"
+	"github.com/absoluteyl/tasks-go/internal/model"
+	"strings"
+)
+
+// TaskTransitions maps each status to the statuses a task may move to from
+// it. Keeping a task in its current status is always allowed.
+type TaskTransitions map[model.TaskStatus][]model.TaskStatus
+
+var DefaultTaskTransitions = TaskTransitions{
+	model.TaskStatusTodo: {
+		model.TaskStatusInProgress, model.TaskStatusBlocked, model.TaskStatusDone, model.TaskStatusCancelled,
+	},
+	model.TaskStatusInProgress: {
+		model.TaskStatusTodo, model.TaskStatusBlocked, model.TaskStatusDone, model.TaskStatusCancelled,
+	},
+	model.TaskStatusBlocked: {
+		model.TaskStatusTodo, model.TaskStatusInProgress, model.TaskStatusCancelled,
+	},
+	model.TaskStatusDone:      {model.TaskStatusTodo},
+	model.TaskStatusCancelled: {model.TaskStatusTodo},
+}
+
+func (t TaskTransitions) Allows(from model.TaskStatus, to model.TaskStatus) bool {
+	if from == to {
+		return true
+	}
+	for _, status := range t[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseTaskTransitions reads a transition graph written as comma separated
+// "from:to|to" entries, e.g. "todo:in_progress|done,in_progress:done".
+func ParseTaskTransitions(value string) (TaskTransitions, error) {
+	transitions := TaskTransitions{}
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		from, targets, ok := strings.Cut(entry, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid status transition %q", entry)
+		}
+
+		fromStatus := model.TaskStatus(strings.TrimSpace(from))
+		if !fromStatus.IsValid() {
+			return nil, fmt.Errorf("invalid task status %q", from)
+		}
+
+		for _, to := range strings.Split(targets, "|") {
+			toStatus := model.TaskStatus(strings.TrimSpace(to))
+			if !toStatus.IsValid() {
+				return nil, fmt.Errorf("invalid task status %q", to)
+			}
+			transitions[fromStatus] = append(transitions[fromStatus], toStatus)
+		}
+	}
+	return transitions, nil
+}
@@ -0,0 +1,43 @@
+package service
+
+import (
+	"github.com/absoluteyl/tasks-go/internal/model"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestTaskTransitions(t *testing.T) {
+	t.Run("DefaultAllows", testDefaultTransitionsAllows)
+	t.Run("Parse", testParseTaskTransitions)
+	t.Run("ParseInvalid", testParseInvalidTaskTransitions)
+}
+
+func testDefaultTransitionsAllows(t *testing.T) {
+	assert.True(t, DefaultTaskTransitions.Allows(model.TaskStatusTodo, model.TaskStatusInProgress))
+	assert.True(t, DefaultTaskTransitions.Allows(model.TaskStatusInProgress, model.TaskStatusDone))
+	assert.True(t, DefaultTaskTransitions.Allows(model.TaskStatusDone, model.TaskStatusTodo))
+	assert.True(t, DefaultTaskTransitions.Allows(model.TaskStatusDone, model.TaskStatusDone))
+	assert.False(t, DefaultTaskTransitions.Allows(model.TaskStatusBlocked, model.TaskStatusDone))
+	assert.False(t, DefaultTaskTransitions.Allows(model.TaskStatusCancelled, model.TaskStatusDone))
+}
+
+func testParseTaskTransitions(t *testing.T) {
+	transitions, err := ParseTaskTransitions("todo:in_progress|done, in_progress:done")
+	assert.NoError(t, err)
+	assert.Equal(t, TaskTransitions{
+		model.TaskStatusTodo:       {model.TaskStatusInProgress, model.TaskStatusDone},
+		model.TaskStatusInProgress: {model.TaskStatusDone},
+	}, transitions)
+	assert.False(t, transitions.Allows(model.TaskStatusDone, model.TaskStatusTodo))
+}
+
+func testParseInvalidTaskTransitions(t *testing.T) {
+	_, err := ParseTaskTransitions("todo")
+	assert.Error(t, err)
+
+	_, err = ParseTaskTransitions("todo:finished")
+	assert.Error(t, err)
+
+	_, err = ParseTaskTransitions("started:done")
+	assert.Error(t, err)
+}
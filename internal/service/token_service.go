@@ -0,0 +1,157 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/absoluteyl/tasks-go/internal/model"
+	"github.com/absoluteyl/tasks-go/internal/repository"
+	"github.com/absoluteyl/tasks-go/pkg/auth"
+	"time"
+)
+
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+type TokenService struct {
+	userRepository         *repository.UserRepository
+	refreshTokenRepository *repository.RefreshTokenRepository
+	revokedTokenRepository *repository.RevokedTokenRepository
+}
+
+func NewTokenServiceWithRepositories(
+	userRepository *repository.UserRepository,
+	refreshTokenRepository *repository.RefreshTokenRepository,
+	revokedTokenRepository *repository.RevokedTokenRepository,
+) *TokenService {
+	return &TokenService{
+		userRepository:         userRepository,
+		refreshTokenRepository: refreshTokenRepository,
+		revokedTokenRepository: revokedTokenRepository,
+	}
+}
+
+// IssueTokens grants the scopes of the user's current role, so role changes
+// take effect with the next refresh.
+func (s *TokenService) IssueTokens(user model.User) (TokenPair, error) {
+	now := time.Now()
+
+	err := s.refreshTokenRepository.DeleteExpiredRefreshTokens(now)
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	accessToken, err := auth.GenerateToken(user.ID, auth.ScopesForRole(user.Role), now.Unix())
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	refreshToken, err := auth.GenerateRefreshToken()
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	_, err = s.refreshTokenRepository.CreateRefreshToken(&model.RefreshToken{
+		UserID:    user.ID,
+		TokenHash: auth.HashToken(refreshToken),
+		ExpiresAt: now.Add(auth.RefreshTokenTTL()),
+	})
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	return TokenPair{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(auth.AccessTokenTTL().Seconds()),
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+// RefreshTokens exchanges a refresh token for a new token pair. The presented
+// refresh token is consumed, so each one can only be used once.
+func (s *TokenService) RefreshTokens(refreshToken string) (TokenPair, error) {
+	tokenHash := auth.HashToken(refreshToken)
+
+	storedToken, err := s.refreshTokenRepository.GetRefreshTokenByHash(tokenHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return TokenPair{}, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	err = s.refreshTokenRepository.DeleteRefreshToken(tokenHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return TokenPair{}, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	if !time.Now().Before(storedToken.ExpiresAt) {
+		return TokenPair{}, ErrInvalidRefreshToken
+	}
+
+	user, err := s.userRepository.GetUserByID(storedToken.UserID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return TokenPair{}, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	return s.IssueTokens(user)
+}
+
+// RevokeToken blocks the access token identified by jti until it would
+// have expired anyway.
+func (s *TokenService) RevokeToken(jti string, expiresAt time.Time) error {
+	err := s.revokedTokenRepository.DeleteExpiredRevokedTokens(time.Now())
+	if err != nil {
+		return err
+	}
+
+	return s.revokedTokenRepository.RevokeToken(jti, expiresAt)
+}
+
+func (s *TokenService) IsTokenRevoked(jti string) (bool, error) {
+	return s.revokedTokenRepository.IsTokenRevoked(jti)
+}
+
+// RevokeRefreshToken discards a refresh token of the given user. Unknown
+// tokens or tokens of other users are ignored.
+func (s *TokenService) RevokeRefreshToken(userID int, refreshToken string) error {
+	tokenHash := auth.HashToken(refreshToken)
+
+	storedToken, err := s.refreshTokenRepository.GetRefreshTokenByHash(tokenHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if storedToken.UserID != userID {
+		return nil
+	}
+
+	err = s.refreshTokenRepository.DeleteRefreshToken(tokenHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	return err
+}
+
+func (s *TokenService) PurgeExpiredTokens() error {
+	now := time.Now()
+
+	err := s.refreshTokenRepository.DeleteExpiredRefreshTokens(now)
+	if err != nil {
+		return err
+	}
+
+	return s.revokedTokenRepository.DeleteExpiredRevokedTokens(now)
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"github.com/absoluteyl/tasks-go/internal/model"
+	"github.com/absoluteyl/tasks-go/pkg/auth"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+var issuedTokens TokenPair
+
+func TestTokenService(t *testing.T) {
+	t.Run("Issue", testIssueTokens)
+	t.Run("Refresh", testRefreshTokens)
+	t.Run("RefreshReused", testRefreshReusedToken)
+	t.Run("RefreshUnknown", testRefreshUnknownToken)
+	t.Run("RefreshExpired", testRefreshExpiredToken)
+	t.Run("RefreshAfterRoleChange", testRefreshAfterRoleChange)
+	t.Run("Revoke", testRevokeToken)
+	t.Run("RevokeRefreshTokenOtherUser", testRevokeRefreshTokenOtherUser)
+	t.Run("RevokeRefreshToken", testRevokeRefreshToken)
+	t.Run("PurgeExpired", testPurgeExpiredTokens)
+}
+
+func testIssueTokens(t *testing.T) {
+	var err error
+	issuedTokens, err = tokenService.IssueTokens(model.User{ID: 1, Role: auth.RoleMember})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, issuedTokens.AccessToken)
+	assert.NotEmpty(t, issuedTokens.RefreshToken)
+	assert.Equal(t, "Bearer", issuedTokens.TokenType)
+	assert.Equal(t, int64(auth.AccessTokenTTL().Seconds()), issuedTokens.ExpiresIn)
+
+	token, err := auth.ParseToken(issuedTokens.AccessToken)
+	assert.NoError(t, err)
+	assert.Equal(t, "tasks:read tasks:write", token.Claims.(jwt.MapClaims)["scope"])
+}
+
+func testRefreshTokens(t *testing.T) {
+	refreshedTokens, err := tokenService.RefreshTokens(issuedTokens.RefreshToken)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, refreshedTokens.AccessToken)
+	assert.NotEqual(t, issuedTokens.RefreshToken, refreshedTokens.RefreshToken)
+}
+
+func testRefreshReusedToken(t *testing.T) {
+	_, err := tokenService.RefreshTokens(issuedTokens.RefreshToken)
+	assert.Equal(t, ErrInvalidRefreshToken, err)
+}
+
+func testRefreshUnknownToken(t *testing.T) {
+	_, err := tokenService.RefreshTokens("unknown")
+	assert.Equal(t, ErrInvalidRefreshToken, err)
+}
+
+func testRefreshExpiredToken(t *testing.T) {
+	refreshToken := "expired"
+	_, err := refreshTokenRepo.CreateRefreshToken(&model.RefreshToken{
+		UserID:    1,
+		TokenHash: auth.HashToken(refreshToken),
+		ExpiresAt: time.Now().Add(-time.Minute),
+	})
+	assert.NoError(t, err)
+
+	_, err = tokenService.RefreshTokens(refreshToken)
+	assert.Equal(t, ErrInvalidRefreshToken, err)
+}
+
+func testRefreshAfterRoleChange(t *testing.T) {
+	tokens, err := tokenService.IssueTokens(model.User{ID: 1, Role: auth.RoleMember})
+	assert.NoError(t, err)
+
+	err = userRepo.UpdateUserRole(1, auth.RoleReadOnly)
+	assert.NoError(t, err)
+	defer userRepo.UpdateUserRole(1, auth.RoleMember)
+
+	refreshedTokens, err := tokenService.RefreshTokens(tokens.RefreshToken)
+	assert.NoError(t, err)
+
+	token, err := auth.ParseToken(refreshedTokens.AccessToken)
+	assert.NoError(t, err)
+	assert.Equal(t, "tasks:read", token.Claims.(jwt.MapClaims)["scope"])
+}
+
+func testRevokeToken(t *testing.T) {
+	revoked, err := tokenService.IsTokenRevoked("jti")
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+
+	err = tokenService.RevokeToken("jti", time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+
+	revoked, err = tokenService.IsTokenRevoked("jti")
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+}
+
+func testRevokeRefreshTokenOtherUser(t *testing.T) {
+	tokens, err := tokenService.IssueTokens(model.User{ID: 1, Role: auth.RoleMember})
+	assert.NoError(t, err)
+	issuedTokens = tokens
+
+	err = tokenService.RevokeRefreshToken(2, issuedTokens.RefreshToken)
+	assert.NoError(t, err)
+
+	_, err = refreshTokenRepo.GetRefreshTokenByHash(auth.HashToken(issuedTokens.RefreshToken))
+	assert.NoError(t, err)
+}
+
+func testRevokeRefreshToken(t *testing.T) {
+	err := tokenService.RevokeRefreshToken(1, issuedTokens.RefreshToken)
+	assert.NoError(t, err)
+
+	_, err = tokenService.RefreshTokens(issuedTokens.RefreshToken)
+	assert.Equal(t, ErrInvalidRefreshToken, err)
+
+	err = tokenService.RevokeRefreshToken(1, issuedTokens.RefreshToken)
+	assert.NoError(t, err)
+}
+
+func testPurgeExpiredTokens(t *testing.T) {
+	err := revokedTokenRepo.RevokeToken("expired-jti", time.Now().Add(-time.Minute))
+	assert.NoError(t, err)
+
+	err = tokenService.PurgeExpiredTokens()
+	assert.NoError(t, err)
+
+	revoked, err := tokenService.IsTokenRevoked("expired-jti")
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+}
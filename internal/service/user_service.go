@@ -0,0 +1,66 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/absoluteyl/tasks-go/internal/model"
+	"github.com/absoluteyl/tasks-go/internal/repository"
+	"github.com/absoluteyl/tasks-go/pkg/auth"
+)
+
+type UserService struct {
+	userRepository *repository.UserRepository
+}
+
+func NewUserServiceWithRepository(userRepository *repository.UserRepository) *UserService {
+	return &UserService{userRepository: userRepository}
+}
+
+// CreateUser reports taken usernames with ErrUserExists, including those
+// taken by a concurrent registration.
+func (s *UserService) CreateUser(username string, password string) (int, error) {
+	passwordHash, err := auth.HashPassword(password)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := s.userRepository.CreateUser(username, passwordHash)
+	if errors.Is(err, repository.ErrDuplicateUser) {
+		return 0, ErrUserExists
+	}
+	return id, err
+}
+
+func (s *UserService) GetUserByID(id int) (model.User, error) {
+	return s.userRepository.GetUserByID(id)
+}
+
+func (s *UserService) GetUserByUsername(username string) (model.User, error) {
+	user, err := s.userRepository.GetUserByUsername(username)
+	return user, notFoundAs(err, ErrUserNotFound)
+}
+
+func (s *UserService) UpdateUserRole(id int, role string) error {
+	if !auth.IsValidRole(role) {
+		return ErrInvalidRole
+	}
+
+	err := s.userRepository.UpdateUserRole(id, role)
+	return notFoundAs(err, ErrUserNotFound)
+}
+
+func (s *UserService) Authenticate(username string, password string) (model.User, error) {
+	user, err := s.userRepository.GetUserByUsername(username)
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.User{}, ErrInvalidCredentials
+	}
+	if err != nil {
+		return model.User{}, err
+	}
+
+	if !auth.CheckPassword(user.PasswordHash, password) {
+		return model.User{}, ErrInvalidCredentials
+	}
+
+	return user, nil
+}
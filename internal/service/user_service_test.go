@@ -0,0 +1,84 @@
+package service
+
+import (
+	"github.com/absoluteyl/tasks-go/pkg/auth"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+const (
+	testUsername = "elsa"
+	testPassword = "let-it-go"
+)
+
+func TestUserService(t *testing.T) {
+	t.Run("Create", testCreateUser)
+	t.Run("CreateDuplicate", testCreateDuplicateUser)
+	t.Run("Authenticate", testAuthenticate)
+	t.Run("AuthenticateWrongPassword", testAuthenticateWrongPassword)
+	t.Run("AuthenticateUnknownUser", testAuthenticateUnknownUser)
+	t.Run("UpdateRole", testUpdateUserRole)
+	t.Run("UpdateRoleInvalid", testUpdateUserRoleInvalid)
+	t.Run("UpdateRoleUnknownUser", testUpdateUserRoleUnknownUser)
+}
+
+func testCreateUser(t *testing.T) {
+	userID, err := userService.CreateUser(testUsername, testPassword)
+	assert.NoError(t, err)
+	assert.NotZero(t, userID)
+
+	user, err := userService.GetUserByID(userID)
+	assert.NoError(t, err)
+	assert.Equal(t, testUsername, user.Username)
+	assert.NotEqual(t, testPassword, user.PasswordHash)
+	assert.Equal(t, auth.RoleMember, user.Role)
+}
+
+func testCreateDuplicateUser(t *testing.T) {
+	_, err := userService.CreateUser(testUsername, testPassword)
+	assert.Equal(t, ErrUserExists, err)
+}
+
+func testAuthenticate(t *testing.T) {
+	user, err := userService.Authenticate(testUsername, testPassword)
+	assert.NoError(t, err)
+	assert.Equal(t, testUsername, user.Username)
+}
+
+func testAuthenticateWrongPassword(t *testing.T) {
+	_, err := userService.Authenticate(testUsername, "invalid")
+	assert.Equal(t, ErrInvalidCredentials, err)
+}
+
+func testAuthenticateUnknownUser(t *testing.T) {
+	_, err := userService.Authenticate("anna", testPassword)
+	assert.Equal(t, ErrInvalidCredentials, err)
+}
+
+func testUpdateUserRole(t *testing.T) {
+	user, err := userService.GetUserByUsername(testUsername)
+	assert.NoError(t, err)
+
+	err = userService.UpdateUserRole(user.ID, auth.RoleAdmin)
+	assert.NoError(t, err)
+
+	user, err = userService.GetUserByID(user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, auth.RoleAdmin, user.Role)
+}
+
+func testUpdateUserRoleInvalid(t *testing.T) {
+	user, err := userService.GetUserByUsername(testUsername)
+	assert.NoError(t, err)
+
+	err = userService.UpdateUserRole(user.ID, "owner")
+	assert.Equal(t, ErrInvalidRole, err)
+}
+
+func testUpdateUserRoleUnknownUser(t *testing.T) {
+	err := userService.UpdateUserRole(999, auth.RoleMember)
+	assert.Equal(t, ErrUserNotFound, err)
+
+	_, err = userService.GetUserByUsername("anna")
+	assert.Equal(t, ErrUserNotFound, err)
+}
@@ -0,0 +1,24 @@
+package auth
+
+import "strings"
+
+const (
+	APIKeyPrefix      = "tsk_"
+	apiKeyVisibleSize = 8
+)
+
+// GenerateAPIKey returns a new API key and the short prefix used to tell
+// keys apart in listings. Only the HashToken digest of the key is stored.
+func GenerateAPIKey() (string, string, error) {
+	secret, err := randomString(32)
+	if err != nil {
+		return "", "", err
+	}
+
+	key := APIKeyPrefix + secret
+	return key, key[:len(APIKeyPrefix)+apiKeyVisibleSize], nil
+}
+
+func IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, APIKeyPrefix)
+}
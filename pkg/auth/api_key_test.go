@@ -0,0 +1,32 @@
+package auth
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestAPIKey(t *testing.T) {
+	t.Run("GenerateAPIKey", testGenerateAPIKey)
+	t.Run("IsAPIKey", testIsAPIKey)
+}
+
+func testGenerateAPIKey(t *testing.T) {
+	key, prefix, err := GenerateAPIKey()
+	assert.NoError(t, err, "Failed to generate API key")
+	assert.True(t, strings.HasPrefix(key, APIKeyPrefix), "API key has no prefix")
+	assert.True(t, strings.HasPrefix(key, prefix), "Prefix does not match API key")
+	assert.Len(t, prefix, len(APIKeyPrefix)+8)
+
+	otherKey, _, err := GenerateAPIKey()
+	assert.NoError(t, err, "Failed to generate API key")
+	assert.NotEqual(t, key, otherKey, "API key is not random")
+}
+
+func testIsAPIKey(t *testing.T) {
+	key, _, err := GenerateAPIKey()
+	assert.NoError(t, err, "Failed to generate API key")
+
+	assert.True(t, IsAPIKey(key))
+	assert.False(t, IsAPIKey("eyJhbGciOiJIUzI1NiJ9.e30.signature"))
+}
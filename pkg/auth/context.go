@@ -0,0 +1,31 @@
+package auth
+
+import (
+	"context"
+	"time"
+)
+
+type contextKey struct{}
+
+// Principal identifies the caller of a request. TokenID and ExpiresAt are
+// set for JWT authentication, APIKeyID for API key authentication.
+type Principal struct {
+	UserID    int
+	TokenID   string
+	ExpiresAt time.Time
+	APIKeyID  int
+	Scopes    []string
+}
+
+func (p Principal) HasScope(scope string) bool {
+	return HasScope(p.Scopes, scope)
+}
+
+func NewContext(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, principal)
+}
+
+func FromContext(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(contextKey{}).(Principal)
+	return principal, ok
+}
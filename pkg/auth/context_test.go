@@ -0,0 +1,25 @@
+package auth
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestContext(t *testing.T) {
+	t.Run("FromEmptyContext", testFromEmptyContext)
+	t.Run("FromContext", testFromContext)
+}
+
+func testFromEmptyContext(t *testing.T) {
+	_, ok := FromContext(context.Background())
+	assert.False(t, ok, "Principal found in empty context")
+}
+
+func testFromContext(t *testing.T) {
+	ctx := NewContext(context.Background(), Principal{UserID: 1})
+
+	principal, ok := FromContext(ctx)
+	assert.True(t, ok, "Principal not found in context")
+	assert.Equal(t, 1, principal.UserID)
+}
@@ -1,32 +1,74 @@
 package auth
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"github.com/dgrijalva/jwt-go"
 	"os"
+	"strconv"
+	"time"
 )
 
-var jwtSecret = os.Getenv("JWT_SECRET")
+var (
+	jwtSecret       = os.Getenv("JWT_SECRET")
+	jwtIssuer       = stringFromEnv("JWT_ISSUER", "tasks-go")
+	accessTokenTTL  = durationFromEnv("JWT_ACCESS_TTL", 15*time.Minute)
+	refreshTokenTTL = durationFromEnv("JWT_REFRESH_TTL", 30*24*time.Hour)
+)
+
+var ErrInvalidIssuer = errors.New("invalid token issuer")
+
+func AccessTokenTTL() time.Duration {
+	return accessTokenTTL
+}
+
+func RefreshTokenTTL() time.Duration {
+	return refreshTokenTTL
+}
 
-func GenerateToken(iat int64) (string, error) {
+func GenerateToken(userID int, scopes []string, iat int64) (string, error) {
 	token := SetSignMethod()
 
-	PrepareClaims(token, iat)
+	err := PrepareClaims(token, userID, scopes, iat)
+	if err != nil {
+		return "", err
+	}
 
 	return Sign(token)
 }
 
 func SetSignMethod() *jwt.Token {
-	token := jwt.New(jwt.SigningMethodHS256)
+	signingKey := CurrentKeySet().SigningKey()
+
+	token := jwt.New(signingKey.Method)
+	if signingKey.ID != "" {
+		token.Header["kid"] = signingKey.ID
+	}
 	return token
 }
 
-func PrepareClaims(token *jwt.Token, iat int64) {
+func PrepareClaims(token *jwt.Token, userID int, scopes []string, iat int64) error {
+	jti, err := randomString(16)
+	if err != nil {
+		return err
+	}
+
 	claims := token.Claims.(jwt.MapClaims)
+	claims["iss"] = jwtIssuer
+	claims["sub"] = strconv.Itoa(userID)
+	claims["scope"] = FormatScopes(scopes)
+	claims["jti"] = jti
 	claims["iat"] = iat
+	claims["nbf"] = iat
+	claims["exp"] = time.Unix(iat, 0).Add(accessTokenTTL).Unix()
+	return nil
 }
 
 func Sign(token *jwt.Token) (string, error) {
-	tokenString, err := token.SignedString([]byte(jwtSecret))
+	tokenString, err := token.SignedString(CurrentKeySet().SigningKey().Key)
 	if err != nil {
 		return "", err
 	}
@@ -34,7 +76,62 @@ func Sign(token *jwt.Token) (string, error) {
 }
 
 func ParseToken(tokenString string) (*jwt.Token, error) {
-	return jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		return []byte(jwtSecret), nil
+	keySet := CurrentKeySet()
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return keySet.VerificationKey(kid, token.Method)
 	})
+	if err != nil {
+		return token, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if ok && !claims.VerifyIssuer(jwtIssuer, true) {
+		return token, ErrInvalidIssuer
+	}
+
+	return token, nil
+}
+
+func IsExpired(err error) bool {
+	var validationErr *jwt.ValidationError
+	if errors.As(err, &validationErr) {
+		return validationErr.Errors&jwt.ValidationErrorExpired != 0
+	}
+	return false
+}
+
+// GenerateRefreshToken returns an opaque random token. Only its HashToken
+// digest should be persisted.
+func GenerateRefreshToken() (string, error) {
+	return randomString(32)
+}
+
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomString(size int) (string, error) {
+	b := make([]byte, size)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func stringFromEnv(key string, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func durationFromEnv(key string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil || value <= 0 {
+		return fallback
+	}
+	return value
 }
@@ -10,10 +10,15 @@ import (
 func TestAuth(t *testing.T) {
 	t.Run("GenerateToken", testGenerateToken)
 	t.Run("ParseToken", testParseToken)
+	t.Run("ParseExpiredToken", testParseExpiredToken)
+	t.Run("ParseTokenWithInvalidIssuer", testParseTokenWithInvalidIssuer)
+	t.Run("ParseTokenWithUnexpectedMethod", testParseTokenWithUnexpectedMethod)
+	t.Run("GenerateRefreshToken", testGenerateRefreshToken)
 }
 
 func testGenerateToken(t *testing.T) {
-	tokenString, err := GenerateToken(time.Now().Unix())
+	now := time.Now().Unix()
+	tokenString, err := GenerateToken(1, ScopesForRole(RoleMember), now)
 	assert.NoError(t, err, "Failed to generate token")
 	assert.NotEmpty(t, tokenString, "Generated token is empty")
 
@@ -27,15 +32,83 @@ func testGenerateToken(t *testing.T) {
 	claims, ok := token.Claims.(jwt.MapClaims)
 	assert.True(t, ok, "Invalid token claims")
 
+	sub, ok := claims["sub"].(string)
+	assert.True(t, ok, "Invalid subject claim")
+	assert.Equal(t, "1", sub, "Unexpected subject claim")
+
+	assert.Equal(t, jwtIssuer, claims["iss"], "Unexpected issuer claim")
+	assert.Equal(t, "tasks:read tasks:write", claims["scope"], "Unexpected scope claim")
+	assert.NotEmpty(t, claims["jti"], "Missing token id claim")
+
 	iat, ok := claims["iat"].(float64)
 	assert.True(t, ok, "Invalid issued at claim")
-	assert.True(t, time.Now().After(time.Unix(int64(iat), 0)), "Token issued at time is in the future")
+	assert.False(t, time.Now().Before(time.Unix(int64(iat), 0)), "Token issued at time is in the future")
+
+	nbf, ok := claims["nbf"].(float64)
+	assert.True(t, ok, "Invalid not before claim")
+	assert.Equal(t, iat, nbf, "Unexpected not before claim")
+
+	exp, ok := claims["exp"].(float64)
+	assert.True(t, ok, "Invalid expiration claim")
+	assert.Equal(t, now+int64(AccessTokenTTL().Seconds()), int64(exp), "Unexpected expiration claim")
+
+	otherTokenString, err := GenerateToken(1, ScopesForRole(RoleMember), now)
+	assert.NoError(t, err, "Failed to generate token")
+	assert.NotEqual(t, tokenString, otherTokenString, "Token id is not unique")
 }
 
 func testParseToken(t *testing.T) {
-	tokenString, err := GenerateToken(time.Now().Unix())
+	tokenString, err := GenerateToken(1, ScopesForRole(RoleMember), time.Now().Unix())
 	assert.NoError(t, err, "Failed to generate token")
 
 	_, err = ParseToken(tokenString)
 	assert.NoError(t, err, "Failed to parse token")
 }
+
+func testParseExpiredToken(t *testing.T) {
+	iat := time.Now().Add(-AccessTokenTTL() - time.Minute).Unix()
+	tokenString, err := GenerateToken(1, ScopesForRole(RoleMember), iat)
+	assert.NoError(t, err, "Failed to generate token")
+
+	_, err = ParseToken(tokenString)
+	assert.Error(t, err, "Expired token accepted")
+	assert.True(t, IsExpired(err), "Expired token not reported as expired")
+}
+
+func testParseTokenWithInvalidIssuer(t *testing.T) {
+	token := SetSignMethod()
+	err := PrepareClaims(token, 1, ScopesForRole(RoleMember), time.Now().Unix())
+	assert.NoError(t, err)
+	token.Claims.(jwt.MapClaims)["iss"] = "someone-else"
+
+	tokenString, err := Sign(token)
+	assert.NoError(t, err, "Failed to sign token")
+
+	_, err = ParseToken(tokenString)
+	assert.Equal(t, ErrInvalidIssuer, err)
+}
+
+func testParseTokenWithUnexpectedMethod(t *testing.T) {
+	token := jwt.New(jwt.SigningMethodNone)
+	err := PrepareClaims(token, 1, ScopesForRole(RoleMember), time.Now().Unix())
+	assert.NoError(t, err)
+
+	tokenString, err := token.SignedString(jwt.UnsafeAllowNoneSignatureType)
+	assert.NoError(t, err, "Failed to sign token")
+
+	_, err = ParseToken(tokenString)
+	assert.Error(t, err, "Unsigned token accepted")
+}
+
+func testGenerateRefreshToken(t *testing.T) {
+	token, err := GenerateRefreshToken()
+	assert.NoError(t, err, "Failed to generate refresh token")
+	assert.NotEmpty(t, token, "Generated refresh token is empty")
+
+	otherToken, err := GenerateRefreshToken()
+	assert.NoError(t, err, "Failed to generate refresh token")
+	assert.NotEqual(t, token, otherToken, "Refresh token is not random")
+
+	assert.Equal(t, HashToken(token), HashToken(token), "Token hash is not deterministic")
+	assert.NotEqual(t, token, HashToken(token), "Token hash equals token")
+}
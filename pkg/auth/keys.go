@@ -0,0 +1,306 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/dgrijalva/jwt-go"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+var ErrUnknownKey = errors.New("unknown signing key")
+
+type SigningKey struct {
+	ID     string
+	Method jwt.SigningMethod
+	Key    interface{}
+}
+
+type VerificationKey struct {
+	ID     string
+	Method jwt.SigningMethod
+	Key    interface{}
+}
+
+// KeySet holds the key new tokens are signed with and every key tokens are
+// still accepted from. Keeping retired public keys around as verification
+// keys lets tokens signed before a rotation stay valid until they expire.
+type KeySet struct {
+	signing      SigningKey
+	verification map[string]VerificationKey
+}
+
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+var currentKeySet atomic.Pointer[KeySet]
+
+func init() {
+	UseKeySet(NewKeySet(SigningKey{
+		Method: jwt.SigningMethodHS256,
+		Key:    []byte(jwtSecret),
+	}))
+}
+
+func NewKeySet(signing SigningKey, verification ...VerificationKey) *KeySet {
+	keySet := &KeySet{
+		signing:      signing,
+		verification: map[string]VerificationKey{},
+	}
+
+	keySet.verification[signing.ID] = VerificationKey{
+		ID:     signing.ID,
+		Method: signing.Method,
+		Key:    publicKey(signing.Key),
+	}
+	for _, key := range verification {
+		keySet.verification[key.ID] = key
+	}
+
+	return keySet
+}
+
+func UseKeySet(keySet *KeySet) {
+	currentKeySet.Store(keySet)
+}
+
+func CurrentKeySet() *KeySet {
+	return currentKeySet.Load()
+}
+
+// LoadKeySetFromEnv switches to asymmetric signing when JWT_SIGNING_KEY
+// points to a PEM encoded RSA or ECDSA private key. Additional public keys
+// accepted during a rotation are listed in JWT_VERIFICATION_KEYS as
+// comma separated "path" or "kid=path" entries.
+func LoadKeySetFromEnv() error {
+	signingKeyPath := os.Getenv("JWT_SIGNING_KEY")
+	if signingKeyPath == "" {
+		return nil
+	}
+
+	pemBytes, err := os.ReadFile(signingKeyPath)
+	if err != nil {
+		return fmt.Errorf("Error reading signing key: %v", err)
+	}
+
+	signing, err := ParsePrivateKeyPEM(os.Getenv("JWT_SIGNING_KEY_ID"), pemBytes)
+	if err != nil {
+		return err
+	}
+
+	var verification []VerificationKey
+	for _, entry := range strings.Split(os.Getenv("JWT_VERIFICATION_KEYS"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		kid, path, ok := strings.Cut(entry, "=")
+		if !ok {
+			kid, path = "", entry
+		}
+
+		pemBytes, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("Error reading verification key: %v", err)
+		}
+
+		key, err := ParsePublicKeyPEM(kid, pemBytes)
+		if err != nil {
+			return err
+		}
+		verification = append(verification, key)
+	}
+
+	UseKeySet(NewKeySet(signing, verification...))
+	return nil
+}
+
+// ParsePrivateKeyPEM reads an RSA or ECDSA private key. When kid is empty
+// the RFC 7638 thumbprint of the public key is used.
+func ParsePrivateKeyPEM(kid string, pemBytes []byte) (SigningKey, error) {
+	var key interface{}
+	if rsaKey, err := jwt.ParseRSAPrivateKeyFromPEM(pemBytes); err == nil {
+		key = rsaKey
+	} else if ecKey, err := jwt.ParseECPrivateKeyFromPEM(pemBytes); err == nil {
+		key = ecKey
+	} else {
+		return SigningKey{}, errors.New("signing key is not a PEM encoded RSA or ECDSA private key")
+	}
+
+	method, err := methodFor(publicKey(key))
+	if err != nil {
+		return SigningKey{}, err
+	}
+
+	if kid == "" {
+		kid, err = thumbprint(publicKey(key))
+		if err != nil {
+			return SigningKey{}, err
+		}
+	}
+
+	return SigningKey{ID: kid, Method: method, Key: key}, nil
+}
+
+func ParsePublicKeyPEM(kid string, pemBytes []byte) (VerificationKey, error) {
+	var key interface{}
+	if rsaKey, err := jwt.ParseRSAPublicKeyFromPEM(pemBytes); err == nil {
+		key = rsaKey
+	} else if ecKey, err := jwt.ParseECPublicKeyFromPEM(pemBytes); err == nil {
+		key = ecKey
+	} else {
+		return VerificationKey{}, errors.New("verification key is not a PEM encoded RSA or ECDSA public key")
+	}
+
+	method, err := methodFor(key)
+	if err != nil {
+		return VerificationKey{}, err
+	}
+
+	if kid == "" {
+		kid, err = thumbprint(key)
+		if err != nil {
+			return VerificationKey{}, err
+		}
+	}
+
+	return VerificationKey{ID: kid, Method: method, Key: key}, nil
+}
+
+func (ks *KeySet) SigningKey() SigningKey {
+	return ks.signing
+}
+
+func (ks *KeySet) VerificationKey(kid string, method jwt.SigningMethod) (interface{}, error) {
+	key, ok := ks.verification[kid]
+	if !ok || key.Method.Alg() != method.Alg() {
+		return nil, ErrUnknownKey
+	}
+	return key.Key, nil
+}
+
+// JWKS publishes the public verification keys. Shared HMAC secrets are
+// never included.
+func (ks *KeySet) JWKS() JWKS {
+	jwks := JWKS{Keys: []JWK{}}
+	for _, key := range ks.verification {
+		jwk, ok := toJWK(key)
+		if ok {
+			jwks.Keys = append(jwks.Keys, jwk)
+		}
+	}
+
+	sort.Slice(jwks.Keys, func(i, j int) bool {
+		return jwks.Keys[i].Kid < jwks.Keys[j].Kid
+	})
+
+	return jwks
+}
+
+func toJWK(key VerificationKey) (JWK, bool) {
+	switch k := key.Key.(type) {
+	case *rsa.PublicKey:
+		return JWK{
+			Kty: "RSA",
+			Use: "sig",
+			Kid: key.ID,
+			Alg: key.Method.Alg(),
+			N:   base64.RawURLEncoding.EncodeToString(k.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes()),
+		}, true
+	case *ecdsa.PublicKey:
+		size := (k.Curve.Params().BitSize + 7) / 8
+		return JWK{
+			Kty: "EC",
+			Use: "sig",
+			Kid: key.ID,
+			Alg: key.Method.Alg(),
+			Crv: k.Curve.Params().Name,
+			X:   base64.RawURLEncoding.EncodeToString(k.X.FillBytes(make([]byte, size))),
+			Y:   base64.RawURLEncoding.EncodeToString(k.Y.FillBytes(make([]byte, size))),
+		}, true
+	}
+	return JWK{}, false
+}
+
+func thumbprint(key interface{}) (string, error) {
+	jwk, ok := toJWK(VerificationKey{Key: key, Method: jwt.SigningMethodNone})
+	if !ok {
+		return "", errors.New("unsupported key type")
+	}
+
+	// RFC 7638 requires the required members in lexicographic order.
+	var members interface{}
+	if jwk.Kty == "RSA" {
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N}
+	} else {
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		}{jwk.Crv, jwk.Kty, jwk.X, jwk.Y}
+	}
+
+	encoded, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(encoded)
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+func methodFor(key interface{}) (jwt.SigningMethod, error) {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return jwt.SigningMethodRS256, nil
+	case *ecdsa.PublicKey:
+		switch k.Curve {
+		case elliptic.P256():
+			return jwt.SigningMethodES256, nil
+		case elliptic.P384():
+			return jwt.SigningMethodES384, nil
+		case elliptic.P521():
+			return jwt.SigningMethodES512, nil
+		}
+	}
+	return nil, errors.New("unsupported key type")
+}
+
+func publicKey(key interface{}) interface{} {
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return &k.PublicKey
+	case *ecdsa.PrivateKey:
+		return &k.PublicKey
+	}
+	return key
+}
@@ -0,0 +1,227 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestKeys(t *testing.T) {
+	t.Run("DefaultKeySet", testDefaultKeySet)
+	t.Run("RSASigning", testRSASigning)
+	t.Run("ECDSASigning", testECDSASigning)
+	t.Run("Rotation", testKeyRotation)
+	t.Run("UnknownKeyID", testUnknownKeyID)
+	t.Run("AlgorithmMismatch", testAlgorithmMismatch)
+	t.Run("JWKS", testJWKS)
+	t.Run("LoadKeySetFromEnv", testLoadKeySetFromEnv)
+	t.Run("LoadKeySetFromEnvInvalidKey", testLoadKeySetFromEnvInvalidKey)
+}
+
+func testDefaultKeySet(t *testing.T) {
+	signingKey := CurrentKeySet().SigningKey()
+	assert.Equal(t, jwt.SigningMethodHS256, signingKey.Method)
+	assert.Empty(t, CurrentKeySet().JWKS().Keys, "Shared secret is published")
+}
+
+func testRSASigning(t *testing.T) {
+	signingKey := mustParsePrivateKey(t, "rsa-1", generateRSAKeyPEM(t))
+	assert.Equal(t, jwt.SigningMethodRS256, signingKey.Method)
+	useKeySetForTest(t, NewKeySet(signingKey))
+
+	tokenString, err := GenerateToken(1, ScopesForRole(RoleMember), time.Now().Unix())
+	assert.NoError(t, err, "Failed to generate token")
+
+	token, err := ParseToken(tokenString)
+	assert.NoError(t, err, "Failed to parse token")
+	assert.Equal(t, "rsa-1", token.Header["kid"])
+	assert.Equal(t, "RS256", token.Header["alg"])
+}
+
+func testECDSASigning(t *testing.T) {
+	signingKey := mustParsePrivateKey(t, "", generateECKeyPEM(t))
+	assert.Equal(t, jwt.SigningMethodES256, signingKey.Method)
+	assert.NotEmpty(t, signingKey.ID, "Key id was not derived from thumbprint")
+	useKeySetForTest(t, NewKeySet(signingKey))
+
+	tokenString, err := GenerateToken(1, ScopesForRole(RoleMember), time.Now().Unix())
+	assert.NoError(t, err, "Failed to generate token")
+
+	token, err := ParseToken(tokenString)
+	assert.NoError(t, err, "Failed to parse token")
+	assert.Equal(t, signingKey.ID, token.Header["kid"])
+	assert.Equal(t, "ES256", token.Header["alg"])
+}
+
+func testKeyRotation(t *testing.T) {
+	oldKey := mustParsePrivateKey(t, "old", generateRSAKeyPEM(t))
+	newKey := mustParsePrivateKey(t, "new", generateECKeyPEM(t))
+
+	useKeySetForTest(t, NewKeySet(oldKey))
+	oldTokenString, err := GenerateToken(1, ScopesForRole(RoleMember), time.Now().Unix())
+	assert.NoError(t, err, "Failed to generate token")
+
+	UseKeySet(NewKeySet(newKey, publicVerificationKey(oldKey)))
+	newTokenString, err := GenerateToken(1, ScopesForRole(RoleMember), time.Now().Unix())
+	assert.NoError(t, err, "Failed to generate token")
+
+	_, err = ParseToken(oldTokenString)
+	assert.NoError(t, err, "Token signed with rotated key rejected")
+
+	token, err := ParseToken(newTokenString)
+	assert.NoError(t, err, "Token signed with new key rejected")
+	assert.Equal(t, "new", token.Header["kid"])
+
+	UseKeySet(NewKeySet(newKey))
+	_, err = ParseToken(oldTokenString)
+	assert.Error(t, err, "Token signed with retired key accepted")
+}
+
+func testUnknownKeyID(t *testing.T) {
+	useKeySetForTest(t, NewKeySet(mustParsePrivateKey(t, "known", generateECKeyPEM(t))))
+
+	token := SetSignMethod()
+	token.Header["kid"] = "unknown"
+	err := PrepareClaims(token, 1, ScopesForRole(RoleMember), time.Now().Unix())
+	assert.NoError(t, err)
+
+	tokenString, err := Sign(token)
+	assert.NoError(t, err, "Failed to sign token")
+
+	_, err = ParseToken(tokenString)
+	assert.Error(t, err, "Token with unknown key id accepted")
+}
+
+func testAlgorithmMismatch(t *testing.T) {
+	signingKey := mustParsePrivateKey(t, "rsa", generateRSAKeyPEM(t))
+	useKeySetForTest(t, NewKeySet(signingKey))
+
+	// A token signed with HS256 using the public key as secret must not be
+	// accepted as RS256 token.
+	publicDER, err := x509.MarshalPKIXPublicKey(publicKey(signingKey.Key))
+	assert.NoError(t, err)
+
+	token := jwt.New(jwt.SigningMethodHS256)
+	token.Header["kid"] = "rsa"
+	err = PrepareClaims(token, 1, ScopesForRole(RoleMember), time.Now().Unix())
+	assert.NoError(t, err)
+
+	tokenString, err := token.SignedString(publicDER)
+	assert.NoError(t, err, "Failed to sign token")
+
+	_, err = ParseToken(tokenString)
+	assert.Error(t, err, "Token with mismatching algorithm accepted")
+}
+
+func testJWKS(t *testing.T) {
+	rsaKey := mustParsePrivateKey(t, "rsa", generateRSAKeyPEM(t))
+	ecKey := mustParsePrivateKey(t, "ec", generateECKeyPEM(t))
+	useKeySetForTest(t, NewKeySet(rsaKey, publicVerificationKey(ecKey)))
+
+	jwks := CurrentKeySet().JWKS()
+	assert.Len(t, jwks.Keys, 2)
+
+	ecJWK, rsaJWK := jwks.Keys[0], jwks.Keys[1]
+
+	assert.Equal(t, "EC", ecJWK.Kty)
+	assert.Equal(t, "ec", ecJWK.Kid)
+	assert.Equal(t, "ES256", ecJWK.Alg)
+	assert.Equal(t, "P-256", ecJWK.Crv)
+	assert.Len(t, ecJWK.X, 43)
+	assert.Len(t, ecJWK.Y, 43)
+
+	assert.Equal(t, "RSA", rsaJWK.Kty)
+	assert.Equal(t, "rsa", rsaJWK.Kid)
+	assert.Equal(t, "RS256", rsaJWK.Alg)
+	assert.Equal(t, "sig", rsaJWK.Use)
+	assert.Equal(t, "AQAB", rsaJWK.E)
+	assert.NotEmpty(t, rsaJWK.N)
+}
+
+func testLoadKeySetFromEnv(t *testing.T) {
+	useKeySetForTest(t, CurrentKeySet())
+
+	dir := t.TempDir()
+	signingKeyPath := filepath.Join(dir, "signing.pem")
+	assert.NoError(t, os.WriteFile(signingKeyPath, generateECKeyPEM(t), 0600))
+
+	oldKey := mustParsePrivateKey(t, "old", generateRSAKeyPEM(t))
+	oldPublicDER, err := x509.MarshalPKIXPublicKey(publicKey(oldKey.Key))
+	assert.NoError(t, err)
+	oldKeyPath := filepath.Join(dir, "old.pem")
+	oldPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: oldPublicDER})
+	assert.NoError(t, os.WriteFile(oldKeyPath, oldPEM, 0600))
+
+	t.Setenv("JWT_SIGNING_KEY", signingKeyPath)
+	t.Setenv("JWT_SIGNING_KEY_ID", "current")
+	t.Setenv("JWT_VERIFICATION_KEYS", "old="+oldKeyPath)
+
+	err = LoadKeySetFromEnv()
+	assert.NoError(t, err, "Failed to load keys")
+
+	keySet := CurrentKeySet()
+	assert.Equal(t, "current", keySet.SigningKey().ID)
+	assert.Equal(t, jwt.SigningMethodES256, keySet.SigningKey().Method)
+	assert.Len(t, keySet.JWKS().Keys, 2)
+
+	_, err = keySet.VerificationKey("old", jwt.SigningMethodRS256)
+	assert.NoError(t, err, "Verification key not loaded")
+}
+
+func testLoadKeySetFromEnvInvalidKey(t *testing.T) {
+	useKeySetForTest(t, CurrentKeySet())
+
+	signingKeyPath := filepath.Join(t.TempDir(), "signing.pem")
+	assert.NoError(t, os.WriteFile(signingKeyPath, []byte("not a key"), 0600))
+	t.Setenv("JWT_SIGNING_KEY", signingKeyPath)
+
+	err := LoadKeySetFromEnv()
+	assert.Error(t, err, "Invalid key accepted")
+}
+
+func useKeySetForTest(t *testing.T, keySet *KeySet) {
+	previous := CurrentKeySet()
+	t.Cleanup(func() { UseKeySet(previous) })
+	UseKeySet(keySet)
+}
+
+func publicVerificationKey(key SigningKey) VerificationKey {
+	return VerificationKey{ID: key.ID, Method: key.Method, Key: publicKey(key.Key)}
+}
+
+func mustParsePrivateKey(t *testing.T, kid string, pemBytes []byte) SigningKey {
+	key, err := ParsePrivateKeyPEM(kid, pemBytes)
+	if err != nil {
+		t.Fatalf("Error parsing private key: %v", err)
+	}
+	return key
+}
+
+func generateRSAKeyPEM(t *testing.T) []byte {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Error generating RSA key: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+}
+
+func generateECKeyPEM(t *testing.T) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Error generating ECDSA key: %v", err)
+	}
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("Error encoding ECDSA key: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+}
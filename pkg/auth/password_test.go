@@ -0,0 +1,30 @@
+package auth
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestPassword(t *testing.T) {
+	t.Run("HashPassword", testHashPassword)
+	t.Run("CheckPassword", testCheckPassword)
+}
+
+func testHashPassword(t *testing.T) {
+	hash, err := HashPassword("password")
+	assert.NoError(t, err, "Failed to hash password")
+	assert.NotEqual(t, "password", hash, "Password is stored in plain text")
+
+	otherHash, err := HashPassword("password")
+	assert.NoError(t, err, "Failed to hash password")
+	assert.NotEqual(t, hash, otherHash, "Password hash is not salted")
+}
+
+func testCheckPassword(t *testing.T) {
+	hash, err := HashPassword("password")
+	assert.NoError(t, err, "Failed to hash password")
+
+	assert.True(t, CheckPassword(hash, "password"), "Valid password rejected")
+	assert.False(t, CheckPassword(hash, "invalid"), "Invalid password accepted")
+	assert.False(t, CheckPassword("not-a-hash", "password"), "Invalid hash accepted")
+}
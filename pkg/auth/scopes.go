@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"sort"
+	"strings"
+)
+
+const (
+	ScopeTasksRead  = "tasks:read"
+	ScopeTasksWrite = "tasks:write"
+	ScopeUsersAdmin = "users:admin"
+)
+
+const (
+	RoleAdmin    = "admin"
+	RoleMember   = "member"
+	RoleReadOnly = "read-only"
+)
+
+var roleScopes = map[string][]string{
+	RoleAdmin:    {ScopeTasksRead, ScopeTasksWrite, ScopeUsersAdmin},
+	RoleMember:   {ScopeTasksRead, ScopeTasksWrite},
+	RoleReadOnly: {ScopeTasksRead},
+}
+
+func IsValidRole(role string) bool {
+	_, ok := roleScopes[role]
+	return ok
+}
+
+func ScopesForRole(role string) []string {
+	return append([]string{}, roleScopes[role]...)
+}
+
+func HasScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// IntersectScopes returns the scopes present in both lists, sorted and
+// without duplicates.
+func IntersectScopes(scopes []string, allowed []string) []string {
+	seen := map[string]bool{}
+	result := []string{}
+	for _, scope := range scopes {
+		if HasScope(allowed, scope) && !seen[scope] {
+			seen[scope] = true
+			result = append(result, scope)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// FormatScopes and ParseScopes convert between a scope list and the space
+// separated form used in the "scope" claim.
+func FormatScopes(scopes []string) string {
+	return strings.Join(scopes, " ")
+}
+
+func ParseScopes(scope string) []string {
+	return strings.Fields(scope)
+}
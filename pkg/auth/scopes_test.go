@@ -0,0 +1,54 @@
+package auth
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestScopes(t *testing.T) {
+	t.Run("IsValidRole", testIsValidRole)
+	t.Run("ScopesForRole", testScopesForRole)
+	t.Run("HasScope", testHasScope)
+	t.Run("IntersectScopes", testIntersectScopes)
+	t.Run("FormatAndParseScopes", testFormatAndParseScopes)
+}
+
+func testIsValidRole(t *testing.T) {
+	assert.True(t, IsValidRole(RoleAdmin))
+	assert.True(t, IsValidRole(RoleMember))
+	assert.True(t, IsValidRole(RoleReadOnly))
+	assert.False(t, IsValidRole("owner"))
+}
+
+func testScopesForRole(t *testing.T) {
+	assert.Equal(t, []string{ScopeTasksRead, ScopeTasksWrite, ScopeUsersAdmin}, ScopesForRole(RoleAdmin))
+	assert.Equal(t, []string{ScopeTasksRead, ScopeTasksWrite}, ScopesForRole(RoleMember))
+	assert.Equal(t, []string{ScopeTasksRead}, ScopesForRole(RoleReadOnly))
+	assert.Empty(t, ScopesForRole("owner"))
+
+	scopes := ScopesForRole(RoleReadOnly)
+	scopes[0] = ScopeUsersAdmin
+	assert.Equal(t, []string{ScopeTasksRead}, ScopesForRole(RoleReadOnly), "Role scopes are mutable")
+}
+
+func testHasScope(t *testing.T) {
+	assert.True(t, HasScope([]string{ScopeTasksRead, ScopeTasksWrite}, ScopeTasksWrite))
+	assert.False(t, HasScope([]string{ScopeTasksRead}, ScopeTasksWrite))
+	assert.False(t, HasScope(nil, ScopeTasksRead))
+}
+
+func testIntersectScopes(t *testing.T) {
+	scopes := IntersectScopes(
+		[]string{ScopeTasksWrite, ScopeUsersAdmin, ScopeTasksRead, ScopeTasksWrite},
+		ScopesForRole(RoleMember),
+	)
+	assert.Equal(t, []string{ScopeTasksRead, ScopeTasksWrite}, scopes)
+	assert.Empty(t, IntersectScopes([]string{ScopeTasksWrite}, ScopesForRole(RoleReadOnly)))
+}
+
+func testFormatAndParseScopes(t *testing.T) {
+	scope := FormatScopes([]string{ScopeTasksRead, ScopeTasksWrite})
+	assert.Equal(t, "tasks:read tasks:write", scope)
+	assert.Equal(t, []string{ScopeTasksRead, ScopeTasksWrite}, ParseScopes(scope))
+	assert.Empty(t, ParseScopes(""))
+}
@@ -0,0 +1,32 @@
+package jsonpatch
+
+// MergePatch applies an RFC 7396 JSON Merge Patch to target. Both are
+// generic JSON values as produced by encoding/json. Objects in the patch are
+// merged member by member, null members remove the member from target and
+// every other value replaces target outright.
+func MergePatch(target interface{}, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = map[string]interface{}{}
+	}
+
+	result := make(map[string]interface{}, len(targetObject))
+	for name, value := range targetObject {
+		result[name] = value
+	}
+
+	for name, value := range patchObject {
+		if value == nil {
+			delete(result, name)
+			continue
+		}
+		result[name] = MergePatch(result[name], value)
+	}
+
+	return result
+}
@@ -0,0 +1,58 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestMergePatch(t *testing.T) {
+	t.Run("RFCExamples", testMergePatchRFCExamples)
+	t.Run("TargetUntouched", testMergePatchTargetUntouched)
+}
+
+func testMergePatchRFCExamples(t *testing.T) {
+	// Test cases from RFC 7396, Appendix A.
+	examples := []struct {
+		target string
+		patch  string
+		result string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+
+	for _, example := range examples {
+		result := MergePatch(decodeJSON(t, example.target), decodeJSON(t, example.patch))
+		assert.Equal(t, decodeJSON(t, example.result), result, "Merging %s into %s", example.patch, example.target)
+	}
+}
+
+func testMergePatchTargetUntouched(t *testing.T) {
+	target := decodeJSON(t, `{"a":"b","c":{"d":"e"}}`)
+
+	MergePatch(target, decodeJSON(t, `{"a":null,"c":{"d":"f"}}`))
+	assert.Equal(t, decodeJSON(t, `{"a":"b","c":{"d":"e"}}`), target)
+}
+
+func decodeJSON(t *testing.T, data string) interface{} {
+	var value interface{}
+	err := json.Unmarshal([]byte(data), &value)
+	if err != nil {
+		t.Fatalf("Error decoding %s: %v", data, err)
+	}
+	return value
+}
@@ -0,0 +1,304 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidPatch = errors.New("invalid json patch")
+	ErrPathNotFound = errors.New("json patch path not found")
+	ErrTestFailed   = errors.New("json patch test failed")
+)
+
+// Operation is a single RFC 6902 JSON Patch operation.
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// Decode parses a JSON Patch document and checks that every operation is
+// complete. It does not look at the document the patch applies to.
+func Decode(data []byte) ([]Operation, error) {
+	var operations []Operation
+	err := json.Unmarshal(data, &operations)
+	if err != nil {
+		return nil, ErrInvalidPatch
+	}
+
+	for _, operation := range operations {
+		switch operation.Op {
+		case "add", "replace", "test":
+			if len(operation.Value) == 0 {
+				return nil, ErrInvalidPatch
+			}
+		case "move", "copy":
+			if _, err := parsePointer(operation.From); err != nil {
+				return nil, err
+			}
+		case "remove":
+		default:
+			return nil, ErrInvalidPatch
+		}
+
+		if _, err := parsePointer(operation.Path); err != nil {
+			return nil, err
+		}
+	}
+
+	return operations, nil
+}
+
+// Apply runs the operations against a copy of doc, a generic JSON value as
+// produced by encoding/json. The operations apply atomically: when one of
+// them fails, the error is returned and doc is left untouched.
+func Apply(doc interface{}, operations []Operation) (interface{}, error) {
+	doc, err := deepCopy(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, operation := range operations {
+		doc, err = apply(doc, operation)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return doc, nil
+}
+
+func apply(doc interface{}, operation Operation) (interface{}, error) {
+	path, err := parsePointer(operation.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	var value interface{}
+	if len(operation.Value) > 0 {
+		err = json.Unmarshal(operation.Value, &value)
+		if err != nil {
+			return nil, ErrInvalidPatch
+		}
+	}
+
+	switch operation.Op {
+	case "add":
+		return add(doc, path, value)
+	case "remove":
+		doc, _, err = remove(doc, path)
+		return doc, err
+	case "replace":
+		if len(path) == 0 {
+			return value, nil
+		}
+		doc, _, err = remove(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, path, value)
+	case "test":
+		current, err := get(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(current, value) {
+			return nil, ErrTestFailed
+		}
+		return doc, nil
+	case "move", "copy":
+		from, err := parsePointer(operation.From)
+		if err != nil {
+			return nil, err
+		}
+		if operation.Op == "move" {
+			if isPrefix(from, path) && len(from) < len(path) {
+				return nil, ErrInvalidPatch
+			}
+			doc, value, err = remove(doc, from)
+		} else {
+			value, err = get(doc, from)
+			if err == nil {
+				value, err = deepCopy(value)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, path, value)
+	}
+
+	return nil, ErrInvalidPatch
+}
+
+func get(doc interface{}, path []string) (interface{}, error) {
+	for _, token := range path {
+		switch node := doc.(type) {
+		case map[string]interface{}:
+			value, ok := node[token]
+			if !ok {
+				return nil, ErrPathNotFound
+			}
+			doc = value
+		case []interface{}:
+			index, err := arrayIndex(token, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+			doc = node[index]
+		default:
+			return nil, ErrPathNotFound
+		}
+	}
+	return doc, nil
+}
+
+func add(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	token, rest := path[0], path[1:]
+	switch node := doc.(type) {
+	case map[string]interface{}:
+		if len(rest) == 0 {
+			node[token] = value
+			return node, nil
+		}
+		child, ok := node[token]
+		if !ok {
+			return nil, ErrPathNotFound
+		}
+		child, err := add(child, rest, value)
+		if err != nil {
+			return nil, err
+		}
+		node[token] = child
+		return node, nil
+	case []interface{}:
+		if len(rest) == 0 {
+			index := len(node)
+			if token != "-" {
+				var err error
+				index, err = arrayIndex(token, len(node))
+				if err != nil {
+					return nil, err
+				}
+			}
+			node = append(node, nil)
+			copy(node[index+1:], node[index:])
+			node[index] = value
+			return node, nil
+		}
+		index, err := arrayIndex(token, len(node)-1)
+		if err != nil {
+			return nil, err
+		}
+		child, err := add(node[index], rest, value)
+		if err != nil {
+			return nil, err
+		}
+		node[index] = child
+		return node, nil
+	}
+
+	return nil, ErrPathNotFound
+}
+
+// remove returns the document without the value at path, and that value.
+func remove(doc interface{}, path []string) (interface{}, interface{}, error) {
+	if len(path) == 0 {
+		return nil, nil, ErrInvalidPatch
+	}
+
+	token, rest := path[0], path[1:]
+	switch node := doc.(type) {
+	case map[string]interface{}:
+		child, ok := node[token]
+		if !ok {
+			return nil, nil, ErrPathNotFound
+		}
+		if len(rest) == 0 {
+			delete(node, token)
+			return node, child, nil
+		}
+		child, removed, err := remove(child, rest)
+		if err != nil {
+			return nil, nil, err
+		}
+		node[token] = child
+		return node, removed, nil
+	case []interface{}:
+		index, err := arrayIndex(token, len(node)-1)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(rest) == 0 {
+			removed := node[index]
+			return append(node[:index], node[index+1:]...), removed, nil
+		}
+		child, removed, err := remove(node[index], rest)
+		if err != nil {
+			return nil, nil, err
+		}
+		node[index] = child
+		return node, removed, nil
+	}
+
+	return nil, nil, ErrPathNotFound
+}
+
+// parsePointer splits an RFC 6901 JSON Pointer into its unescaped tokens.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, ErrInvalidPatch
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+	}
+	return tokens, nil
+}
+
+func arrayIndex(token string, max int) (int, error) {
+	if token == "" || (len(token) > 1 && token[0] == '0') || strings.Trim(token, "0123456789") != "" {
+		return 0, ErrPathNotFound
+	}
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || index > max {
+		return 0, ErrPathNotFound
+	}
+	return index, nil
+}
+
+func isPrefix(prefix []string, path []string) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+	for i := range prefix {
+		if prefix[i] != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func deepCopy(value interface{}) (interface{}, error) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var copied interface{}
+	err = json.Unmarshal(encoded, &copied)
+	return copied, err
+}
@@ -0,0 +1,105 @@
+package jsonpatch
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestPatch(t *testing.T) {
+	t.Run("Decode", testDecode)
+	t.Run("DecodeInvalid", testDecodeInvalid)
+	t.Run("Apply", testApply)
+	t.Run("ApplyErrors", testApplyErrors)
+	t.Run("ApplyIsAtomic", testApplyIsAtomic)
+}
+
+func testDecode(t *testing.T) {
+	operations, err := Decode([]byte(`[
+		{"op": "test", "path": "/a", "value": null},
+		{"op": "remove", "path": "/a"},
+		{"op": "move", "from": "/b", "path": "/c"}
+	]`))
+	assert.NoError(t, err)
+	assert.Len(t, operations, 3)
+	assert.Equal(t, "null", string(operations[0].Value))
+	assert.Equal(t, "/b", operations[2].From)
+}
+
+func testDecodeInvalid(t *testing.T) {
+	invalidPatches := []string{
+		`{"op": "remove", "path": "/a"}`,
+		`[{"op": "delete", "path": "/a"}]`,
+		`[{"op": "add", "path": "/a"}]`,
+		`[{"op": "replace", "path": "a", "value": 1}]`,
+		`[{"op": "copy", "from": "b", "path": "/a"}]`,
+	}
+
+	for _, patch := range invalidPatches {
+		_, err := Decode([]byte(patch))
+		assert.Equal(t, ErrInvalidPatch, err, "Decoded %s", patch)
+	}
+}
+
+func testApply(t *testing.T) {
+	examples := []struct {
+		doc    string
+		patch  string
+		result string
+	}{
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`, `{"baz":"qux","foo":"bar"}`},
+		{`{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`},
+		{`{"foo":["bar"]}`, `[{"op":"add","path":"/foo/-","value":"baz"}]`, `{"foo":["bar","baz"]}`},
+		{`{"baz":"qux","foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, `{"foo":"bar"}`},
+		{`{"foo":["bar","qux","baz"]}`, `[{"op":"remove","path":"/foo/1"}]`, `{"foo":["bar","baz"]}`},
+		{`{"baz":"qux","foo":"bar"}`, `[{"op":"replace","path":"/baz","value":"boo"}]`, `{"baz":"boo","foo":"bar"}`},
+		{`{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`, `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`, `{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`},
+		{`{"foo":["a","b"]}`, `[{"op":"copy","from":"/foo/0","path":"/foo/-"}]`, `{"foo":["a","b","a"]}`},
+		{`{"a/b":1,"m~n":2}`, `[{"op":"test","path":"/a~1b","value":1},{"op":"replace","path":"/m~0n","value":3}]`, `{"a/b":1,"m~n":3}`},
+		{`{"foo":"bar"}`, `[{"op":"test","path":"/foo","value":"bar"},{"op":"remove","path":"/foo"}]`, `{}`},
+		{`{"foo":"bar"}`, `[{"op":"replace","path":"","value":[1]}]`, `[1]`},
+	}
+
+	for _, example := range examples {
+		operations, err := Decode([]byte(example.patch))
+		assert.NoError(t, err)
+
+		result, err := Apply(decodeJSON(t, example.doc), operations)
+		assert.NoError(t, err, "Applying %s", example.patch)
+		assert.Equal(t, decodeJSON(t, example.result), result, "Applying %s", example.patch)
+	}
+}
+
+func testApplyErrors(t *testing.T) {
+	examples := []struct {
+		patch string
+		err   error
+	}{
+		{`[{"op":"test","path":"/foo","value":"baz"}]`, ErrTestFailed},
+		{`[{"op":"test","path":"/list/0","value":"1"}]`, ErrTestFailed},
+		{`[{"op":"remove","path":"/missing"}]`, ErrPathNotFound},
+		{`[{"op":"replace","path":"/missing","value":1}]`, ErrPathNotFound},
+		{`[{"op":"add","path":"/missing/child","value":1}]`, ErrPathNotFound},
+		{`[{"op":"add","path":"/list/3","value":1}]`, ErrPathNotFound},
+		{`[{"op":"remove","path":"/list/01"}]`, ErrPathNotFound},
+		{`[{"op":"remove","path":"/list/-"}]`, ErrPathNotFound},
+		{`[{"op":"move","from":"/list","path":"/list/0"}]`, ErrInvalidPatch},
+	}
+
+	for _, example := range examples {
+		operations, err := Decode([]byte(example.patch))
+		assert.NoError(t, err)
+
+		_, err = Apply(decodeJSON(t, `{"foo":"bar","list":[1,2]}`), operations)
+		assert.Equal(t, example.err, err, "Applying %s", example.patch)
+	}
+}
+
+func testApplyIsAtomic(t *testing.T) {
+	doc := decodeJSON(t, `{"foo":"bar"}`)
+	operations, err := Decode([]byte(`[{"op":"remove","path":"/foo"},{"op":"test","path":"/foo","value":"bar"}]`))
+	assert.NoError(t, err)
+
+	_, err = Apply(doc, operations)
+	assert.Equal(t, ErrPathNotFound, err)
+	assert.Equal(t, decodeJSON(t, `{"foo":"bar"}`), doc)
+}
@@ -3,6 +3,9 @@ package testutils
 import (
 	"database/sql"
 	"fmt"
+	"github.com/absoluteyl/tasks-go/internal/dialect"
+	"github.com/absoluteyl/tasks-go/internal/migration"
+	_ "github.com/lib/pq"
 	"os"
 )
 
@@ -11,7 +14,7 @@ var dbPath = "test.db"
 
 func ConnectDB() (*sql.DB, error) {
 	var err error
-	db, err := sql.Open(dbDriver, dbPath)
+	db, err := sql.Open(dbDriver, dialect.SQLiteDSN(dbPath))
 	if err != nil {
 		return nil, fmt.Errorf("Error connecting to test database: %v", err)
 	}
@@ -19,16 +22,15 @@ func ConnectDB() (*sql.DB, error) {
 	return db, nil
 }
 
-func PrepareTaskTable(db *sql.DB) error {
-	createTableSQL := `
-	CREATE TABLE IF NOT EXISTS tasks (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		name TEXT,
-		status INTEGER DEFAULT 0
-	);`
-	_, err := db.Exec(createTableSQL)
+func MigrateDB(db *sql.DB) error {
+	migrator, err := migration.NewMigrator(db)
 	if err != nil {
-		return fmt.Errorf("Error creating tasks table: %v", err)
+		return fmt.Errorf("Error loading migrations: %v", err)
+	}
+
+	_, err = migrator.Up()
+	if err != nil {
+		return fmt.Errorf("Error migrating test database: %v", err)
 	}
 
 	return nil
@@ -42,3 +44,38 @@ func RemoveDB() error {
 
 	return nil
 }
+
+// ConnectPostgresDB connects to the database named by TEST_POSTGRES_DSN, e.g.
+// a local or embedded server. It returns nil when the variable is unset, so
+// that Postgres tests can be skipped.
+func ConnectPostgresDB() (*sql.DB, error) {
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		return nil, nil
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("Error connecting to Postgres test database: %v", err)
+	}
+
+	return db, nil
+}
+
+// ResetDB reverts every migration, which leaves a server database empty.
+func ResetDB(db *sql.DB) error {
+	migrator, err := migration.NewMigrator(db)
+	if err != nil {
+		return fmt.Errorf("Error loading migrations: %v", err)
+	}
+
+	for {
+		reverted, err := migrator.Down()
+		if err != nil {
+			return fmt.Errorf("Error resetting test database: %v", err)
+		}
+		if reverted == nil {
+			return nil
+		}
+	}
+}
@@ -2,6 +2,7 @@ package testutils
 
 import (
 	"encoding/json"
+	"github.com/absoluteyl/tasks-go/pkg/auth"
 	"github.com/stretchr/testify/assert"
 	"net/http"
 	"net/http/httptest"
@@ -12,6 +13,14 @@ func SetupAuthorizationHeader(req *http.Request, authStr string) {
 	req.Header.Add("Authorization", authStr)
 }
 
+func WithPrincipal(req *http.Request, userID int) *http.Request {
+	ctx := auth.NewContext(req.Context(), auth.Principal{
+		UserID: userID,
+		Scopes: auth.ScopesForRole(auth.RoleMember),
+	})
+	return req.WithContext(ctx)
+}
+
 func PrepareJsonBody(t *testing.T, data map[string]interface{}) []byte {
 	body, err := json.Marshal(data)
 	if err != nil {